@@ -6,6 +6,8 @@ import (
 	"os"
 	"time"
 
+	"go-admin/models"
+
 	"github.com/joho/godotenv"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -22,6 +24,8 @@ func ConnectDB() {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_NAME"))
 	database, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
+		// data lama belum tentu konsisten, jangan paksa foreign key saat migrasi
+		DisableForeignKeyConstraintWhenMigrating: true,
 	})
 	if err != nil {
 		log.Fatal("Gagal koneksi database:", err)
@@ -47,4 +51,46 @@ func ConnectDB() {
 	// 	&models.Kadarkum{},
 	// 	&models.User{},
 	// )
+
+	// AutoMigrate untuk tabel/kolom baru saja, struktur lama tetap dari admingo.sql
+	if err := DB.AutoMigrate(
+		&models.User{},
+		&models.Pengaturan{},
+		&models.Posbankum{},
+		&models.Paralegal{},
+		&models.Pja{},
+		&models.Kadarkum{},
+		&models.AksesDokumen{},
+		&models.BatasAksesDokumen{},
+		&models.KategoriLampiran{},
+		&models.Lampiran{},
+		&models.DokumenTeks{},
+		&models.OCRJob{},
+		&models.Karantina{},
+		&models.TandaTanganDokumen{},
+		&models.UploadResumable{},
+		&models.BlobDokumen{},
+		&models.RiwayatVerifikasi{},
+		&models.EmailOutbox{},
+		&models.PreferensiEmail{},
+		&models.Notifikasi{},
+		&models.Komentar{},
+		&models.Pelatihan{},
+		&models.PesertaPelatihan{},
+		&models.SertifikatParalegal{},
+		&models.Konsultasi{},
+		&models.TindakLanjutKonsultasi{},
+		&models.Mediasi{},
+		&models.PeriodePJA{},
+		&models.KriteriaPJA{},
+		&models.JuriPJA{},
+		&models.NominasiPJA{},
+		&models.NilaiPJA{},
+		&models.KuesionerKadarkum{},
+		&models.IndikatorKadarkum{},
+		&models.PenilaianKadarkum{},
+		&models.JawabanKadarkum{},
+	); err != nil {
+		log.Fatal("Gagal migrasi database:", err)
+	}
 }
@@ -3,14 +3,16 @@ package controllers
 import (
 	"fmt"
 	"log"
-	"mime"
 	"net/http"
-	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"go-admin/config"
 	"go-admin/models"
+	"go-admin/utils"
 
+	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"github.com/jung-kurt/gofpdf"
 )
@@ -19,9 +21,10 @@ import (
 
 // Struktur untuk menampung nama dan dokumen Paralegal
 type ParalegalData struct {
-	ID      uint // ID diperlukan untuk link dokumen di template
-	Nama    string
-	Dokumen string
+	ID        uint // ID diperlukan untuk link dokumen di template
+	Nama      string
+	Dokumen   string
+	Lampirans []models.Lampiran
 }
 
 // Struktur untuk data Paralegal per kelurahan
@@ -90,11 +93,20 @@ type DashboardData struct {
 	TotalKadarkumProvinsi   int
 	TotalPjaProvinsi        int
 	TotalParalegalProvinsi  int
+	DemografiParalegal      DemografiParalegal // sebaran jenis kelamin & umur untuk tab Paralegal
+	SertifikasiParalegal    []RekapSertifikasi // paralegal yang sudah/belum bersertifikat dasar per kabupaten/kota
+	Konsultasi              []RekapKonsultasi  // konsultasi hukum per bulan per kabupaten/kota, tahun berjalan
+	TahunKonsultasi         int
+	PenilaianKadarkum       []RekapPenilaianKadarkum // sebaran nilai penilaian Kadarkum per kabupaten/kota, tahun penilaian terakhir
+	TotalPenilaianKadarkum  RekapPenilaianKadarkum
+	TahunPenilaianKadarkum  int
+	NamaBulan               []string
 	TotalKelurahanProvinsi  int
 	PersenPosbankumProvinsi float64
 	PersenKadarkumProvinsi  float64
 	PersenPjaProvinsi       float64
 	AllKabupatens           []models.Kabupaten // Data untuk list checkbox wilayah
+	FormZIP                 FormZIP            // Pilihan program + wilayah untuk unduh ZIP dokumen
 	BaseHref                string
 }
 
@@ -109,6 +121,8 @@ func hitungPersen(tercapai, total int) float64 {
 // ==================== CONTROLLER ====================
 
 func UserDashboard(c *gin.Context) {
+	skBerlaku := filterSKBerlaku()         // SK kedaluwarsa bisa dikecualikan dari hitungan (lihat Pengaturan)
+	terverifikasi := filterTerverifikasi() // hanya data yang disetujui verifikator Kanwil (lihat Pengaturan)
 	var provinsi models.Provinsi
 
 	if err := config.DB.Preload("Kabupatens.Kecamatans.Kelurahans").First(&provinsi).Error; err != nil {
@@ -142,14 +156,15 @@ func UserDashboard(c *gin.Context) {
 			// ================== POSBANKUM ==================
 			var totalPos, tercapaiPos int64
 			config.DB.Model(&models.Kelurahan{}).Where("kecamatan_id = ?", kec.ID).Count(&totalPos)
-			config.DB.Model(&models.Posbankum{}).
+			config.DB.Model(&models.Posbankum{}).Scopes(skBerlaku("posbankums"), terverifikasi("posbankums")).
 				Joins("JOIN kelurahans ON kelurahans.id = posbankums.kelurahan_id").
 				Where("kelurahans.kecamatan_id = ?", kec.ID).Count(&tercapaiPos)
 
 			var kelurahanDocsPos []KelurahanDokumen
 			for _, kel := range kec.Kelurahans {
 				var posbankums []models.Posbankum
-				config.DB.Where("kelurahan_id = ?", kel.ID).Find(&posbankums)
+				config.DB.Preload("Lampirans", urutLampiran).Preload("Lampirans.Kategori").
+					Scopes(skBerlaku("posbankums"), terverifikasi("posbankums")).Where("kelurahan_id = ?", kel.ID).Find(&posbankums)
 
 				tercapai := 0
 				if len(posbankums) > 0 {
@@ -178,14 +193,15 @@ func UserDashboard(c *gin.Context) {
 			// ================== KADARKUM ==================
 			var totalK, tercapaiK int64
 			config.DB.Model(&models.Kelurahan{}).Where("kecamatan_id = ?", kec.ID).Count(&totalK)
-			config.DB.Model(&models.Kadarkum{}).
+			config.DB.Model(&models.Kadarkum{}).Scopes(skBerlaku("kadarkums"), terverifikasi("kadarkums")).
 				Joins("JOIN kelurahans ON kelurahans.id = kadarkums.kelurahan_id").
 				Where("kelurahans.kecamatan_id = ?", kec.ID).Count(&tercapaiK)
 
 			var kelurahanDocsKadarkum []KelurahanDokumen
 			for _, kel := range kec.Kelurahans {
 				var kadarkums []models.Kadarkum
-				config.DB.Where("kelurahan_id = ?", kel.ID).Find(&kadarkums)
+				config.DB.Preload("Lampirans", urutLampiran).Preload("Lampirans.Kategori").
+					Scopes(skBerlaku("kadarkums"), terverifikasi("kadarkums")).Where("kelurahan_id = ?", kel.ID).Find(&kadarkums)
 				tercapai := 0
 				if len(kadarkums) > 0 {
 					tercapai = 1
@@ -212,14 +228,15 @@ func UserDashboard(c *gin.Context) {
 			// ================== PJA ==================
 			var totalP, tercapaiP int64
 			config.DB.Model(&models.Kelurahan{}).Where("kecamatan_id = ?", kec.ID).Count(&totalP)
-			config.DB.Model(&models.Pja{}).
+			config.DB.Model(&models.Pja{}).Scopes(skBerlaku("pjas"), terverifikasi("pjas")).
 				Joins("JOIN kelurahans ON kelurahans.id = pjas.kelurahan_id").
 				Where("kelurahans.kecamatan_id = ?", kec.ID).Count(&tercapaiP)
 
 			var kelurahanDocsPja []KelurahanDokumen
 			for _, kel := range kec.Kelurahans {
 				var pjas []models.Pja
-				config.DB.Where("kelurahan_id = ?", kel.ID).Find(&pjas)
+				config.DB.Preload("Lampirans", urutLampiran).Preload("Lampirans.Kategori").
+					Scopes(skBerlaku("pjas"), terverifikasi("pjas")).Where("kelurahan_id = ?", kel.ID).Find(&pjas)
 				tercapai := 0
 				if len(pjas) > 0 {
 					tercapai = 1
@@ -250,16 +267,19 @@ func UserDashboard(c *gin.Context) {
 			for _, kel := range kec.Kelurahans {
 				var paralegalsFromDB []models.Paralegal
 				config.DB.Model(&models.Paralegal{}).
+					Preload("Lampirans", urutLampiran).Preload("Lampirans.Kategori").
 					Joins("JOIN posbankums ON posbankums.id = paralegals.posbankum_id").
 					Where("posbankums.kelurahan_id = ?", kel.ID).
+					Scopes(terverifikasi("paralegals"), terverifikasi("posbankums")).
 					Find(&paralegalsFromDB)
 
 				var paralegalDataForKelurahan []ParalegalData
 				for _, p := range paralegalsFromDB {
 					paralegalDataForKelurahan = append(paralegalDataForKelurahan, ParalegalData{
-						ID:      p.ID, // Kirim ID ke template
-						Nama:    p.Nama,
-						Dokumen: p.Dokumen,
+						ID:        p.ID, // Kirim ID ke template
+						Nama:      p.Nama,
+						Dokumen:   p.Dokumen,
+						Lampirans: p.Lampirans,
 					})
 				}
 
@@ -322,6 +342,13 @@ func UserDashboard(c *gin.Context) {
 		totalParalegalProv += totalParalegalKab
 	}
 
+	// sebaran nilai penilaian Kadarkum (kosong kalau belum pernah ada penilaian)
+	var penilaianKadarkum []RekapPenilaianKadarkum
+	tahunPenilaian, adaPenilaian := tahunPenilaianKadarkumTerakhir()
+	if adaPenilaian {
+		penilaianKadarkum = rekapPenilaianKadarkum(provinsi.Kabupatens, tahunPenilaian, terverifikasi("kadarkums"))
+	}
+
 	data := DashboardData{
 		Title:                   "Dashboard User",
 		Provinsi:                provinsi.Name,
@@ -333,21 +360,33 @@ func UserDashboard(c *gin.Context) {
 		TotalKadarkumProvinsi:   tercapaiKadProv,
 		TotalPjaProvinsi:        tercapaiPJAProv,
 		TotalParalegalProvinsi:  totalParalegalProv,
+		DemografiParalegal:      demografiParalegal(terverifikasi("paralegals")),
+		SertifikasiParalegal:    rekapSertifikasiParalegal(provinsi.Kabupatens, terverifikasi("paralegals"), terverifikasi("posbankums")),
+		Konsultasi:              rekapKonsultasi(provinsi.Kabupatens, time.Now().Year(), terverifikasi("posbankums")),
+		TahunKonsultasi:         time.Now().Year(),
+		PenilaianKadarkum:       penilaianKadarkum,
+		TotalPenilaianKadarkum:  totalRekapKadarkum(penilaianKadarkum),
+		TahunPenilaianKadarkum:  tahunPenilaian,
+		NamaBulan:               namaBulan,
 		TotalKelurahanProvinsi:  totalKelurahanProv,
 		PersenPosbankumProvinsi: hitungPersen(tercapaiPosProv, totalKelurahanProv),
 		PersenKadarkumProvinsi:  hitungPersen(tercapaiKadProv, totalKelurahanProv),
 		PersenPjaProvinsi:       hitungPersen(tercapaiPJAProv, totalKelurahanProv),
 		AllKabupatens:           provinsi.Kabupatens, // Kirim data semua kabupaten
+		FormZIP:                 FormZIP{Action: "/user/unduh-zip", Kabupatens: provinsi.Kabupatens},
 	}
 
 	c.HTML(http.StatusOK, "user_dashboard.html", data)
 }
 
-// ViewDocument adalah handler universal untuk menampilkan dokumen
+// ViewDocument adalah handler universal untuk menampilkan dokumen.
+// Bisa diakses tanpa login kalau link-nya bertanda tangan (masih berlaku) atau dokumennya publik.
 func ViewDocument(c *gin.Context) {
 	docType := c.Param("type")
 	id := c.Param("id")
 	var filePath string
+	var entitasID uint
+	var publik bool
 
 	switch docType {
 	case "posbankum":
@@ -357,6 +396,8 @@ func ViewDocument(c *gin.Context) {
 			return
 		}
 		filePath = data.Dokumen
+		entitasID = data.ID
+		publik = data.DokumenPublik && data.StatusVerifikasi == models.StatusTerverifikasi
 	case "paralegal":
 		var data models.Paralegal
 		if err := config.DB.First(&data, id).Error; err != nil {
@@ -364,6 +405,8 @@ func ViewDocument(c *gin.Context) {
 			return
 		}
 		filePath = data.Dokumen
+		entitasID = data.ID
+		publik = data.DokumenPublik && data.StatusVerifikasi == models.StatusTerverifikasi
 	case "pja":
 		var data models.Pja
 		if err := config.DB.First(&data, id).Error; err != nil {
@@ -371,6 +414,8 @@ func ViewDocument(c *gin.Context) {
 			return
 		}
 		filePath = data.Dokumen
+		entitasID = data.ID
+		publik = data.DokumenPublik && data.StatusVerifikasi == models.StatusTerverifikasi
 	case "kadarkum":
 		var data models.Kadarkum
 		if err := config.DB.First(&data, id).Error; err != nil {
@@ -378,27 +423,46 @@ func ViewDocument(c *gin.Context) {
 			return
 		}
 		filePath = data.Dokumen
+		entitasID = data.ID
+		publik = data.DokumenPublik && data.StatusVerifikasi == models.StatusTerverifikasi
+	case "lampiran":
+		// lampiran tidak pernah publik, hanya untuk user login atau link bertanda tangan
+		var data models.Lampiran
+		if err := config.DB.First(&data, id).Error; err != nil {
+			c.String(http.StatusNotFound, "Lampiran tidak ditemukan")
+			return
+		}
+		filePath = data.Path
+		entitasID = data.ID
+	case "komentar":
+		// lampiran diskusi sama seperti lampiran record: tidak pernah publik
+		var data models.Komentar
+		if err := config.DB.First(&data, id).Error; err != nil || data.LampiranPath == "" {
+			c.String(http.StatusNotFound, "Lampiran komentar tidak ditemukan")
+			return
+		}
+		filePath = data.LampiranPath
+		entitasID = data.ID
 	default:
 		c.String(http.StatusBadRequest, "Tipe dokumen tidak valid")
 		return
 	}
 
-	if filePath == "" {
-		c.String(http.StatusNotFound, "Path dokumen kosong atau tidak tersedia.")
+	// akses: sudah login, link bertanda tangan yang masih berlaku, atau dokumen publik (yang sudah terverifikasi)
+	if sessions.Default(c).Get("user") == nil && !publik &&
+		!utils.VerifikasiLinkDokumen(docType, id, c.Query("exp"), c.Query("sig")) {
+		c.Redirect(http.StatusFound, "/login")
 		return
 	}
 
-	fileName := filepath.Base(filePath)
-	contentType := mime.TypeByExtension(filepath.Ext(fileName))
-	if contentType == "" {
-		contentType = "application/octet-stream"
+	if tampilkanDenganTTD(c, docType, entitasID, filePath) {
+		return
 	}
-
-	c.Header("Content-Disposition", "inline; filename="+fileName)
-	c.Header("Content-Type", contentType)
-	c.File(filePath)
+	kirimDokumen(c, docType, entitasID, filePath)
 }
 func CetakPDF(c *gin.Context) {
+	skBerlaku := filterSKBerlaku()         // SK kedaluwarsa bisa dikecualikan dari hitungan (lihat Pengaturan)
+	terverifikasi := filterTerverifikasi() // hanya data yang disetujui verifikator Kanwil (lihat Pengaturan)
 	kategoriTerpilih := c.PostFormArray("kategori")
 	wilayahTerpilih := c.PostFormArray("wilayah")
 
@@ -419,6 +483,9 @@ func CetakPDF(c *gin.Context) {
 	summaries := make(map[string][]KabupatenSummary)
 
 	for _, kategori := range kategoriTerpilih {
+		if kategori == "sertifikasi" || kategori == "konsultasi" {
+			continue // laporan sertifikasi paralegal dan konsultasi punya tabel sendiri (lihat di bawah)
+		}
 		hasil := []KabupatenSummary{}
 
 		for _, kab := range provinsi.Kabupatens {
@@ -449,19 +516,19 @@ func CetakPDF(c *gin.Context) {
 					switch kategori {
 					case "posbankum":
 						var pos []models.Posbankum
-						config.DB.Where("kelurahan_id = ?", kel.ID).Find(&pos)
+						config.DB.Scopes(skBerlaku("posbankums"), terverifikasi("posbankums")).Where("kelurahan_id = ?", kel.ID).Find(&pos)
 						if len(pos) > 0 {
 							tercapai = 1
 						}
 					case "kadarkum":
 						var kad []models.Kadarkum
-						config.DB.Where("kelurahan_id = ?", kel.ID).Find(&kad)
+						config.DB.Scopes(skBerlaku("kadarkums"), terverifikasi("kadarkums")).Where("kelurahan_id = ?", kel.ID).Find(&kad)
 						if len(kad) > 0 {
 							tercapai = 1
 						}
 					case "pja":
 						var pjas []models.Pja
-						config.DB.Where("kelurahan_id = ?", kel.ID).Find(&pjas)
+						config.DB.Scopes(skBerlaku("pjas"), terverifikasi("pjas")).Where("kelurahan_id = ?", kel.ID).Find(&pjas)
 						if len(pjas) > 0 {
 							tercapai = 1
 						}
@@ -470,6 +537,7 @@ func CetakPDF(c *gin.Context) {
 						config.DB.Table("paralegals").
 							Joins("JOIN posbankums ON posbankums.id = paralegals.posbankum_id").
 							Where("posbankums.kelurahan_id = ?", kel.ID).
+							Scopes(terverifikasi("paralegals"), terverifikasi("posbankums")).
 							Count(&paralegalCount)
 						if paralegalCount > 0 {
 							tercapai = 1
@@ -523,6 +591,9 @@ func CetakPDF(c *gin.Context) {
 
 	// Loop kategori
 	for _, kategori := range kategoriTerpilih {
+		if kategori == "sertifikasi" || kategori == "konsultasi" {
+			continue
+		}
 		k := strings.ToLower(kategori)
 		dataKab := summaries[k]
 
@@ -573,6 +644,83 @@ func CetakPDF(c *gin.Context) {
 		pdf.Ln(8)
 	}
 
+	// ======================= Sertifikasi Paralegal =======================
+	if slices.Contains(kategoriTerpilih, "sertifikasi") {
+		var kabupatens []models.Kabupaten
+		for _, kab := range provinsi.Kabupatens {
+			if slices.Contains(wilayahTerpilih, kab.Name) {
+				kabupatens = append(kabupatens, kab)
+			}
+		}
+
+		pdf.SetFont("Arial", "B", 12)
+		pdf.Cell(0, 8, "SERTIFIKASI PARALEGAL (PELATIHAN DASAR)")
+		pdf.Ln(10)
+
+		pdf.SetFont("Arial", "B", 10)
+		pdf.CellFormat(80, 7, "Kabupaten/Kota", "1", 0, "", false, 0, "")
+		pdf.CellFormat(40, 7, "Bersertifikat", "1", 0, "C", false, 0, "")
+		pdf.CellFormat(30, 7, "Persentase", "1", 0, "C", false, 0, "")
+		pdf.CellFormat(40, 7, "Belum", "1", 1, "C", false, 0, "")
+
+		for _, r := range rekapSertifikasiParalegal(kabupatens, terverifikasi("paralegals"), terverifikasi("posbankums")) {
+			pdf.SetFont("Arial", "B", 10)
+			pdf.CellFormat(80, 7, r.NamaKabupaten, "1", 0, "", false, 0, "")
+			pdf.CellFormat(40, 7, fmt.Sprintf("%d/%d", r.Bersertifikat, r.Total), "1", 0, "C", false, 0, "")
+			pdf.CellFormat(30, 7, fmt.Sprintf("%.2f%%", r.Persentase()), "1", 0, "C", false, 0, "")
+			pdf.CellFormat(40, 7, fmt.Sprintf("%d", r.Belum()), "1", 1, "C", false, 0, "")
+
+			// daftar paralegal yang belum bersertifikat dasar
+			for _, p := range r.TanpaSertifikat {
+				pdf.SetFont("Arial", "", 9)
+				pdf.CellFormat(80, 7, "      "+p.Nama, "1", 0, "", false, 0, "")
+				pdf.CellFormat(110, 7, fmt.Sprintf("Kel/Desa %s, Kec. %s", p.NamaKelurahan, p.NamaKecamatan), "1", 1, "", false, 0, "")
+			}
+		}
+		pdf.Ln(8)
+	}
+
+	// ======================= Konsultasi Hukum =======================
+	if slices.Contains(kategoriTerpilih, "konsultasi") {
+		var kabupatens []models.Kabupaten
+		for _, kab := range provinsi.Kabupatens {
+			if slices.Contains(wilayahTerpilih, kab.Name) {
+				kabupatens = append(kabupatens, kab)
+			}
+		}
+		tahun := time.Now().Year()
+
+		pdf.SetFont("Arial", "B", 12)
+		pdf.Cell(0, 8, fmt.Sprintf("KONSULTASI HUKUM DI POSBANKUM TAHUN %d", tahun))
+		pdf.Ln(10)
+
+		// 12 kolom bulan + total + dirujuk, muat di A4 portrait
+		barisKonsultasi := func(nama string, r RekapKonsultasi, gaya string) {
+			pdf.SetFont("Arial", gaya, 8)
+			pdf.CellFormat(58, 6, nama, "1", 0, "", false, 0, "")
+			for _, n := range r.Bulan {
+				pdf.CellFormat(9, 6, fmt.Sprintf("%d", n), "1", 0, "C", false, 0, "")
+			}
+			pdf.CellFormat(12, 6, fmt.Sprintf("%d", r.Total), "1", 0, "C", false, 0, "")
+			pdf.CellFormat(12, 6, fmt.Sprintf("%d", r.Dirujuk), "1", 1, "C", false, 0, "")
+		}
+		pdf.SetFont("Arial", "B", 8)
+		pdf.CellFormat(58, 7, "Kabupaten/Kota / Posbankum", "1", 0, "", false, 0, "")
+		for _, b := range namaBulan {
+			pdf.CellFormat(9, 7, b, "1", 0, "C", false, 0, "")
+		}
+		pdf.CellFormat(12, 7, "Total", "1", 0, "C", false, 0, "")
+		pdf.CellFormat(12, 7, "Rujuk", "1", 1, "C", false, 0, "")
+
+		for _, r := range rekapKonsultasi(kabupatens, tahun, terverifikasi("posbankums")) {
+			barisKonsultasi(r.Nama, r, "B")
+			for _, p := range r.Posbankums {
+				barisKonsultasi("   "+p.Nama, p, "")
+			}
+		}
+		pdf.Ln(8)
+	}
+
 	// ======================= Output =======================
 	c.Header("Content-Type", "application/pdf")
 	c.Header("Content-Disposition", "inline; filename=laporan_penyuluh_hukum.pdf")
@@ -7,6 +7,7 @@ import (
 
 	"go-admin/config"
 	"go-admin/models"
+	"go-admin/utils"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -43,6 +44,8 @@ func AdminPanel(c *gin.Context) {
 	config.DB.Table("pjas").Count(&totalPJA)
 	config.DB.Table("kadarkums").Count(&totalKadarkum)
 
+	penilaianKadarkum, tahunPenilaianKadarkum := ringkasanPenilaianKadarkum()
+
 	// Siapkan slice untuk hasil pencarian. Gunakan interface{} agar bisa menampung
 	// slice dari berbagai model.
 	var searchResults []interface{}
@@ -127,23 +130,42 @@ func AdminPanel(c *gin.Context) {
 		}
 	}
 
+	skKedaluwarsa, skSegera := ringkasanSK()
+
 	// Render ke admin.html dengan data yang sudah disiapkan
 	c.HTML(http.StatusOK, "admin.html", gin.H{
-		"Title":            "Dashboard",
-		"user":             user,
-		"Query":            q,
-		"SelectedCategory": selectedCategory,
-		"Page":             page,
-		"Limit":            limit,
-		"SearchResults":    searchResults,
-		"TotalPages":       totalPages,
-		"totalProvinsi":    totalProvinsi,
-		"totalKabupaten":   totalKabupaten,
-		"totalKecamatan":   totalKecamatan,
-		"totalKelurahan":   totalKelurahan,
-		"totalParalegal":   totalParalegal,
-		"totalPosbankum":   totalPosbankum,
-		"totalPJA":         totalPJA,
-		"totalKadarkum":    totalKadarkum,
+		"Title":              "Dashboard",
+		"user":               user,
+		"Query":              q,
+		"SelectedCategory":   selectedCategory,
+		"Page":               page,
+		"Limit":              limit,
+		"SearchResults":      searchResults,
+		"TotalPages":         totalPages,
+		"totalProvinsi":      totalProvinsi,
+		"totalKabupaten":     totalKabupaten,
+		"totalKecamatan":     totalKecamatan,
+		"totalKelurahan":     totalKelurahan,
+		"totalParalegal":     totalParalegal,
+		"totalPosbankum":     totalPosbankum,
+		"totalPJA":           totalPJA,
+		"totalKadarkum":      totalKadarkum,
+		"demografiParalegal": demografiParalegal(),
+		"karantinaBaru":      jumlahKarantinaBaru(),
+		"blobBermasalah":     jumlahBlobBermasalah(),
+		"menungguVerifikasi": jumlahMenungguVerifikasi(),
+		"emailGagal":         jumlahEmailGagal(),
+		"skKedaluwarsa":      skKedaluwarsa,
+		"skSegera":           skSegera,
+		"konsultasiBulanIni": jumlahKonsultasiBulanIni(),
+		"penilaianKadarkum":  penilaianKadarkum,
+		"tahunPenilaian":     tahunPenilaianKadarkum,
+	})
+}
+
+// RateLimitStats menampilkan metrik rate limiter (jumlah request diizinkan/ditolak per budget)
+func RateLimitStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"limiters": utils.SemuaStatRateLimit(),
 	})
 }
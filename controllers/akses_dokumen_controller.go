@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ================== HELPER ==================
+
+// melebihiBatasUnduh true kalau user/IP ini sudah mencapai batas dokumen hari ini.
+// Dipanggil di dalam transaksi yang sudah mengunci baris batasnya.
+func melebihiBatasUnduh(tx *gorm.DB, batas models.BatasAksesDokumen, username, ip string) bool {
+	now := time.Now()
+	awalHari := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	db := tx.Model(&models.AksesDokumen{}).
+		Where("tipe = ? AND entitas_id = ? AND diizinkan = ? AND created_at >= ?", batas.Tipe, batas.EntitasID, true, awalHari)
+	if username != "" {
+		db = db.Where("username = ?", username)
+	} else {
+		db = db.Where("username = '' AND ip = ?", ip)
+	}
+
+	var total int64
+	db.Count(&total)
+	return total >= int64(batas.MaksPerHari)
+}
+
+// catatAkses mencatat satu akses dokumen dan menentukan apakah PDF-nya diberi watermark.
+// false kalau ditolak karena batas unduhan, penolakan tetap tercatat. Error berarti log/batas tidak bisa
+// diperiksa (mis. database bermasalah); dokumen tidak dikirim, tapi itu bukan karena batas tercapai.
+func catatAkses(c *gin.Context, tipe string, id uint, filePath string) (models.AksesDokumen, bool, error) {
+	username, _ := sessions.Default(c).Get("user").(string)
+	akses := models.AksesDokumen{
+		Tipe:      tipe,
+		EntitasID: id,
+		Versi:     filepath.Base(filePath),
+		Username:  username,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Diizinkan: true,
+	}
+
+	// PDF untuk user login bisa diberi watermark, ID-nya dicatat supaya salinan bocor bisa dilacak
+	if username != "" && strings.EqualFold(filepath.Ext(filePath), ".pdf") && perluWatermark(tipe, id) {
+		akses.IDWatermark = uuid.New().String()
+	}
+
+	// hitung dan catat dalam satu transaksi; baris batas dikunci (FOR UPDATE) supaya
+	// request paralel (mis. unduhan ZIP) antre dan tidak bisa melewati batas harian
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var batas models.BatasAksesDokumen
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("tipe = ? AND entitas_id = ?", tipe, id).First(&batas).Error
+		if err == nil && melebihiBatasUnduh(tx, batas, username, akses.IP) {
+			akses.Diizinkan = false
+		} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return tx.Create(&akses).Error
+	})
+	if err != nil {
+		// batas tidak bisa dipastikan -> tolak daripada lolos tanpa tercatat
+		log.Printf("Gagal mencatat akses dokumen %s/%d: %v", tipe, id, err)
+		return akses, false, err
+	}
+	return akses, akses.Diizinkan, nil
+}
+
+// kirimDokumen mengirim file dokumen ke browser sekaligus mencatat log akses.
+// Semua handler yang menampilkan dokumen harus lewat sini.
+func kirimDokumen(c *gin.Context, tipe string, id uint, filePath string) {
+	if filePath == "" {
+		c.String(http.StatusNotFound, "Path dokumen kosong atau tidak tersedia.")
+		return
+	}
+
+	akses, diizinkan, err := catatAkses(c, tipe, id, filePath)
+	if err != nil {
+		c.String(http.StatusServiceUnavailable, "⚠️ Dokumen sedang tidak bisa dibuka karena gangguan sistem, silakan coba lagi beberapa saat lagi.")
+		return
+	}
+	if !diizinkan {
+		c.String(http.StatusTooManyRequests, "🚫 Batas unduhan dokumen ini untuk hari ini sudah tercapai.")
+		return
+	}
+
+	if akses.IDWatermark != "" {
+		kirimDenganWatermark(c, filePath, akses)
+		return
+	}
+
+	fileName := filepath.Base(filePath)
+	contentType := mime.TypeByExtension(filepath.Ext(fileName))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	c.Header("Content-Disposition", "inline; filename="+fileName)
+	c.Header("Content-Type", contentType)
+	c.File(filePath)
+}
+
+// ================== LAPORAN AKSES ==================
+
+// Ringkasan dokumen yang paling sering dibuka
+type DokumenTeratas struct {
+	Tipe      string
+	EntitasID uint
+	Total     int64
+	Terakhir  time.Time
+}
+
+func AksesDokumenIndex(c *gin.Context) {
+	username := c.Query("username")
+	idWatermark := strings.TrimSpace(c.Query("watermark"))
+
+	limit := 50
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	// dokumen paling sering diakses
+	var teratas []DokumenTeratas
+	config.DB.Model(&models.AksesDokumen{}).
+		Select("tipe, entitas_id, COUNT(*) AS total, MAX(created_at) AS terakhir").
+		Where("diizinkan = ?", true).
+		Group("tipe, entitas_id").
+		Order("total DESC").
+		Limit(20).
+		Scan(&teratas)
+
+	// riwayat akses (bisa difilter per user, "-" untuk anonim, atau ID watermark dari salinan yang bocor)
+	db := config.DB.Model(&models.AksesDokumen{})
+	if idWatermark != "" {
+		db = db.Where("id_watermark = ?", idWatermark)
+	}
+	switch username {
+	case "":
+	case "-":
+		db = db.Where("username = ''")
+	default:
+		db = db.Where("username = ?", username)
+	}
+
+	var total int64
+	db.Count(&total)
+
+	var riwayat []models.AksesDokumen
+	db.Order("created_at DESC").Offset(offset).Limit(limit).Find(&riwayat)
+
+	var batas []models.BatasAksesDokumen
+	config.DB.Order("tipe, entitas_id").Find(&batas)
+
+	c.HTML(http.StatusOK, "akses_dokumen.html", gin.H{
+		"Title":      "Log Akses Dokumen",
+		"Teratas":    teratas,
+		"Riwayat":    riwayat,
+		"Batas":      batas,
+		"Username":   username,
+		"Watermark":  idWatermark,
+		"Page":       page,
+		"Offset":     offset,
+		"TotalPages": int(math.Ceil(float64(total) / float64(limit))),
+		"user":       sessions.Default(c).Get("user"),
+	})
+}
+
+// ================== BATAS UNDUHAN ==================
+
+func BatasAksesStore(c *gin.Context) {
+	tipe := c.PostForm("tipe")
+	entitasID, _ := strconv.Atoi(c.PostForm("entitas_id"))
+	maks, _ := strconv.Atoi(c.PostForm("maks_per_hari"))
+
+	switch tipe {
+	case "posbankum", "paralegal", "pja", "kadarkum":
+	default:
+		c.String(http.StatusBadRequest, "Tipe dokumen tidak valid")
+		return
+	}
+	if entitasID <= 0 || maks <= 0 {
+		c.String(http.StatusBadRequest, "ID dokumen dan batas per hari wajib diisi")
+		return
+	}
+
+	var batas models.BatasAksesDokumen
+	config.DB.Where("tipe = ? AND entitas_id = ?", tipe, entitasID).First(&batas)
+	batas.Tipe = tipe
+	batas.EntitasID = uint(entitasID)
+	batas.MaksPerHari = maks
+	batas.Keterangan = utils.SanitizeInput(c.PostForm("keterangan"))
+
+	if err := config.DB.Save(&batas).Error; err != nil {
+		c.String(http.StatusInternalServerError, "Gagal simpan batas unduhan")
+		return
+	}
+	c.Redirect(http.StatusFound, "/admin/akses-dokumen")
+}
+
+func BatasAksesDelete(c *gin.Context) {
+	config.DB.Delete(&models.BatasAksesDokumen{}, c.Param("id"))
+	c.Redirect(http.StatusFound, "/admin/akses-dokumen")
+}
@@ -0,0 +1,619 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ================== SIMPAN & LEPAS ==================
+
+// kunciBlob mencegah blob dihapus tepat saat isi yang sama sedang disimpan ulang
+var kunciBlob sync.Mutex
+
+// jedaHapusBlob: blob yang baru disimpan tidak langsung dihapus walaupun belum ada record yang memakainya,
+// karena record-nya mungkin belum sempat dibuat. Blob yatim yang lebih tua dibersihkan job integritas.
+const jedaHapusBlob = 10 * time.Minute
+
+// simpanBlob menyimpan isi file ke penyimpanan blob lalu mencatat/menyegarkan datanya
+func simpanBlob(r io.Reader, contentType string) (string, error) {
+	kunciBlob.Lock()
+	defer kunciBlob.Unlock()
+
+	sha, path, ukuran, err := utils.SimpanBlob(r, contentType)
+	if err != nil {
+		return "", err
+	}
+	blob := models.BlobDokumen{SHA256: sha, Path: path, Ukuran: ukuran, ContentType: contentType, Status: models.BlobOK}
+	err = config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "sha256"}},
+		DoUpdates: clause.AssignmentColumns([]string{"path", "ukuran", "status", "pesan", "updated_at"}),
+	}).Create(&blob).Error
+	return path, err
+}
+
+// ================== STAGING & TRANSAKSI ==================
+
+// Dokumen dari form tidak langsung masuk ke penyimpanan blob. File ditaruh di folder staging dulu,
+// baru dipindah ke uploads/blob setelah transaksi database berhasil di-commit. Kalau transaksi
+// gagal, file staging dibuang sehingga tidak ada file yatim maupun record yang menunjuk file kosong.
+
+// umurStaging: file staging yang lebih tua dari ini dianggap sisa request yang gagal/terputus
+const umurStaging = time.Hour
+
+// folderStaging ada di dalam folder upload sementara supaya tidak pernah terlayani sebagai file statis
+func folderStaging() string {
+	return filepath.Join(folderUploadSementara(), "staging")
+}
+
+// berkasStaging adalah dokumen yang sudah lolos validasi dan menunggu transaksi database
+type berkasStaging struct {
+	path        string // file di folder staging: <sha>_<acak><ext>
+	sha         string
+	ukuran      int64
+	contentType string
+}
+
+// pathBlob adalah lokasi akhir file setelah transaksi berhasil
+func (st *berkasStaging) pathBlob() string {
+	return utils.PathBlob(st.sha, utils.EkstensiBlob(st.contentType))
+}
+
+// namaStaging menyertakan hash di nama file supaya pembersih bisa memulihkan file yang
+// transaksinya sudah commit tapi prosesnya mati sebelum file sempat dipindah
+func namaStaging(sha, contentType string) string {
+	return filepath.Join(folderStaging(), sha+"_"+uuid.New().String()+utils.EkstensiBlob(contentType))
+}
+
+// stagingDariReader menulis isi r ke folder staging
+func stagingDariReader(r io.Reader, contentType string) (*berkasStaging, error) {
+	tmp, sha, ukuran, err := utils.TulisSementara(r, folderStaging())
+	if err != nil {
+		return nil, err
+	}
+	path := namaStaging(sha, contentType)
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	return &berkasStaging{path: path, sha: sha, ukuran: ukuran, contentType: contentType}, nil
+}
+
+// stagingDariFile memindahkan file di disk (mis. hasil upload resumable) ke folder staging
+func stagingDariFile(asal, contentType string) (*berkasStaging, error) {
+	sha, ukuran, err := utils.HashFile(asal)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(folderStaging(), 0o700); err != nil {
+		return nil, err
+	}
+	path := namaStaging(sha, contentType)
+	if err := pindahkanFile(asal, path); err != nil {
+		return nil, err
+	}
+	return &berkasStaging{path: path, sha: sha, ukuran: ukuran, contentType: contentType}, nil
+}
+
+// buang menghapus file staging, dipakai kalau dokumen batal disimpan
+func (st *berkasStaging) buang() {
+	if st == nil {
+		return
+	}
+	if err := os.Remove(st.path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Gagal menghapus file staging %s: %v", st.path, err)
+	}
+}
+
+// simpanDenganDokumen menjalankan simpan di dalam satu transaksi bersama pencatatan blob-nya.
+// path yang diterima simpan adalah lokasi akhir dokumen (kosong kalau st nil, berarti tidak ada dokumen baru).
+// File baru dipindah ke penyimpanan blob setelah commit; kalau transaksi gagal, file staging dibuang.
+func simpanDenganDokumen(st *berkasStaging, simpan func(tx *gorm.DB, path string) error) error {
+	return simpanDenganBerkas([]*berkasStaging{st}, func(tx *gorm.DB, paths []string) error {
+		return simpan(tx, paths[0])
+	})
+}
+
+// simpanDenganBerkas sama dengan simpanDenganDokumen untuk beberapa file sekaligus (mis. dokumen SK dan foto).
+// paths[i] adalah lokasi akhir sts[i], kosong kalau sts[i] nil.
+func simpanDenganBerkas(sts []*berkasStaging, simpan func(tx *gorm.DB, paths []string) error) error {
+	paths := make([]string, len(sts))
+	for i, st := range sts {
+		if st != nil {
+			paths[i] = st.pathBlob()
+		}
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := simpan(tx, paths); err != nil {
+			return err
+		}
+		for i, st := range sts {
+			if st == nil {
+				continue
+			}
+			blob := models.BlobDokumen{SHA256: st.sha, Path: paths[i], Ukuran: st.ukuran, ContentType: st.contentType, Status: models.BlobOK}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "sha256"}},
+				DoUpdates: clause.AssignmentColumns([]string{"path", "ukuran", "status", "pesan", "updated_at"}),
+			}).Create(&blob).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		for _, st := range sts {
+			st.buang()
+		}
+		return err
+	}
+
+	kunciBlob.Lock()
+	defer kunciBlob.Unlock()
+	for i, st := range sts {
+		if st == nil {
+			continue
+		}
+		if _, err := utils.PasangBlob(st.path, st.sha, utils.EkstensiBlob(st.contentType)); err != nil {
+			// record sudah tersimpan; file staging dibiarkan supaya dipulihkan pembersih
+			log.Printf("Gagal memindahkan %s ke penyimpanan blob: %v", st.path, err)
+		}
+		perbaruiRefBlob(paths[i])
+	}
+	return nil
+}
+
+// BersihkanStaging menghapus file staging sisa request yang gagal. File yang transaksinya sudah
+// commit (path blob-nya dipakai record) tapi belum terpasang dipindahkan ke penyimpanan blob.
+func BersihkanStaging() {
+	file, _ := filepath.Glob(filepath.Join(folderStaging(), "*"))
+	batas := time.Now().Add(-umurStaging)
+	dihapus, dipulihkan := 0, 0
+	for _, path := range file {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || info.ModTime().After(batas) {
+			continue
+		}
+
+		nama := filepath.Base(path)
+		sha, _, ok := strings.Cut(nama, "_")
+		if ok && len(sha) == 64 {
+			ext := filepath.Ext(nama)
+			pathBlob := utils.PathBlob(sha, ext)
+			kunciBlob.Lock()
+			pulihkan := !utils.FileAda(pathBlob) && jumlahRefPath(pathBlob) > 0
+			if pulihkan {
+				if _, err := utils.PasangBlob(path, sha, ext); err != nil {
+					log.Printf("Gagal memulihkan %s: %v", path, err)
+				} else {
+					log.Printf("File staging %s dipulihkan ke %s", nama, pathBlob)
+					dipulihkan++
+				}
+			}
+			kunciBlob.Unlock()
+			if pulihkan {
+				continue
+			}
+		}
+
+		if err := os.Remove(path); err == nil {
+			dihapus++
+		}
+	}
+
+	if dihapus > 0 || dipulihkan > 0 {
+		log.Printf("Pembersihan staging: %d file dihapus, %d file dipulihkan", dihapus, dipulihkan)
+	}
+}
+
+// jumlahRefPath menghitung record yang memakai path sebagai dokumen utama atau lampiran
+func jumlahRefPath(path string) int {
+	total := int64(0)
+	for _, m := range []any{&models.Posbankum{}, &models.Paralegal{}, &models.Pja{}, &models.Kadarkum{}} {
+		var n int64
+		config.DB.Model(m).Where("dokumen = ?", path).Count(&n)
+		total += n
+	}
+	for _, m := range []any{&models.Posbankum{}, &models.Paralegal{}} {
+		var n int64
+		config.DB.Model(m).Where("foto = ?", path).Count(&n)
+		total += n
+	}
+	var n, k, m int64
+	config.DB.Model(&models.Lampiran{}).Where("path = ?", path).Count(&n)
+	config.DB.Model(&models.Komentar{}).Where("lampiran_path = ?", path).Count(&k)
+	config.DB.Model(&models.Mediasi{}).Where("dokumen_kesepakatan = ?", path).Count(&m)
+	return int(total + n + k + m)
+}
+
+// perbaruiRefBlob menghitung ulang jumlah referensi. updated_at sengaja tidak disentuh
+// karena dipakai sebagai penanda kapan blob terakhir disimpan.
+func perbaruiRefBlob(path string) int {
+	n := jumlahRefPath(path)
+	config.DB.Model(&models.BlobDokumen{}).Where("path = ?", path).UpdateColumn("jumlah_ref", n)
+	return n
+}
+
+// lepasFile dipanggil SETELAH record berhenti memakai path (record dihapus atau dokumennya diganti).
+// File baru benar-benar dihapus kalau tidak ada record lain yang masih memakainya.
+func lepasFile(path string) {
+	if path == "" {
+		return
+	}
+	kunciBlob.Lock()
+	defer kunciBlob.Unlock()
+
+	if perbaruiRefBlob(path) > 0 {
+		return
+	}
+	var blob models.BlobDokumen
+	if err := config.DB.Where("path = ?", path).First(&blob).Error; err == nil {
+		if blob.UpdatedAt != nil && time.Since(*blob.UpdatedAt) < jedaHapusBlob {
+			return
+		}
+		config.DB.Delete(&blob)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Gagal menghapus file %s: %v", path, err)
+	}
+}
+
+// gantiPathDokumen mengarahkan semua record dari path lama ke path baru
+func gantiPathDokumen(lama, baru string) {
+	for _, m := range []any{&models.Posbankum{}, &models.Paralegal{}, &models.Pja{}, &models.Kadarkum{}} {
+		config.DB.Model(m).Where("dokumen = ?", lama).UpdateColumn("dokumen", baru)
+	}
+	config.DB.Model(&models.Lampiran{}).Where("path = ?", lama).UpdateColumn("path", baru)
+	config.DB.Model(&models.Komentar{}).Where("lampiran_path = ?", lama).UpdateColumn("lampiran_path", baru)
+	config.DB.Model(&models.Posbankum{}).Where("foto = ?", lama).UpdateColumn("foto", baru)
+	config.DB.Model(&models.Paralegal{}).Where("foto = ?", lama).UpdateColumn("foto", baru)
+	config.DB.Model(&models.Mediasi{}).Where("dokumen_kesepakatan = ?", lama).UpdateColumn("dokumen_kesepakatan", baru)
+	config.DB.Model(&models.TandaTanganDokumen{}).Where("path = ?", lama).UpdateColumn("path", baru)
+	config.DB.Model(&models.OCRJob{}).Where("path = ?", lama).UpdateColumn("path", baru)
+}
+
+// ================== REFERENSI ==================
+
+// RefBlob adalah satu record yang memakai sebuah file
+type RefBlob struct {
+	Tipe  string
+	ID    uint
+	Label string
+	URL   string // halaman edit record (untuk lampiran: record induknya)
+}
+
+// referensiPaths mengelompokkan record pemakai per path
+func referensiPaths(paths []string) map[string][]RefBlob {
+	hasil := make(map[string][]RefBlob, len(paths))
+	if len(paths) == 0 {
+		return hasil
+	}
+	tambah := func(path, tipe string, id uint, label string) {
+		hasil[path] = append(hasil[path], RefBlob{Tipe: tipe, ID: id, Label: label, URL: fmt.Sprintf("/admin/%s/edit/%d", tipe, id)})
+	}
+
+	var posbankums []models.Posbankum
+	config.DB.Preload("Kelurahan").Where("dokumen IN ?", paths).Find(&posbankums)
+	for _, d := range posbankums {
+		tambah(d.Dokumen, "posbankum", d.ID, d.Kelurahan.Name)
+	}
+	var fotos []models.Posbankum
+	config.DB.Preload("Kelurahan").Where("foto IN ?", paths).Find(&fotos)
+	for _, d := range fotos {
+		hasil[d.Foto] = append(hasil[d.Foto], RefBlob{Tipe: "foto posbankum", ID: d.ID, Label: d.Kelurahan.Name,
+			URL: fmt.Sprintf("/admin/posbankum/edit/%d", d.ID)})
+	}
+	var pjas []models.Pja
+	config.DB.Preload("Kelurahan").Where("dokumen IN ?", paths).Find(&pjas)
+	for _, d := range pjas {
+		tambah(d.Dokumen, "pja", d.ID, d.Kelurahan.Name)
+	}
+	var kadarkums []models.Kadarkum
+	config.DB.Preload("Kelurahan").Where("dokumen IN ?", paths).Find(&kadarkums)
+	for _, d := range kadarkums {
+		tambah(d.Dokumen, "kadarkum", d.ID, d.Kelurahan.Name)
+	}
+	var paralegals []models.Paralegal
+	config.DB.Where("dokumen IN ?", paths).Find(&paralegals)
+	for _, d := range paralegals {
+		tambah(d.Dokumen, "paralegal", d.ID, d.Nama)
+	}
+	var fotoParalegals []models.Paralegal
+	config.DB.Where("foto IN ?", paths).Find(&fotoParalegals)
+	for _, d := range fotoParalegals {
+		hasil[d.Foto] = append(hasil[d.Foto], RefBlob{Tipe: "foto paralegal", ID: d.ID, Label: d.Nama,
+			URL: fmt.Sprintf("/admin/paralegal/edit/%d", d.ID)})
+	}
+
+	var lampirans []models.Lampiran
+	config.DB.Where("path IN ?", paths).Find(&lampirans)
+	for _, l := range lampirans {
+		hasil[l.Path] = append(hasil[l.Path], RefBlob{
+			Tipe:  "lampiran " + l.EntitasType,
+			ID:    l.EntitasID,
+			Label: l.NamaAsli,
+			URL:   fmt.Sprintf("/admin/%s/edit/%d", l.EntitasType, l.EntitasID),
+		})
+	}
+
+	var mediasis []models.Mediasi
+	config.DB.Where("dokumen_kesepakatan IN ?", paths).Find(&mediasis)
+	for _, d := range mediasis {
+		tambah(d.DokumenKesepakatan, "mediasi", d.ID, d.PihakPertama+" - "+d.PihakKedua)
+	}
+
+	var komentars []models.Komentar
+	config.DB.Where("lampiran_path IN ?", paths).Find(&komentars)
+	for _, k := range komentars {
+		hasil[k.LampiranPath] = append(hasil[k.LampiranPath], RefBlob{
+			Tipe:  "komentar " + k.Tipe,
+			ID:    k.EntitasID,
+			Label: k.LampiranNama,
+			URL:   fmt.Sprintf("/admin/%s/edit/%d", k.Tipe, k.EntitasID),
+		})
+	}
+	return hasil
+}
+
+// FileLama adalah record yang filenya belum dipindah ke penyimpanan blob
+type FileLama struct {
+	RefBlob
+	Path string
+	Ada  bool // false = file tidak ditemukan di disk
+}
+
+// daftarFileLama mencari record yang masih memakai path di luar blob store
+func daftarFileLama() []FileLama {
+	var hasil []FileLama
+	cek := func(tipe string, id uint, path string) {
+		if path == "" || utils.PathDiBlob(path) {
+			return
+		}
+		hasil = append(hasil, FileLama{
+			RefBlob: RefBlob{Tipe: tipe, ID: id, URL: fmt.Sprintf("/admin/%s/edit/%d", tipe, id)},
+			Path:    path,
+			Ada:     utils.FileAda(path),
+		})
+	}
+
+	var posbankums []models.Posbankum
+	config.DB.Select("id, dokumen").Find(&posbankums)
+	for _, d := range posbankums {
+		cek("posbankum", d.ID, d.Dokumen)
+	}
+	var paralegals []models.Paralegal
+	config.DB.Select("id, dokumen").Find(&paralegals)
+	for _, d := range paralegals {
+		cek("paralegal", d.ID, d.Dokumen)
+	}
+	var pjas []models.Pja
+	config.DB.Select("id, dokumen").Find(&pjas)
+	for _, d := range pjas {
+		cek("pja", d.ID, d.Dokumen)
+	}
+	var kadarkums []models.Kadarkum
+	config.DB.Select("id, dokumen").Find(&kadarkums)
+	for _, d := range kadarkums {
+		cek("kadarkum", d.ID, d.Dokumen)
+	}
+	var lampirans []models.Lampiran
+	config.DB.Select("id, entitas_type, entitas_id, path").Find(&lampirans)
+	for _, l := range lampirans {
+		if l.Path == "" || utils.PathDiBlob(l.Path) {
+			continue
+		}
+		hasil = append(hasil, FileLama{
+			RefBlob: RefBlob{Tipe: "lampiran " + l.EntitasType, ID: l.EntitasID,
+				URL: fmt.Sprintf("/admin/%s/edit/%d", l.EntitasType, l.EntitasID)},
+			Path: l.Path,
+			Ada:  utils.FileAda(l.Path),
+		})
+	}
+	return hasil
+}
+
+// ================== JOB INTEGRITAS ==================
+
+var integritasBerjalan atomic.Bool
+
+// PeriksaIntegritasBlob menghitung ulang hash semua blob, menandai yang rusak/hilang,
+// menyegarkan jumlah referensi dan membuang blob yang sudah tidak dipakai record mana pun.
+// Dipakai oleh perintah `go run . periksa-blob`, job berkala dan tombol di halaman Integritas Dokumen
+// (pemicu = username yang menekan tombol, kosong untuk job berkala/CLI).
+func PeriksaIntegritasBlob(pemicu string) {
+	if !integritasBerjalan.CompareAndSwap(false, true) {
+		log.Printf("Pemeriksaan integritas dokumen masih berjalan, dilewati")
+		return
+	}
+	defer integritasBerjalan.Store(false)
+
+	var blobs []models.BlobDokumen
+	config.DB.Find(&blobs)
+
+	jumlah := map[string]int{}
+	dibuang := 0
+	for _, b := range blobs {
+		// blob yatim yang sudah lewat jeda dibuang
+		if perbaruiRefBlob(b.Path) == 0 && b.UpdatedAt != nil && time.Since(*b.UpdatedAt) > jedaHapusBlob {
+			lepasFile(b.Path)
+			dibuang++
+			continue
+		}
+
+		status, pesan := models.BlobOK, ""
+		sha, n, err := utils.HashFile(b.Path)
+		switch {
+		case os.IsNotExist(err):
+			status, pesan = models.BlobHilang, "File tidak ditemukan di disk"
+		case err != nil:
+			status, pesan = models.BlobRusak, "File tidak bisa dibaca: "+err.Error()
+		case sha != b.SHA256:
+			status, pesan = models.BlobRusak, fmt.Sprintf("Isi berubah: hash sekarang %s…, ukuran %d byte (semula %d)", sha[:12], n, b.Ukuran)
+		}
+		if status != b.Status {
+			log.Printf("Integritas dokumen %s: %s -> %s %s", b.Path, b.Status, status, pesan)
+		}
+		jumlah[status]++
+
+		config.DB.Model(&b).UpdateColumns(map[string]any{
+			"status":       status,
+			"pesan":        pesan,
+			"diperiksa_at": time.Now(),
+		})
+	}
+
+	putus := 0
+	for _, f := range daftarFileLama() {
+		if !f.Ada {
+			putus++
+		}
+	}
+
+	log.Printf("Pemeriksaan integritas selesai: %d ok, %d rusak, %d hilang, %d blob yatim dibuang, %d file lama hilang",
+		jumlah[models.BlobOK], jumlah[models.BlobRusak], jumlah[models.BlobHilang], dibuang, putus)
+
+	// job berkala hanya memberi tahu admin kalau ada masalah
+	bermasalah := jumlah[models.BlobRusak] + jumlah[models.BlobHilang] + putus
+	if pemicu != "" || bermasalah > 0 {
+		beritahuPekerjaan(pemicu, "Pemeriksaan integritas dokumen selesai",
+			fmt.Sprintf("%d file utuh, %d rusak, %d hilang, %d file lama hilang",
+				jumlah[models.BlobOK], jumlah[models.BlobRusak], jumlah[models.BlobHilang], putus),
+			"/admin/integritas")
+	}
+}
+
+// MulaiPemeriksaIntegritas menjalankan PeriksaIntegritasBlob berkala di background
+// (env INTEGRITAS_DOKUMEN_JAM, bawaan 24 jam)
+func MulaiPemeriksaIntegritas() {
+	interval := 24 * time.Hour
+	if jam, err := strconv.Atoi(os.Getenv("INTEGRITAS_DOKUMEN_JAM")); err == nil && jam > 0 {
+		interval = time.Duration(jam) * time.Hour
+	}
+	go func() {
+		for {
+			time.Sleep(interval)
+			PeriksaIntegritasBlob("")
+		}
+	}()
+}
+
+// MigrasiKeBlob memindahkan file lama uploads/<tipe>/<uuid> ke penyimpanan blob.
+// File dengan isi sama otomatis digabung. Dipakai oleh perintah `go run . migrasi-blob`.
+func MigrasiKeBlob() {
+	dipindah, gagal := 0, 0
+	for _, f := range daftarFileLama() {
+		// satu path lama bisa dipakai beberapa record; setelah dipindah, sisanya terlewati di sini
+		if !utils.FileAda(f.Path) {
+			continue
+		}
+		lama := f.Path
+		baru, err := func() (string, error) {
+			src, err := os.Open(lama)
+			if err != nil {
+				return "", err
+			}
+			defer src.Close()
+			return simpanBlob(src, utils.ContentTypeFile(lama))
+		}()
+		if err != nil {
+			log.Printf("Gagal memindahkan %s: %v", lama, err)
+			gagal++
+			continue
+		}
+
+		gantiPathDokumen(lama, baru)
+		perbaruiRefBlob(baru)
+		lepasFile(lama)
+		dipindah++
+	}
+	log.Printf("Migrasi ke penyimpanan blob selesai: %d file dipindah, %d gagal", dipindah, gagal)
+}
+
+// jumlahBlobBermasalah untuk penanda di dashboard admin
+func jumlahBlobBermasalah() int64 {
+	var total int64
+	config.DB.Model(&models.BlobDokumen{}).Where("status <> ?", models.BlobOK).Count(&total)
+	return total
+}
+
+// ================== HALAMAN ADMIN ==================
+
+// BarisBlob adalah satu blob beserta record pemakainya
+type BarisBlob struct {
+	models.BlobDokumen
+	Ref []RefBlob
+}
+
+func IntegritasIndex(c *gin.Context) {
+	var bermasalah, bersama []models.BlobDokumen
+	config.DB.Where("status <> ?", models.BlobOK).Order("diperiksa_at DESC").Find(&bermasalah)
+	config.DB.Where("jumlah_ref > 1").Order("jumlah_ref DESC, ukuran DESC").Limit(200).Find(&bersama)
+
+	var paths []string
+	for _, b := range append(append([]models.BlobDokumen{}, bermasalah...), bersama...) {
+		paths = append(paths, b.Path)
+	}
+	ref := referensiPaths(paths)
+	baris := func(blobs []models.BlobDokumen) []BarisBlob {
+		hasil := make([]BarisBlob, 0, len(blobs))
+		for _, b := range blobs {
+			hasil = append(hasil, BarisBlob{BlobDokumen: b, Ref: ref[b.Path]})
+		}
+		return hasil
+	}
+
+	var ringkasan struct {
+		Total  int64
+		Ukuran int64
+		Hemat  int64 // byte yang tidak perlu disimpan ulang karena dipakai bersama
+	}
+	config.DB.Model(&models.BlobDokumen{}).
+		Select("COUNT(*) AS total, COALESCE(SUM(ukuran), 0) AS ukuran, COALESCE(SUM(CASE WHEN jumlah_ref > 1 THEN ukuran * (jumlah_ref - 1) ELSE 0 END), 0) AS hemat").
+		Scan(&ringkasan)
+
+	var hilangLama []FileLama
+	fileLama := daftarFileLama()
+	for _, f := range fileLama {
+		if !f.Ada {
+			hilangLama = append(hilangLama, f)
+		}
+	}
+
+	c.HTML(http.StatusOK, "integritas.html", gin.H{
+		"Title":      "Integritas Dokumen",
+		"Bermasalah": baris(bermasalah),
+		"Bersama":    baris(bersama),
+		"TotalBlob":  ringkasan.Total,
+		"UkuranMB":   fmt.Sprintf("%.1f", float64(ringkasan.Ukuran)/(1024*1024)),
+		"HematMB":    fmt.Sprintf("%.1f", float64(ringkasan.Hemat)/(1024*1024)),
+		"JumlahLama": len(fileLama),
+		"HilangLama": hilangLama,
+		"SedangCek":  integritasBerjalan.Load(),
+		"Dimulai":    c.Query("mulai") != "",
+	})
+}
+
+// IntegritasPeriksa menjalankan pemeriksaan integritas di background
+func IntegritasPeriksa(c *gin.Context) {
+	username, _ := penggunaLogin(c)
+	go PeriksaIntegritasBlob(username)
+	c.Redirect(http.StatusFound, "/admin/integritas?mulai=1")
+}
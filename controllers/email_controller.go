@@ -0,0 +1,670 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Email notifikasi memakai pola outbox: event hanya menulis baris email_outboxes (di transaksi yang sama
+// dengan perubahan datanya), worker di background yang mengirim ke SMTP dan mengulang yang gagal.
+// Untuk uji lokal jalankan MailHog lalu isi SMTP_HOST=127.0.0.1 (port bawaan 1025), lihat utils/email.go.
+
+// ================== TEMPLAT PESAN ==================
+
+// TemplatEmail adalah subjek dan isi (text/template) satu jenis email.
+// Subjek (tanpa awalan [JADI]) dan Ringkas juga dipakai untuk notifikasi di aplikasi.
+type TemplatEmail struct {
+	Judul      string // ditampilkan di halaman preferensi & outbox
+	Keterangan string
+	Subjek     string
+	Isi        string
+	Ringkas    string // satu baris untuk daftar notifikasi
+}
+
+// DataEmail adalah isian templat email
+type DataEmail struct {
+	Nama          string // username penerima
+	Tipe          string // Posbankum, Paralegal, Kadarkum, PJA
+	Label         string
+	Wilayah       string
+	Komentar      string
+	Oleh          string
+	Role          string
+	Sumber        string
+	BerlakuSampai string
+	SisaHari      int
+	Path          string // halaman terkait di aplikasi, mis. /admin/verifikasi
+}
+
+// Tautan adalah URL lengkap Path untuk isi email
+func (d DataEmail) Tautan() string {
+	return tautanAplikasi(d.Path)
+}
+
+var templatEmail = map[string]TemplatEmail{
+	models.EmailDiajukan: {
+		Judul:      "Data diajukan untuk verifikasi",
+		Keterangan: "Untuk verifikator: ada data program baru yang menunggu diperiksa.",
+		Subjek:     "[JADI] {{ .Tipe }} {{ .Label }} menunggu verifikasi",
+		Ringkas:    "Diajukan oleh {{ .Oleh }} · {{ .Wilayah }}",
+		Isi: `Yth. {{ .Nama }},
+
+Data {{ .Tipe }} berikut diajukan oleh {{ .Oleh }} dan menunggu verifikasi:
+
+  Data    : {{ .Label }}
+  Wilayah : {{ .Wilayah }}
+
+Silakan periksa di antrean verifikasi:
+{{ .Tautan }}`,
+	},
+	models.EmailDitolak: {
+		Judul:      "Data ditolak verifikator",
+		Keterangan: "Data yang Anda ajukan dikembalikan dengan catatan perbaikan.",
+		Subjek:     "[JADI] {{ .Tipe }} {{ .Label }} ditolak",
+		Ringkas:    "Ditolak oleh {{ .Oleh }}: {{ .Komentar }}",
+		Isi: `Yth. {{ .Nama }},
+
+Data {{ .Tipe }} yang Anda ajukan ditolak oleh {{ .Oleh }}:
+
+  Data    : {{ .Label }}
+  Wilayah : {{ .Wilayah }}
+  Catatan : {{ .Komentar }}
+
+Silakan perbaiki lalu ajukan kembali:
+{{ .Tautan }}`,
+	},
+	models.EmailTerverifikasi: {
+		Judul:      "Data terverifikasi",
+		Keterangan: "Data yang Anda ajukan disetujui dan mulai dihitung di dashboard.",
+		Subjek:     "[JADI] {{ .Tipe }} {{ .Label }} terverifikasi",
+		Ringkas:    "Disetujui oleh {{ .Oleh }} · {{ .Wilayah }}",
+		Isi: `Yth. {{ .Nama }},
+
+Data {{ .Tipe }} yang Anda ajukan telah disetujui oleh {{ .Oleh }} dan sekarang dihitung di dashboard:
+
+  Data    : {{ .Label }}
+  Wilayah : {{ .Wilayah }}{{ if .Komentar }}
+  Catatan : {{ .Komentar }}{{ end }}
+
+{{ .Tautan }}`,
+	},
+	models.EmailSKBerakhir: {
+		Judul:      "Masa berlaku SK",
+		Keterangan: "SK di wilayah Anda segera berakhir atau sudah kedaluwarsa.",
+		Subjek:     "[JADI] SK {{ .Tipe }} {{ .Label }} {{ if lt .SisaHari 0 }}sudah berakhir{{ else }}berakhir {{ .SisaHari }} hari lagi{{ end }}",
+		Ringkas:    "{{ .Wilayah }} · berlaku sampai {{ .BerlakuSampai }}",
+		Isi: `Yth. {{ .Nama }},
+
+{{ if lt .SisaHari 0 }}SK berikut sudah tidak berlaku sejak {{ .BerlakuSampai }}:{{ else }}SK berikut akan berakhir pada {{ .BerlakuSampai }} ({{ .SisaHari }} hari lagi):{{ end }}
+
+  Program : {{ .Tipe }}
+  Data    : {{ .Label }}
+  Wilayah : {{ .Wilayah }}
+
+Mohon unggah SK perpanjangan/baru:
+{{ .Tautan }}`,
+	},
+	models.EmailAkunBaru: {
+		Judul:  "Akun baru",
+		Subjek: "[JADI] Akun Anda sudah dibuat",
+		Isi: `Yth. {{ .Nama }},
+
+Akun Anda di JADI - Jambi Database Informasi Pembinaan Hukum sudah dibuat:
+
+  Username : {{ .Nama }}
+  Role     : {{ .Role }}
+
+{{ if eq .Sumber "lokal" }}Silakan masuk dengan password yang diberikan admin:{{ else }}Silakan masuk menggunakan akun SSO instansi:{{ end }}
+{{ .Tautan }}`,
+	},
+	models.EmailPekerjaan: {
+		Judul:      "Pekerjaan background",
+		Keterangan: "Untuk admin: hasil pemeriksaan integritas dokumen dan OCR yang gagal.",
+		Subjek:     "[JADI] {{ .Label }}",
+		Ringkas:    "{{ .Komentar }}",
+		Isi: `Yth. {{ .Nama }},
+
+{{ .Label }}.
+
+  Hasil : {{ .Komentar }}
+
+Detail:
+{{ .Tautan }}`,
+	},
+	models.EmailKomentar: {
+		Judul:      "Diskusi data",
+		Keterangan: "Anda disebut (@username) atau komentar Anda dibalas di diskusi sebuah data.",
+		Subjek:     "[JADI] {{ .Oleh }} {{ if eq .Sumber \"balasan\" }}membalas komentar Anda{{ else }}menyebut Anda{{ end }} di {{ .Tipe }} {{ .Label }}",
+		Ringkas:    "{{ .Oleh }}: {{ .Komentar }}",
+		Isi: `Yth. {{ .Nama }},
+
+{{ .Oleh }} {{ if eq .Sumber "balasan" }}membalas komentar Anda{{ else }}menyebut Anda{{ end }} di diskusi {{ .Tipe }}:
+
+  Data    : {{ .Label }}
+  Wilayah : {{ .Wilayah }}
+
+{{ .Komentar }}
+
+Balas di aplikasi:
+{{ .Tautan }}`,
+	},
+	models.EmailMalware: {
+		Judul:      "Malware terdeteksi",
+		Keterangan: "Untuk admin: file upload atau arsip terdeteksi malware dan dipindahkan ke karantina.",
+		Subjek:     "[JADI] Malware terdeteksi pada {{ .Label }}",
+		Ringkas:    "{{ .Komentar }}{{ if .Tipe }} · {{ .Tipe }}{{ end }}{{ if .Oleh }} · diunggah {{ .Oleh }}{{ end }}",
+		Isi: `Yth. {{ .Nama }},
+
+Pemindai antivirus menemukan malware dan file sudah dipindahkan ke karantina:
+
+  File    : {{ .Label }}
+  Virus   : {{ .Komentar }}{{ if .Tipe }}
+  Data    : {{ .Tipe }}{{ end }}
+  Sumber  : {{ if eq .Sumber "upload" }}upload{{ if .Oleh }} oleh {{ .Oleh }}{{ end }}{{ else }}pindai ulang arsip{{ end }}
+
+Tinjau di halaman karantina:
+{{ .Tautan }}`,
+	},
+}
+
+// jenisEmailDiatur adalah jenis email yang bisa dimatikan user (akun baru selalu dikirim)
+var jenisEmailDiatur = []string{models.EmailDiajukan, models.EmailDitolak, models.EmailTerverifikasi, models.EmailSKBerakhir, models.EmailKomentar, models.EmailPekerjaan}
+
+const footerEmail = `
+
+--
+Email ini dikirim otomatis oleh JADI - Jambi Database Informasi Pembinaan Hukum, mohon tidak dibalas.
+Atur notifikasi email Anda: %s
+`
+
+// tautanAplikasi membentuk URL lengkap untuk isi email (env APP_URL, mis. https://jadi.example.go.id)
+func tautanAplikasi(path string) string {
+	base := strings.TrimRight(os.Getenv("APP_URL"), "/")
+	if base == "" {
+		base = "http://127.0.0.1:8182"
+	}
+	return base + path
+}
+
+// namaProgram -> nama tipe data untuk ditampilkan ke manusia
+func namaProgram(tipe string) string {
+	if tipe == "pja" {
+		return "PJA"
+	}
+	if tipe == "" {
+		return ""
+	}
+	return strings.ToUpper(tipe[:1]) + tipe[1:]
+}
+
+func isiTemplat(nama, teks string, data DataEmail) (string, error) {
+	tmpl, err := template.New(nama).Parse(teks)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// renderEmail mengisi templat satu jenis email
+func renderEmail(jenis string, data DataEmail) (subjek, isi string, err error) {
+	t, ok := templatEmail[jenis]
+	if !ok {
+		return "", "", fmt.Errorf("jenis email tidak dikenal: %s", jenis)
+	}
+	if subjek, err = isiTemplat(jenis+"_subjek", t.Subjek, data); err != nil {
+		return "", "", err
+	}
+	if isi, err = isiTemplat(jenis+"_isi", t.Isi, data); err != nil {
+		return "", "", err
+	}
+	subjek = strings.Join(strings.Fields(subjek), " ") // subjek satu baris
+	isi += fmt.Sprintf(footerEmail, tautanAplikasi("/akun/notifikasi"))
+	return subjek, isi, nil
+}
+
+// ================== OUTBOX ==================
+
+// emailDiizinkan -> false kalau user mematikan jenis email ini di preferensinya.
+// Akun baru dan peringatan malware selalu dikirim.
+func emailDiizinkan(db *gorm.DB, userID uint, jenis string) bool {
+	if jenis == models.EmailAkunBaru || jenis == models.EmailMalware {
+		return true
+	}
+	var pref models.PreferensiEmail
+	if err := db.Where("user_id = ? AND jenis = ?", userID, jenis).Limit(1).Find(&pref).Error; err != nil || pref.ID == 0 {
+		return true
+	}
+	return pref.Aktif
+}
+
+// antreEmail menulis satu email untuk user ke outbox. Dilewati kalau user tidak punya email valid,
+// mematikan jenis ini, atau email dengan kunci yang sama sudah pernah diantrekan untuknya.
+func antreEmail(db *gorm.DB, user models.User, jenis, kunci string, data DataEmail) error {
+	if !utils.EmailValid(user.Email) || !emailDiizinkan(db, user.ID, jenis) {
+		return nil
+	}
+	if kunci != "" {
+		var n int64
+		db.Model(&models.EmailOutbox{}).Where("kunci = ? AND user_id = ?", kunci, user.ID).Count(&n)
+		if n > 0 {
+			return nil
+		}
+	}
+	data.Nama = user.Username
+	subjek, isi, err := renderEmail(jenis, data)
+	if err != nil {
+		return err
+	}
+	userID := user.ID
+	return db.Create(&models.EmailOutbox{
+		UserID:  &userID,
+		Ke:      user.Email,
+		Jenis:   jenis,
+		Kunci:   kunci,
+		Subjek:  subjek,
+		Isi:     isi,
+		Status:  models.EmailAntre,
+		KirimAt: time.Now(),
+	}).Error
+}
+
+// labelEntitas mengambil nama dan wilayah record untuk isi email (pakai tx supaya record baru terbaca)
+func labelEntitas(db *gorm.DB, tipe string, id uint) (label, wilayah string) {
+	switch tipe {
+	case "posbankum":
+		var d models.Posbankum
+		db.Preload("Kelurahan.Kecamatan.Kabupaten").Limit(1).Find(&d, id)
+		return d.Kelurahan.Name, namaWilayah(d.Kelurahan)
+	case "kadarkum":
+		var d models.Kadarkum
+		db.Preload("Kelurahan.Kecamatan.Kabupaten").Limit(1).Find(&d, id)
+		return d.Kelurahan.Name, namaWilayah(d.Kelurahan)
+	case "pja":
+		var d models.Pja
+		db.Preload("Kelurahan.Kecamatan.Kabupaten").Limit(1).Find(&d, id)
+		return d.Kelurahan.Name, namaWilayah(d.Kelurahan)
+	case "paralegal":
+		var d models.Paralegal
+		db.Preload("Posbankum.Kelurahan.Kecamatan.Kabupaten").Limit(1).Find(&d, id)
+		return d.Nama, namaWilayah(d.Posbankum.Kelurahan)
+	}
+	return "", ""
+}
+
+// beritahuVerifikasi dipanggil catatRiwayat: diajukan -> verifikator & admin,
+// ditolak/terverifikasi -> user yang terakhir mengajukan. Pelaku perubahan tidak diberi tahu.
+func beritahuVerifikasi(tx *gorm.DB, tipe string, id uint, ke, komentar, oleh string) error {
+	var jenis string
+	switch ke {
+	case models.StatusDiajukan:
+		jenis = models.EmailDiajukan
+	case models.StatusDitolak:
+		jenis = models.EmailDitolak
+	case models.StatusTerverifikasi:
+		jenis = models.EmailTerverifikasi
+	default:
+		return nil
+	}
+
+	label, wilayah := labelEntitas(tx, tipe, id)
+	data := DataEmail{Tipe: namaProgram(tipe), Label: label, Wilayah: wilayah, Komentar: komentar, Oleh: oleh}
+
+	var penerima []models.User
+	if jenis == models.EmailDiajukan {
+		data.Path = "/admin/verifikasi"
+		tx.Where("role IN ?", []string{"verifikator", "admin"}).Find(&penerima)
+	} else {
+		data.Path = fmt.Sprintf("/admin/%s/edit/%d", tipe, id)
+		var pengaju []string
+		tx.Model(&models.RiwayatVerifikasi{}).
+			Where("tipe = ? AND entitas_id = ? AND ke = ?", tipe, id, models.StatusDiajukan).
+			Order("id DESC").Limit(1).Pluck("username", &pengaju)
+		if len(pengaju) == 0 {
+			return nil
+		}
+		tx.Where("username = ?", pengaju[0]).Find(&penerima)
+	}
+
+	for _, u := range penerima {
+		if u.Username == oleh {
+			continue
+		}
+		if err := beritahu(tx, u, jenis, "", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// antreEmailAkunBaru mengirim info akun ke user yang baru dibuat (lokal oleh admin, atau otomatis dari SSO)
+func antreEmailAkunBaru(user models.User) {
+	sumber := user.Sumber
+	if sumber == "" {
+		sumber = "lokal"
+	}
+	data := DataEmail{Role: user.Role, Sumber: sumber, Path: "/login"}
+	if err := antreEmail(config.DB, user, models.EmailAkunBaru, fmt.Sprintf("akun:%d", user.ID), data); err != nil {
+		log.Printf("Gagal mengantrekan email akun baru %s: %v", user.Username, err)
+	}
+}
+
+// ================== PENGINGAT SK ==================
+
+// PeriksaSKBerakhir mengirim pengingat SK yang segera berakhir (lihat Pengaturan) ke operator
+// wilayahnya dan admin, lalu sekali lagi setelah SK lewat. Tiap pengingat hanya dikirim sekali per penerima.
+func PeriksaSKBerakhir() {
+	hari := hariPeringatanSK()
+	y, m, d := time.Now().Date()
+	hariIni := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	batas := time.Now().AddDate(0, 0, hari).Format("2006-01-02")
+	// SK yang sudah lama kedaluwarsa tidak diingatkan lagi, cukup tampil di halaman Masa Berlaku SK
+	awal := time.Now().AddDate(0, 0, -7).Format("2006-01-02")
+	filter := func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Kelurahan.Kecamatan.Kabupaten").Where("berlaku_sampai BETWEEN ? AND ?", awal, batas)
+	}
+
+	var admins []models.User
+	config.DB.Where("role = ?", "admin").Find(&admins)
+
+	jumlah := 0
+	ingatkan := func(tipe string, id uint, kel models.Kelurahan, sk models.DataSK) {
+		if sk.BerlakuSampai == nil {
+			return
+		}
+		sisa := int(math.Round(sk.BerlakuSampai.Sub(hariIni).Hours() / 24))
+		tahap := "segera"
+		if sisa < 0 {
+			tahap = "berakhir"
+		}
+		kunci := fmt.Sprintf("sk:%s:%d:%s:%s", tipe, id, sk.BerlakuSampai.Format("2006-01-02"), tahap)
+		data := DataEmail{
+			Tipe:          namaProgram(tipe),
+			Label:         kel.Name,
+			Wilayah:       namaWilayah(kel),
+			BerlakuSampai: sk.BerlakuSampai.Format("02-01-2006"),
+			SisaHari:      sisa,
+			Path:          fmt.Sprintf("/admin/%s/edit/%d", tipe, id),
+		}
+
+		var operators []models.User
+		config.DB.Where("role = ? AND kabupaten_id = ?", "operator", kel.Kecamatan.KabupatenID).Find(&operators)
+		for _, u := range append(operators, admins...) {
+			if err := beritahu(config.DB, u, models.EmailSKBerakhir, kunci, data); err != nil {
+				log.Printf("Gagal mengantrekan pengingat SK %s/%d: %v", tipe, id, err)
+				return
+			}
+		}
+		jumlah++
+	}
+
+	var posbankums []models.Posbankum
+	config.DB.Scopes(filter).Find(&posbankums)
+	for _, d := range posbankums {
+		ingatkan("posbankum", d.ID, d.Kelurahan, d.DataSK)
+	}
+	var kadarkums []models.Kadarkum
+	config.DB.Scopes(filter).Find(&kadarkums)
+	for _, d := range kadarkums {
+		ingatkan("kadarkum", d.ID, d.Kelurahan, d.DataSK)
+	}
+	var pjas []models.Pja
+	config.DB.Scopes(filter).Find(&pjas)
+	for _, d := range pjas {
+		ingatkan("pja", d.ID, d.Kelurahan, d.DataSK)
+	}
+
+	if jumlah > 0 {
+		log.Printf("Pengingat SK: %d SK segera/sudah berakhir diperiksa", jumlah)
+	}
+}
+
+// ================== WORKER PENGIRIM ==================
+
+// maksPercobaanEmail sebelum email ditandai gagal; jeda antar percobaan makin panjang
+const maksPercobaanEmail = 6
+
+var jedaUlangEmail = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour, 6 * time.Hour}
+
+// KirimAntreanEmail mengirim email yang sudah jatuh tempo di outbox
+func KirimAntreanEmail() {
+	if !utils.EmailAktif() {
+		return
+	}
+	var antrean []models.EmailOutbox
+	config.DB.Where("status = ? AND kirim_at <= ?", models.EmailAntre, time.Now()).
+		Order("id").Limit(50).Find(&antrean)
+	for i := range antrean {
+		kirimEmailOutbox(&antrean[i])
+	}
+}
+
+func kirimEmailOutbox(e *models.EmailOutbox) {
+	err := utils.KirimEmail(e.Ke, e.Subjek, e.Isi)
+	e.Percobaan++
+	if err == nil {
+		sekarang := time.Now()
+		e.Status = models.EmailTerkirim
+		e.TerkirimAt = &sekarang
+		e.PesanError = ""
+	} else {
+		e.PesanError = err.Error()
+		if e.Percobaan >= maksPercobaanEmail {
+			e.Status = models.EmailGagal
+		} else {
+			e.KirimAt = time.Now().Add(jedaUlangEmail[min(e.Percobaan, len(jedaUlangEmail))-1])
+		}
+		log.Printf("Gagal mengirim email #%d ke %s (percobaan %d): %v", e.ID, e.Ke, e.Percobaan, err)
+	}
+	if err := config.DB.Save(e).Error; err != nil {
+		log.Printf("Gagal memperbarui status email #%d: %v", e.ID, err)
+	}
+}
+
+// MulaiPengirimEmail menjalankan pengirim outbox (tiap menit) dan pengingat SK (tiap hari) di background
+func MulaiPengirimEmail() {
+	if !utils.EmailAktif() {
+		log.Println("SMTP_HOST belum diatur, email notifikasi hanya diantrekan")
+	}
+	go func() {
+		for {
+			KirimAntreanEmail()
+			time.Sleep(time.Minute)
+		}
+	}()
+	go func() {
+		for {
+			PeriksaSKBerakhir()
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+}
+
+// ================== HALAMAN ADMIN ==================
+
+// jumlahEmailGagal untuk penanda di dashboard admin
+func jumlahEmailGagal() int64 {
+	var n int64
+	config.DB.Model(&models.EmailOutbox{}).Where("status = ?", models.EmailGagal).Count(&n)
+	return n
+}
+
+// EmailIndex menampilkan outbox email beserta status pengirimannya
+func EmailIndex(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit := 25
+	status := c.Query("status")
+
+	db := config.DB.Model(&models.EmailOutbox{})
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	var total int64
+	db.Count(&total)
+	var emails []models.EmailOutbox
+	db.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&emails)
+
+	jumlah := map[string]int64{}
+	var semua int64
+	for _, s := range []string{models.EmailAntre, models.EmailTerkirim, models.EmailGagal} {
+		var n int64
+		config.DB.Model(&models.EmailOutbox{}).Where("status = ?", s).Count(&n)
+		jumlah[s] = n
+		semua += n
+	}
+
+	c.HTML(http.StatusOK, "email.html", gin.H{
+		"Title":      "Email Notifikasi",
+		"Emails":     emails,
+		"Status":     status,
+		"Jumlah":     jumlah,
+		"Total":      semua,
+		"Templat":    templatEmail,
+		"SMTPAktif":  utils.EmailAktif(),
+		"Pengirim":   utils.PengirimEmail(),
+		"Page":       page,
+		"TotalPages": int(math.Ceil(float64(total) / float64(limit))),
+		"Error":      c.Query("error"),
+		"Sukses":     c.Query("sukses"),
+		"user":       sessions.Default(c).Get("user"),
+	})
+}
+
+// EmailKirimUlang menjadwalkan ulang email yang gagal/tertunda untuk segera dikirim
+func EmailKirimUlang(c *gin.Context) {
+	res := config.DB.Model(&models.EmailOutbox{}).
+		Where("id = ? AND status <> ?", c.Param("id"), models.EmailTerkirim).
+		Updates(map[string]any{"status": models.EmailAntre, "percobaan": 0, "kirim_at": time.Now()})
+	if res.Error != nil || res.RowsAffected == 0 {
+		kembaliDenganError(c, "/admin/email", "error", "Email tidak ditemukan atau sudah terkirim")
+		return
+	}
+	kembaliDenganError(c, "/admin/email", "sukses", "Email dijadwalkan ulang, akan dikirim dalam satu menit")
+}
+
+// EmailTes mengirim email uji langsung (tanpa outbox) untuk memeriksa konfigurasi SMTP
+func EmailTes(c *gin.Context) {
+	ke := strings.TrimSpace(c.PostForm("ke"))
+	if !utils.EmailValid(ke) {
+		kembaliDenganError(c, "/admin/email", "error", "Alamat email tidak valid")
+		return
+	}
+	username, _ := penggunaLogin(c)
+	isi := fmt.Sprintf("Email uji dari JADI, dikirim oleh %s pada %s.\nKonfigurasi SMTP sudah benar.",
+		username, time.Now().Format("02-01-2006 15:04"))
+	if err := utils.KirimEmail(ke, "[JADI] Email uji", isi); err != nil {
+		kembaliDenganError(c, "/admin/email", "error", "Gagal mengirim: "+err.Error())
+		return
+	}
+	kembaliDenganError(c, "/admin/email", "sukses", "Email uji terkirim ke "+ke)
+}
+
+// ================== PREFERENSI USER ==================
+
+// PilihanEmail adalah satu baris di halaman preferensi notifikasi
+type PilihanEmail struct {
+	Jenis string
+	TemplatEmail
+	Aktif bool
+}
+
+func userLogin(c *gin.Context) (models.User, error) {
+	username, _ := penggunaLogin(c)
+	var user models.User
+	err := config.DB.Where("username = ?", username).First(&user).Error
+	return user, err
+}
+
+func pilihanEmail(userID uint) []PilihanEmail {
+	var pilihan []PilihanEmail
+	for _, jenis := range jenisEmailDiatur {
+		pilihan = append(pilihan, PilihanEmail{
+			Jenis:        jenis,
+			TemplatEmail: templatEmail[jenis],
+			Aktif:        emailDiizinkan(config.DB, userID, jenis),
+		})
+	}
+	return pilihan
+}
+
+// NotifikasiAkun menampilkan alamat email dan pilihan notifikasi user yang login
+func NotifikasiAkun(c *gin.Context) {
+	user, err := userLogin(c)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/logout")
+		return
+	}
+	c.HTML(http.StatusOK, "notifikasi_akun.html", gin.H{
+		"Title":   "Notifikasi Email",
+		"Akun":    user,
+		"Beranda": halamanAwal(user.Role),
+		"Pilihan": pilihanEmail(user.ID),
+		"Sukses":  c.Query("sukses"),
+		"Error":   c.Query("error"),
+		"user":    user.Username,
+	})
+}
+
+// NotifikasiAkunSimpan menyimpan email (akun lokal) dan pilihan notifikasi
+func NotifikasiAkunSimpan(c *gin.Context) {
+	user, err := userLogin(c)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/logout")
+		return
+	}
+
+	// email akun SSO selalu diambil dari direktori saat login
+	if user.Sumber == "" || user.Sumber == "lokal" {
+		email := strings.TrimSpace(c.PostForm("email"))
+		if email != "" && !utils.EmailValid(email) {
+			kembaliDenganError(c, "/akun/notifikasi", "error", "Alamat email tidak valid")
+			return
+		}
+		user.Email = email
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("email", user.Email).Error; err != nil {
+			return err
+		}
+		for _, jenis := range jenisEmailDiatur {
+			pref := models.PreferensiEmail{UserID: user.ID, Jenis: jenis}
+			if err := tx.Where(pref).FirstOrInit(&pref).Error; err != nil {
+				return err
+			}
+			pref.Aktif = c.PostForm(jenis) == "1"
+			if err := tx.Save(&pref).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Gagal menyimpan preferensi email %s: %v", user.Username, err)
+		kembaliDenganError(c, "/akun/notifikasi", "error", "Gagal menyimpan, coba lagi")
+		return
+	}
+	kembaliDenganError(c, "/akun/notifikasi", "sukses", "Pengaturan notifikasi disimpan")
+}
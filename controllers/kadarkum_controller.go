@@ -1,18 +1,15 @@
 package controllers
 
 import (
+	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
-	"strings"
 
 	"go-admin/config"
 	"go-admin/models"
 	"go-admin/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -30,6 +27,7 @@ func KadarkumIndex(c *gin.Context) {
 
 	var kadarkums []models.Kadarkum
 	db := config.DB.Model(&models.Kadarkum{}).
+		Scopes(scopeWilayahOperator(c, "kadarkum")).
 		Preload("Kelurahan").
 		Preload("Kelurahan.Kecamatan").
 		Preload("Kelurahan.Kecamatan.Kabupaten")
@@ -52,6 +50,14 @@ func KadarkumIndex(c *gin.Context) {
 
 	totalPages := int((total + int64(limit) - 1) / int64(limit))
 
+	// status tanda tangan elektronik dan nilai penilaian terakhir untuk badge di tabel
+	ids := make([]uint, len(kadarkums))
+	kelurahanIDs := make([]uint, len(kadarkums))
+	for i, d := range kadarkums {
+		ids[i] = d.ID
+		kelurahanIDs[i] = d.KelurahanID
+	}
+
 	c.HTML(http.StatusOK, "kadarkum_index.html", gin.H{
 		"Title":      "Data Kadarkum",
 		"Kadarkums":  kadarkums,
@@ -59,6 +65,9 @@ func KadarkumIndex(c *gin.Context) {
 		"Page":       page,
 		"Offset":     offset,
 		"TotalPages": totalPages,
+		"TTD":        statusTTDMap("kadarkum", ids),
+		"Penilaian":  penilaianKadarkumTerakhir(kelurahanIDs),
+		"FormZIP":    formZIP(c, "/admin/unduh-zip", "kadarkum"),
 	})
 }
 
@@ -81,56 +90,54 @@ func KadarkumStore(c *gin.Context) {
 			"Title":          "Tambah Kadarkum",
 			"ErrorKelurahan": "❌ Kadarkum untuk kelurahan ini sudah ada",
 			"Catatan":        catatan,
+			"SK":             dataSKDariForm(c),
 		})
 		return
 	}
 
-	file, err := c.FormFile("dokumen")
-	if err != nil {
-		c.HTML(http.StatusOK, "kadarkum_create.html", gin.H{
-			"Title":     "Tambah Kadarkum",
-			"ErrorFile": "❌ Dokumen wajib diupload",
-			"Catatan":   catatan,
-		})
-		return
+	// dokumen dari upload biasa atau upload resumable yang sudah selesai
+	st, ada, msg := siapkanDokumenForm(c, "kadarkum", 0)
+	if !ada {
+		msg = "❌ Dokumen wajib diupload"
 	}
-
-	// validasi file
-	if !utils.ValidatePDFUpload(c, file) {
+	if msg != "" {
 		c.HTML(http.StatusOK, "kadarkum_create.html", gin.H{
 			"Title":     "Tambah Kadarkum",
-			"ErrorFile": "❌ File tidak valid. Pastikan file adalah PDF dan ukurannya di bawah 10MB.",
+			"ErrorFile": msg,
 			"Catatan":   catatan,
+			"SK":        dataSKDariForm(c),
 		})
 		return
 	}
 
-	uploadPath := "uploads/kadarkum"
-	os.MkdirAll(uploadPath, os.ModePerm)
-
-	// generate nama file unik menggunakan uuid
-	ext := filepath.Ext(file.Filename)
-	newName := uuid.New().String() + ext
-	fullPath := filepath.Join(uploadPath, newName)
+	kadarkum := models.Kadarkum{
+		KelurahanID:   uint(kelurahanID),
+		Catatan:       catatan,
+		DokumenPublik: c.PostForm("dokumen_publik") == "1",
+		DataSK:        dataSKDariForm(c),
+		Verifikasi:    models.Verifikasi{StatusVerifikasi: statusSetelahSimpan(c, "")},
+	}
 
-	if err := c.SaveUploadedFile(file, fullPath); err != nil {
+	if err := simpanDenganDokumen(st, func(tx *gorm.DB, path string) error {
+		kadarkum.Dokumen = path
+		if err := tx.Create(&kadarkum).Error; err != nil {
+			return err
+		}
+		return catatRiwayat(tx, c, "kadarkum", kadarkum.ID, "", kadarkum.StatusVerifikasi, "")
+	}); err != nil {
+		log.Printf("Gagal menyimpan kadarkum: %v", err)
 		c.HTML(http.StatusOK, "kadarkum_create.html", gin.H{
 			"Title":     "Tambah Kadarkum",
-			"ErrorFile": "❌ Gagal upload file",
+			"ErrorFile": "❌ Gagal menyimpan data, dokumen belum tersimpan. Silakan coba lagi.",
 			"Catatan":   catatan,
+			"SK":        dataSKDariForm(c),
 		})
 		return
 	}
 
-	publicPath := strings.ReplaceAll(fullPath, "\\", "/")
-
-	kadarkum := models.Kadarkum{
-		KelurahanID: uint(kelurahanID),
-		Dokumen:     publicPath,
-		Catatan:     catatan,
-	}
-
-	config.DB.Create(&kadarkum)
+	// ekstrak teks PDF untuk pencarian isi dokumen dan verifikasi tanda tangan elektroniknya
+	go indeksDokumen("kadarkum", kadarkum.ID, kadarkum.Dokumen)
+	go periksaTTD("kadarkum", kadarkum.ID, kadarkum.Dokumen)
 	c.Redirect(http.StatusFound, "/admin/kadarkum")
 }
 
@@ -141,9 +148,10 @@ func KadarkumView(c *gin.Context) {
 		c.String(http.StatusNotFound, "Dokumen tidak ditemukan: "+err.Error())
 		return
 	}
-	c.Header("Content-Disposition", "inline; filename="+filepath.Base(kadarkum.Dokumen))
-	c.Header("Content-Type", "application/pdf")
-	c.File(kadarkum.Dokumen)
+	if tampilkanDenganTTD(c, "kadarkum", kadarkum.ID, kadarkum.Dokumen) {
+		return
+	}
+	kirimDokumen(c, "kadarkum", kadarkum.ID, kadarkum.Dokumen)
 }
 
 // ================== EDIT FORM ==================
@@ -164,8 +172,22 @@ func KadarkumEdit(c *gin.Context) {
 	}
 
 	c.HTML(http.StatusOK, "kadarkum_edit.html", gin.H{
-		"Title":    "Edit Kadarkum",
-		"Kadarkum": kadarkum,
+		"Title":             "Edit Kadarkum",
+		"Kadarkum":          kadarkum,
+		"EntitasTipe":       "kadarkum",
+		"EntitasID":         kadarkum.ID,
+		"Lampirans":         daftarLampiran("kadarkum", kadarkum.ID),
+		"KategoriLampirans": semuaKategoriLampiran(),
+		"ErrorLampiran":     c.Query("error_lampiran"),
+		"OCR":               statusOCR("kadarkum", kadarkum.ID),
+		"OCRLampiran":       statusOCRLampiran("kadarkum", kadarkum.ID),
+		"TTD":               statusTTD("kadarkum", kadarkum.ID),
+		"Verifikasi":        infoVerifikasi("kadarkum", kadarkum.ID, kadarkum.Verifikasi),
+		"Komentars":         daftarKomentar("kadarkum", kadarkum.ID),
+		"ErrorKomentar":     c.Query("error_komentar"),
+		"SaranSK":           saranSK("kadarkum", kadarkum.ID),
+		"HariPeringatanSK":  hariPeringatanSK(),
+		"RiwayatPenilaian":  riwayatPenilaianKadarkum(kadarkum.KelurahanID),
 	})
 }
 
@@ -197,44 +219,48 @@ func KadarkumUpdate(c *gin.Context) {
 	kadarkum.KelurahanID = uint(kelurahanID)
 
 	kadarkum.Catatan = utils.SanitizeInput(c.PostForm("catatan"))
+	kadarkum.DokumenPublik = c.PostForm("dokumen_publik") == "1"
+	kadarkum.DataSK = dataSKDariForm(c)
 
-	file, err := c.FormFile("dokumen")
-	if err == nil {
-		if !utils.ValidatePDFUpload(c, file) {
-			c.HTML(http.StatusOK, "kadarkum_edit.html", gin.H{
-				"Title":     "Edit Kadarkum",
-				"Kadarkum":  kadarkum,
-				"ErrorFile": "❌ File tidak valid. Pastikan file adalah PDF dan ukurannya di bawah 10MB.",
-			})
-			return
-		}
-
-		uploadPath := "uploads/kadarkum"
-		os.MkdirAll(uploadPath, os.ModePerm)
-
-		// generate nama file unik menggunakan uuid
-		ext := filepath.Ext(file.Filename)
-		newName := uuid.New().String() + ext
-		newPath := filepath.Join(uploadPath, newName)
-
-		if err := c.SaveUploadedFile(file, newPath); err != nil {
-			c.HTML(http.StatusOK, "kadarkum_edit.html", gin.H{
-				"Title":     "Edit Kadarkum",
-				"Kadarkum":  kadarkum,
-				"ErrorFile": "❌ Gagal upload file",
-			})
-			return
+	// dokumen baru dari upload biasa atau upload resumable yang sudah selesai
+	st, adaFile, msg := siapkanDokumenForm(c, "kadarkum", kadarkum.ID)
+	if msg != "" {
+		c.HTML(http.StatusOK, "kadarkum_edit.html", gin.H{
+			"Title":     "Edit Kadarkum",
+			"Kadarkum":  kadarkum,
+			"ErrorFile": msg,
+		})
+		return
+	}
+	dokumenLama := kadarkum.Dokumen
+	statusLama := kadarkum.StatusVerifikasi
+	kadarkum.StatusVerifikasi = statusSetelahSimpan(c, statusLama)
+	if err := simpanDenganDokumen(st, func(tx *gorm.DB, path string) error {
+		if adaFile {
+			kadarkum.Dokumen = path
 		}
-
-		// hapus file lama kalau ada
-		if kadarkum.Dokumen != "" {
-			_ = os.Remove(kadarkum.Dokumen)
+		if err := tx.Save(&kadarkum).Error; err != nil {
+			return err
 		}
-
-		kadarkum.Dokumen = strings.ReplaceAll(newPath, "\\", "/")
+		return catatRiwayat(tx, c, "kadarkum", kadarkum.ID, statusLama, kadarkum.StatusVerifikasi, "")
+	}); err != nil {
+		log.Printf("Gagal menyimpan kadarkum %d: %v", kadarkum.ID, err)
+		kadarkum.Dokumen = dokumenLama
+		kadarkum.StatusVerifikasi = statusLama
+		c.HTML(http.StatusOK, "kadarkum_edit.html", gin.H{
+			"Title":     "Edit Kadarkum",
+			"Kadarkum":  kadarkum,
+			"ErrorFile": "❌ Gagal menyimpan data, dokumen belum tersimpan. Silakan coba lagi.",
+		})
+		return
 	}
 
-	config.DB.Save(&kadarkum)
+	// dokumen diganti: file lama dilepas (dihapus kalau tidak dipakai record lain), indeks ulang teksnya
+	if adaFile {
+		lepasFile(dokumenLama)
+		go indeksDokumen("kadarkum", kadarkum.ID, kadarkum.Dokumen)
+		go periksaTTD("kadarkum", kadarkum.ID, kadarkum.Dokumen)
+	}
 	c.Redirect(http.StatusFound, "/admin/kadarkum")
 }
 
@@ -248,11 +274,34 @@ func KadarkumDelete(c *gin.Context) {
 		return
 	}
 
-	// hapus file kalau ada
-	if kadarkum.Dokumen != "" {
-		_ = os.Remove(kadarkum.Dokumen)
+	// semua penghapusan di DB satu transaksi, file baru dilepas setelah commit
+	var fileLampiran []string
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		paths, err := hapusLampiranEntitas(tx, "kadarkum", kadarkum.ID)
+		if err != nil {
+			return err
+		}
+		fileLampiran = paths
+		if err := hapusIndeksDokumen(tx, "kadarkum", kadarkum.ID); err != nil {
+			return err
+		}
+		// diskusi, riwayat verifikasi dan notifikasi record ini
+		paths, err = hapusJejakEntitas(tx, "kadarkum", kadarkum.ID)
+		if err != nil {
+			return err
+		}
+		fileLampiran = append(fileLampiran, paths...)
+		return tx.Delete(&kadarkum).Error
+	}); err != nil {
+		log.Printf("Gagal menghapus kadarkum %d: %v", kadarkum.ID, err)
+		c.String(http.StatusInternalServerError, "❌ Gagal menghapus data, tidak ada yang berubah. Silakan coba lagi.")
+		return
 	}
 
-	config.DB.Delete(&kadarkum)
+	// file hanya dihapus kalau tidak dipakai record lain
+	for _, p := range fileLampiran {
+		lepasFile(p)
+	}
+	lepasFile(kadarkum.Dokumen)
 	c.Redirect(http.StatusFound, "/admin/kadarkum")
 }
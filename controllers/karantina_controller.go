@@ -0,0 +1,325 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ================== HELPER ==================
+
+// folderKarantina ada di luar uploads/ supaya tidak pernah ikut terlayani ke browser
+func folderKarantina() string {
+	if dir := os.Getenv("KARANTINA_DIR"); dir != "" {
+		return dir
+	}
+	return "karantina"
+}
+
+// pathKarantinaBaru membuat nama file acak tanpa ekstensi asli
+func pathKarantinaBaru() string {
+	os.MkdirAll(folderKarantina(), 0o700)
+	return filepath.Join(folderKarantina(), uuid.New().String()+".bin")
+}
+
+// catatKarantina menyimpan record karantina lalu memberi tahu semua admin (notifikasi + email)
+func catatKarantina(k models.Karantina) {
+	if err := config.DB.Create(&k).Error; err != nil {
+		log.Printf("Gagal mencatat karantina %s: %v", k.Path, err)
+	}
+	log.Printf("⚠️ MALWARE terdeteksi (%s) pada %s/%d file %q, dikarantina di %s", k.Virus, k.Tipe, k.EntitasID, k.NamaAsli, k.Path)
+
+	var admins []models.User
+	config.DB.Where("role = ?", "admin").Find(&admins)
+	data := DataEmail{
+		Tipe:     namaProgram(k.Tipe),
+		Label:    k.NamaAsli,
+		Komentar: k.Virus,
+		Oleh:     k.Username,
+		Sumber:   k.Sumber,
+		Path:     "/admin/karantina",
+	}
+	kunci := ""
+	if k.ID != 0 {
+		kunci = fmt.Sprintf("karantina:%d", k.ID)
+	}
+	for _, u := range admins {
+		if err := beritahu(config.DB, u, models.EmailMalware, kunci, data); err != nil {
+			log.Printf("Gagal memberi tahu %s soal karantina %s: %v", u.Username, k.NamaAsli, err)
+		}
+	}
+}
+
+// periksaMalware memindai file upload sebelum disimpan ke uploads/.
+// Kalau terinfeksi, file disalin ke folder karantina lalu pesan error untuk form dikembalikan.
+// String kosong berarti file aman untuk disimpan.
+func periksaMalware(c *gin.Context, file *multipart.FileHeader, tipe string, entitasID uint) string {
+	hasil, err := utils.ScanUpload(file)
+	if err != nil {
+		log.Printf("Pemindaian antivirus gagal untuk %s/%d: %v", tipe, entitasID, err)
+		if utils.ScanGagalTertutup() {
+			return "❌ Pemindai antivirus sedang tidak tersedia, coba upload lagi nanti."
+		}
+		return ""
+	}
+	if hasil.Bersih {
+		return ""
+	}
+
+	tujuan := pathKarantinaBaru()
+	if err := salinUpload(file, tujuan); err != nil {
+		log.Printf("Gagal menyalin file ke karantina: %v", err)
+		tujuan = ""
+	}
+
+	username, _ := sessions.Default(c).Get("user").(string)
+	catatKarantina(models.Karantina{
+		Tipe:      tipe,
+		EntitasID: entitasID,
+		NamaAsli:  filepath.Base(file.Filename),
+		Path:      tujuan,
+		Virus:     hasil.Virus,
+		Sumber:    "upload",
+		Username:  username,
+		IP:        c.ClientIP(),
+	})
+	return fmt.Sprintf("❌ File terdeteksi malware (%s) dan sudah dikarantina. Hubungi admin.", hasil.Virus)
+}
+
+func salinUpload(file *multipart.FileHeader, tujuan string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(tujuan, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// jumlahKarantinaBaru untuk penanda di dashboard admin
+func jumlahKarantinaBaru() int64 {
+	var total int64
+	config.DB.Model(&models.Karantina{}).Where("ditinjau = ?", false).Count(&total)
+	return total
+}
+
+// ================== PINDAI ULANG ==================
+
+// karantinakanFile memindahkan file arsip yang terinfeksi ke karantina
+func karantinakanFile(tipe string, id uint, path, virus string) {
+	tujuan := pathKarantinaBaru()
+	if err := os.Rename(path, tujuan); err != nil {
+		log.Printf("Gagal memindahkan %s ke karantina: %v", path, err)
+		return
+	}
+	os.Chmod(tujuan, 0o600)
+
+	// file di penyimpanan blob sudah tidak ada, catatannya ikut dihapus
+	config.DB.Where("path = ?", path).Delete(&models.BlobDokumen{})
+
+	catatKarantina(models.Karantina{
+		Tipe:      tipe,
+		EntitasID: id,
+		NamaAsli:  filepath.Base(path),
+		Path:      tujuan,
+		Virus:     virus,
+		Sumber:    "pindai-ulang",
+	})
+}
+
+// kosongkanRefPath melepas semua record yang memakai file terinfeksi. Kolomnya sama dengan yang
+// dihitung jumlahRefPath, karena satu blob bisa dipakai bersama oleh beberapa tabel.
+func kosongkanRefPath(path string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		dokumen := []struct {
+			tipe  string
+			model any
+		}{
+			{"posbankum", &models.Posbankum{}},
+			{"paralegal", &models.Paralegal{}},
+			{"pja", &models.Pja{}},
+			{"kadarkum", &models.Kadarkum{}},
+		}
+		for _, d := range dokumen {
+			var ids []uint
+			if err := tx.Model(d.model).Where("dokumen = ?", path).Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				continue
+			}
+			if err := tx.Model(d.model).Where("id IN ?", ids).Update("dokumen", "").Error; err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if err := hapusIndeksDokumen(tx, d.tipe, id); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, m := range []any{&models.Posbankum{}, &models.Paralegal{}} {
+			if err := tx.Model(m).Where("foto = ?", path).Update("foto", "").Error; err != nil {
+				return err
+			}
+		}
+
+		var lampirans []models.Lampiran
+		if err := tx.Where("path = ?", path).Find(&lampirans).Error; err != nil {
+			return err
+		}
+		for _, l := range lampirans {
+			if err := tx.Delete(&l).Error; err != nil {
+				return err
+			}
+			if err := hapusIndeksDokumen(tx, "lampiran", l.ID); err != nil {
+				return err
+			}
+		}
+
+		// isi komentar tetap ada, hanya lampirannya yang dilepas
+		if err := tx.Model(&models.Komentar{}).Where("lampiran_path = ?", path).Updates(map[string]any{
+			"lampiran_path":         "",
+			"lampiran_nama":         "",
+			"lampiran_content_type": "",
+			"lampiran_ukuran":       0,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Mediasi{}).Where("dokumen_kesepakatan = ?", path).
+			Update("dokumen_kesepakatan", "").Error
+	})
+}
+
+// PindaiUlangSemuaDokumen memindai seluruh arsip dokumen, foto, lampiran, lampiran komentar dan
+// dokumen kesepakatan mediasi dengan antivirus. Dipakai oleh perintah `go run . rescan-dokumen`.
+func PindaiUlangSemuaDokumen() {
+	log.Printf("Pindai ulang arsip dengan %s", utils.ScannerDokumen().Nama())
+	diperiksa, terinfeksi := 0, 0
+
+	// satu file blob bisa dipakai beberapa record, tiap path cukup dipindai sekali
+	sudahDipindai := map[string]bool{}
+
+	pindai := func(tipe string, id uint, path string) {
+		if path == "" || sudahDipindai[path] || !utils.FileAda(path) {
+			return
+		}
+		sudahDipindai[path] = true
+		diperiksa++
+		hasil, err := utils.ScanFile(path)
+		if err != nil {
+			log.Printf("Gagal memindai %s/%d (%s): %v", tipe, id, path, err)
+			return
+		}
+		if hasil.Bersih {
+			return
+		}
+		terinfeksi++
+		karantinakanFile(tipe, id, path, hasil.Virus)
+		// semua record pemakai file ini (di tabel mana pun) ikut dikosongkan, walaupun gagal dipindah
+		if err := kosongkanRefPath(path); err != nil {
+			log.Printf("Gagal melepas referensi file terinfeksi %s: %v", path, err)
+		}
+	}
+
+	var posbankums []models.Posbankum
+	config.DB.Where("dokumen <> '' OR foto <> ''").Find(&posbankums)
+	for _, d := range posbankums {
+		pindai("posbankum", d.ID, d.Dokumen)
+		pindai("posbankum", d.ID, d.Foto)
+	}
+
+	var paralegals []models.Paralegal
+	config.DB.Where("dokumen <> '' OR foto <> ''").Find(&paralegals)
+	for _, d := range paralegals {
+		pindai("paralegal", d.ID, d.Dokumen)
+		pindai("paralegal", d.ID, d.Foto)
+	}
+
+	var pjas []models.Pja
+	config.DB.Where("dokumen <> ''").Find(&pjas)
+	for _, d := range pjas {
+		pindai("pja", d.ID, d.Dokumen)
+	}
+
+	var kadarkums []models.Kadarkum
+	config.DB.Where("dokumen <> ''").Find(&kadarkums)
+	for _, d := range kadarkums {
+		pindai("kadarkum", d.ID, d.Dokumen)
+	}
+
+	var lampirans []models.Lampiran
+	config.DB.Find(&lampirans)
+	for _, l := range lampirans {
+		pindai("lampiran", l.ID, l.Path)
+	}
+
+	var komentars []models.Komentar
+	config.DB.Where("lampiran_path <> ''").Find(&komentars)
+	for _, k := range komentars {
+		pindai("komentar", k.ID, k.LampiranPath)
+	}
+
+	var mediasis []models.Mediasi
+	config.DB.Where("dokumen_kesepakatan <> ''").Find(&mediasis)
+	for _, m := range mediasis {
+		pindai("mediasi", m.ID, m.DokumenKesepakatan)
+	}
+
+	log.Printf("Pindai ulang selesai: %d file diperiksa, %d terinfeksi dan dikarantina", diperiksa, terinfeksi)
+}
+
+// ================== HALAMAN ADMIN ==================
+
+func KarantinaIndex(c *gin.Context) {
+	var items []models.Karantina
+	config.DB.Order("ditinjau, created_at DESC").Find(&items)
+
+	c.HTML(http.StatusOK, "karantina.html", gin.H{
+		"Title":   "Karantina Malware",
+		"Items":   items,
+		"Scanner": utils.ScannerDokumen().Nama(),
+		"user":    sessions.Default(c).Get("user"),
+	})
+}
+
+// KarantinaTinjau menandai temuan sudah ditinjau admin
+func KarantinaTinjau(c *gin.Context) {
+	config.DB.Model(&models.Karantina{}).Where("id = ?", c.Param("id")).Update("ditinjau", true)
+	c.Redirect(http.StatusFound, "/admin/karantina")
+}
+
+// KarantinaDelete menghapus file karantina secara permanen
+func KarantinaDelete(c *gin.Context) {
+	var item models.Karantina
+	if err := config.DB.First(&item, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Data karantina tidak ditemukan")
+		return
+	}
+	if item.Path != "" {
+		_ = os.Remove(item.Path)
+	}
+	config.DB.Delete(&item)
+	c.Redirect(http.StatusFound, "/admin/karantina")
+}
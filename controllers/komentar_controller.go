@@ -0,0 +1,362 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Diskusi per record program menggantikan obrolan di luar aplikasi: operator, verifikator dan admin
+// saling berkomentar (boleh dengan lampiran dan @username), tampil di halaman edit dan halaman Diskusi.
+// Komentar tidak pernah diubah/dihapus (kecuali ikut terhapus bersama record-nya), dan ikut tampil di riwayat record.
+
+// maksIsiKomentar membatasi panjang satu komentar (karakter)
+const maksIsiKomentar = 5000
+
+// polaSebutan mencari @username di isi komentar
+var polaSebutan = regexp.MustCompile(`@([\p{L}\p{N}_.\-]+)`)
+
+// ================== TAMPILAN ==================
+
+// BarisKomentar adalah satu komentar beserta balasannya
+type BarisKomentar struct {
+	models.Komentar
+	Balasan []BarisKomentar
+}
+
+// IsiHTML menampilkan isi komentar dengan baris baru dan @username yang valid ditebalkan
+func (k BarisKomentar) IsiHTML() template.HTML {
+	disebut := map[string]bool{}
+	for _, u := range strings.Split(k.Disebut, ",") {
+		disebut[u] = true
+	}
+	isi := template.HTMLEscapeString(k.Isi)
+	isi = polaSebutan.ReplaceAllStringFunc(isi, func(s string) string {
+		nama := strings.TrimRight(s[1:], ".-")
+		if !disebut[nama] {
+			return s
+		}
+		return `<span class="fw-bold text-primary">@` + nama + `</span>` + s[1+len(nama):]
+	})
+	return template.HTML(strings.ReplaceAll(isi, "\n", "<br>"))
+}
+
+// daftarKomentar mengambil diskusi satu record: komentar lama di atas, balasan di bawah induknya
+func daftarKomentar(tipe string, id uint) []BarisKomentar {
+	var semua []models.Komentar
+	config.DB.Where("tipe = ? AND entitas_id = ?", tipe, id).Order("id").Find(&semua)
+
+	var hasil []BarisKomentar
+	posisi := map[uint]int{}
+	for _, k := range semua {
+		if k.IndukID == nil {
+			posisi[k.ID] = len(hasil)
+			hasil = append(hasil, BarisKomentar{Komentar: k})
+		}
+	}
+	for _, k := range semua {
+		if k.IndukID == nil {
+			continue
+		}
+		if i, ok := posisi[*k.IndukID]; ok {
+			hasil[i].Balasan = append(hasil[i].Balasan, BarisKomentar{Komentar: k})
+		}
+	}
+	return hasil
+}
+
+// jumlahKomentar untuk penanda di antrean verifikasi
+func jumlahKomentar(tipe string, id uint) int64 {
+	var n int64
+	config.DB.Model(&models.Komentar{}).Where("tipe = ? AND entitas_id = ?", tipe, id).Count(&n)
+	return n
+}
+
+// JejakRecord adalah satu baris riwayat record: perpindahan status verifikasi atau komentar diskusi
+type JejakRecord struct {
+	RiwayatID  uint
+	KomentarID uint
+	Waktu      time.Time
+	Username   string
+	Dari       string
+	Ke         string
+	Komentar   string
+	Lampiran   string
+}
+
+// jejakRecord menggabungkan riwayat verifikasi dan komentar satu record, terbaru di atas
+func jejakRecord(tipe string, id uint, riwayat []models.RiwayatVerifikasi) []JejakRecord {
+	var jejak []JejakRecord
+	for _, r := range riwayat {
+		j := JejakRecord{RiwayatID: r.ID, Username: r.Username, Dari: r.Dari, Ke: r.Ke, Komentar: r.Komentar}
+		if r.CreatedAt != nil {
+			j.Waktu = *r.CreatedAt
+		}
+		jejak = append(jejak, j)
+	}
+	var komentars []models.Komentar
+	config.DB.Where("tipe = ? AND entitas_id = ?", tipe, id).Find(&komentars)
+	for _, k := range komentars {
+		j := JejakRecord{KomentarID: k.ID, Username: k.Username, Komentar: k.Isi, Lampiran: k.LampiranNama}
+		if k.CreatedAt != nil {
+			j.Waktu = *k.CreatedAt
+		}
+		jejak = append(jejak, j)
+	}
+	sort.SliceStable(jejak, func(a, b int) bool { return jejak[a].Waktu.After(jejak[b].Waktu) })
+	return jejak
+}
+
+// ================== SEBUTAN & NOTIFIKASI ==================
+
+// bolehDiskusi -> true kalau user boleh membuka diskusi record ini (operator hanya di wilayahnya)
+func bolehDiskusi(u models.User, tipe string, id uint) bool {
+	switch u.Role {
+	case "admin", "verifikator":
+		return true
+	case "operator":
+		return u.KabupatenID != nil && kabupatenKelurahan(kelurahanRecord(tipe, id)) == *u.KabupatenID
+	}
+	return false
+}
+
+// halamanDiskusi -> halaman diskusi record untuk user: verifikator tidak punya akses ke halaman edit
+func halamanDiskusi(role, tipe string, id uint) string {
+	if role == "verifikator" {
+		return fmt.Sprintf("/admin/diskusi/%s/%d", tipe, id)
+	}
+	return fmt.Sprintf("/admin/%s/edit/%d#diskusi", tipe, id)
+}
+
+// hapusJejakEntitas dipanggil di dalam transaksi saat record program dihapus: komentar, riwayat verifikasi
+// dan notifikasi yang menautkan ke record itu ikut dibuang supaya tidak ada tautan mati.
+// Path lampiran komentar dikembalikan untuk dilepas setelah transaksi commit.
+func hapusJejakEntitas(tx *gorm.DB, tipe string, id uint) ([]string, error) {
+	var paths []string
+	if err := tx.Model(&models.Komentar{}).Where("tipe = ? AND entitas_id = ? AND lampiran_path <> ''", tipe, id).
+		Pluck("lampiran_path", &paths).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("tipe = ? AND entitas_id = ?", tipe, id).Delete(&models.Komentar{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("tipe = ? AND entitas_id = ?", tipe, id).Delete(&models.RiwayatVerifikasi{}).Error; err != nil {
+		return nil, err
+	}
+	tautan := []string{
+		fmt.Sprintf("/admin/%s/edit/%d", tipe, id),
+		halamanDiskusi("operator", tipe, id),
+		halamanDiskusi("verifikator", tipe, id),
+	}
+	if err := tx.Where("tautan IN ?", tautan).Delete(&models.Notifikasi{}).Error; err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// userDisebut mencari user yang di-@ di isi komentar dan boleh membuka record-nya
+func userDisebut(isi, tipe string, id uint) []models.User {
+	var nama []string
+	for _, m := range polaSebutan.FindAllStringSubmatch(isi, -1) {
+		nama = append(nama, strings.TrimRight(m[1], ".-"))
+	}
+	if len(nama) == 0 {
+		return nil
+	}
+	var users, hasil []models.User
+	config.DB.Where("username IN ?", nama).Find(&users)
+	for _, u := range users {
+		if bolehDiskusi(u, tipe, id) {
+			hasil = append(hasil, u)
+		}
+	}
+	return hasil
+}
+
+// beritahuKomentar memberi tahu user yang disebut dan penulis komentar yang dibalas (kecuali penulisnya sendiri)
+func beritahuKomentar(tx *gorm.DB, k models.Komentar, disebut []models.User, induk *models.Komentar) error {
+	label, wilayah := labelEntitas(tx, k.Tipe, k.EntitasID)
+	data := DataEmail{Tipe: namaProgram(k.Tipe), Label: label, Wilayah: wilayah, Komentar: k.Isi, Oleh: k.Username}
+
+	sudah := map[string]bool{k.Username: true}
+	kirim := func(u models.User, sumber string) error {
+		if sudah[u.Username] {
+			return nil
+		}
+		sudah[u.Username] = true
+		d := data
+		d.Sumber = sumber
+		d.Path = halamanDiskusi(u.Role, k.Tipe, k.EntitasID)
+		return beritahu(tx, u, models.EmailKomentar, fmt.Sprintf("komentar:%d", k.ID), d)
+	}
+
+	for _, u := range disebut {
+		if err := kirim(u, "sebut"); err != nil {
+			return err
+		}
+	}
+	if induk != nil {
+		var penulis models.User
+		if err := tx.Where("username = ?", induk.Username).Limit(1).Find(&penulis).Error; err == nil && penulis.ID != 0 &&
+			bolehDiskusi(penulis, k.Tipe, k.EntitasID) {
+			return kirim(penulis, "balasan")
+		}
+	}
+	return nil
+}
+
+// ================== HANDLER ==================
+
+// DiskusiIndex menampilkan diskusi satu record; dipakai verifikator yang tidak membuka halaman edit
+func DiskusiIndex(c *gin.Context) {
+	tipe := c.Param("tipe")
+	id, _ := strconv.Atoi(c.Param("id"))
+	model := modelVerifikasi(tipe)
+	if model == nil || id <= 0 {
+		c.String(http.StatusBadRequest, "Data tidak valid")
+		return
+	}
+	var v models.Verifikasi
+	if err := config.DB.Model(model).Where("id = ?", id).First(&v).Error; err != nil {
+		c.String(http.StatusNotFound, "Data tidak ditemukan")
+		return
+	}
+	username, role := penggunaLogin(c)
+	label, wilayah := labelEntitas(config.DB, tipe, uint(id))
+
+	c.HTML(http.StatusOK, "diskusi.html", gin.H{
+		"Title":          "Diskusi " + namaProgram(tipe) + " " + label,
+		"NamaProgram":    namaProgram(tipe),
+		"Label":          label,
+		"Wilayah":        wilayah,
+		"EntitasTipe":    tipe,
+		"EntitasID":      uint(id),
+		"Komentars":      daftarKomentar(tipe, uint(id)),
+		"ErrorKomentar":  c.Query("error_komentar"),
+		"Verifikasi":     infoVerifikasi(tipe, uint(id), v),
+		"BolehEdit":      role == "admin" || role == "operator",
+		"HalamanDiskusi": true,
+		"Beranda":        halamanAwal(role),
+		"user":           username,
+	})
+}
+
+// DiskusiStore menyimpan komentar (atau balasan) baru beserta lampiran opsionalnya
+func DiskusiStore(c *gin.Context) {
+	tipe := c.Param("tipe")
+	id, _ := strconv.Atoi(c.Param("id"))
+	model := modelVerifikasi(tipe)
+	if model == nil || id <= 0 {
+		c.String(http.StatusBadRequest, "Data tidak valid")
+		return
+	}
+	if err := config.DB.First(model, id).Error; err != nil {
+		c.String(http.StatusNotFound, "Data tidak ditemukan")
+		return
+	}
+	username, role := penggunaLogin(c)
+
+	// kembali ke halaman asal form: halaman edit (admin/operator) atau halaman diskusi
+	kembali := fmt.Sprintf("/admin/diskusi/%s/%d", tipe, id)
+	if c.PostForm("dari") == "edit" && role != "verifikator" {
+		kembali = fmt.Sprintf("/admin/%s/edit/%d", tipe, id)
+	}
+
+	// teks bebas disimpan apa adanya (tanpa URL-decode/sanitasi HTML); escape dilakukan sekali saat tampil
+	isi := strings.TrimSpace(strings.ToValidUTF8(c.PostForm("isi"), ""))
+	if isi == "" {
+		kembaliDenganError(c, kembali, "error_komentar", "Isi komentar wajib diisi")
+		return
+	}
+	if utf8.RuneCountInString(isi) > maksIsiKomentar {
+		kembaliDenganError(c, kembali, "error_komentar", fmt.Sprintf("Komentar maksimal %d karakter", maksIsiKomentar))
+		return
+	}
+
+	komentar := models.Komentar{Tipe: tipe, EntitasID: uint(id), Username: username, Isi: isi}
+
+	// balasan selalu ditempel ke komentar utama supaya utas hanya satu tingkat
+	var induk *models.Komentar
+	if indukID, err := strconv.Atoi(c.PostForm("induk_id")); err == nil && indukID > 0 {
+		var k models.Komentar
+		if err := config.DB.Where("id = ? AND tipe = ? AND entitas_id = ?", indukID, tipe, id).First(&k).Error; err != nil {
+			kembaliDenganError(c, kembali, "error_komentar", "Komentar yang dibalas tidak ditemukan")
+			return
+		}
+		induk = &k
+		utama := k.ID
+		if k.IndukID != nil {
+			utama = *k.IndukID
+		}
+		komentar.IndukID = &utama
+	}
+
+	var st *berkasStaging
+	file, err := c.FormFile("file")
+	if err != nil && !errors.Is(err, http.ErrMissingFile) {
+		kembaliDenganError(c, kembali, "error_komentar", "Gagal upload file")
+		return
+	}
+	if file != nil {
+		contentType, err := utils.ValidateUpload(file, []string{"pdf", "jpg", "png"}, utils.MaxUploadSize)
+		if err != nil {
+			kembaliDenganError(c, kembali, "error_komentar", err.Error())
+			return
+		}
+		if msg := periksaMalware(c, file, "komentar", uint(id)); msg != "" {
+			kembaliDenganError(c, kembali, "error_komentar", msg)
+			return
+		}
+		src, err := file.Open()
+		if err != nil {
+			kembaliDenganError(c, kembali, "error_komentar", "Gagal upload file")
+			return
+		}
+		st, err = stagingDariReader(src, contentType)
+		src.Close()
+		if err != nil {
+			kembaliDenganError(c, kembali, "error_komentar", "Gagal upload file")
+			return
+		}
+		komentar.LampiranNama = utils.SanitizeInput(filepath.Base(file.Filename))
+		komentar.LampiranContentType = contentType
+		komentar.LampiranUkuran = file.Size
+	}
+
+	disebut := userDisebut(isi, tipe, uint(id))
+	var nama []string
+	for _, u := range disebut {
+		nama = append(nama, u.Username)
+	}
+	komentar.Disebut = strings.Join(nama, ",")
+
+	if err := simpanDenganDokumen(st, func(tx *gorm.DB, path string) error {
+		komentar.LampiranPath = path
+		if err := tx.Create(&komentar).Error; err != nil {
+			return err
+		}
+		return beritahuKomentar(tx, komentar, disebut, induk)
+	}); err != nil {
+		log.Printf("Gagal menyimpan komentar %s/%d: %v", tipe, id, err)
+		kembaliDenganError(c, kembali, "error_komentar", "Gagal simpan komentar")
+		return
+	}
+
+	c.Redirect(http.StatusFound, kembali+"#diskusi")
+}
@@ -0,0 +1,438 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Register konsultasi hukum di Posbankum. Operator kabupaten/kota hanya mencatat dan melihat konsultasi
+// di wilayahnya (WilayahOperator lewat posbankum_id/paralegal_id dan :id). Identitas klien hanya disimpan
+// kalau klien setuju; laporan dan dashboard hanya memakai jumlah.
+
+// namaBulan untuk judul kolom rekap bulanan
+var namaBulan = []string{"Jan", "Feb", "Mar", "Apr", "Mei", "Jun", "Jul", "Agu", "Sep", "Okt", "Nov", "Des"}
+
+// ================== REKAP BULANAN ==================
+
+// RekapKonsultasi adalah jumlah konsultasi per bulan dalam satu tahun untuk satu kabupaten/kota atau Posbankum.
+// Dirujuk menghitung konsultasi yang pernah dirujuk ke OBH/instansi lain (tujuan rujukan terisi).
+// Di level kabupaten, Posbankums berisi rincian Posbankum yang punya konsultasi.
+type RekapKonsultasi struct {
+	Nama       string
+	Bulan      [12]int
+	Total      int
+	Dirujuk    int
+	Posbankums []RekapKonsultasi
+}
+
+// rekapKonsultasi menghitung konsultasi per bulan pada tahun tertentu, per kabupaten/kota (urut sesuai daftar
+// kabupatens, yang kosong tetap ada) dan per Posbankum di dalamnya. Scope dipakai untuk filter, mis. hanya
+// Posbankum terverifikasi atau wilayah operator.
+func rekapKonsultasi(kabupatens []models.Kabupaten, tahun int, scopes ...func(*gorm.DB) *gorm.DB) []RekapKonsultasi {
+	var baris []struct {
+		KabupatenID   uint
+		PosbankumID   uint
+		NamaKelurahan string
+		NamaKecamatan string
+		Bulan         int
+		Jumlah        int
+		Dirujuk       int
+	}
+	config.DB.Model(&models.Konsultasi{}).
+		Select("kecamatans.kabupaten_id, konsultasis.posbankum_id, kelurahans.name AS nama_kelurahan, "+
+			"kecamatans.name AS nama_kecamatan, MONTH(konsultasis.tanggal) AS bulan, COUNT(*) AS jumlah, "+
+			"SUM(CASE WHEN konsultasis.rujukan <> '' THEN 1 ELSE 0 END) AS dirujuk").
+		Joins("JOIN posbankums ON posbankums.id = konsultasis.posbankum_id").
+		Joins("JOIN kelurahans ON kelurahans.id = posbankums.kelurahan_id").
+		Joins("JOIN kecamatans ON kecamatans.id = kelurahans.kecamatan_id").
+		Where("YEAR(konsultasis.tanggal) = ?", tahun).
+		Scopes(scopes...).
+		Group("kecamatans.kabupaten_id, konsultasis.posbankum_id, kelurahans.name, kecamatans.name, MONTH(konsultasis.tanggal)").
+		Order("kecamatans.name, kelurahans.name").
+		Scan(&baris)
+
+	indeksKab := make(map[uint]int, len(kabupatens))
+	hasil := make([]RekapKonsultasi, len(kabupatens))
+	for i, kab := range kabupatens {
+		indeksKab[kab.ID] = i
+		hasil[i].Nama = kab.Name
+	}
+	indeksPos := map[uint]int{}
+	for _, b := range baris {
+		i, ok := indeksKab[b.KabupatenID]
+		if !ok || b.Bulan < 1 || b.Bulan > 12 {
+			continue
+		}
+		kab := &hasil[i]
+		j, ok := indeksPos[b.PosbankumID]
+		if !ok {
+			j = len(kab.Posbankums)
+			indeksPos[b.PosbankumID] = j
+			kab.Posbankums = append(kab.Posbankums, RekapKonsultasi{Nama: b.NamaKelurahan + ", " + b.NamaKecamatan})
+		}
+		pos := &kab.Posbankums[j]
+		for _, r := range []*RekapKonsultasi{kab, pos} {
+			r.Bulan[b.Bulan-1] += b.Jumlah
+			r.Total += b.Jumlah
+			r.Dirujuk += b.Dirujuk
+		}
+	}
+	return hasil
+}
+
+// tahunRekap membaca ?tahun=, default tahun berjalan
+func tahunRekap(c *gin.Context) int {
+	tahun, err := strconv.Atoi(c.Query("tahun"))
+	if err != nil || tahun < 2000 || tahun > time.Now().Year()+1 {
+		return time.Now().Year()
+	}
+	return tahun
+}
+
+// jumlahKonsultasiBulanIni untuk ringkasan di dashboard admin
+func jumlahKonsultasiBulanIni() int64 {
+	var n int64
+	awal := time.Now().Format("2006-01") + "-01"
+	config.DB.Model(&models.Konsultasi{}).Where("tanggal >= ?", awal).Count(&n)
+	return n
+}
+
+// KonsultasiRekap menampilkan jumlah konsultasi per bulan per kabupaten/kota dan per Posbankum
+func KonsultasiRekap(c *gin.Context) {
+	tahun := tahunRekap(c)
+	var kabupatens []models.Kabupaten
+	db := config.DB.Order("name")
+	if kabupatenID, dibatasi := kabupatenOperator(c); dibatasi {
+		db = db.Where("id = ?", kabupatenID)
+	}
+	db.Find(&kabupatens)
+
+	rekap := rekapKonsultasi(kabupatens, tahun)
+	var total RekapKonsultasi
+	for _, r := range rekap {
+		for i, n := range r.Bulan {
+			total.Bulan[i] += n
+		}
+		total.Total += r.Total
+		total.Dirujuk += r.Dirujuk
+	}
+
+	c.HTML(http.StatusOK, "konsultasi_rekap.html", gin.H{
+		"Title":     "Rekap Konsultasi Hukum",
+		"Tahun":     tahun,
+		"Rekap":     rekap,
+		"Total":     total,
+		"NamaBulan": namaBulan,
+	})
+}
+
+// ================== REGISTER ==================
+
+// KonsultasiIndex menampilkan register konsultasi, bisa difilter Posbankum, kategori, hasil, bulan dan kata kunci
+func KonsultasiIndex(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	kategori := c.Query("kategori")
+	hasil := c.Query("hasil")
+	bulan := c.Query("bulan") // format input type=month, mis. 2026-10
+	posbankumID, _ := strconv.Atoi(c.Query("posbankum_id"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit := 50
+
+	db := config.DB.Model(&models.Konsultasi{}).
+		Joins("JOIN posbankums ON posbankums.id = konsultasis.posbankum_id").
+		Joins("JOIN kelurahans ON kelurahans.id = posbankums.kelurahan_id").
+		Scopes(scopeWilayahOperator(c, "konsultasi"))
+	if q != "" {
+		db = db.Where("konsultasis.uraian LIKE ? OR konsultasis.nama_klien LIKE ? OR kelurahans.name LIKE ?",
+			"%"+q+"%", "%"+q+"%", "%"+q+"%")
+	}
+	if posbankumID > 0 {
+		db = db.Where("konsultasis.posbankum_id = ?", posbankumID)
+	}
+	if slices.Contains(models.KategoriKonsultasi, kategori) {
+		db = db.Where("konsultasis.kategori = ?", kategori)
+	}
+	if slices.Contains(models.HasilKonsultasi, hasil) {
+		db = db.Where("konsultasis.hasil = ?", hasil)
+	}
+	if awal, err := time.ParseInLocation("2006-01", bulan, time.Local); err == nil {
+		db = db.Where("konsultasis.tanggal >= ? AND konsultasis.tanggal < ?",
+			awal.Format("2006-01-02"), awal.AddDate(0, 1, 0).Format("2006-01-02"))
+	} else {
+		bulan = ""
+	}
+
+	var total int64
+	db.Count(&total)
+	var konsultasis []models.Konsultasi
+	db.Preload("Posbankum.Kelurahan.Kecamatan.Kabupaten").Preload("Paralegal").
+		Order("konsultasis.tanggal DESC, konsultasis.id DESC").
+		Offset((page - 1) * limit).Limit(limit).Find(&konsultasis)
+
+	c.HTML(http.StatusOK, "konsultasi_index.html", gin.H{
+		"Title":       "Register Konsultasi Hukum",
+		"Konsultasis": konsultasis,
+		"Total":       total,
+		"Search":      q,
+		"Kategori":    kategori,
+		"Kategoris":   models.KategoriKonsultasi,
+		"Hasil":       hasil,
+		"Hasils":      models.HasilKonsultasi,
+		"Bulan":       bulan,
+		"PosbankumID": posbankumID,
+		"Page":        page,
+		"TotalPages":  int(math.Ceil(float64(total) / float64(limit))),
+		"Error":       c.Query("error"),
+	})
+}
+
+// konsultasiDariForm membaca dan memvalidasi isian form konsultasi. Nama dan kontak klien dibuang
+// kalau klien tidak memberi persetujuan.
+func konsultasiDariForm(c *gin.Context, k *models.Konsultasi) string {
+	posbankumID, _ := strconv.Atoi(c.PostForm("posbankum_id"))
+	k.PosbankumID = uint(posbankumID)
+	k.ParalegalID = nil
+	if id, err := strconv.Atoi(c.PostForm("paralegal_id")); err == nil && id > 0 {
+		pid := uint(id)
+		k.ParalegalID = &pid
+	}
+	k.Tanggal = tanggalForm(c, "tanggal")
+	k.PersetujuanKlien = c.PostForm("persetujuan_klien") == "1"
+	k.NamaKlien = strings.TrimSpace(utils.SanitizeInput(c.PostForm("nama_klien")))
+	k.JenisKelaminKlien = c.PostForm("jenis_kelamin_klien")
+	k.Kategori = c.PostForm("kategori")
+	k.Uraian = strings.TrimSpace(utils.SanitizeInput(c.PostForm("uraian")))
+	k.Hasil = c.PostForm("hasil")
+	k.Rujukan = strings.TrimSpace(utils.SanitizeInput(c.PostForm("rujukan")))
+
+	var kontakValid bool
+	k.KontakKlien, kontakValid = utils.NormalisasiTelepon(c.PostForm("kontak_klien"))
+	if !k.PersetujuanKlien {
+		k.NamaKlien, k.KontakKlien, kontakValid = "", "", true
+	}
+
+	var posbankum models.Posbankum
+	switch {
+	case config.DB.First(&posbankum, k.PosbankumID).Error != nil:
+		return "Pilih Posbankum dari daftar"
+	case k.Tanggal == nil:
+		return "Tanggal konsultasi wajib diisi"
+	case k.Tanggal.After(time.Now()):
+		return "Tanggal konsultasi tidak boleh di masa depan"
+	case !slices.Contains(models.KategoriKonsultasi, k.Kategori):
+		return "Pilih kategori perkara"
+	case !slices.Contains(models.HasilKonsultasi, k.Hasil):
+		return "Pilih hasil konsultasi"
+	case k.JenisKelaminKlien != "" && k.JenisKelaminKlien != "L" && k.JenisKelaminKlien != "P":
+		return "Jenis kelamin klien tidak valid"
+	case k.PersetujuanKlien && k.NamaKlien == "":
+		return "Nama klien wajib diisi kalau klien setuju identitasnya dicatat"
+	case !kontakValid:
+		return "Nomor kontak klien tidak valid"
+	case k.Hasil == models.HasilDirujuk && k.Rujukan == "":
+		return "Isi OBH/instansi tujuan rujukan"
+	case len([]rune(k.NamaKlien)) > 191 || len([]rune(k.Rujukan)) > 191:
+		return "Nama klien atau tujuan rujukan terlalu panjang"
+	}
+	if k.ParalegalID != nil {
+		var n int64
+		config.DB.Model(&models.Paralegal{}).Where("id = ? AND posbankum_id = ?", *k.ParalegalID, k.PosbankumID).Count(&n)
+		if n == 0 {
+			return "Paralegal yang menangani harus dari Posbankum yang sama"
+		}
+	}
+	return ""
+}
+
+// formKonsultasi menyiapkan data halaman form (create/edit)
+func formKonsultasi(k models.Konsultasi, pesan string) gin.H {
+	judul := "Catat Konsultasi"
+	if k.ID != 0 {
+		judul = "Edit Konsultasi"
+	}
+	// label Posbankum terpilih untuk kolom autocomplete
+	var posbankum models.Posbankum
+	labelPosbankum := ""
+	if k.PosbankumID != 0 && config.DB.Preload("Kelurahan.Kecamatan.Kabupaten").First(&posbankum, k.PosbankumID).Error == nil {
+		labelPosbankum = posbankum.Kelurahan.Name + " - " + posbankum.Kelurahan.Kecamatan.Name + " - " +
+			posbankum.Kelurahan.Kecamatan.Kabupaten.Name
+	}
+	var paralegals []models.Paralegal
+	if k.PosbankumID != 0 {
+		config.DB.Where("posbankum_id = ?", k.PosbankumID).Order("nama").Find(&paralegals)
+	}
+	var paralegalID uint
+	if k.ParalegalID != nil {
+		paralegalID = *k.ParalegalID
+	}
+	var tindakLanjuts []models.TindakLanjutKonsultasi
+	if k.ID != 0 {
+		config.DB.Where("konsultasi_id = ?", k.ID).Order("tanggal, id").Find(&tindakLanjuts)
+	}
+	return gin.H{
+		"Title":          judul,
+		"Konsultasi":     k,
+		"LabelPosbankum": labelPosbankum,
+		"Paralegals":     paralegals,
+		"ParalegalID":    paralegalID,
+		"TindakLanjuts":  tindakLanjuts,
+		"Kategoris":      models.KategoriKonsultasi,
+		"Hasils":         models.HasilKonsultasi,
+		"Error":          pesan,
+	}
+}
+
+func KonsultasiCreate(c *gin.Context) {
+	k := models.Konsultasi{Hasil: models.HasilProses}
+	// dari halaman Posbankum: ?posbankum_id= langsung terisi kalau masih di wilayah operator
+	if id, err := strconv.Atoi(c.Query("posbankum_id")); err == nil {
+		var n int64
+		config.DB.Model(&models.Posbankum{}).Scopes(scopeWilayahOperator(c, "posbankum")).Where("id = ?", id).Count(&n)
+		if n > 0 {
+			k.PosbankumID = uint(id)
+		}
+	}
+	c.HTML(http.StatusOK, "konsultasi_form.html", formKonsultasi(k, ""))
+}
+
+func KonsultasiStore(c *gin.Context) {
+	var k models.Konsultasi
+	if msg := konsultasiDariForm(c, &k); msg != "" {
+		c.HTML(http.StatusOK, "konsultasi_form.html", formKonsultasi(k, msg))
+		return
+	}
+	k.Dicatat, _ = penggunaLogin(c)
+	if err := config.DB.Create(&k).Error; err != nil {
+		c.HTML(http.StatusOK, "konsultasi_form.html", formKonsultasi(k, "❌ Gagal menyimpan konsultasi, silakan coba lagi"))
+		return
+	}
+	c.Redirect(http.StatusFound, fmt.Sprintf("/admin/konsultasi/edit/%d", k.ID))
+}
+
+func KonsultasiEdit(c *gin.Context) {
+	var k models.Konsultasi
+	if err := config.DB.First(&k, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Konsultasi tidak ditemukan")
+		return
+	}
+	data := formKonsultasi(k, c.Query("error"))
+	data["ErrorTindakLanjut"] = c.Query("error_tindak_lanjut")
+	c.HTML(http.StatusOK, "konsultasi_form.html", data)
+}
+
+func KonsultasiUpdate(c *gin.Context) {
+	var k models.Konsultasi
+	if err := config.DB.First(&k, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Konsultasi tidak ditemukan")
+		return
+	}
+	if msg := konsultasiDariForm(c, &k); msg != "" {
+		c.HTML(http.StatusOK, "konsultasi_form.html", formKonsultasi(k, msg))
+		return
+	}
+	if err := config.DB.Save(&k).Error; err != nil {
+		c.HTML(http.StatusOK, "konsultasi_form.html", formKonsultasi(k, "❌ Gagal menyimpan konsultasi, silakan coba lagi"))
+		return
+	}
+	c.Redirect(http.StatusFound, fmt.Sprintf("/admin/konsultasi/edit/%d", k.ID))
+}
+
+// KonsultasiDelete menghapus konsultasi beserta catatan tindak lanjutnya
+func KonsultasiDelete(c *gin.Context) {
+	var k models.Konsultasi
+	if err := config.DB.First(&k, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Konsultasi tidak ditemukan")
+		return
+	}
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("konsultasi_id = ?", k.ID).Delete(&models.TindakLanjutKonsultasi{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&k).Error
+	}); err != nil {
+		kembaliDenganError(c, "/admin/konsultasi", "error", "Gagal menghapus konsultasi")
+		return
+	}
+	c.Redirect(http.StatusFound, "/admin/konsultasi")
+}
+
+// KonsultasiParalegal (JSON) daftar paralegal satu Posbankum untuk pilihan "ditangani oleh" di form
+func KonsultasiParalegal(c *gin.Context) {
+	var paralegals []models.Paralegal
+	config.DB.Model(&models.Paralegal{}).Scopes(scopeWilayahOperator(c, "paralegal")).
+		Where("posbankum_id = ?", c.Query("posbankum_id")).Order("nama").Find(&paralegals)
+
+	results := []gin.H{}
+	for _, p := range paralegals {
+		results = append(results, gin.H{"id": p.ID, "nama": p.Nama})
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// ================== TINDAK LANJUT ==================
+
+// TindakLanjutStore menambah catatan tindak lanjut dan (opsional) memperbarui hasil konsultasi
+func TindakLanjutStore(c *gin.Context) {
+	var k models.Konsultasi
+	if err := config.DB.First(&k, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Konsultasi tidak ditemukan")
+		return
+	}
+	kembali := fmt.Sprintf("/admin/konsultasi/edit/%d", k.ID)
+
+	tanggal := tanggalForm(c, "tanggal")
+	catatan := strings.TrimSpace(utils.SanitizeInput(c.PostForm("catatan")))
+	hasil := c.PostForm("hasil")
+	switch {
+	case tanggal == nil:
+		kembaliDenganError(c, kembali, "error_tindak_lanjut", "Tanggal tindak lanjut wajib diisi")
+		return
+	case k.Tanggal != nil && tanggal.Before(*k.Tanggal):
+		kembaliDenganError(c, kembali, "error_tindak_lanjut", "Tanggal tindak lanjut tidak boleh sebelum tanggal konsultasi")
+		return
+	case catatan == "":
+		kembaliDenganError(c, kembali, "error_tindak_lanjut", "Catatan tindak lanjut wajib diisi")
+		return
+	case hasil != "" && !slices.Contains(models.HasilKonsultasi, hasil):
+		kembaliDenganError(c, kembali, "error_tindak_lanjut", "Hasil konsultasi tidak valid")
+		return
+	case hasil == models.HasilDirujuk && k.Rujukan == "":
+		kembaliDenganError(c, kembali, "error_tindak_lanjut", "Isi tujuan rujukan di form konsultasi sebelum menandai dirujuk")
+		return
+	}
+
+	username, _ := penggunaLogin(c)
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.TindakLanjutKonsultasi{
+			KonsultasiID: k.ID,
+			Tanggal:      tanggal,
+			Catatan:      catatan,
+			Username:     username,
+		}).Error; err != nil {
+			return err
+		}
+		if hasil != "" && hasil != k.Hasil {
+			return tx.Model(&k).Update("hasil", hasil).Error
+		}
+		return nil
+	}); err != nil {
+		kembaliDenganError(c, kembali, "error_tindak_lanjut", "Gagal menyimpan tindak lanjut")
+		return
+	}
+	c.Redirect(http.StatusFound, kembali+"#tindak-lanjut")
+}
@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ================== HELPER ==================
+
+// entitasValid mengecek tipe entitas pemilik lampiran
+func entitasValid(tipe string) bool {
+	switch tipe {
+	case "posbankum", "kadarkum", "pja", "paralegal":
+		return true
+	}
+	return false
+}
+
+// kembaliDenganError redirect ke halaman asal dengan pesan error di query string
+func kembaliDenganError(c *gin.Context, tujuan, key, pesan string) {
+	c.Redirect(http.StatusFound, tujuan+"?"+key+"="+url.QueryEscape(pesan))
+}
+
+// urutLampiran dipakai di Preload supaya lampiran tampil sesuai urutan
+func urutLampiran(db *gorm.DB) *gorm.DB {
+	return db.Order("urutan, id")
+}
+
+// daftarLampiran mengambil semua lampiran milik satu record
+func daftarLampiran(tipe string, id uint) []models.Lampiran {
+	var lampirans []models.Lampiran
+	config.DB.Preload("Kategori").
+		Where("entitas_type = ? AND entitas_id = ?", tipe, id).
+		Order("urutan, id").
+		Find(&lampirans)
+	return lampirans
+}
+
+// semuaKategoriLampiran untuk pilihan kategori di form upload
+func semuaKategoriLampiran() []models.KategoriLampiran {
+	var kategoris []models.KategoriLampiran
+	config.DB.Order("urutan, nama").Find(&kategoris)
+	return kategoris
+}
+
+// hapusLampiranEntitas dipanggil di dalam transaksi saat record induk dihapus.
+// File tidak disentuh; path yang dikembalikan baru dilepas setelah transaksi commit.
+func hapusLampiranEntitas(tx *gorm.DB, tipe string, id uint) ([]string, error) {
+	var paths []string
+	for _, l := range daftarLampiran(tipe, id) {
+		if err := tx.Delete(&l).Error; err != nil {
+			return nil, err
+		}
+		if err := hapusIndeksDokumen(tx, "lampiran", l.ID); err != nil {
+			return nil, err
+		}
+		paths = append(paths, l.Path)
+	}
+	return paths, nil
+}
+
+// ================== KATEGORI LAMPIRAN ==================
+
+func KategoriLampiranIndex(c *gin.Context) {
+	c.HTML(http.StatusOK, "kategori_lampiran.html", gin.H{
+		"Title":     "Kategori Lampiran",
+		"Kategoris": semuaKategoriLampiran(),
+		"Error":     c.Query("error"),
+	})
+}
+
+// kategoriDariForm membaca dan memvalidasi isian form kategori
+func kategoriDariForm(c *gin.Context, k *models.KategoriLampiran) string {
+	k.Nama = strings.TrimSpace(utils.SanitizeInput(c.PostForm("nama")))
+	k.MaksUkuranMB, _ = strconv.Atoi(c.PostForm("maks_ukuran_mb"))
+	k.Urutan, _ = strconv.Atoi(c.PostForm("urutan"))
+	k.Watermark = c.PostForm("watermark") == "1"
+
+	var tipe []string
+	for _, t := range c.PostFormArray("tipe") {
+		switch t {
+		case "pdf", "jpg", "png":
+			tipe = append(tipe, t)
+		}
+	}
+	k.TipeDiizinkan = strings.Join(tipe, ",")
+
+	switch {
+	case k.Nama == "":
+		return "Nama kategori wajib diisi"
+	case len(tipe) == 0:
+		return "Pilih minimal satu tipe file"
+	case k.MaksUkuranMB < 1 || k.MaksUkuranMB > 50:
+		return "Ukuran maksimal harus 1-50 MB"
+	}
+	return ""
+}
+
+func KategoriLampiranStore(c *gin.Context) {
+	var kategori models.KategoriLampiran
+	if msg := kategoriDariForm(c, &kategori); msg != "" {
+		kembaliDenganError(c, "/admin/kategori-lampiran", "error", msg)
+		return
+	}
+	if err := config.DB.Create(&kategori).Error; err != nil {
+		kembaliDenganError(c, "/admin/kategori-lampiran", "error", "Nama kategori sudah ada")
+		return
+	}
+	c.Redirect(http.StatusFound, "/admin/kategori-lampiran")
+}
+
+func KategoriLampiranUpdate(c *gin.Context) {
+	var kategori models.KategoriLampiran
+	if err := config.DB.First(&kategori, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Kategori tidak ditemukan")
+		return
+	}
+	if msg := kategoriDariForm(c, &kategori); msg != "" {
+		kembaliDenganError(c, "/admin/kategori-lampiran", "error", msg)
+		return
+	}
+	if err := config.DB.Save(&kategori).Error; err != nil {
+		kembaliDenganError(c, "/admin/kategori-lampiran", "error", "Nama kategori sudah ada")
+		return
+	}
+	c.Redirect(http.StatusFound, "/admin/kategori-lampiran")
+}
+
+func KategoriLampiranDelete(c *gin.Context) {
+	var count int64
+	config.DB.Model(&models.Lampiran{}).Where("kategori_id = ?", c.Param("id")).Count(&count)
+	if count > 0 {
+		kembaliDenganError(c, "/admin/kategori-lampiran", "error", "Kategori masih dipakai oleh lampiran")
+		return
+	}
+	config.DB.Delete(&models.KategoriLampiran{}, c.Param("id"))
+	c.Redirect(http.StatusFound, "/admin/kategori-lampiran")
+}
+
+// ================== LAMPIRAN ==================
+
+// LampiranStore menyimpan lampiran baru untuk satu record
+func LampiranStore(c *gin.Context) {
+	tipe := c.PostForm("entitas_type")
+	entitasID, _ := strconv.Atoi(c.PostForm("entitas_id"))
+	if !entitasValid(tipe) || entitasID <= 0 {
+		c.String(http.StatusBadRequest, "Data induk lampiran tidak valid")
+		return
+	}
+	kembali := fmt.Sprintf("/admin/%s/edit/%d", tipe, entitasID)
+
+	var kategori models.KategoriLampiran
+	if err := config.DB.First(&kategori, c.PostForm("kategori_id")).Error; err != nil {
+		kembaliDenganError(c, kembali, "error_lampiran", "Kategori lampiran wajib dipilih")
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		kembaliDenganError(c, kembali, "error_lampiran", "File wajib diupload")
+		return
+	}
+
+	contentType, err := utils.ValidateUpload(file, strings.Split(kategori.TipeDiizinkan, ","), int64(kategori.MaksUkuranMB)*1024*1024)
+	if err != nil {
+		kembaliDenganError(c, kembali, "error_lampiran", err.Error())
+		return
+	}
+
+	// pindai antivirus sebelum file masuk ke uploads/
+	if msg := periksaMalware(c, file, "lampiran", 0); msg != "" {
+		kembaliDenganError(c, kembali, "error_lampiran", msg)
+		return
+	}
+
+	// ditaruh di staging dulu, masuk penyimpanan blob setelah record-nya tersimpan
+	src, err := file.Open()
+	if err != nil {
+		kembaliDenganError(c, kembali, "error_lampiran", "Gagal upload file")
+		return
+	}
+	st, err := stagingDariReader(src, contentType)
+	src.Close()
+	if err != nil {
+		kembaliDenganError(c, kembali, "error_lampiran", "Gagal upload file")
+		return
+	}
+
+	// taruh di urutan paling akhir
+	var urutan int
+	config.DB.Model(&models.Lampiran{}).
+		Where("entitas_type = ? AND entitas_id = ?", tipe, entitasID).
+		Select("COALESCE(MAX(urutan), 0)").Scan(&urutan)
+
+	lampiran := models.Lampiran{
+		EntitasType: tipe,
+		EntitasID:   uint(entitasID),
+		KategoriID:  kategori.ID,
+		NamaAsli:    utils.SanitizeInput(filepath.Base(file.Filename)),
+		ContentType: contentType,
+		Ukuran:      file.Size,
+		Urutan:      urutan + 1,
+		Keterangan:  utils.SanitizeInput(c.PostForm("keterangan")),
+	}
+	if err := simpanDenganDokumen(st, func(tx *gorm.DB, path string) error {
+		lampiran.Path = path
+		return tx.Create(&lampiran).Error
+	}); err != nil {
+		log.Printf("Gagal menyimpan lampiran %s/%d: %v", tipe, entitasID, err)
+		kembaliDenganError(c, kembali, "error_lampiran", "Gagal simpan lampiran")
+		return
+	}
+
+	go indeksDokumen("lampiran", lampiran.ID, lampiran.Path)
+	go periksaTTD("lampiran", lampiran.ID, lampiran.Path)
+
+	c.Redirect(http.StatusFound, kembali)
+}
+
+// LampiranPindah menukar urutan lampiran dengan tetangganya (arah=naik/turun)
+func LampiranPindah(c *gin.Context) {
+	var lampiran models.Lampiran
+	if err := config.DB.First(&lampiran, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Lampiran tidak ditemukan")
+		return
+	}
+	kembali := fmt.Sprintf("/admin/%s/edit/%d", lampiran.EntitasType, lampiran.EntitasID)
+
+	daftar := daftarLampiran(lampiran.EntitasType, lampiran.EntitasID)
+	for i := range daftar {
+		if daftar[i].ID != lampiran.ID {
+			continue
+		}
+		j := i - 1
+		if c.PostForm("arah") == "turun" {
+			j = i + 1
+		}
+		if j < 0 || j >= len(daftar) {
+			break
+		}
+
+		// normalisasi urutan sesuai posisi sekarang lalu tukar
+		daftar[i], daftar[j] = daftar[j], daftar[i]
+		config.DB.Transaction(func(tx *gorm.DB) error {
+			for k, l := range daftar {
+				if err := tx.Model(&models.Lampiran{}).Where("id = ?", l.ID).Update("urutan", k+1).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		break
+	}
+
+	c.Redirect(http.StatusFound, kembali)
+}
+
+func LampiranDelete(c *gin.Context) {
+	var lampiran models.Lampiran
+	if err := config.DB.First(&lampiran, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Lampiran tidak ditemukan")
+		return
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&lampiran).Error; err != nil {
+			return err
+		}
+		return hapusIndeksDokumen(tx, "lampiran", lampiran.ID)
+	}); err != nil {
+		log.Printf("Gagal menghapus lampiran %d: %v", lampiran.ID, err)
+		c.String(http.StatusInternalServerError, "❌ Gagal menghapus lampiran, tidak ada yang berubah. Silakan coba lagi.")
+		return
+	}
+	// file hanya dihapus kalau tidak dipakai record lain
+	lepasFile(lampiran.Path)
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/admin/%s/edit/%d", lampiran.EntitasType, lampiran.EntitasID))
+}
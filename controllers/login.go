@@ -1,10 +1,13 @@
 package controllers
 
 import (
+	"errors"
+	"log"
 	"net/http"
 
 	"go-admin/config"
 	"go-admin/models"
+	"go-admin/utils"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -22,7 +25,9 @@ func HashPassword(password string) (string, error) {
 // Login Page
 func ShowLogin(c *gin.Context) {
 	c.HTML(http.StatusOK, "login.html", gin.H{
-		"Title": "Login",
+		"Title":      "Login",
+		"OIDCAktif":  utils.OIDCAktif(),
+		"LoginLokal": pengaturanAktif(PengaturanLoginLokal, true) || utils.LDAPAktif(),
 	})
 }
 
@@ -33,47 +38,93 @@ func DoLogin(c *gin.Context) {
 
 	var user models.User
 	// cari user berdasarkan username
-	if err := config.DB.Where("username = ?", username).First(&user).Error; err != nil {
-		c.HTML(http.StatusOK, "login.html", gin.H{
-			"Title": "Login",
-			"error": "❌ Username tidak ditemukan",
-		})
+	errUser := config.DB.Where("username = ?", username).First(&user).Error
+
+	// akun lokal -> cek password di DB
+	if errUser == nil && (user.Sumber == "" || user.Sumber == "lokal") {
+		if !pengaturanAktif(PengaturanLoginLokal, true) {
+			loginGagal(c, "❌ Login lokal dinonaktifkan, silakan masuk lewat SSO")
+			return
+		}
+
+		// cek password pakai bcrypt
+		err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+		if err != nil {
+			// fallback: kalau password masih plain text di DB
+			if user.Password != "" && user.Password == password {
+				// langsung update jadi hashed
+				hashed, _ := HashPassword(password)
+				user.Password = hashed
+				config.DB.Save(&user)
+			} else {
+				loginGagal(c, "❌ Password salah")
+				return
+			}
+		}
+
+		loginBerhasil(c, user)
 		return
 	}
 
-	// cek password pakai bcrypt
-	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-	if err != nil {
-		// fallback: kalau password masih plain text di DB
-		if user.Password == password {
-			// langsung update jadi hashed
-			hashed, _ := HashPassword(password)
-			user.Password = hashed
-			config.DB.Save(&user)
-		} else {
-			c.HTML(http.StatusOK, "login.html", gin.H{
-				"Title": "Login",
-				"error": "❌ Password salah",
-			})
+	// bukan akun lokal -> coba bind ke LDAP
+	if utils.LDAPAktif() {
+		identitas, err := utils.LDAPAuthenticate(username, password)
+		if err != nil {
+			if !errors.Is(err, utils.ErrKredensialDirektori) {
+				log.Printf("Login LDAP gagal: %v", err)
+			}
+			loginGagal(c, "❌ Username atau password salah")
+			return
+		}
+
+		user, err := userDariDirektori(identitas, "ldap")
+		if err != nil {
+			loginGagal(c, "❌ "+err.Error())
 			return
 		}
+		loginBerhasil(c, user)
+		return
+	}
+
+	if errUser == nil {
+		loginGagal(c, "❌ Akun ini terdaftar lewat SSO, silakan masuk lewat SSO")
+		return
 	}
+	loginGagal(c, "❌ Username tidak ditemukan")
+}
 
-	// simpan session
+// loginBerhasil menyimpan session lalu redirect sesuai role
+func loginBerhasil(c *gin.Context, user models.User) {
 	session := sessions.Default(c)
 	session.Set("user", user.Username)
-	session.Set("role", user.Role) // simpan role (admin/user)
+	session.Set("role", user.Role) // simpan role (admin/verifikator/operator/user)
 	session.Save()
 
-	// redirect sesuai role
-	switch user.Role {
+	c.Redirect(http.StatusFound, halamanAwal(user.Role))
+}
+
+// halamanAwal adalah halaman pertama tiap role setelah login
+func halamanAwal(role string) string {
+	switch role {
 	case "admin":
-		c.Redirect(http.StatusFound, "/admin")
+		return "/admin"
+	case "verifikator":
+		return "/admin/verifikasi"
+	case "operator":
+		return "/admin/posbankum"
 	case "user":
-		c.Redirect(http.StatusFound, "/user")
-	default:
-		c.Redirect(http.StatusFound, "/login")
+		return "/user"
 	}
+	return "/login"
+}
+
+func loginGagal(c *gin.Context, pesan string) {
+	c.HTML(http.StatusOK, "login.html", gin.H{
+		"Title":      "Login",
+		"Error":      pesan,
+		"OIDCAktif":  utils.OIDCAktif(),
+		"LoginLokal": pengaturanAktif(PengaturanLoginLokal, true) || utils.LDAPAktif(),
+	})
 }
 
 // Logout
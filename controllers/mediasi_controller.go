@@ -0,0 +1,284 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Log mediasi kepala desa/lurah: perkara yang diselesaikan di luar pengadilan per kelurahan/desa.
+// Operator kabupaten/kota hanya mencatat dan melihat perkara di wilayahnya. Kasus-kasus ini ditarik
+// ke dosier saat desa diusulkan untuk Peacemaker Justice Award (lihat penghargaan_pja_controller.go).
+
+// ================== INDEX ==================
+
+// MediasiIndex menampilkan log mediasi, bisa difilter kelurahan, jenis sengketa, hasil, tahun dan kata kunci
+func MediasiIndex(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	jenis := c.Query("jenis")
+	hasil := c.Query("hasil")
+	tahun, _ := strconv.Atoi(c.Query("tahun"))
+	kelurahanID, _ := strconv.Atoi(c.Query("kelurahan_id"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit := 50
+
+	db := config.DB.Model(&models.Mediasi{}).
+		Joins("JOIN kelurahans ON kelurahans.id = mediasis.kelurahan_id").
+		Scopes(scopeWilayahOperator(c, "mediasi"))
+	if q != "" {
+		db = db.Where("mediasis.pihak_pertama LIKE ? OR mediasis.pihak_kedua LIKE ? OR mediasis.mediator LIKE ? OR kelurahans.name LIKE ?",
+			"%"+q+"%", "%"+q+"%", "%"+q+"%", "%"+q+"%")
+	}
+	if kelurahanID > 0 {
+		db = db.Where("mediasis.kelurahan_id = ?", kelurahanID)
+	}
+	if slices.Contains(models.JenisSengketa, jenis) {
+		db = db.Where("mediasis.jenis_sengketa = ?", jenis)
+	}
+	if slices.Contains(models.HasilPerdamaian, hasil) {
+		db = db.Where("mediasis.hasil = ?", hasil)
+	}
+	if tahun > 0 {
+		db = db.Where("YEAR(mediasis.tanggal_mulai) = ?", tahun)
+	}
+
+	var total int64
+	db.Count(&total)
+	var mediasis []models.Mediasi
+	db.Preload("Kelurahan.Kecamatan.Kabupaten").
+		Order("mediasis.tanggal_mulai DESC, mediasis.id DESC").
+		Offset((page - 1) * limit).Limit(limit).Find(&mediasis)
+
+	c.HTML(http.StatusOK, "mediasi_index.html", gin.H{
+		"Title":       "Log Mediasi Desa",
+		"Mediasis":    mediasis,
+		"Total":       total,
+		"Search":      q,
+		"Jenis":       jenis,
+		"JenisList":   models.JenisSengketa,
+		"Hasil":       hasil,
+		"Hasils":      models.HasilPerdamaian,
+		"Tahun":       tahun,
+		"KelurahanID": kelurahanID,
+		"Page":        page,
+		"TotalPages":  int(math.Ceil(float64(total) / float64(limit))),
+		"Error":       c.Query("error"),
+	})
+}
+
+// ================== FORM ==================
+
+// mediasiDariForm membaca dan memvalidasi isian form mediasi (tanpa dokumen kesepakatan)
+func mediasiDariForm(c *gin.Context, m *models.Mediasi) string {
+	kelurahanID, _ := strconv.Atoi(c.PostForm("kelurahan_id"))
+	m.KelurahanID = uint(kelurahanID)
+	m.Mediator = strings.TrimSpace(utils.SanitizeInput(c.PostForm("mediator")))
+	m.PihakPertama = strings.TrimSpace(utils.SanitizeInput(c.PostForm("pihak_pertama")))
+	m.PihakKedua = strings.TrimSpace(utils.SanitizeInput(c.PostForm("pihak_kedua")))
+	m.JenisSengketa = c.PostForm("jenis_sengketa")
+	m.Uraian = strings.TrimSpace(utils.SanitizeInput(c.PostForm("uraian")))
+	m.TanggalMulai = tanggalForm(c, "tanggal_mulai")
+	m.TanggalSelesai = tanggalForm(c, "tanggal_selesai")
+	m.Hasil = c.PostForm("hasil")
+
+	var n int64
+	config.DB.Model(&models.Kelurahan{}).Where("id = ?", m.KelurahanID).Count(&n)
+	switch {
+	case n == 0:
+		return "Pilih kelurahan/desa dari daftar"
+	case m.Mediator == "":
+		return "Nama kepala desa/lurah yang memediasi wajib diisi"
+	case m.PihakPertama == "" || m.PihakKedua == "":
+		return "Nama kedua pihak yang bersengketa wajib diisi"
+	case len([]rune(m.Mediator)) > 191 || len([]rune(m.PihakPertama)) > 191 || len([]rune(m.PihakKedua)) > 191:
+		return "Nama mediator atau para pihak terlalu panjang"
+	case !slices.Contains(models.JenisSengketa, m.JenisSengketa):
+		return "Pilih jenis sengketa"
+	case !slices.Contains(models.HasilPerdamaian, m.Hasil):
+		return "Pilih hasil mediasi"
+	case m.TanggalMulai == nil:
+		return "Tanggal mulai mediasi wajib diisi"
+	case m.TanggalMulai.After(time.Now()):
+		return "Tanggal mulai tidak boleh di masa depan"
+	case m.TanggalSelesai != nil && m.TanggalSelesai.Before(*m.TanggalMulai):
+		return "Tanggal selesai tidak boleh sebelum tanggal mulai"
+	case m.Hasil != models.MediasiProses && m.TanggalSelesai == nil:
+		return "Isi tanggal selesai untuk mediasi yang sudah berakhir"
+	}
+	return ""
+}
+
+// formMediasi menyiapkan data halaman form (create/edit)
+func formMediasi(m models.Mediasi, pesan string) gin.H {
+	judul := "Catat Mediasi"
+	if m.ID != 0 {
+		judul = "Edit Mediasi"
+	}
+	// label kelurahan terpilih untuk kolom autocomplete
+	var kelurahan models.Kelurahan
+	labelKelurahan := ""
+	if m.KelurahanID != 0 && config.DB.Preload("Kecamatan.Kabupaten").First(&kelurahan, m.KelurahanID).Error == nil {
+		labelKelurahan = kelurahan.Name + " (" + kelurahan.Kecamatan.Name + " - " + kelurahan.Kecamatan.Kabupaten.Name + ")"
+	}
+	return gin.H{
+		"Title":          judul,
+		"Mediasi":        m,
+		"LabelKelurahan": labelKelurahan,
+		"JenisList":      models.JenisSengketa,
+		"Hasils":         models.HasilPerdamaian,
+		"Error":          pesan,
+	}
+}
+
+func MediasiCreate(c *gin.Context) {
+	m := models.Mediasi{Hasil: models.MediasiProses}
+	// dari log per desa: ?kelurahan_id= langsung terisi kalau masih di wilayah operator
+	if id, err := strconv.Atoi(c.Query("kelurahan_id")); err == nil {
+		if kabupatenID, dibatasi := kabupatenOperator(c); !dibatasi || kabupatenKelurahan(uint(id)) == kabupatenID {
+			m.KelurahanID = uint(id)
+		}
+	}
+	c.HTML(http.StatusOK, "mediasi_form.html", formMediasi(m, ""))
+}
+
+func MediasiStore(c *gin.Context) {
+	var m models.Mediasi
+	if msg := mediasiDariForm(c, &m); msg != "" {
+		c.HTML(http.StatusOK, "mediasi_form.html", formMediasi(m, msg))
+		return
+	}
+	// dokumen kesepakatan boleh menyusul, mis. setelah akta perdamaian ditandatangani
+	st, _, msg := siapkanDokumenForm(c, "mediasi", 0)
+	if msg != "" {
+		c.HTML(http.StatusOK, "mediasi_form.html", formMediasi(m, msg))
+		return
+	}
+	m.Dicatat, _ = penggunaLogin(c)
+	simpan := func(tx *gorm.DB) error { return tx.Create(&m).Error }
+	var err error
+	if st != nil {
+		err = simpanDenganDokumen(st, func(tx *gorm.DB, path string) error {
+			m.DokumenKesepakatan = path
+			return simpan(tx)
+		})
+	} else {
+		err = simpan(config.DB)
+	}
+	if err != nil {
+		log.Printf("Gagal menyimpan mediasi: %v", err)
+		m.DokumenKesepakatan = ""
+		c.HTML(http.StatusOK, "mediasi_form.html", formMediasi(m, "❌ Gagal menyimpan mediasi, silakan coba lagi"))
+		return
+	}
+	c.Redirect(http.StatusFound, fmt.Sprintf("/admin/mediasi/edit/%d", m.ID))
+}
+
+func MediasiEdit(c *gin.Context) {
+	var m models.Mediasi
+	if err := config.DB.First(&m, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Mediasi tidak ditemukan")
+		return
+	}
+	data := formMediasi(m, c.Query("error"))
+	// usulan PJA yang memakai kasus ini sebagai bukti
+	var nominasis []models.NominasiPJA
+	config.DB.Preload("Periode").
+		Joins("JOIN nominasi_pja_mediasis ON nominasi_pja_mediasis.nominasi_pja_id = nominasi_pjas.id").
+		Where("nominasi_pja_mediasis.mediasi_id = ?", m.ID).Find(&nominasis)
+	data["Nominasis"] = nominasis
+	c.HTML(http.StatusOK, "mediasi_form.html", data)
+}
+
+func MediasiUpdate(c *gin.Context) {
+	var m models.Mediasi
+	if err := config.DB.First(&m, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Mediasi tidak ditemukan")
+		return
+	}
+	if msg := mediasiDariForm(c, &m); msg != "" {
+		c.HTML(http.StatusOK, "mediasi_form.html", formMediasi(m, msg))
+		return
+	}
+	st, adaFile, msg := siapkanDokumenForm(c, "mediasi", m.ID)
+	if msg != "" {
+		c.HTML(http.StatusOK, "mediasi_form.html", formMediasi(m, msg))
+		return
+	}
+	dokumenLama := m.DokumenKesepakatan
+	var err error
+	if adaFile {
+		err = simpanDenganDokumen(st, func(tx *gorm.DB, path string) error {
+			m.DokumenKesepakatan = path
+			return tx.Save(&m).Error
+		})
+	} else {
+		err = config.DB.Save(&m).Error
+	}
+	if err != nil {
+		log.Printf("Gagal menyimpan mediasi %d: %v", m.ID, err)
+		m.DokumenKesepakatan = dokumenLama
+		c.HTML(http.StatusOK, "mediasi_form.html", formMediasi(m, "❌ Gagal menyimpan mediasi, silakan coba lagi"))
+		return
+	}
+	// dokumen diganti: file lama dilepas (dihapus kalau tidak dipakai record lain)
+	if adaFile {
+		lepasFile(dokumenLama)
+	}
+	c.Redirect(http.StatusFound, fmt.Sprintf("/admin/mediasi/edit/%d", m.ID))
+}
+
+// MediasiView menampilkan dokumen kesepakatan perdamaian
+func MediasiView(c *gin.Context) {
+	var m models.Mediasi
+	if err := config.DB.First(&m, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Mediasi tidak ditemukan")
+		return
+	}
+	kirimDokumen(c, "mediasi", m.ID, m.DokumenKesepakatan)
+}
+
+// MediasiDelete menghapus mediasi dan mengeluarkannya dari dosier usulan PJA. Kasus yang menjadi bukti
+// usulan pada periode yang sudah ditetapkan tidak boleh dihapus.
+func MediasiDelete(c *gin.Context) {
+	var m models.Mediasi
+	if err := config.DB.First(&m, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Mediasi tidak ditemukan")
+		return
+	}
+	var terkunci int64
+	config.DB.Model(&models.NominasiPJA{}).
+		Joins("JOIN nominasi_pja_mediasis ON nominasi_pja_mediasis.nominasi_pja_id = nominasi_pjas.id").
+		Joins("JOIN periode_pjas ON periode_pjas.id = nominasi_pjas.periode_id").
+		Where("nominasi_pja_mediasis.mediasi_id = ? AND periode_pjas.status = ?", m.ID, models.PeriodePJASelesai).
+		Count(&terkunci)
+	if terkunci > 0 {
+		kembaliDenganError(c, "/admin/mediasi", "error", "Mediasi ini menjadi bukti penghargaan PJA yang sudah ditetapkan, tidak bisa dihapus")
+		return
+	}
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM nominasi_pja_mediasis WHERE mediasi_id = ?", m.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&m).Error
+	}); err != nil {
+		kembaliDenganError(c, "/admin/mediasi", "error", "Gagal menghapus mediasi")
+		return
+	}
+	lepasFile(m.DokumenKesepakatan)
+	c.Redirect(http.StatusFound, "/admin/mediasi")
+}
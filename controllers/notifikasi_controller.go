@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-admin/config"
+	"go-admin/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Notifikasi di aplikasi dibuat dari event yang sama dengan email (lihat email_controller.go):
+// satu panggilan beritahu menulis notifikasi untuk lonceng lalu mengantrekan email-nya.
+
+// ================== PEMBUAT NOTIFIKASI ==================
+
+// beritahu mencatat notifikasi untuk user lalu mengantrekan email-nya (kalau user punya email & mengizinkan).
+// Notifikasi dengan kunci yang sama hanya dibuat sekali per user.
+func beritahu(db *gorm.DB, user models.User, jenis, kunci string, data DataEmail) error {
+	if kunci != "" {
+		var n int64
+		db.Model(&models.Notifikasi{}).Where("kunci = ? AND user_id = ?", kunci, user.ID).Count(&n)
+		if n > 0 {
+			return nil
+		}
+	}
+	data.Nama = user.Username
+	t, ok := templatEmail[jenis]
+	if !ok {
+		return fmt.Errorf("jenis notifikasi tidak dikenal: %s", jenis)
+	}
+	judul, err := isiTemplat(jenis+"_subjek", t.Subjek, data)
+	if err != nil {
+		return err
+	}
+	pesan, err := isiTemplat(jenis+"_ringkas", t.Ringkas, data)
+	if err != nil {
+		return err
+	}
+	if err := db.Create(&models.Notifikasi{
+		UserID: user.ID,
+		Jenis:  jenis,
+		Kunci:  kunci,
+		Judul:  strings.TrimPrefix(strings.Join(strings.Fields(judul), " "), "[JADI] "),
+		Pesan:  pesan,
+		Tautan: data.Path,
+	}).Error; err != nil {
+		return err
+	}
+	return antreEmail(db, user, jenis, kunci, data)
+}
+
+// beritahuPekerjaan melaporkan hasil pekerjaan background ke user yang memulainya,
+// atau ke semua admin kalau dijalankan terjadwal (pemicu kosong)
+func beritahuPekerjaan(pemicu, label, hasil, path string) {
+	var penerima []models.User
+	if pemicu != "" {
+		config.DB.Where("username = ?", pemicu).Find(&penerima)
+	} else {
+		config.DB.Where("role = ?", "admin").Find(&penerima)
+	}
+	data := DataEmail{Label: label, Komentar: hasil, Path: path}
+	for _, u := range penerima {
+		if err := beritahu(config.DB, u, models.EmailPekerjaan, "", data); err != nil {
+			log.Printf("Gagal membuat notifikasi %q untuk %s: %v", label, u.Username, err)
+		}
+	}
+}
+
+// ================== HALAMAN NOTIFIKASI ==================
+
+// NotifikasiIndex menampilkan notifikasi user yang login, terbaru di atas
+func NotifikasiIndex(c *gin.Context) {
+	user, err := userLogin(c)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/logout")
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit := 20
+	belumDibaca := c.Query("filter") == "belum"
+
+	db := config.DB.Model(&models.Notifikasi{}).Where("user_id = ?", user.ID)
+	if belumDibaca {
+		db = db.Where("dibaca_at IS NULL")
+	}
+	var total int64
+	db.Count(&total)
+	var notifikasis []models.Notifikasi
+	db.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&notifikasis)
+
+	c.HTML(http.StatusOK, "notifikasi.html", gin.H{
+		"Title":       "Notifikasi",
+		"Notifikasis": notifikasis,
+		"BelumDibaca": belumDibaca,
+		"Jumlah":      jumlahBelumDibaca(user.ID),
+		"Beranda":     halamanAwal(user.Role),
+		"Page":        page,
+		"TotalPages":  int(math.Ceil(float64(total) / float64(limit))),
+		"user":        user.Username,
+	})
+}
+
+func jumlahBelumDibaca(userID uint) int64 {
+	var n int64
+	config.DB.Model(&models.Notifikasi{}).Where("user_id = ? AND dibaca_at IS NULL", userID).Count(&n)
+	return n
+}
+
+// NotifikasiJumlah (JSON) untuk lonceng di layout, dipanggil berkala:
+// jumlah belum dibaca dan 5 notifikasi terbaru yang belum dibaca
+func NotifikasiJumlah(c *gin.Context) {
+	user, err := userLogin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "belum login"})
+		return
+	}
+	var terbaru []models.Notifikasi
+	config.DB.Where("user_id = ? AND dibaca_at IS NULL", user.ID).Order("id DESC").Limit(5).Find(&terbaru)
+
+	type item struct {
+		ID     uint   `json:"id"`
+		Judul  string `json:"judul"`
+		Pesan  string `json:"pesan"`
+		Tautan string `json:"tautan"`
+		Waktu  string `json:"waktu"`
+	}
+	items := make([]item, 0, len(terbaru))
+	for _, n := range terbaru {
+		it := item{ID: n.ID, Judul: n.Judul, Pesan: n.Pesan, Tautan: n.Tautan}
+		if n.CreatedAt != nil {
+			it.Waktu = n.CreatedAt.Format(time.RFC3339)
+		}
+		items = append(items, it)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"belum_dibaca": jumlahBelumDibaca(user.ID),
+		"terbaru":      items,
+	})
+}
+
+// NotifikasiBaca menandai satu notifikasi sudah dibaca lalu membuka halaman terkaitnya
+func NotifikasiBaca(c *gin.Context) {
+	user, err := userLogin(c)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/logout")
+		return
+	}
+	var n models.Notifikasi
+	if err := config.DB.Where("id = ? AND user_id = ?", c.Param("id"), user.ID).First(&n).Error; err != nil {
+		c.Redirect(http.StatusFound, "/notifikasi")
+		return
+	}
+	if n.DibacaAt == nil {
+		config.DB.Model(&n).Update("dibaca_at", time.Now())
+	}
+	// hanya path lokal, jangan sampai jadi open redirect
+	if c.PostForm("buka") == "1" && strings.HasPrefix(n.Tautan, "/") && !strings.HasPrefix(n.Tautan, "//") {
+		c.Redirect(http.StatusFound, n.Tautan)
+		return
+	}
+	c.Redirect(http.StatusFound, "/notifikasi")
+}
+
+// NotifikasiBacaSemua menandai semua notifikasi user sudah dibaca
+func NotifikasiBacaSemua(c *gin.Context) {
+	user, err := userLogin(c)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/logout")
+		return
+	}
+	config.DB.Model(&models.Notifikasi{}).Where("user_id = ? AND dibaca_at IS NULL", user.ID).
+		Update("dibaca_at", time.Now())
+	c.Redirect(http.StatusFound, "/notifikasi")
+}
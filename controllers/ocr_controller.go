@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// sinyal untuk membangunkan worker OCR begitu ada job baru
+var bangunOCR = make(chan struct{}, 1)
+
+func bangunkanWorkerOCR() {
+	select {
+	case bangunOCR <- struct{}{}:
+	default:
+	}
+}
+
+// ================== ANTREAN ==================
+
+// antrekanOCR memasukkan (atau mengulang) job OCR untuk satu dokumen
+func antrekanOCR(tipe string, id uint, path string) {
+	var job models.OCRJob
+	config.DB.Where("tipe = ? AND entitas_id = ?", tipe, id).First(&job)
+
+	job.Tipe = tipe
+	job.EntitasID = id
+	job.Path = path
+	job.Status = models.OCRMenunggu
+	job.Percobaan = 0
+	job.Pesan = ""
+	job.JumlahHalaman = 0
+	job.RataKeyakinan = 0
+	job.SelesaiAt = nil
+
+	if err := config.DB.Save(&job).Error; err != nil {
+		log.Printf("Gagal antrekan OCR %s/%d: %v", tipe, id, err)
+		return
+	}
+	bangunkanWorkerOCR()
+}
+
+// sudahDiOCR true kalau file yang sama sudah pernah selesai di-OCR
+func sudahDiOCR(tipe string, id uint, path string) bool {
+	var total int64
+	config.DB.Model(&models.OCRJob{}).
+		Where("tipe = ? AND entitas_id = ? AND path = ? AND status = ?", tipe, id, path, models.OCRSelesai).
+		Count(&total)
+	return total > 0
+}
+
+// statusOCR mengambil job OCR satu dokumen untuk ditampilkan di halaman edit, nil kalau tidak ada
+func statusOCR(tipe string, id uint) *models.OCRJob {
+	var job models.OCRJob
+	if err := config.DB.Where("tipe = ? AND entitas_id = ?", tipe, id).First(&job).Error; err != nil {
+		return nil
+	}
+	return &job
+}
+
+// statusOCRLampiran mengambil job OCR semua lampiran satu record, key = ID lampiran
+func statusOCRLampiran(tipe string, id uint) map[uint]*models.OCRJob {
+	var jobs []models.OCRJob
+	config.DB.Where("tipe = ? AND entitas_id IN (?)", "lampiran",
+		config.DB.Model(&models.Lampiran{}).Select("id").Where("entitas_type = ? AND entitas_id = ?", tipe, id)).
+		Find(&jobs)
+
+	hasil := make(map[uint]*models.OCRJob, len(jobs))
+	for i := range jobs {
+		hasil[jobs[i].EntitasID] = &jobs[i]
+	}
+	return hasil
+}
+
+// ================== WORKER ==================
+
+// MulaiWorkerOCR menjalankan satu goroutine yang memproses antrean OCR satu per satu.
+// OCR berat di CPU, jadi sengaja tidak paralel.
+func MulaiWorkerOCR() {
+	// job yang terputus karena server mati dikembalikan ke antrean
+	config.DB.Model(&models.OCRJob{}).Where("status = ?", models.OCRProses).Update("status", models.OCRMenunggu)
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			for prosesJobOCR() {
+			}
+			select {
+			case <-bangunOCR:
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// prosesJobOCR mengerjakan satu job dari antrean, false kalau antrean kosong
+func prosesJobOCR() bool {
+	var job models.OCRJob
+	if err := config.DB.Where("status = ?", models.OCRMenunggu).Order("id").First(&job).Error; err != nil {
+		return false
+	}
+
+	// klaim job, kalau statusnya sudah berubah berarti diambil/diganti di tempat lain
+	res := config.DB.Model(&models.OCRJob{}).
+		Where("id = ? AND status = ?", job.ID, models.OCRMenunggu).
+		Updates(map[string]any{"status": models.OCRProses, "percobaan": gorm.Expr("percobaan + 1")})
+	if res.RowsAffected == 0 {
+		return true
+	}
+
+	hasil, errOCR := utils.OCRPDF(job.Path)
+
+	// dokumen bisa diganti atau dihapus selama OCR berjalan, hasilnya dibuang saja
+	var sekarang models.OCRJob
+	if err := config.DB.First(&sekarang, job.ID).Error; err != nil ||
+		sekarang.Path != job.Path || sekarang.Status != models.OCRProses {
+		return true
+	}
+
+	if errOCR != nil {
+		log.Printf("OCR gagal %s/%d: %v", job.Tipe, job.EntitasID, errOCR)
+		config.DB.Model(&sekarang).Updates(map[string]any{"status": models.OCRGagal, "pesan": errOCR.Error()})
+		beritahuOCRGagal(job, errOCR)
+		return true
+	}
+
+	var rows []models.DokumenTeks
+	var totalKeyakinan float64
+	for i, h := range hasil {
+		totalKeyakinan += h.Keyakinan
+		if h.Teks == "" {
+			continue
+		}
+		rows = append(rows, models.DokumenTeks{
+			Tipe:      job.Tipe,
+			EntitasID: job.EntitasID,
+			Halaman:   i + 1,
+			Isi:       h.Teks,
+			Sumber:    "ocr",
+			Keyakinan: h.Keyakinan,
+		})
+	}
+
+	selesai := time.Now()
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tipe = ? AND entitas_id = ?", job.Tipe, job.EntitasID).Delete(&models.DokumenTeks{}).Error; err != nil {
+			return err
+		}
+		if len(rows) > 0 {
+			if err := tx.CreateInBatches(&rows, 50).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&sekarang).Updates(map[string]any{
+			"status":         models.OCRSelesai,
+			"pesan":          "",
+			"jumlah_halaman": len(hasil),
+			"rata_keyakinan": totalKeyakinan / float64(len(hasil)),
+			"selesai_at":     &selesai,
+		}).Error
+	})
+	if err != nil {
+		log.Printf("Gagal simpan hasil OCR %s/%d: %v", job.Tipe, job.EntitasID, err)
+		config.DB.Model(&sekarang).Updates(map[string]any{"status": models.OCRGagal, "pesan": err.Error()})
+		beritahuOCRGagal(job, err)
+	}
+	return true
+}
+
+// beritahuOCRGagal memberi tahu admin supaya OCR bisa diulang dari halaman edit record
+func beritahuOCRGagal(job models.OCRJob, err error) {
+	tipe, id := job.Tipe, job.EntitasID
+	if tipe == "lampiran" {
+		var l models.Lampiran
+		if config.DB.First(&l, id).Error == nil {
+			tipe, id = l.EntitasType, l.EntitasID
+		}
+	}
+	beritahuPekerjaan("", fmt.Sprintf("OCR dokumen %s #%d gagal", namaProgram(job.Tipe), job.EntitasID),
+		err.Error(), fmt.Sprintf("/admin/%s/edit/%d", tipe, id))
+}
+
+// ================== HANDLER ==================
+
+// OCRUlang memasukkan lagi job OCR yang gagal ke antrean
+func OCRUlang(c *gin.Context) {
+	var job models.OCRJob
+	if err := config.DB.First(&job, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Job OCR tidak ditemukan")
+		return
+	}
+
+	antrekanOCR(job.Tipe, job.EntitasID, job.Path)
+
+	// kembali ke halaman edit record pemilik dokumen
+	tipe, id := job.Tipe, job.EntitasID
+	if tipe == "lampiran" {
+		var l models.Lampiran
+		if err := config.DB.First(&l, id).Error; err == nil {
+			tipe, id = l.EntitasType, l.EntitasID
+		}
+	}
+	c.Redirect(http.StatusFound, fmt.Sprintf("/admin/%s/edit/%d", tipe, id))
+}
@@ -1,24 +1,26 @@
 package controllers
 
 import (
+	"fmt"
+	"log"
 	"net/http"
-	"os"
-	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"go-admin/config"
 	"go-admin/models"
 	"go-admin/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 // ================== INDEX ==================
 func ParalegalIndex(c *gin.Context) {
 	search := c.Query("q")
+	sertifikasi := c.Query("sertifikasi")
 
 	limit := 50
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -29,6 +31,7 @@ func ParalegalIndex(c *gin.Context) {
 
 	var paralegals []models.Paralegal
 	db := config.DB.Model(&models.Paralegal{}).
+		Scopes(scopeWilayahOperator(c, "paralegal")).
 		Preload("Posbankum").
 		Preload("Posbankum.Kelurahan").
 		Preload("Posbankum.Kelurahan.Kecamatan").
@@ -39,6 +42,13 @@ func ParalegalIndex(c *gin.Context) {
 			Joins("JOIN kelurahans ON kelurahans.id = posbankums.kelurahan_id").
 			Where("paralegals.nama LIKE ? OR kelurahans.name LIKE ?", "%"+search+"%", "%"+search+"%")
 	}
+	// filter sertifikat jenjang dasar yang masih berlaku
+	switch sertifikasi {
+	case "sudah":
+		db = db.Scopes(scopeSertifikatDasar(true))
+	case "belum":
+		db = db.Scopes(scopeSertifikatDasar(false))
+	}
 
 	var total int64
 	if err := db.Count(&total).Error; err != nil {
@@ -53,27 +63,155 @@ func ParalegalIndex(c *gin.Context) {
 
 	totalPages := int((total + int64(limit) - 1) / int64(limit))
 
+	// status tanda tangan elektronik dan sertifikat dasar untuk badge di tabel
+	ids := make([]uint, len(paralegals))
+	for i, d := range paralegals {
+		ids[i] = d.ID
+	}
+
 	c.HTML(http.StatusOK, "paralegal_index.html", gin.H{
-		"Title":      "Data Paralegal",
-		"Paralegals": paralegals,
-		"Search":     search,
-		"Page":       page,
-		"Offset":     offset,
-		"TotalPages": totalPages,
+		"Title":         "Data Paralegal",
+		"Paralegals":    paralegals,
+		"Search":        search,
+		"Sertifikasi":   sertifikasi,
+		"Bersertifikat": sertifikatDasarMap(ids),
+		"Page":          page,
+		"Offset":        offset,
+		"TotalPages":    totalPages,
+		"TTD":           statusTTDMap("paralegal", ids),
+		"FormZIP":       formZIP(c, "/admin/unduh-zip", "paralegal"),
 	})
 }
 
 // ================== CREATE FORM ==================
 func ParalegalCreate(c *gin.Context) {
 	var posbankums []models.Posbankum
-	config.DB.Preload("Kelurahan").Find(&posbankums)
+	config.DB.Scopes(scopeWilayahOperator(c, "posbankum")).Preload("Kelurahan").Find(&posbankums)
 
 	c.HTML(http.StatusOK, "paralegal_create.html", gin.H{
 		"Title":      "Tambah Paralegal",
 		"Posbankums": posbankums,
+		"Profil":     models.ProfilParalegal{},
 	})
 }
 
+// ================== PROFIL ==================
+
+// profilParalegalDariForm membaca dan memvalidasi identitas paralegal dari form create/edit.
+// lama adalah profil yang tersimpan (kosong untuk record baru): NIK boleh dikosongkan saat edit
+// kalau sudah pernah diisi, artinya tidak diubah. Jenis kelamin dan tanggal lahir yang kosong
+// diisi dari NIK; kalau diisi tapi tidak cocok dengan NIK dianggap salah ketik.
+// Foto tidak diisi di sini (lihat siapkanFotoForm). Pesan error dikembalikan per isian yang salah.
+func profilParalegalDariForm(c *gin.Context, lama models.ProfilParalegal) (models.ProfilParalegal, []string) {
+	var errs []string
+	p := models.ProfilParalegal{
+		NIK:            utils.NormalisasiNIK(c.PostForm("nik")),
+		NIKTerenkripsi: lama.NIKTerenkripsi,
+		NIKHash:        lama.NIKHash,
+		JenisKelamin:   c.PostForm("jenis_kelamin"),
+		TanggalLahir:   tanggalForm(c, "tanggal_lahir"),
+		Pendidikan:     c.PostForm("pendidikan"),
+		Alamat:         strings.TrimSpace(utils.SanitizeInput(c.PostForm("alamat"))),
+		Foto:           lama.Foto,
+	}
+
+	if p.JenisKelamin != "" && p.JenisKelamin != "L" && p.JenisKelamin != "P" {
+		errs = append(errs, "Jenis kelamin tidak valid")
+		p.JenisKelamin = ""
+	}
+	if p.Pendidikan != "" && !slices.Contains(models.JenjangPendidikan, p.Pendidikan) {
+		errs = append(errs, "Pendidikan terakhir tidak valid")
+		p.Pendidikan = ""
+	}
+	if len([]rune(p.Alamat)) > 500 {
+		errs = append(errs, "Alamat maksimal 500 karakter")
+	}
+	var ok bool
+	if p.Telepon, ok = utils.NormalisasiTelepon(c.PostForm("telepon")); !ok {
+		errs = append(errs, "Nomor telepon tidak valid (contoh: 081234567890)")
+	}
+	if p.TanggalLahir != nil && p.TanggalLahir.After(time.Now()) {
+		errs = append(errs, "Tanggal lahir tidak boleh di masa depan")
+	}
+
+	// NIK acuan untuk cek jenis kelamin dan tanggal lahir: NIK baru dari form atau NIK yang tersimpan
+	nik := p.NIK
+	switch {
+	case nik != "":
+		if !utils.NIKValid(nik) {
+			return p, append(errs, "NIK harus 16 digit angka sesuai KTP (cek kode wilayah dan tanggal lahir di dalamnya)")
+		}
+		terenkripsi, err := utils.EnkripsiNIK(nik)
+		if err != nil {
+			log.Printf("Gagal mengenkripsi NIK: %v", err)
+			return p, append(errs, "NIK gagal disimpan, silakan coba lagi")
+		}
+		hash := utils.HashNIK(nik)
+		p.NIKTerenkripsi = terenkripsi
+		p.NIKHash = &hash
+	case lama.NIKTerenkripsi == "":
+		return p, append(errs, "NIK wajib diisi")
+	default:
+		var err error
+		if nik, err = utils.DekripsiNIK(lama.NIKTerenkripsi); err != nil {
+			log.Printf("Gagal membuka NIK tersimpan: %v", err)
+			return p, errs
+		}
+	}
+
+	jenisKelamin, tanggalLahir, _ := utils.InfoNIK(nik)
+	if p.JenisKelamin == "" {
+		p.JenisKelamin = jenisKelamin
+	} else if p.JenisKelamin != jenisKelamin {
+		errs = append(errs, "Jenis kelamin tidak sesuai dengan NIK")
+	}
+	if p.TanggalLahir == nil {
+		p.TanggalLahir = &tanggalLahir
+	} else if !p.TanggalLahir.Equal(tanggalLahir) {
+		errs = append(errs, "Tanggal lahir tidak sesuai dengan NIK")
+	}
+	return p, errs
+}
+
+// duplikasiNIK mencari paralegal lain dengan NIK yang sama (lewat hash NIK).
+// Mengembalikan pesan siap tampil, kosong kalau tidak ada duplikat.
+func duplikasiNIK(p models.ProfilParalegal, kecuali uint) string {
+	if p.NIKHash == nil {
+		return ""
+	}
+	var lain models.Paralegal
+	if err := config.DB.Preload("Posbankum.Kelurahan").
+		Where("nik_hash = ? AND id <> ?", *p.NIKHash, kecuali).First(&lain).Error; err != nil {
+		return ""
+	}
+	return fmt.Sprintf("❌ NIK sudah terdaftar atas nama %s (Posbankum %s). Satu orang hanya boleh terdaftar sekali sebagai paralegal.",
+		lain.Nama, lain.Posbankum.Kelurahan.Name)
+}
+
+// NIKSamaran dipakai template untuk menampilkan NIK tersimpan tanpa membuka seluruh digitnya
+func NIKSamaran(terenkripsi string) string {
+	if terenkripsi == "" {
+		return ""
+	}
+	nik, err := utils.DekripsiNIK(terenkripsi)
+	if err != nil {
+		log.Printf("Gagal membuka NIK tersimpan: %v", err)
+		return "NIK tidak terbaca"
+	}
+	return utils.SamarkanNIK(nik)
+}
+
+// ParalegalFoto menampilkan foto paralegal (hanya untuk pengguna yang login, data pribadi)
+func ParalegalFoto(c *gin.Context) {
+	var paralegal models.Paralegal
+	if err := config.DB.First(&paralegal, c.Param("id")).Error; err != nil || paralegal.Foto == "" {
+		c.String(http.StatusNotFound, "Foto tidak ditemukan")
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.File(paralegal.Foto)
+}
+
 // ================== API SEARCH POSBANKUM ==================
 func PosbankumSearch(c *gin.Context) {
 	term := c.Query("term")
@@ -114,45 +252,87 @@ func ParalegalStore(c *gin.Context) {
 	posbankumID, _ := strconv.Atoi(c.PostForm("posbankum_id"))
 
 	nama := utils.SanitizeInput(c.PostForm("nama"))
-	var dokumenPath string
-
-	file, err := c.FormFile("dokumen")
-	if err == nil {
-		if !utils.ValidatePDFUpload(c, file) {
-			c.HTML(http.StatusOK, "paralegal_create.html", gin.H{
-				"Title":     "Tambah Paralegal",
-				"ErrorFile": "❌ File tidak valid. Pastikan file adalah PDF dan ukurannya di bawah 10MB.",
-				"Nama":      nama,
-			})
-			return
-		}
+	dokumenPublik := c.PostForm("dokumen_publik") == "1"
 
-		uploadPath := "uploads/paralegal"
-		os.MkdirAll(uploadPath, os.ModePerm)
-
-		// generate nama file unik menggunakan uuid
-		ext := filepath.Ext(file.Filename)
-		newName := uuid.New().String() + ext
-		fullPath := filepath.Join(uploadPath, newName)
-
-		if err := c.SaveUploadedFile(file, fullPath); err != nil {
-			c.HTML(http.StatusOK, "paralegal_create.html", gin.H{
-				"Title":     "Tambah Paralegal",
-				"ErrorFile": "Gagal upload file",
-				"Nama":      nama,
-			})
-			return
+	// identitas wajib lengkap dan NIK belum dipakai paralegal lain
+	profil, errProfil := profilParalegalDariForm(c, models.ProfilParalegal{})
+	if len(errProfil) == 0 {
+		if msg := duplikasiNIK(profil, 0); msg != "" {
+			errProfil = append(errProfil, msg)
 		}
-		dokumenPath = strings.ReplaceAll(fullPath, "\\", "/")
+	}
+	if len(errProfil) > 0 {
+		c.HTML(http.StatusOK, "paralegal_create.html", gin.H{
+			"Title":         "Tambah Paralegal",
+			"ErrorProfil":   errProfil,
+			"Nama":          nama,
+			"DokumenPublik": dokumenPublik,
+			"Profil":        profil,
+		})
+		return
+	}
+
+	// dokumen opsional, dari upload biasa atau upload resumable yang sudah selesai
+	st, _, msg := siapkanDokumenForm(c, "paralegal", 0)
+	if msg != "" {
+		c.HTML(http.StatusOK, "paralegal_create.html", gin.H{
+			"Title":         "Tambah Paralegal",
+			"ErrorFile":     msg,
+			"Nama":          nama,
+			"DokumenPublik": dokumenPublik,
+			"Profil":        profil,
+		})
+		return
+	}
+	stFoto, msg := siapkanFotoForm(c, "paralegal", 0)
+	if msg != "" {
+		st.buang()
+		c.HTML(http.StatusOK, "paralegal_create.html", gin.H{
+			"Title":         "Tambah Paralegal",
+			"ErrorFoto":     msg,
+			"Nama":          nama,
+			"DokumenPublik": dokumenPublik,
+			"Profil":        profil,
+		})
+		return
 	}
 
 	paralegal := models.Paralegal{
-		PosbankumID: uint(posbankumID),
-		Nama:        nama,
-		Dokumen:     dokumenPath,
+		PosbankumID:     uint(posbankumID),
+		Nama:            nama,
+		DokumenPublik:   dokumenPublik,
+		Verifikasi:      models.Verifikasi{StatusVerifikasi: statusSetelahSimpan(c, "")},
+		ProfilParalegal: profil,
+	}
+
+	if err := simpanDenganBerkas([]*berkasStaging{st, stFoto}, func(tx *gorm.DB, paths []string) error {
+		paralegal.Dokumen = paths[0]
+		paralegal.Foto = paths[1]
+		if err := tx.Create(&paralegal).Error; err != nil {
+			return err
+		}
+		return catatRiwayat(tx, c, "paralegal", paralegal.ID, "", paralegal.StatusVerifikasi, "")
+	}); err != nil {
+		log.Printf("Gagal menyimpan paralegal: %v", err)
+		data := gin.H{
+			"Title":         "Tambah Paralegal",
+			"ErrorFile":     "❌ Gagal menyimpan data, dokumen belum tersimpan. Silakan coba lagi.",
+			"Nama":          nama,
+			"DokumenPublik": dokumenPublik,
+			"Profil":        profil,
+		}
+		// NIK yang sama baru saja disimpan user lain (unique index nik_hash)
+		if msg := duplikasiNIK(profil, 0); msg != "" {
+			delete(data, "ErrorFile")
+			data["ErrorProfil"] = []string{msg}
+		}
+		c.HTML(http.StatusOK, "paralegal_create.html", data)
+		return
 	}
 
-	config.DB.Create(&paralegal)
+	// ekstrak teks PDF untuk pencarian isi dokumen dan verifikasi tanda tangan elektroniknya
+	go indeksDokumen("paralegal", paralegal.ID, paralegal.Dokumen)
+	go periksaTTD("paralegal", paralegal.ID, paralegal.Dokumen)
 	c.Redirect(http.StatusFound, "/admin/paralegal")
 }
 
@@ -168,9 +348,80 @@ func ParalegalView(c *gin.Context) {
 		c.String(http.StatusNotFound, err.Error())
 		return
 	}
-	c.Header("Content-Disposition", "inline; filename="+filepath.Base(paralegal.Dokumen))
-	c.Header("Content-Type", "application/pdf")
-	c.File(paralegal.Dokumen)
+	if tampilkanDenganTTD(c, "paralegal", paralegal.ID, paralegal.Dokumen) {
+		return
+	}
+	kirimDokumen(c, "paralegal", paralegal.ID, paralegal.Dokumen)
+}
+
+// ================== DEMOGRAFI ==================
+
+// KelompokUmur adalah satu baris sebaran umur paralegal di dashboard
+type KelompokUmur struct {
+	Label  string
+	Jumlah int
+}
+
+// DemografiParalegal adalah sebaran jenis kelamin dan umur paralegal untuk dashboard.
+// Data lama yang belum punya jenis kelamin/tanggal lahir dihitung sebagai "belum diisi".
+type DemografiParalegal struct {
+	Total        int
+	LakiLaki     int
+	Perempuan    int
+	TanpaData    int // jenis kelamin belum diisi
+	KelompokUmur []KelompokUmur
+}
+
+// batasKelompokUmur: umur maksimal (inklusif) tiap kelompok, kelompok terakhir tanpa batas
+var batasKelompokUmur = []struct {
+	Label string
+	Maks  int
+}{
+	{"< 25 tahun", 24},
+	{"25-34 tahun", 34},
+	{"35-44 tahun", 44},
+	{"45-54 tahun", 54},
+	{">= 55 tahun", -1},
+}
+
+// demografiParalegal menghitung sebaran jenis kelamin dan umur paralegal
+// (scope dipakai untuk filter, mis. hanya yang terverifikasi)
+func demografiParalegal(scopes ...func(*gorm.DB) *gorm.DB) DemografiParalegal {
+	var rows []models.ProfilParalegal
+	config.DB.Model(&models.Paralegal{}).Scopes(scopes...).
+		Select("paralegals.jenis_kelamin, paralegals.tanggal_lahir").Scan(&rows)
+
+	d := DemografiParalegal{Total: len(rows)}
+	for _, b := range batasKelompokUmur {
+		d.KelompokUmur = append(d.KelompokUmur, KelompokUmur{Label: b.Label})
+	}
+	tanpaUmur := 0
+	sekarang := time.Now()
+	for _, p := range rows {
+		switch p.JenisKelamin {
+		case "L":
+			d.LakiLaki++
+		case "P":
+			d.Perempuan++
+		default:
+			d.TanpaData++
+		}
+		umur := p.Umur(sekarang)
+		if umur < 0 {
+			tanpaUmur++
+			continue
+		}
+		for i, b := range batasKelompokUmur {
+			if b.Maks < 0 || umur <= b.Maks {
+				d.KelompokUmur[i].Jumlah++
+				break
+			}
+		}
+	}
+	if tanpaUmur > 0 {
+		d.KelompokUmur = append(d.KelompokUmur, KelompokUmur{Label: "Belum diisi", Jumlah: tanpaUmur})
+	}
+	return d
 }
 
 // ================== EDIT FORM ==================
@@ -192,12 +443,29 @@ func ParalegalEdit(c *gin.Context) {
 	}
 
 	var posbankums []models.Posbankum
-	config.DB.Preload("Kelurahan").Find(&posbankums)
+	config.DB.Scopes(scopeWilayahOperator(c, "posbankum")).Preload("Kelurahan").Find(&posbankums)
 
 	c.HTML(http.StatusOK, "paralegal_edit.html", gin.H{
-		"Title":      "Edit Paralegal",
-		"Paralegal":  paralegal,
-		"Posbankums": posbankums,
+		"Title":             "Edit Paralegal",
+		"Paralegal":         paralegal,
+		"Profil":            paralegal.ProfilParalegal,
+		"Posbankums":        posbankums,
+		"EntitasTipe":       "paralegal",
+		"EntitasID":         paralegal.ID,
+		"Lampirans":         daftarLampiran("paralegal", paralegal.ID),
+		"KategoriLampirans": semuaKategoriLampiran(),
+		"ErrorLampiran":     c.Query("error_lampiran"),
+		"OCR":               statusOCR("paralegal", paralegal.ID),
+		"OCRLampiran":       statusOCRLampiran("paralegal", paralegal.ID),
+		"TTD":               statusTTD("paralegal", paralegal.ID),
+		"Verifikasi":        infoVerifikasi("paralegal", paralegal.ID, paralegal.Verifikasi),
+		"Komentars":         daftarKomentar("paralegal", paralegal.ID),
+		"ErrorKomentar":     c.Query("error_komentar"),
+		"Sertifikats":       daftarSertifikat(paralegal.ID),
+		"RiwayatPelatihan":  riwayatPelatihan(paralegal.ID),
+		"JenjangPelatihan":  models.JenjangPelatihan,
+		"HariPeringatanSK":  hariPeringatanSK(),
+		"ErrorSertifikat":   c.Query("error_sertifikat"),
 	})
 }
 
@@ -213,44 +481,95 @@ func ParalegalUpdate(c *gin.Context) {
 	paralegal.Nama = utils.SanitizeInput(c.PostForm("nama"))
 	posbankumID, _ := strconv.Atoi(c.PostForm("posbankum_id"))
 	paralegal.PosbankumID = uint(posbankumID)
+	paralegal.DokumenPublik = c.PostForm("dokumen_publik") == "1"
 
-	file, err := c.FormFile("dokumen")
-	if err == nil {
-		if !utils.ValidatePDFUpload(c, file) {
-			c.HTML(http.StatusOK, "paralegal_edit.html", gin.H{
-				"Title":     "Edit Paralegal",
-				"Paralegal": paralegal,
-				"ErrorFile": "❌ File tidak valid. Pastikan file adalah PDF dan ukurannya di bawah 10MB.",
-			})
-			return
+	// identitas dari form; NIK dan foto lama tetap dipakai kecuali diganti atau dihapus
+	fotoLama := paralegal.Foto
+	profil, errProfil := profilParalegalDariForm(c, paralegal.ProfilParalegal)
+	if c.PostForm("hapus_foto") == "1" {
+		profil.Foto = ""
+	}
+	if len(errProfil) == 0 {
+		if msg := duplikasiNIK(profil, paralegal.ID); msg != "" {
+			errProfil = append(errProfil, msg)
 		}
+	}
+	paralegal.ProfilParalegal = profil
+	if len(errProfil) > 0 {
+		c.HTML(http.StatusOK, "paralegal_edit.html", gin.H{
+			"Title":       "Edit Paralegal",
+			"Paralegal":   paralegal,
+			"Profil":      profil,
+			"ErrorProfil": errProfil,
+		})
+		return
+	}
 
-		uploadPath := "uploads/paralegal"
-		os.MkdirAll(uploadPath, os.ModePerm)
-
-		// generate nama file unik menggunakan uuid
-		ext := filepath.Ext(file.Filename)
-		newName := uuid.New().String() + ext
-		newPath := filepath.Join(uploadPath, newName)
-
-		if err := c.SaveUploadedFile(file, newPath); err != nil {
-			c.HTML(http.StatusOK, "paralegal_edit.html", gin.H{
-				"Title":     "Edit Paralegal",
-				"Paralegal": paralegal,
-				"ErrorFile": "❌ Gagal upload file",
-			})
-			return
+	// dokumen baru dari upload biasa atau upload resumable yang sudah selesai
+	st, adaFile, msg := siapkanDokumenForm(c, "paralegal", paralegal.ID)
+	if msg != "" {
+		c.HTML(http.StatusOK, "paralegal_edit.html", gin.H{
+			"Title":     "Edit Paralegal",
+			"Paralegal": paralegal,
+			"Profil":    profil,
+			"ErrorFile": msg,
+		})
+		return
+	}
+	stFoto, msg := siapkanFotoForm(c, "paralegal", paralegal.ID)
+	if msg != "" {
+		st.buang()
+		c.HTML(http.StatusOK, "paralegal_edit.html", gin.H{
+			"Title":     "Edit Paralegal",
+			"Paralegal": paralegal,
+			"Profil":    profil,
+			"ErrorFoto": msg,
+		})
+		return
+	}
+	dokumenLama := paralegal.Dokumen
+	statusLama := paralegal.StatusVerifikasi
+	paralegal.StatusVerifikasi = statusSetelahSimpan(c, statusLama)
+	if err := simpanDenganBerkas([]*berkasStaging{st, stFoto}, func(tx *gorm.DB, paths []string) error {
+		if adaFile {
+			paralegal.Dokumen = paths[0]
 		}
-
-		// hapus file lama kalau ada
-		if paralegal.Dokumen != "" {
-			_ = os.Remove(paralegal.Dokumen)
+		if stFoto != nil {
+			paralegal.Foto = paths[1]
 		}
-
-		paralegal.Dokumen = strings.ReplaceAll(newPath, "\\", "/")
+		if err := tx.Save(&paralegal).Error; err != nil {
+			return err
+		}
+		return catatRiwayat(tx, c, "paralegal", paralegal.ID, statusLama, paralegal.StatusVerifikasi, "")
+	}); err != nil {
+		log.Printf("Gagal menyimpan paralegal %d: %v", paralegal.ID, err)
+		paralegal.Dokumen = dokumenLama
+		paralegal.Foto = fotoLama
+		paralegal.StatusVerifikasi = statusLama
+		data := gin.H{
+			"Title":     "Edit Paralegal",
+			"Paralegal": paralegal,
+			"Profil":    paralegal.ProfilParalegal,
+			"ErrorFile": "❌ Gagal menyimpan data, dokumen belum tersimpan. Silakan coba lagi.",
+		}
+		// NIK yang sama baru saja disimpan user lain (unique index nik_hash)
+		if msg := duplikasiNIK(profil, paralegal.ID); msg != "" {
+			delete(data, "ErrorFile")
+			data["ErrorProfil"] = []string{msg}
+		}
+		c.HTML(http.StatusOK, "paralegal_edit.html", data)
+		return
 	}
 
-	config.DB.Save(&paralegal)
+	if paralegal.Foto != fotoLama {
+		lepasFile(fotoLama)
+	}
+	// dokumen diganti: file lama dilepas (dihapus kalau tidak dipakai record lain), indeks ulang teksnya
+	if adaFile {
+		lepasFile(dokumenLama)
+		go indeksDokumen("paralegal", paralegal.ID, paralegal.Dokumen)
+		go periksaTTD("paralegal", paralegal.ID, paralegal.Dokumen)
+	}
 	c.Redirect(http.StatusFound, "/admin/paralegal")
 }
 
@@ -264,13 +583,50 @@ func ParalegalDelete(c *gin.Context) {
 		return
 	}
 
-	// hapus file PDF kalau ada
-	if paralegal.Dokumen != "" {
-		_ = os.Remove(paralegal.Dokumen)
+	// semua penghapusan di DB satu transaksi, file baru dilepas setelah commit
+	var fileLampiran []string
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// lampiran beserta indeksnya
+		paths, err := hapusLampiranEntitas(tx, "paralegal", paralegal.ID)
+		if err != nil {
+			return err
+		}
+		fileLampiran = paths
+		if err := hapusIndeksDokumen(tx, "paralegal", paralegal.ID); err != nil {
+			return err
+		}
+		// diskusi, riwayat verifikasi dan notifikasi record ini
+		paths, err = hapusJejakEntitas(tx, "paralegal", paralegal.ID)
+		if err != nil {
+			return err
+		}
+		fileLampiran = append(fileLampiran, paths...)
+
+		// keikutsertaan pelatihan dan sertifikat ikut terhapus
+		if err := tx.Where("paralegal_id = ?", paralegal.ID).Delete(&models.PesertaPelatihan{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("paralegal_id = ?", paralegal.ID).Delete(&models.SertifikatParalegal{}).Error; err != nil {
+			return err
+		}
+		// konsultasi yang ditanganinya tetap tercatat di Posbankum
+		if err := tx.Model(&models.Konsultasi{}).Where("paralegal_id = ?", paralegal.ID).Update("paralegal_id", nil).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&paralegal).Error
+	}); err != nil {
+		log.Printf("Gagal menghapus paralegal %d: %v", paralegal.ID, err)
+		c.String(http.StatusInternalServerError, "❌ Gagal menghapus data, tidak ada yang berubah. Silakan coba lagi.")
+		return
 	}
 
-	// hapus record
-	config.DB.Delete(&paralegal)
+	// file hanya dihapus kalau tidak dipakai record lain
+	for _, p := range fileLampiran {
+		lepasFile(p)
+	}
+	lepasFile(paralegal.Dokumen)
+	lepasFile(paralegal.Foto)
 
 	c.Redirect(http.StatusFound, "/admin/paralegal")
 }
@@ -0,0 +1,581 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Pelatihan Paralegal dikelola admin Kanwil; operator kabupaten/kota mendaftarkan paralegal di wilayahnya
+// sebagai peserta dan mencatat sertifikatnya. Sertifikat jenjang dasar yang masih berlaku dipakai
+// untuk laporan "paralegal belum bersertifikat dasar" di dashboard dan Cetak PDF.
+
+// ================== SERTIFIKASI (LAPORAN) ==================
+
+// scopeSertifikatDasar membatasi paralegal yang sudah (punya=true) atau belum punya
+// sertifikat jenjang dasar yang masih berlaku
+func scopeSertifikatDasar(punya bool) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		sub := config.DB.Model(&models.SertifikatParalegal{}).Select("paralegal_id").
+			Where("jenjang = ? AND (berlaku_sampai IS NULL OR berlaku_sampai >= CURDATE())", models.JenjangDasar)
+		if punya {
+			return db.Where("paralegals.id IN (?)", sub)
+		}
+		return db.Where("paralegals.id NOT IN (?)", sub)
+	}
+}
+
+// ParalegalTanpaSertifikat adalah satu baris daftar paralegal yang belum bersertifikat dasar
+type ParalegalTanpaSertifikat struct {
+	ID            uint
+	Nama          string
+	NamaKelurahan string
+	NamaKecamatan string
+	KabupatenID   uint
+}
+
+// RekapSertifikasi adalah jumlah paralegal dan yang sudah bersertifikat dasar di satu kabupaten/kota
+type RekapSertifikasi struct {
+	NamaKabupaten   string
+	Total           int
+	Bersertifikat   int
+	TanpaSertifikat []ParalegalTanpaSertifikat
+}
+
+// Belum adalah jumlah paralegal yang belum bersertifikat dasar
+func (r RekapSertifikasi) Belum() int {
+	return r.Total - r.Bersertifikat
+}
+
+// Persentase paralegal yang sudah bersertifikat dasar
+func (r RekapSertifikasi) Persentase() float64 {
+	return hitungPersen(r.Bersertifikat, r.Total)
+}
+
+// rekapSertifikasiParalegal menghitung sertifikasi dasar paralegal per kabupaten/kota (urut sesuai daftar kabupatens)
+// beserta nama paralegal yang belum bersertifikat. Scope dipakai untuk filter, mis. hanya yang terverifikasi.
+func rekapSertifikasiParalegal(kabupatens []models.Kabupaten, scopes ...func(*gorm.DB) *gorm.DB) []RekapSertifikasi {
+	dasar := func(punya bool) *gorm.DB {
+		return config.DB.Model(&models.Paralegal{}).
+			Joins("JOIN posbankums ON posbankums.id = paralegals.posbankum_id").
+			Joins("JOIN kelurahans ON kelurahans.id = posbankums.kelurahan_id").
+			Joins("JOIN kecamatans ON kecamatans.id = kelurahans.kecamatan_id").
+			Scopes(scopes...).Scopes(scopeSertifikatDasar(punya))
+	}
+
+	var sudah []struct {
+		KabupatenID uint
+		Jumlah      int
+	}
+	dasar(true).Select("kecamatans.kabupaten_id, COUNT(*) AS jumlah").
+		Group("kecamatans.kabupaten_id").Scan(&sudah)
+	var belum []ParalegalTanpaSertifikat
+	dasar(false).Select("paralegals.id, paralegals.nama, kelurahans.name AS nama_kelurahan, " +
+		"kecamatans.name AS nama_kecamatan, kecamatans.kabupaten_id").
+		Order("kecamatans.name, kelurahans.name, paralegals.nama").Scan(&belum)
+
+	indeks := make(map[uint]int, len(kabupatens))
+	hasil := make([]RekapSertifikasi, len(kabupatens))
+	for i, kab := range kabupatens {
+		indeks[kab.ID] = i
+		hasil[i].NamaKabupaten = kab.Name
+	}
+	for _, s := range sudah {
+		if i, ok := indeks[s.KabupatenID]; ok {
+			hasil[i].Total += s.Jumlah
+			hasil[i].Bersertifikat = s.Jumlah
+		}
+	}
+	for _, p := range belum {
+		if i, ok := indeks[p.KabupatenID]; ok {
+			hasil[i].Total++
+			hasil[i].TanpaSertifikat = append(hasil[i].TanpaSertifikat, p)
+		}
+	}
+	return hasil
+}
+
+// sertifikatDasarMap menandai paralegal (dari daftar id) yang sudah bersertifikat dasar, untuk badge di tabel
+func sertifikatDasarMap(ids []uint) map[uint]bool {
+	hasil := map[uint]bool{}
+	if len(ids) == 0 {
+		return hasil
+	}
+	var punya []uint
+	config.DB.Model(&models.Paralegal{}).Where("paralegals.id IN ?", ids).
+		Scopes(scopeSertifikatDasar(true)).Pluck("paralegals.id", &punya)
+	for _, id := range punya {
+		hasil[id] = true
+	}
+	return hasil
+}
+
+// ================== PELATIHAN ==================
+
+// JumlahPeserta adalah jumlah peserta dan yang lulus di satu pelatihan
+type JumlahPeserta struct {
+	Peserta int
+	Lulus   int
+}
+
+// PelatihanIndex menampilkan daftar pelatihan, bisa difilter jenjang, tahun dan kata kunci
+func PelatihanIndex(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	jenjang := c.Query("jenjang")
+	tahun, _ := strconv.Atoi(c.Query("tahun"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit := 20
+
+	db := config.DB.Model(&models.Pelatihan{})
+	if q != "" {
+		db = db.Where("judul LIKE ? OR penyelenggara LIKE ? OR lokasi LIKE ?", "%"+q+"%", "%"+q+"%", "%"+q+"%")
+	}
+	if slices.Contains(models.JenjangPelatihan, jenjang) {
+		db = db.Where("jenjang = ?", jenjang)
+	}
+	if tahun > 0 {
+		db = db.Where("YEAR(tanggal_mulai) = ?", tahun)
+	}
+	var total int64
+	db.Count(&total)
+	var pelatihans []models.Pelatihan
+	db.Order("tanggal_mulai DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&pelatihans)
+
+	// jumlah peserta dan yang lulus per pelatihan
+	ids := make([]uint, len(pelatihans))
+	for i, p := range pelatihans {
+		ids[i] = p.ID
+	}
+	var jumlah []struct {
+		PelatihanID uint
+		JumlahPeserta
+	}
+	if len(ids) > 0 {
+		config.DB.Model(&models.PesertaPelatihan{}).
+			Select("pelatihan_id, COUNT(*) AS peserta, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS lulus", models.PesertaLulus).
+			Where("pelatihan_id IN ?", ids).Group("pelatihan_id").Scan(&jumlah)
+	}
+	peserta := map[uint]JumlahPeserta{}
+	for _, j := range jumlah {
+		peserta[j.PelatihanID] = j.JumlahPeserta
+	}
+
+	_, role := penggunaLogin(c)
+	c.HTML(http.StatusOK, "pelatihan_index.html", gin.H{
+		"Title":       "Pelatihan Paralegal",
+		"Pelatihans":  pelatihans,
+		"Peserta":     peserta,
+		"Search":      q,
+		"Jenjang":     jenjang,
+		"Jenjangs":    models.JenjangPelatihan,
+		"Tahun":       tahun,
+		"Page":        page,
+		"TotalPages":  int(math.Ceil(float64(total) / float64(limit))),
+		"BolehKelola": role == "admin",
+		"Error":       c.Query("error"),
+	})
+}
+
+// pelatihanDariForm membaca dan memvalidasi isian form pelatihan
+func pelatihanDariForm(c *gin.Context, p *models.Pelatihan) string {
+	p.Judul = strings.TrimSpace(utils.SanitizeInput(c.PostForm("judul")))
+	p.Penyelenggara = strings.TrimSpace(utils.SanitizeInput(c.PostForm("penyelenggara")))
+	p.Jenjang = c.PostForm("jenjang")
+	p.TanggalMulai = tanggalForm(c, "tanggal_mulai")
+	p.TanggalSelesai = tanggalForm(c, "tanggal_selesai")
+	p.Lokasi = strings.TrimSpace(utils.SanitizeInput(c.PostForm("lokasi")))
+	p.Keterangan = strings.TrimSpace(utils.SanitizeInput(c.PostForm("keterangan")))
+
+	switch {
+	case p.Judul == "":
+		return "Judul pelatihan wajib diisi"
+	case p.Penyelenggara == "":
+		return "Penyelenggara wajib diisi"
+	case !slices.Contains(models.JenjangPelatihan, p.Jenjang):
+		return "Pilih jenjang kurikulum"
+	case p.TanggalMulai == nil:
+		return "Tanggal mulai wajib diisi"
+	case p.TanggalSelesai != nil && p.TanggalSelesai.Before(*p.TanggalMulai):
+		return "Tanggal selesai tidak boleh sebelum tanggal mulai"
+	case len([]rune(p.Judul)) > 191 || len([]rune(p.Penyelenggara)) > 191 || len([]rune(p.Lokasi)) > 255:
+		return "Judul, penyelenggara atau lokasi terlalu panjang"
+	}
+	return ""
+}
+
+func PelatihanCreate(c *gin.Context) {
+	c.HTML(http.StatusOK, "pelatihan_form.html", gin.H{
+		"Title":     "Tambah Pelatihan",
+		"Pelatihan": models.Pelatihan{Jenjang: models.JenjangDasar},
+		"Jenjangs":  models.JenjangPelatihan,
+	})
+}
+
+func PelatihanStore(c *gin.Context) {
+	var pelatihan models.Pelatihan
+	if msg := pelatihanDariForm(c, &pelatihan); msg != "" {
+		c.HTML(http.StatusOK, "pelatihan_form.html", gin.H{
+			"Title":     "Tambah Pelatihan",
+			"Pelatihan": pelatihan,
+			"Jenjangs":  models.JenjangPelatihan,
+			"Error":     msg,
+		})
+		return
+	}
+	if err := config.DB.Create(&pelatihan).Error; err != nil {
+		c.HTML(http.StatusOK, "pelatihan_form.html", gin.H{
+			"Title":     "Tambah Pelatihan",
+			"Pelatihan": pelatihan,
+			"Jenjangs":  models.JenjangPelatihan,
+			"Error":     "❌ Gagal menyimpan pelatihan, silakan coba lagi",
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, fmt.Sprintf("/admin/pelatihan/detail/%d", pelatihan.ID))
+}
+
+func PelatihanEdit(c *gin.Context) {
+	var pelatihan models.Pelatihan
+	if err := config.DB.First(&pelatihan, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Pelatihan tidak ditemukan")
+		return
+	}
+	c.HTML(http.StatusOK, "pelatihan_form.html", gin.H{
+		"Title":     "Edit Pelatihan",
+		"Pelatihan": pelatihan,
+		"Jenjangs":  models.JenjangPelatihan,
+	})
+}
+
+func PelatihanUpdate(c *gin.Context) {
+	var pelatihan models.Pelatihan
+	if err := config.DB.First(&pelatihan, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Pelatihan tidak ditemukan")
+		return
+	}
+	if msg := pelatihanDariForm(c, &pelatihan); msg != "" {
+		c.HTML(http.StatusOK, "pelatihan_form.html", gin.H{
+			"Title":     "Edit Pelatihan",
+			"Pelatihan": pelatihan,
+			"Jenjangs":  models.JenjangPelatihan,
+			"Error":     msg,
+		})
+		return
+	}
+	if err := config.DB.Save(&pelatihan).Error; err != nil {
+		c.HTML(http.StatusOK, "pelatihan_form.html", gin.H{
+			"Title":     "Edit Pelatihan",
+			"Pelatihan": pelatihan,
+			"Jenjangs":  models.JenjangPelatihan,
+			"Error":     "❌ Gagal menyimpan pelatihan, silakan coba lagi",
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, fmt.Sprintf("/admin/pelatihan/detail/%d", pelatihan.ID))
+}
+
+// PelatihanDelete menghapus pelatihan beserta daftar pesertanya. Sertifikat yang sudah terbit tetap
+// milik paralegal, hanya kaitannya ke pelatihan yang dilepas.
+func PelatihanDelete(c *gin.Context) {
+	var pelatihan models.Pelatihan
+	if err := config.DB.First(&pelatihan, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Pelatihan tidak ditemukan")
+		return
+	}
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.SertifikatParalegal{}).Where("pelatihan_id = ?", pelatihan.ID).
+			Update("pelatihan_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("pelatihan_id = ?", pelatihan.ID).Delete(&models.PesertaPelatihan{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&pelatihan).Error
+	}); err != nil {
+		kembaliDenganError(c, "/admin/pelatihan", "error", "Gagal menghapus pelatihan")
+		return
+	}
+	c.Redirect(http.StatusFound, "/admin/pelatihan")
+}
+
+// BarisPeserta adalah peserta pelatihan beserta sertifikat yang terbit dari pelatihan itu
+type BarisPeserta struct {
+	models.PesertaPelatihan
+	Sertifikat *models.SertifikatParalegal
+}
+
+// PelatihanDetail menampilkan pelatihan, pesertanya (operator hanya melihat peserta di wilayahnya),
+// form pendaftaran peserta dan penerbitan sertifikat
+func PelatihanDetail(c *gin.Context) {
+	var pelatihan models.Pelatihan
+	if err := config.DB.First(&pelatihan, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Pelatihan tidak ditemukan")
+		return
+	}
+
+	var pesertas []models.PesertaPelatihan
+	config.DB.Preload("Paralegal.Posbankum.Kelurahan.Kecamatan.Kabupaten").
+		Where("pelatihan_id = ?", pelatihan.ID).
+		Where("paralegal_id IN (?)", config.DB.Model(&models.Paralegal{}).Select("paralegals.id").
+			Scopes(scopeWilayahOperator(c, "paralegal"))).
+		Order("id").Find(&pesertas)
+
+	var sertifikats []models.SertifikatParalegal
+	config.DB.Where("pelatihan_id = ?", pelatihan.ID).Find(&sertifikats)
+	perParalegal := map[uint]*models.SertifikatParalegal{}
+	for i := range sertifikats {
+		perParalegal[sertifikats[i].ParalegalID] = &sertifikats[i]
+	}
+	baris := make([]BarisPeserta, len(pesertas))
+	for i, p := range pesertas {
+		baris[i] = BarisPeserta{PesertaPelatihan: p, Sertifikat: perParalegal[p.ParalegalID]}
+	}
+
+	_, role := penggunaLogin(c)
+	c.HTML(http.StatusOK, "pelatihan_detail.html", gin.H{
+		"Title":            "Detail Pelatihan",
+		"Pelatihan":        pelatihan,
+		"Pesertas":         baris,
+		"BolehKelola":      role == "admin",
+		"HariPeringatanSK": hariPeringatanSK(),
+		"Error":            c.Query("error"),
+	})
+}
+
+// PelatihanCariParalegal (JSON) untuk autocomplete pendaftaran peserta; operator hanya melihat paralegal di wilayahnya
+func PelatihanCariParalegal(c *gin.Context) {
+	term := strings.TrimSpace(c.Query("term"))
+	var paralegals []models.Paralegal
+	db := config.DB.Model(&models.Paralegal{}).Preload("Posbankum.Kelurahan").
+		Scopes(scopeWilayahOperator(c, "paralegal"))
+	if term != "" {
+		db = db.Joins("JOIN posbankums ON posbankums.id = paralegals.posbankum_id").
+			Joins("JOIN kelurahans ON kelurahans.id = posbankums.kelurahan_id").
+			Where("paralegals.nama LIKE ? OR kelurahans.name LIKE ?", "%"+term+"%", "%"+term+"%")
+	}
+	db.Order("paralegals.nama").Limit(20).Find(&paralegals)
+
+	results := []gin.H{}
+	for _, p := range paralegals {
+		results = append(results, gin.H{
+			"id":   p.ID,
+			"text": p.Nama + " - " + p.Posbankum.Kelurahan.Name,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// ================== PESERTA ==================
+
+// PesertaStore mendaftarkan paralegal ke pelatihan (wilayah paralegal dicek WilayahOperator lewat paralegal_id)
+func PesertaStore(c *gin.Context) {
+	pelatihanID, _ := strconv.Atoi(c.PostForm("pelatihan_id"))
+	kembali := fmt.Sprintf("/admin/pelatihan/detail/%d", pelatihanID)
+	var pelatihan models.Pelatihan
+	if err := config.DB.First(&pelatihan, pelatihanID).Error; err != nil {
+		c.String(http.StatusNotFound, "Pelatihan tidak ditemukan")
+		return
+	}
+	var paralegal models.Paralegal
+	if err := config.DB.First(&paralegal, c.PostForm("paralegal_id")).Error; err != nil {
+		kembaliDenganError(c, kembali, "error", "Pilih paralegal dari daftar")
+		return
+	}
+	var n int64
+	config.DB.Model(&models.PesertaPelatihan{}).
+		Where("pelatihan_id = ? AND paralegal_id = ?", pelatihan.ID, paralegal.ID).Count(&n)
+	if n > 0 {
+		kembaliDenganError(c, kembali, "error", paralegal.Nama+" sudah terdaftar di pelatihan ini")
+		return
+	}
+
+	username, _ := penggunaLogin(c)
+	peserta := models.PesertaPelatihan{
+		PelatihanID: pelatihan.ID,
+		ParalegalID: paralegal.ID,
+		Status:      models.PesertaTerdaftar,
+		Didaftarkan: username,
+	}
+	if err := config.DB.Create(&peserta).Error; err != nil {
+		kembaliDenganError(c, kembali, "error", "Gagal mendaftarkan peserta")
+		return
+	}
+	c.Redirect(http.StatusFound, kembali)
+}
+
+// PesertaUpdate mengubah status kelulusan peserta
+func PesertaUpdate(c *gin.Context) {
+	var peserta models.PesertaPelatihan
+	if err := config.DB.First(&peserta, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Peserta tidak ditemukan")
+		return
+	}
+	kembali := fmt.Sprintf("/admin/pelatihan/detail/%d", peserta.PelatihanID)
+	status := c.PostForm("status")
+	switch status {
+	case models.PesertaTerdaftar, models.PesertaLulus, models.PesertaTidakLulus:
+	default:
+		kembaliDenganError(c, kembali, "error", "Status peserta tidak valid")
+		return
+	}
+	var sertifikat int64
+	config.DB.Model(&models.SertifikatParalegal{}).
+		Where("pelatihan_id = ? AND paralegal_id = ?", peserta.PelatihanID, peserta.ParalegalID).Count(&sertifikat)
+	if sertifikat > 0 && status != models.PesertaLulus {
+		kembaliDenganError(c, kembali, "error", "Sertifikat sudah terbit, hapus sertifikatnya dulu sebelum mengubah kelulusan")
+		return
+	}
+	config.DB.Model(&peserta).Update("status", status)
+	c.Redirect(http.StatusFound, kembali)
+}
+
+// PesertaDelete membatalkan pendaftaran peserta yang belum punya sertifikat dari pelatihan ini
+func PesertaDelete(c *gin.Context) {
+	var peserta models.PesertaPelatihan
+	if err := config.DB.First(&peserta, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Peserta tidak ditemukan")
+		return
+	}
+	kembali := fmt.Sprintf("/admin/pelatihan/detail/%d", peserta.PelatihanID)
+	var sertifikat int64
+	config.DB.Model(&models.SertifikatParalegal{}).
+		Where("pelatihan_id = ? AND paralegal_id = ?", peserta.PelatihanID, peserta.ParalegalID).Count(&sertifikat)
+	if sertifikat > 0 {
+		kembaliDenganError(c, kembali, "error", "Sertifikat sudah terbit, hapus sertifikatnya dulu")
+		return
+	}
+	config.DB.Delete(&peserta)
+	c.Redirect(http.StatusFound, kembali)
+}
+
+// ================== SERTIFIKAT ==================
+
+// daftarSertifikat mengambil sertifikat milik satu paralegal, terbaru di atas
+func daftarSertifikat(paralegalID uint) []models.SertifikatParalegal {
+	var sertifikats []models.SertifikatParalegal
+	config.DB.Preload("Pelatihan").Where("paralegal_id = ?", paralegalID).
+		Order("tanggal_terbit DESC, id DESC").Find(&sertifikats)
+	return sertifikats
+}
+
+// riwayatPelatihan mengambil pelatihan yang pernah diikuti satu paralegal
+func riwayatPelatihan(paralegalID uint) []models.PesertaPelatihan {
+	var pesertas []models.PesertaPelatihan
+	config.DB.Preload("Pelatihan").Where("paralegal_id = ?", paralegalID).Order("id DESC").Find(&pesertas)
+	return pesertas
+}
+
+// sertifikatDariForm membaca dan memvalidasi isian form sertifikat. Sertifikat dari pelatihan yang tercatat
+// mengikuti jenjang pelatihannya, penerbit default penyelenggara, dan hanya untuk peserta yang lulus.
+func sertifikatDariForm(c *gin.Context, s *models.SertifikatParalegal) string {
+	s.Nomor = strings.TrimSpace(utils.SanitizeInput(c.PostForm("nomor")))
+	s.Jenjang = c.PostForm("jenjang")
+	s.Penerbit = strings.TrimSpace(utils.SanitizeInput(c.PostForm("penerbit")))
+	s.TanggalTerbit = tanggalForm(c, "tanggal_terbit")
+	s.BerlakuSampai = tanggalForm(c, "berlaku_sampai")
+
+	if id, err := strconv.Atoi(c.PostForm("pelatihan_id")); err == nil && id > 0 {
+		var pelatihan models.Pelatihan
+		if err := config.DB.First(&pelatihan, id).Error; err != nil {
+			return "Pelatihan tidak ditemukan"
+		}
+		var peserta models.PesertaPelatihan
+		if err := config.DB.Where("pelatihan_id = ? AND paralegal_id = ?", pelatihan.ID, s.ParalegalID).
+			First(&peserta).Error; err != nil || peserta.Status != models.PesertaLulus {
+			return "Sertifikat hanya untuk peserta yang lulus pelatihan ini"
+		}
+		pid := pelatihan.ID
+		s.PelatihanID = &pid
+		s.Jenjang = pelatihan.Jenjang
+		if s.Penerbit == "" {
+			s.Penerbit = pelatihan.Penyelenggara
+		}
+	}
+
+	switch {
+	case s.Nomor == "":
+		return "Nomor sertifikat wajib diisi"
+	case len([]rune(s.Nomor)) > 100 || len([]rune(s.Penerbit)) > 191:
+		return "Nomor sertifikat atau penerbit terlalu panjang"
+	case !slices.Contains(models.JenjangPelatihan, s.Jenjang):
+		return "Pilih jenjang sertifikat"
+	case s.TanggalTerbit == nil:
+		return "Tanggal terbit wajib diisi"
+	case s.TanggalTerbit.After(time.Now()):
+		return "Tanggal terbit tidak boleh di masa depan"
+	case s.BerlakuSampai != nil && !s.BerlakuSampai.After(*s.TanggalTerbit):
+		return "Masa berlaku harus setelah tanggal terbit"
+	}
+	return ""
+}
+
+// halamanSertifikat: kembali ke halaman pelatihan kalau form dikirim dari sana, selain itu ke halaman edit paralegal
+func halamanSertifikat(c *gin.Context, paralegalID uint) string {
+	if id, err := strconv.Atoi(c.PostForm("pelatihan_id")); err == nil && id > 0 && c.PostForm("dari") == "pelatihan" {
+		return fmt.Sprintf("/admin/pelatihan/detail/%d", id)
+	}
+	return fmt.Sprintf("/admin/paralegal/edit/%d", paralegalID)
+}
+
+// SertifikatStore mencatat sertifikat paralegal (wilayah paralegal dicek WilayahOperator lewat paralegal_id)
+func SertifikatStore(c *gin.Context) {
+	var paralegal models.Paralegal
+	if err := config.DB.First(&paralegal, c.PostForm("paralegal_id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Paralegal tidak ditemukan")
+		return
+	}
+	kembali := halamanSertifikat(c, paralegal.ID)
+	key := "error_sertifikat"
+	if strings.HasPrefix(kembali, "/admin/pelatihan") {
+		key = "error"
+	}
+
+	sertifikat := models.SertifikatParalegal{ParalegalID: paralegal.ID}
+	if msg := sertifikatDariForm(c, &sertifikat); msg != "" {
+		kembaliDenganError(c, kembali, key, msg)
+		return
+	}
+	var n int64
+	config.DB.Model(&models.SertifikatParalegal{}).Where("nomor = ?", sertifikat.Nomor).Count(&n)
+	if n > 0 {
+		kembaliDenganError(c, kembali, key, "Nomor sertifikat "+sertifikat.Nomor+" sudah tercatat")
+		return
+	}
+	if err := config.DB.Create(&sertifikat).Error; err != nil {
+		kembaliDenganError(c, kembali, key, "Gagal menyimpan sertifikat")
+		return
+	}
+	if key == "error_sertifikat" {
+		kembali += "#sertifikat"
+	}
+	c.Redirect(http.StatusFound, kembali)
+}
+
+// SertifikatDelete menghapus catatan sertifikat (mis. salah input)
+func SertifikatDelete(c *gin.Context) {
+	var sertifikat models.SertifikatParalegal
+	if err := config.DB.First(&sertifikat, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Sertifikat tidak ditemukan")
+		return
+	}
+	config.DB.Delete(&sertifikat)
+	if sertifikat.PelatihanID != nil && c.PostForm("dari") == "pelatihan" {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/admin/pelatihan/detail/%d", *sertifikat.PelatihanID))
+		return
+	}
+	c.Redirect(http.StatusFound, fmt.Sprintf("/admin/paralegal/edit/%d#sertifikat", sertifikat.ParalegalID))
+}
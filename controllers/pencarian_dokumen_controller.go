@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ================== INDEKS TEKS ==================
+
+// indeksDokumen mengekstrak teks PDF lalu menyimpannya per halaman.
+// Index lama untuk record yang sama selalu diganti, jadi aman dipanggil ulang saat dokumen diganti.
+// PDF tanpa text layer (hasil scan) dimasukkan ke antrean OCR.
+// Dipanggil lewat goroutine setelah upload supaya request tidak menunggu pdftotext.
+func indeksDokumen(tipe string, id uint, path string) {
+	if path == "" || strings.ToLower(filepath.Ext(path)) != ".pdf" || !utils.FileAda(path) {
+		hapusTeksDokumen(config.DB, tipe, id)
+		return
+	}
+
+	halaman, err := utils.EkstrakTeksPDF(path)
+	if err != nil {
+		log.Printf("Gagal ekstrak teks %s/%d: %v", tipe, id, err)
+		return
+	}
+
+	var rows []models.DokumenTeks
+	for i, isi := range halaman {
+		if strings.TrimSpace(isi) == "" {
+			continue
+		}
+		rows = append(rows, models.DokumenTeks{
+			Tipe:      tipe,
+			EntitasID: id,
+			Halaman:   i + 1,
+			Isi:       isi,
+			Sumber:    "pdf",
+		})
+	}
+
+	if len(rows) == 0 {
+		// hasil OCR untuk file yang sama tetap dipakai, tidak perlu OCR ulang
+		if sudahDiOCR(tipe, id, path) {
+			return
+		}
+		config.DB.Where("tipe = ? AND entitas_id = ?", tipe, id).Delete(&models.DokumenTeks{})
+		antrekanOCR(tipe, id, path)
+		return
+	}
+
+	hapusTeksDokumen(config.DB, tipe, id)
+	if err := config.DB.CreateInBatches(&rows, 50).Error; err != nil {
+		log.Printf("Gagal simpan indeks teks %s/%d: %v", tipe, id, err)
+	}
+}
+
+// hapusIndeksDokumen membuang teks hasil ekstraksi, job OCR dan hasil verifikasi tanda tangan milik satu record
+func hapusIndeksDokumen(tx *gorm.DB, tipe string, id uint) error {
+	if err := hapusTeksDokumen(tx, tipe, id); err != nil {
+		return err
+	}
+	return tx.Where("tipe = ? AND entitas_id = ?", tipe, id).Delete(&models.TandaTanganDokumen{}).Error
+}
+
+// hapusTeksDokumen hanya membuang teks dan job OCR, hasil verifikasi tanda tangan diurus periksaTTD
+func hapusTeksDokumen(tx *gorm.DB, tipe string, id uint) error {
+	if err := tx.Where("tipe = ? AND entitas_id = ?", tipe, id).Delete(&models.DokumenTeks{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("tipe = ? AND entitas_id = ?", tipe, id).Delete(&models.OCRJob{}).Error
+}
+
+// IndeksUlangSemuaDokumen mengisi ulang indeks teks untuk semua file yang sudah ada.
+// Dipakai oleh perintah `go run . reindex-dokumen`.
+func IndeksUlangSemuaDokumen() {
+	total := 0
+	indeks := func(tipe string, id uint, path string) {
+		indeksDokumen(tipe, id, path)
+		total++
+	}
+
+	var posbankums []models.Posbankum
+	config.DB.Where("dokumen <> ''").Find(&posbankums)
+	for _, d := range posbankums {
+		indeks("posbankum", d.ID, d.Dokumen)
+	}
+
+	var paralegals []models.Paralegal
+	config.DB.Where("dokumen <> ''").Find(&paralegals)
+	for _, d := range paralegals {
+		indeks("paralegal", d.ID, d.Dokumen)
+	}
+
+	var pjas []models.Pja
+	config.DB.Where("dokumen <> ''").Find(&pjas)
+	for _, d := range pjas {
+		indeks("pja", d.ID, d.Dokumen)
+	}
+
+	var kadarkums []models.Kadarkum
+	config.DB.Where("dokumen <> ''").Find(&kadarkums)
+	for _, d := range kadarkums {
+		indeks("kadarkum", d.ID, d.Dokumen)
+	}
+
+	var lampirans []models.Lampiran
+	config.DB.Where("content_type = ?", "application/pdf").Find(&lampirans)
+	for _, l := range lampirans {
+		indeks("lampiran", l.ID, l.Path)
+	}
+
+	var halaman int64
+	config.DB.Model(&models.DokumenTeks{}).Count(&halaman)
+	log.Printf("Indeks ulang selesai: %d dokumen diproses, %d halaman terindeks", total, halaman)
+}
+
+// ================== PENCARIAN ==================
+
+// TemuanHalaman adalah satu halaman yang cocok dengan kata kunci
+type TemuanHalaman struct {
+	Halaman   int
+	Snippet   template.HTML
+	Link      string
+	Sumber    string  // pdf atau ocr
+	Keyakinan float64 // hanya untuk hasil OCR
+}
+
+// HasilPencarianDokumen mengelompokkan temuan per record
+type HasilPencarianDokumen struct {
+	Tipe      string
+	EntitasID uint
+	Judul     string
+	LinkEdit  string
+	Temuan    []TemuanHalaman
+}
+
+// judulDokumen membuat label yang mudah dibaca dan link edit untuk satu record
+func judulDokumen(tipe string, id uint) (string, string) {
+	edit := fmt.Sprintf("/admin/%s/edit/%d", tipe, id)
+	switch tipe {
+	case "posbankum":
+		var d models.Posbankum
+		if config.DB.Preload("Kelurahan").First(&d, id).Error == nil {
+			return "Posbankum " + d.Kelurahan.Name, edit
+		}
+	case "kadarkum":
+		var d models.Kadarkum
+		if config.DB.Preload("Kelurahan").First(&d, id).Error == nil {
+			return "Kadarkum " + d.Kelurahan.Name, edit
+		}
+	case "pja":
+		var d models.Pja
+		if config.DB.Preload("Kelurahan").First(&d, id).Error == nil {
+			return "PJA " + d.Kelurahan.Name, edit
+		}
+	case "paralegal":
+		var d models.Paralegal
+		if config.DB.First(&d, id).Error == nil {
+			return "Paralegal " + d.Nama, edit
+		}
+	case "lampiran":
+		var l models.Lampiran
+		if config.DB.Preload("Kategori").First(&l, id).Error == nil {
+			induk, link := judulDokumen(l.EntitasType, l.EntitasID)
+			return fmt.Sprintf("%s — %s: %s", induk, l.Kategori.Nama, l.NamaAsli), link
+		}
+	}
+	return fmt.Sprintf("%s #%d (sudah dihapus)", tipe, id), ""
+}
+
+func PencarianDokumen(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	tipe := c.Query("tipe")
+
+	var hasil []HasilPencarianDokumen
+	jumlahHalaman := 0
+
+	if q != "" {
+		cari := func() *gorm.DB {
+			db := config.DB.Model(&models.DokumenTeks{})
+			if tipe != "" {
+				db = db.Where("tipe = ?", tipe)
+			}
+			return db
+		}
+
+		// FULLTEXT dengan frasa utuh (boolean mode), cocok untuk nomor SK dan nama
+		var rows []models.DokumenTeks
+		frasa := `"` + strings.ReplaceAll(q, `"`, " ") + `"`
+		cari().Where("MATCH(isi) AGAINST(? IN BOOLEAN MODE)", frasa).
+			Order("tipe, entitas_id, halaman").Limit(200).Find(&rows)
+
+		// token pendek / tanda baca tidak masuk indeks FULLTEXT, jatuh ke LIKE
+		if len(rows) == 0 {
+			like := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q) + "%"
+			cari().Where("isi LIKE ?", like).
+				Order("tipe, entitas_id, halaman").Limit(200).Find(&rows)
+		}
+
+		jumlahHalaman = len(rows)
+		posisi := map[string]int{}
+		for _, r := range rows {
+			key := fmt.Sprintf("%s/%d", r.Tipe, r.EntitasID)
+			i, ok := posisi[key]
+			if !ok {
+				judul, link := judulDokumen(r.Tipe, r.EntitasID)
+				hasil = append(hasil, HasilPencarianDokumen{
+					Tipe:      r.Tipe,
+					EntitasID: r.EntitasID,
+					Judul:     judul,
+					LinkEdit:  link,
+				})
+				i = len(hasil) - 1
+				posisi[key] = i
+			}
+			hasil[i].Temuan = append(hasil[i].Temuan, TemuanHalaman{
+				Halaman:   r.Halaman,
+				Snippet:   utils.SnippetSorot(r.Isi, q, 80),
+				Link:      fmt.Sprintf("/view-document/%s/%d#page=%d", r.Tipe, r.EntitasID, r.Halaman),
+				Sumber:    r.Sumber,
+				Keyakinan: r.Keyakinan,
+			})
+		}
+	}
+
+	c.HTML(http.StatusOK, "pencarian_dokumen.html", gin.H{
+		"Title":         "Cari Isi Dokumen",
+		"Q":             q,
+		"Tipe":          tipe,
+		"Hasil":         hasil,
+		"JumlahHalaman": jumlahHalaman,
+		"user":          sessions.Default(c).Get("user"),
+	})
+}
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// Kunci pengaturan yang dipakai aplikasi
+const (
+	PengaturanLoginLokal      = "login_lokal_aktif"
+	PengaturanGrupAdmin       = "sso_grup_admin"
+	PengaturanGrupVerifikator = "sso_grup_verifikator"
+	PengaturanGrupOperator    = "sso_grup_operator"
+	PengaturanGrupUser        = "sso_grup_user"
+
+	PengaturanKecualikanSK     = "kecualikan_sk_kedaluwarsa"
+	PengaturanHariPeringatanSK = "sk_hari_peringatan"
+
+	PengaturanWatermarkTipe   = "watermark_tipe" // dipisah koma: posbankum,paralegal,pja,kadarkum
+	PengaturanWatermarkPublik = "watermark_dokumen_publik"
+
+	PengaturanMaksUploadPrefix = "maks_upload_mb_" // + tipe dokumen, mis. maks_upload_mb_posbankum
+
+	PengaturanHitungBelumVerifikasi = "hitung_belum_verifikasi" // data yang belum terverifikasi ikut dihitung tercapai
+)
+
+// ================== HELPER ==================
+
+// nilaiPengaturan mengambil nilai pengaturan, pakai bawaan kalau belum pernah disimpan
+func nilaiPengaturan(kunci, bawaan string) string {
+	var p models.Pengaturan
+	if err := config.DB.Where("kunci = ?", kunci).First(&p).Error; err != nil {
+		return bawaan
+	}
+	return p.Nilai
+}
+
+// pengaturanAktif membaca pengaturan on/off ("1" = aktif)
+func pengaturanAktif(kunci string, bawaan bool) bool {
+	def := "0"
+	if bawaan {
+		def = "1"
+	}
+	return nilaiPengaturan(kunci, def) == "1"
+}
+
+// simpanPengaturan insert atau update satu pengaturan
+func simpanPengaturan(kunci, nilai string) error {
+	return config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "kunci"}},
+		DoUpdates: clause.AssignmentColumns([]string{"nilai", "updated_at"}),
+	}).Create(&models.Pengaturan{Kunci: kunci, Nilai: nilai}).Error
+}
+
+// maksUploadSemua batas ukuran dokumen utama per tipe (MB) untuk form pengaturan
+func maksUploadSemua() map[string]int64 {
+	hasil := map[string]int64{}
+	for _, tipe := range []string{"posbankum", "paralegal", "pja", "kadarkum"} {
+		hasil[tipe] = MaksUploadMB(tipe)
+	}
+	return hasil
+}
+
+// maksUploadDariForm membaca batas ukuran per tipe, nilai di luar 1-1024 MB diabaikan
+func maksUploadDariForm(c *gin.Context) map[string]int64 {
+	hasil := maksUploadSemua()
+	for tipe := range hasil {
+		if mb, err := strconv.Atoi(c.PostForm(PengaturanMaksUploadPrefix + tipe)); err == nil && mb >= 1 && mb <= 1024 {
+			hasil[tipe] = int64(mb)
+		}
+	}
+	return hasil
+}
+
+func boolKeNilai(aktif bool) string {
+	if aktif {
+		return "1"
+	}
+	return "0"
+}
+
+// ================== FORM ==================
+
+// dataPengaturan isi halaman pengaturan sesuai nilai tersimpan; status OIDC/LDAP selalu dari konfigurasi
+func dataPengaturan() gin.H {
+	return gin.H{
+		"Title":      "Pengaturan",
+		"LoginLokal": pengaturanAktif(PengaturanLoginLokal, true),
+		"GrupAdmin":  nilaiPengaturan(PengaturanGrupAdmin, ""),
+		"GrupUser":   nilaiPengaturan(PengaturanGrupUser, ""),
+		"OIDCAktif":  utils.OIDCAktif(),
+		"LDAPAktif":  utils.LDAPAktif(),
+
+		"GrupVerifikator": nilaiPengaturan(PengaturanGrupVerifikator, ""),
+		"GrupOperator":    nilaiPengaturan(PengaturanGrupOperator, ""),
+
+		"KecualikanSK":     pengaturanAktif(PengaturanKecualikanSK, false),
+		"HariPeringatanSK": hariPeringatanSK(),
+
+		"WatermarkTipe":   nilaiPengaturan(PengaturanWatermarkTipe, ""),
+		"WatermarkPublik": pengaturanAktif(PengaturanWatermarkPublik, false),
+
+		"MaksUpload": maksUploadSemua(),
+
+		"HitungBelumVerifikasi": pengaturanAktif(PengaturanHitungBelumVerifikasi, false),
+	}
+}
+
+func PengaturanForm(c *gin.Context) {
+	data := dataPengaturan()
+	data["Sukses"] = c.Query("sukses") != ""
+	c.HTML(http.StatusOK, "pengaturan.html", data)
+}
+
+// ================== UPDATE ==================
+func PengaturanUpdate(c *gin.Context) {
+	loginLokal := c.PostForm("login_lokal_aktif") == "1"
+
+	// jangan sampai semua pintu login tertutup
+	if !loginLokal && !utils.OIDCAktif() && !utils.LDAPAktif() {
+		// isian form dipertahankan, status SSO tetap dari konfigurasi
+		data := dataPengaturan()
+		data["LoginLokal"] = true
+		data["Error"] = "❌ Login lokal tidak bisa dimatikan karena OIDC/LDAP belum dikonfigurasi"
+		data["GrupAdmin"] = c.PostForm("sso_grup_admin")
+		data["GrupUser"] = c.PostForm("sso_grup_user")
+		data["GrupVerifikator"] = c.PostForm(PengaturanGrupVerifikator)
+		data["GrupOperator"] = c.PostForm(PengaturanGrupOperator)
+		data["KecualikanSK"] = c.PostForm(PengaturanKecualikanSK) == "1"
+		data["HariPeringatanSK"] = c.PostForm(PengaturanHariPeringatanSK)
+		data["WatermarkTipe"] = watermarkTipeDariForm(c)
+		data["WatermarkPublik"] = c.PostForm(PengaturanWatermarkPublik) == "1"
+		data["MaksUpload"] = maksUploadDariForm(c)
+		data["HitungBelumVerifikasi"] = c.PostForm(PengaturanHitungBelumVerifikasi) == "1"
+		c.HTML(http.StatusOK, "pengaturan.html", data)
+		return
+	}
+
+	nilai := map[string]string{
+		PengaturanLoginLokal: boolKeNilai(loginLokal),
+		PengaturanGrupAdmin:  strings.TrimSpace(utils.SanitizeInput(c.PostForm("sso_grup_admin"))),
+		PengaturanGrupUser:   strings.TrimSpace(utils.SanitizeInput(c.PostForm("sso_grup_user"))),
+
+		PengaturanGrupVerifikator: strings.TrimSpace(utils.SanitizeInput(c.PostForm(PengaturanGrupVerifikator))),
+		PengaturanGrupOperator:    strings.TrimSpace(utils.SanitizeInput(c.PostForm(PengaturanGrupOperator))),
+
+		PengaturanKecualikanSK: boolKeNilai(c.PostForm(PengaturanKecualikanSK) == "1"),
+
+		PengaturanWatermarkTipe:   watermarkTipeDariForm(c),
+		PengaturanWatermarkPublik: boolKeNilai(c.PostForm(PengaturanWatermarkPublik) == "1"),
+
+		PengaturanHitungBelumVerifikasi: boolKeNilai(c.PostForm(PengaturanHitungBelumVerifikasi) == "1"),
+	}
+	if hari, err := strconv.Atoi(c.PostForm(PengaturanHariPeringatanSK)); err == nil && hari >= 0 {
+		nilai[PengaturanHariPeringatanSK] = strconv.Itoa(hari)
+	}
+	for tipe, mb := range maksUploadDariForm(c) {
+		nilai[PengaturanMaksUploadPrefix+tipe] = strconv.FormatInt(mb, 10)
+	}
+	for kunci, v := range nilai {
+		if err := simpanPengaturan(kunci, v); err != nil {
+			c.String(http.StatusInternalServerError, "Gagal simpan pengaturan")
+			return
+		}
+	}
+
+	c.Redirect(http.StatusFound, "/admin/pengaturan?sukses=1")
+}
@@ -0,0 +1,1011 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Putaran tahunan Peacemaker Justice Award (PJA). Alurnya:
+//  1. admin membuka periode (rubrik disalin dari periode sebelumnya atau rubrik bawaan) dan menunjuk juri;
+//  2. operator/admin mengusulkan desa/kelurahan, kasus mediasi tahun itu ditarik ke dosier usulan;
+//  3. periode masuk tahap penilaian, tiap juri memberi skor 0-100 per kriteria;
+//  4. admin menetapkan hasil: nilai akhir = jumlah (rata-rata skor juri per kriteria x bobot) / total bobot,
+//     peringkat teratas menjadi pemenang, dan hasilnya dicatat ke data PJA desa/kelurahan tersebut.
+
+// kriteriaBawaan dipakai untuk periode pertama (periode berikutnya menyalin rubrik periode sebelumnya)
+var kriteriaBawaan = []models.KriteriaPJA{
+	{Nama: "Jumlah perkara yang didamaikan", Keterangan: "Banyaknya sengketa yang selesai dengan kesepakatan damai", Bobot: 30},
+	{Nama: "Kualitas kesepakatan perdamaian", Keterangan: "Kesepakatan tertulis, adil bagi para pihak dan dipatuhi", Bobot: 25},
+	{Nama: "Keragaman jenis sengketa", Keterangan: "Kemampuan menangani berbagai jenis perkara", Bobot: 15},
+	{Nama: "Inovasi dan keberlanjutan", Keterangan: "Mekanisme penyelesaian sengketa yang melembaga di desa", Bobot: 20},
+	{Nama: "Kelengkapan dokumentasi", Keterangan: "Berita acara dan dokumen kesepakatan tersedia", Bobot: 10},
+}
+
+// ================== PERHITUNGAN NILAI ==================
+
+// NilaiNominasi adalah rekap nilai berbobot satu nominasi
+type NilaiNominasi struct {
+	Nilai       float64
+	JuriSelesai int // juri yang sudah mengisi semua kriteria
+}
+
+// hitungNilaiPJA menghitung nilai akhir nominasi-nominasi satu periode. Skor per kriteria dirata-rata dari
+// juri yang sudah mengisinya lalu dijumlah sesuai bobot; juri yang sudah dicopot tidak dihitung.
+func hitungNilaiPJA(periode models.PeriodePJA, nominasiIDs []uint) map[uint]NilaiNominasi {
+	hasil := map[uint]NilaiNominasi{}
+	if len(nominasiIDs) == 0 || len(periode.Kriterias) == 0 {
+		return hasil
+	}
+	juriIDs := make([]uint, len(periode.Juris))
+	for i, j := range periode.Juris {
+		juriIDs[i] = j.UserID
+	}
+	bobot := map[uint]int{}
+	jumlahBobot := 0
+	for _, k := range periode.Kriterias {
+		bobot[k.ID] = k.Bobot
+		jumlahBobot += k.Bobot
+	}
+	if jumlahBobot == 0 || len(juriIDs) == 0 {
+		return hasil
+	}
+
+	var nilais []models.NilaiPJA
+	config.DB.Where("nominasi_id IN ? AND user_id IN ?", nominasiIDs, juriIDs).Find(&nilais)
+
+	type kunci struct{ nominasi, id uint }
+	jumlahSkor := map[kunci]int{}
+	jumlahJuri := map[kunci]int{}
+	terisi := map[kunci]int{} // kriteria yang diisi per (nominasi, juri)
+	for _, n := range nilais {
+		if _, ok := bobot[n.KriteriaID]; !ok {
+			continue
+		}
+		jumlahSkor[kunci{n.NominasiID, n.KriteriaID}] += n.Skor
+		jumlahJuri[kunci{n.NominasiID, n.KriteriaID}]++
+		terisi[kunci{n.NominasiID, n.UserID}]++
+	}
+	for _, id := range nominasiIDs {
+		var r NilaiNominasi
+		for kriteriaID, b := range bobot {
+			k := kunci{id, kriteriaID}
+			if jumlahJuri[k] > 0 {
+				r.Nilai += float64(jumlahSkor[k]) / float64(jumlahJuri[k]) * float64(b)
+			}
+		}
+		r.Nilai /= float64(jumlahBobot)
+		for _, juriID := range juriIDs {
+			if terisi[kunci{id, juriID}] == len(bobot) {
+				r.JuriSelesai++
+			}
+		}
+		hasil[id] = r
+	}
+	return hasil
+}
+
+// muatPeriodePJA mengambil periode beserta rubrik (urut) dan jurinya
+func muatPeriodePJA(id uint) (models.PeriodePJA, error) {
+	var p models.PeriodePJA
+	err := config.DB.
+		Preload("Kriterias", func(db *gorm.DB) *gorm.DB { return db.Order("urutan, id") }).
+		Preload("Juris.User").
+		First(&p, "id = ?", id).Error
+	return p, err
+}
+
+// idParam membaca id dari URL/form, 0 kalau tidak valid
+func idParam(s string) uint {
+	id, err := strconv.Atoi(s)
+	if err != nil || id < 0 {
+		return 0
+	}
+	return uint(id)
+}
+
+// totalBobot rubrik satu periode, harus 100 sebelum penilaian dimulai
+func totalBobot(p models.PeriodePJA) int {
+	total := 0
+	for _, k := range p.Kriterias {
+		total += k.Bobot
+	}
+	return total
+}
+
+// ================== PERIODE ==================
+
+// RingkasanPeriode adalah satu baris daftar periode
+type RingkasanPeriode struct {
+	models.PeriodePJA
+	Nominasi int64
+}
+
+// PenghargaanIndex menampilkan daftar periode PJA
+func PenghargaanIndex(c *gin.Context) {
+	var periodes []models.PeriodePJA
+	config.DB.Order("tahun DESC").Find(&periodes)
+	baris := make([]RingkasanPeriode, len(periodes))
+	for i, p := range periodes {
+		baris[i].PeriodePJA = p
+		config.DB.Model(&models.NominasiPJA{}).Where("periode_id = ?", p.ID).
+			Scopes(scopeWilayahOperator(c, "nominasi_pja")).Count(&baris[i].Nominasi)
+	}
+
+	_, role := penggunaLogin(c)
+	c.HTML(http.StatusOK, "penghargaan_pja_index.html", gin.H{
+		"Title":       "Penghargaan PJA",
+		"Periodes":    baris,
+		"TahunBaru":   tahunRekap(c),
+		"BolehKelola": role == "admin",
+		"Error":       c.Query("error"),
+	})
+}
+
+// PeriodeStore membuka periode baru; rubrik disalin dari periode terakhir atau rubrik bawaan
+func PeriodeStore(c *gin.Context) {
+	tahun, err := strconv.Atoi(c.PostForm("tahun"))
+	if err != nil || tahun < 2000 || tahun > 2100 {
+		kembaliDenganError(c, "/admin/penghargaan-pja", "error", "Tahun periode tidak valid")
+		return
+	}
+	var n int64
+	config.DB.Model(&models.PeriodePJA{}).Where("tahun = ?", tahun).Count(&n)
+	if n > 0 {
+		kembaliDenganError(c, "/admin/penghargaan-pja", "error", fmt.Sprintf("Periode %d sudah ada", tahun))
+		return
+	}
+
+	rubrik := kriteriaBawaan
+	var terakhir models.PeriodePJA
+	if config.DB.Order("tahun DESC").First(&terakhir).Error == nil {
+		var sebelumnya []models.KriteriaPJA
+		config.DB.Where("periode_id = ?", terakhir.ID).Order("urutan, id").Find(&sebelumnya)
+		if len(sebelumnya) > 0 {
+			rubrik = sebelumnya
+		}
+	}
+
+	periode := models.PeriodePJA{
+		Tahun:   tahun,
+		Status:  models.PeriodePJADibuka,
+		Catatan: strings.TrimSpace(utils.SanitizeInput(c.PostForm("catatan"))),
+	}
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&periode).Error; err != nil {
+			return err
+		}
+		for i, k := range rubrik {
+			kriteria := models.KriteriaPJA{PeriodeID: periode.ID, Nama: k.Nama, Keterangan: k.Keterangan, Bobot: k.Bobot, Urutan: i + 1}
+			if err := tx.Create(&kriteria).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		kembaliDenganError(c, "/admin/penghargaan-pja", "error", "Gagal membuka periode")
+		return
+	}
+	c.Redirect(http.StatusFound, fmt.Sprintf("/admin/penghargaan-pja/periode/%d", periode.ID))
+}
+
+// BarisNominasi adalah satu baris tabel nominasi di halaman periode
+type BarisNominasi struct {
+	models.NominasiPJA
+	NilaiNominasi
+	JumlahKasus int
+	JumlahDamai int
+}
+
+// PeriodeDetail menampilkan rubrik, juri dan nominasi satu periode (operator hanya melihat usulan di wilayahnya)
+func PeriodeDetail(c *gin.Context) {
+	periode, err := muatPeriodePJA(idParam(c.Param("periode")))
+	if err != nil {
+		c.String(http.StatusNotFound, "Periode tidak ditemukan")
+		return
+	}
+
+	var nominasis []models.NominasiPJA
+	config.DB.Preload("Kelurahan.Kecamatan.Kabupaten").Preload("Mediasis").
+		Where("periode_id = ?", periode.ID).Scopes(scopeWilayahOperator(c, "nominasi_pja")).
+		Order("peringkat = 0, peringkat, id").Find(&nominasis)
+	ids := make([]uint, len(nominasis))
+	for i, n := range nominasis {
+		ids[i] = n.ID
+	}
+	nilai := hitungNilaiPJA(periode, ids)
+	baris := make([]BarisNominasi, len(nominasis))
+	for i, n := range nominasis {
+		baris[i] = BarisNominasi{NominasiPJA: n, NilaiNominasi: nilai[n.ID], JumlahKasus: len(n.Mediasis)}
+		if periode.Status == models.PeriodePJASelesai {
+			baris[i].Nilai = n.NilaiAkhir
+		}
+		for _, m := range n.Mediasis {
+			if m.Hasil == models.MediasiDamai {
+				baris[i].JumlahDamai++
+			}
+		}
+	}
+	// sebelum ditetapkan, urutkan sementara menurut nilai berjalan
+	if periode.Status != models.PeriodePJASelesai {
+		sort.SliceStable(baris, func(i, j int) bool { return baris[i].Nilai > baris[j].Nilai })
+	}
+
+	// calon juri: admin dan verifikator yang belum ditunjuk
+	var calonJuri []models.User
+	juriIDs := []uint{0}
+	for _, j := range periode.Juris {
+		juriIDs = append(juriIDs, j.UserID)
+	}
+	config.DB.Where("role IN ? AND id NOT IN ?", []string{"admin", "verifikator"}, juriIDs).
+		Order("username").Find(&calonJuri)
+
+	_, role := penggunaLogin(c)
+	c.HTML(http.StatusOK, "penghargaan_pja_periode.html", gin.H{
+		"Title":       fmt.Sprintf("Penghargaan PJA %d", periode.Tahun),
+		"Periode":     periode,
+		"Nominasis":   baris,
+		"TotalBobot":  totalBobot(periode),
+		"CalonJuri":   calonJuri,
+		"BolehKelola": role == "admin",
+		"Dibuka":      periode.Status == models.PeriodePJADibuka,
+		"Penilaian":   periode.Status == models.PeriodePJAPenilaian,
+		"Selesai":     periode.Status == models.PeriodePJASelesai,
+		"Error":       c.Query("error"),
+		"Info":        c.Query("info"),
+	})
+}
+
+// PeriodeTahap memindah periode antara tahap usulan dan penilaian. Penilaian hanya bisa dimulai
+// kalau rubrik berbobot total 100, juri sudah ditunjuk dan ada usulan.
+func PeriodeTahap(c *gin.Context) {
+	periode, err := muatPeriodePJA(idParam(c.Param("periode")))
+	if err != nil {
+		c.String(http.StatusNotFound, "Periode tidak ditemukan")
+		return
+	}
+	kembali := fmt.Sprintf("/admin/penghargaan-pja/periode/%d", periode.ID)
+	tahap := c.PostForm("status")
+
+	switch {
+	case periode.Status == models.PeriodePJASelesai:
+		kembaliDenganError(c, kembali, "error", "Hasil periode ini sudah ditetapkan")
+		return
+	case tahap == models.PeriodePJAPenilaian:
+		var n int64
+		config.DB.Model(&models.NominasiPJA{}).Where("periode_id = ?", periode.ID).Count(&n)
+		switch {
+		case totalBobot(periode) != 100:
+			kembaliDenganError(c, kembali, "error", "Total bobot rubrik harus 100% sebelum penilaian dimulai")
+			return
+		case len(periode.Juris) == 0:
+			kembaliDenganError(c, kembali, "error", "Tunjuk minimal satu juri sebelum penilaian dimulai")
+			return
+		case n == 0:
+			kembaliDenganError(c, kembali, "error", "Belum ada desa/kelurahan yang diusulkan")
+			return
+		}
+	case tahap != models.PeriodePJADibuka:
+		kembaliDenganError(c, kembali, "error", "Tahap tidak valid")
+		return
+	}
+	config.DB.Model(&periode).Update("status", tahap)
+	c.Redirect(http.StatusFound, kembali)
+}
+
+// KriteriaStore menambah atau mengubah butir rubrik (hanya selama usulan dibuka)
+func KriteriaStore(c *gin.Context) {
+	periode, err := muatPeriodePJA(idParam(c.Param("periode")))
+	if err != nil {
+		c.String(http.StatusNotFound, "Periode tidak ditemukan")
+		return
+	}
+	kembali := fmt.Sprintf("/admin/penghargaan-pja/periode/%d", periode.ID)
+	if periode.Status != models.PeriodePJADibuka {
+		kembaliDenganError(c, kembali, "error", "Rubrik hanya bisa diubah selama usulan dibuka")
+		return
+	}
+
+	var k models.KriteriaPJA
+	if id, err := strconv.Atoi(c.PostForm("kriteria_id")); err == nil && id > 0 {
+		if config.DB.Where("periode_id = ?", periode.ID).First(&k, id).Error != nil {
+			kembaliDenganError(c, kembali, "error", "Kriteria tidak ditemukan")
+			return
+		}
+	} else {
+		k = models.KriteriaPJA{PeriodeID: periode.ID, Urutan: len(periode.Kriterias) + 1}
+	}
+	k.Nama = strings.TrimSpace(utils.SanitizeInput(c.PostForm("nama")))
+	k.Keterangan = strings.TrimSpace(utils.SanitizeInput(c.PostForm("keterangan")))
+	bobot, err := strconv.Atoi(c.PostForm("bobot"))
+	switch {
+	case k.Nama == "" || len([]rune(k.Nama)) > 191:
+		kembaliDenganError(c, kembali, "error", "Nama kriteria wajib diisi (maksimal 191 karakter)")
+		return
+	case err != nil || bobot < 1 || bobot > 100:
+		kembaliDenganError(c, kembali, "error", "Bobot kriteria harus 1-100")
+		return
+	}
+	k.Bobot = bobot
+	if err := config.DB.Save(&k).Error; err != nil {
+		kembaliDenganError(c, kembali, "error", "Gagal menyimpan kriteria")
+		return
+	}
+	c.Redirect(http.StatusFound, kembali+"#rubrik")
+}
+
+// KriteriaDelete menghapus butir rubrik (hanya selama usulan dibuka)
+func KriteriaDelete(c *gin.Context) {
+	var k models.KriteriaPJA
+	if err := config.DB.First(&k, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Kriteria tidak ditemukan")
+		return
+	}
+	kembali := fmt.Sprintf("/admin/penghargaan-pja/periode/%d", k.PeriodeID)
+	var periode models.PeriodePJA
+	config.DB.First(&periode, k.PeriodeID)
+	if periode.Status != models.PeriodePJADibuka {
+		kembaliDenganError(c, kembali, "error", "Rubrik hanya bisa diubah selama usulan dibuka")
+		return
+	}
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kriteria_id = ?", k.ID).Delete(&models.NilaiPJA{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&k).Error
+	}); err != nil {
+		kembaliDenganError(c, kembali, "error", "Gagal menghapus kriteria")
+		return
+	}
+	c.Redirect(http.StatusFound, kembali+"#rubrik")
+}
+
+// JuriStore menunjuk admin/verifikator sebagai juri periode
+func JuriStore(c *gin.Context) {
+	periode, err := muatPeriodePJA(idParam(c.Param("periode")))
+	if err != nil {
+		c.String(http.StatusNotFound, "Periode tidak ditemukan")
+		return
+	}
+	kembali := fmt.Sprintf("/admin/penghargaan-pja/periode/%d", periode.ID)
+	if periode.Status == models.PeriodePJASelesai {
+		kembaliDenganError(c, kembali, "error", "Hasil periode ini sudah ditetapkan")
+		return
+	}
+	var user models.User
+	if err := config.DB.Where("role IN ?", []string{"admin", "verifikator"}).First(&user, "id = ?", idParam(c.PostForm("user_id"))).Error; err != nil {
+		kembaliDenganError(c, kembali, "error", "Juri harus akun admin atau verifikator")
+		return
+	}
+	if err := config.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.JuriPJA{PeriodeID: periode.ID, UserID: user.ID}).Error; err != nil {
+		kembaliDenganError(c, kembali, "error", "Gagal menunjuk juri")
+		return
+	}
+	c.Redirect(http.StatusFound, kembali+"#juri")
+}
+
+// JuriDelete mencopot juri; skor yang sudah diberikan ikut dihapus supaya tidak terhitung
+func JuriDelete(c *gin.Context) {
+	var j models.JuriPJA
+	if err := config.DB.First(&j, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Juri tidak ditemukan")
+		return
+	}
+	kembali := fmt.Sprintf("/admin/penghargaan-pja/periode/%d", j.PeriodeID)
+	var periode models.PeriodePJA
+	config.DB.First(&periode, j.PeriodeID)
+	if periode.Status == models.PeriodePJASelesai {
+		kembaliDenganError(c, kembali, "error", "Hasil periode ini sudah ditetapkan")
+		return
+	}
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND nominasi_id IN (?)", j.UserID,
+			tx.Model(&models.NominasiPJA{}).Select("id").Where("periode_id = ?", j.PeriodeID)).
+			Delete(&models.NilaiPJA{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&j).Error
+	}); err != nil {
+		kembaliDenganError(c, kembali, "error", "Gagal mencopot juri")
+		return
+	}
+	c.Redirect(http.StatusFound, kembali+"#juri")
+}
+
+// errPeriodeBukanPenilaian dipakai kalau periode sudah ditutup oleh request lain saat hasil ditetapkan
+var errPeriodeBukanPenilaian = errors.New("periode tidak lagi pada tahap penilaian")
+
+// nilaiSetara membandingkan nilai akhir pada ketelitian yang ditampilkan (2 desimal)
+func nilaiSetara(a, b float64) bool {
+	return math.Round(a*100) == math.Round(b*100)
+}
+
+// PeriodeTetapkan menghitung nilai akhir, menyusun peringkat, menandai pemenang dan mencatat hasilnya
+// ke data PJA desa/kelurahan. Semua juri harus sudah menilai semua usulan.
+func PeriodeTetapkan(c *gin.Context) {
+	periode, err := muatPeriodePJA(idParam(c.Param("periode")))
+	if err != nil {
+		c.String(http.StatusNotFound, "Periode tidak ditemukan")
+		return
+	}
+	kembali := fmt.Sprintf("/admin/penghargaan-pja/periode/%d", periode.ID)
+	if periode.Status != models.PeriodePJAPenilaian {
+		kembaliDenganError(c, kembali, "error", "Hasil hanya bisa ditetapkan pada tahap penilaian")
+		return
+	}
+	jumlahPemenang, err := strconv.Atoi(c.PostForm("jumlah_pemenang"))
+	if err != nil || jumlahPemenang < 1 {
+		kembaliDenganError(c, kembali, "error", "Jumlah pemenang minimal 1")
+		return
+	}
+
+	var nominasis []models.NominasiPJA
+	config.DB.Where("periode_id = ?", periode.ID).Order("id").Find(&nominasis)
+	ids := make([]uint, len(nominasis))
+	for i, n := range nominasis {
+		ids[i] = n.ID
+	}
+	nilai := hitungNilaiPJA(periode, ids)
+	belum := 0
+	for _, id := range ids {
+		belum += len(periode.Juris) - nilai[id].JuriSelesai
+	}
+	if belum > 0 {
+		kembaliDenganError(c, kembali, "error", fmt.Sprintf("Masih ada %d penilaian juri yang belum lengkap", belum))
+		return
+	}
+	sort.SliceStable(nominasis, func(i, j int) bool {
+		a, b := nilai[nominasis[i].ID].Nilai, nilai[nominasis[j].ID].Nilai
+		return !nilaiSetara(a, b) && a > b
+	})
+
+	// nilai sama di batas pemenang tidak diputuskan oleh urutan data, admin harus memutuskan dulu
+	if jumlahPemenang < len(nominasis) {
+		terakhir, berikut := nilai[nominasis[jumlahPemenang-1].ID].Nilai, nilai[nominasis[jumlahPemenang].ID].Nilai
+		if nilaiSetara(terakhir, berikut) {
+			kembaliDenganError(c, kembali, "error", fmt.Sprintf(
+				"Nilai akhir peringkat %d dan %d sama (%.2f). Ubah jumlah pemenang atau minta juri meninjau ulang nilainya.",
+				jumlahPemenang, jumlahPemenang+1, terakhir))
+			return
+		}
+	}
+
+	tanpaPJA := 0
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// status diubah lebih dulu secara bersyarat supaya dua penetapan bersamaan tidak sama-sama jalan
+		res := tx.Model(&models.PeriodePJA{}).Where("id = ? AND status = ?", periode.ID, models.PeriodePJAPenilaian).
+			Update("status", models.PeriodePJASelesai)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errPeriodeBukanPenilaian
+		}
+
+		for i := range nominasis {
+			n := &nominasis[i]
+			n.NilaiAkhir = nilai[n.ID].Nilai
+			// nilai sama berbagi peringkat
+			n.Peringkat = i + 1
+			if i > 0 && nilaiSetara(n.NilaiAkhir, nominasis[i-1].NilaiAkhir) {
+				n.Peringkat = nominasis[i-1].Peringkat
+			}
+			n.Hasil = models.PenghargaanNominasi
+			if i < jumlahPemenang {
+				n.Hasil = models.PenghargaanPemenang
+			}
+			if err := tx.Model(n).Select("nilai_akhir", "peringkat", "hasil").Updates(n).Error; err != nil {
+				return err
+			}
+			res := tx.Model(&models.Pja{}).Where("kelurahan_id = ?", n.KelurahanID).Updates(map[string]any{
+				"hasil_penghargaan": n.Hasil,
+				"tahun_penghargaan": periode.Tahun,
+				"nilai_penghargaan": n.NilaiAkhir,
+			})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				tanpaPJA++
+			}
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errPeriodeBukanPenilaian):
+		kembaliDenganError(c, kembali, "error", "Hasil periode ini sudah ditetapkan atau periode tidak lagi pada tahap penilaian")
+		return
+	case err != nil:
+		log.Printf("Gagal menetapkan hasil PJA %d: %v", periode.Tahun, err)
+		kembaliDenganError(c, kembali, "error", "Gagal menetapkan hasil, silakan coba lagi")
+		return
+	}
+	if tanpaPJA > 0 {
+		kembaliDenganError(c, kembali, "info", fmt.Sprintf("%d desa/kelurahan belum punya data PJA; hasilnya tercatat otomatis saat data PJA dibuat", tanpaPJA))
+		return
+	}
+	c.Redirect(http.StatusFound, kembali)
+}
+
+// hasilPenghargaanTerakhir mengisi hasil penghargaan PJA terakhir desa/kelurahan ke record PJA yang baru dibuat
+func hasilPenghargaanTerakhir(pja *models.Pja) {
+	var n models.NominasiPJA
+	err := config.DB.Preload("Periode").
+		Joins("JOIN periode_pjas ON periode_pjas.id = nominasi_pjas.periode_id").
+		Where("nominasi_pjas.kelurahan_id = ? AND periode_pjas.status = ?", pja.KelurahanID, models.PeriodePJASelesai).
+		Order("periode_pjas.tahun DESC").First(&n).Error
+	if err != nil {
+		return
+	}
+	pja.HasilPenghargaan = n.Hasil
+	pja.TahunPenghargaan = n.Periode.Tahun
+	pja.NilaiPenghargaan = n.NilaiAkhir
+}
+
+// riwayatNominasiPJA adalah semua usulan PJA satu desa/kelurahan, periode terbaru dulu (untuk halaman edit PJA)
+func riwayatNominasiPJA(kelurahanID uint) []models.NominasiPJA {
+	var nominasis []models.NominasiPJA
+	config.DB.Preload("Periode").Joins("JOIN periode_pjas ON periode_pjas.id = nominasi_pjas.periode_id").
+		Where("nominasi_pjas.kelurahan_id = ?", kelurahanID).Order("periode_pjas.tahun DESC").Find(&nominasis)
+	return nominasis
+}
+
+// ================== USULAN (NOMINASI) ==================
+
+// mediasiDosier mengambil kasus mediasi desa/kelurahan pada tahun periode untuk dipilih ke dosier
+func mediasiDosier(kelurahanID uint, tahun int) []models.Mediasi {
+	var mediasis []models.Mediasi
+	if kelurahanID != 0 {
+		config.DB.Where("kelurahan_id = ? AND YEAR(tanggal_mulai) = ?", kelurahanID, tahun).
+			Order("tanggal_mulai, id").Find(&mediasis)
+	}
+	return mediasis
+}
+
+// formNominasi menyiapkan data form usulan; terpilih berisi id mediasi yang masuk dosier
+func formNominasi(n models.NominasiPJA, periode models.PeriodePJA, terpilih []uint, pesan string) gin.H {
+	judul := "Usulkan Desa/Kelurahan"
+	if n.ID != 0 {
+		judul = "Edit Usulan"
+	}
+	var kelurahan models.Kelurahan
+	labelKelurahan := ""
+	if n.KelurahanID != 0 && config.DB.Preload("Kecamatan.Kabupaten").First(&kelurahan, n.KelurahanID).Error == nil {
+		labelKelurahan = kelurahan.Name + " (" + kelurahan.Kecamatan.Name + " - " + kelurahan.Kecamatan.Kabupaten.Name + ")"
+	}
+	dipilih := map[uint]bool{}
+	for _, id := range terpilih {
+		dipilih[id] = true
+	}
+	return gin.H{
+		"Title":          judul,
+		"Nominasi":       n,
+		"Periode":        periode,
+		"LabelKelurahan": labelKelurahan,
+		"Mediasis":       mediasiDosier(n.KelurahanID, periode.Tahun),
+		"Dipilih":        dipilih,
+		"Error":          pesan,
+	}
+}
+
+// nominasiDariForm membaca isian usulan dan kasus mediasi yang dipilih ke dosier
+func nominasiDariForm(c *gin.Context, n *models.NominasiPJA, periode models.PeriodePJA) ([]uint, string) {
+	if n.ID == 0 {
+		kelurahanID, _ := strconv.Atoi(c.PostForm("kelurahan_id"))
+		n.KelurahanID = uint(kelurahanID)
+	}
+	n.NamaKepalaDesa = strings.TrimSpace(utils.SanitizeInput(c.PostForm("nama_kepala_desa")))
+	n.Alasan = strings.TrimSpace(utils.SanitizeInput(c.PostForm("alasan")))
+
+	var terpilih []uint
+	for _, v := range c.PostFormArray("mediasi_id") {
+		if id, err := strconv.Atoi(v); err == nil {
+			terpilih = append(terpilih, uint(id))
+		}
+	}
+	// hanya kasus desa/kelurahan itu sendiri pada tahun periode
+	valid := 0
+	for _, m := range mediasiDosier(n.KelurahanID, periode.Tahun) {
+		if slices.Contains(terpilih, m.ID) {
+			valid++
+		}
+	}
+
+	var duplikat int64
+	config.DB.Model(&models.NominasiPJA{}).
+		Where("periode_id = ? AND kelurahan_id = ? AND id <> ?", periode.ID, n.KelurahanID, n.ID).Count(&duplikat)
+	switch {
+	case periode.Status != models.PeriodePJADibuka:
+		return terpilih, "Usulan periode ini sudah ditutup"
+	case n.KelurahanID == 0:
+		return terpilih, "Pilih desa/kelurahan dari daftar"
+	case duplikat > 0:
+		return terpilih, "Desa/kelurahan ini sudah diusulkan pada periode ini"
+	case n.NamaKepalaDesa == "" || len([]rune(n.NamaKepalaDesa)) > 191:
+		return terpilih, "Nama kepala desa/lurah wajib diisi (maksimal 191 karakter)"
+	case valid == 0 || valid != len(terpilih):
+		return terpilih, fmt.Sprintf("Pilih minimal satu kasus mediasi tahun %d dari desa/kelurahan ini untuk dosier", periode.Tahun)
+	}
+	return terpilih, ""
+}
+
+// NominasiCreate menampilkan form usulan; ?kelurahan_id= memuat kasus mediasi desa tersebut
+func NominasiCreate(c *gin.Context) {
+	periode, err := muatPeriodePJA(idParam(c.Query("periode_id")))
+	if err != nil {
+		c.String(http.StatusNotFound, "Periode tidak ditemukan")
+		return
+	}
+	n := models.NominasiPJA{PeriodeID: periode.ID}
+	if id, err := strconv.Atoi(c.Query("kelurahan_id")); err == nil {
+		if kabupatenID, dibatasi := kabupatenOperator(c); !dibatasi || kabupatenKelurahan(uint(id)) == kabupatenID {
+			n.KelurahanID = uint(id)
+		}
+	}
+	// semua kasus yang berakhir damai langsung dicentang
+	var terpilih []uint
+	for _, m := range mediasiDosier(n.KelurahanID, periode.Tahun) {
+		if m.Hasil == models.MediasiDamai {
+			terpilih = append(terpilih, m.ID)
+		}
+	}
+	pesan := ""
+	if periode.Status != models.PeriodePJADibuka {
+		pesan = "Usulan periode ini sudah ditutup"
+	}
+	c.HTML(http.StatusOK, "nominasi_pja_form.html", formNominasi(n, periode, terpilih, pesan))
+}
+
+func NominasiStore(c *gin.Context) {
+	periode, err := muatPeriodePJA(idParam(c.PostForm("periode_id")))
+	if err != nil {
+		c.String(http.StatusNotFound, "Periode tidak ditemukan")
+		return
+	}
+	n := models.NominasiPJA{PeriodeID: periode.ID}
+	terpilih, msg := nominasiDariForm(c, &n, periode)
+	if msg != "" {
+		c.HTML(http.StatusOK, "nominasi_pja_form.html", formNominasi(n, periode, terpilih, msg))
+		return
+	}
+	n.Diusulkan, _ = penggunaLogin(c)
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Mediasis").Create(&n).Error; err != nil {
+			return err
+		}
+		return tx.Model(&n).Association("Mediasis").Replace(mediasiByID(terpilih))
+	}); err != nil {
+		log.Printf("Gagal menyimpan usulan PJA: %v", err)
+		c.HTML(http.StatusOK, "nominasi_pja_form.html", formNominasi(n, periode, terpilih, "❌ Gagal menyimpan usulan, silakan coba lagi"))
+		return
+	}
+	c.Redirect(http.StatusFound, fmt.Sprintf("/admin/penghargaan-pja/usulan/dosier/%d", n.ID))
+}
+
+// mediasiByID membuat slice Mediasi berisi id saja untuk association Replace
+func mediasiByID(ids []uint) []models.Mediasi {
+	mediasis := make([]models.Mediasi, len(ids))
+	for i, id := range ids {
+		mediasis[i].ID = id
+	}
+	return mediasis
+}
+
+func NominasiEdit(c *gin.Context) {
+	var n models.NominasiPJA
+	if err := config.DB.Preload("Mediasis").First(&n, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Usulan tidak ditemukan")
+		return
+	}
+	periode, _ := muatPeriodePJA(n.PeriodeID)
+	terpilih := make([]uint, len(n.Mediasis))
+	for i, m := range n.Mediasis {
+		terpilih[i] = m.ID
+	}
+	pesan := ""
+	if periode.Status != models.PeriodePJADibuka {
+		pesan = "Usulan periode ini sudah ditutup"
+	}
+	c.HTML(http.StatusOK, "nominasi_pja_form.html", formNominasi(n, periode, terpilih, pesan))
+}
+
+func NominasiUpdate(c *gin.Context) {
+	var n models.NominasiPJA
+	if err := config.DB.First(&n, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Usulan tidak ditemukan")
+		return
+	}
+	periode, _ := muatPeriodePJA(n.PeriodeID)
+	terpilih, msg := nominasiDariForm(c, &n, periode)
+	if msg != "" {
+		c.HTML(http.StatusOK, "nominasi_pja_form.html", formNominasi(n, periode, terpilih, msg))
+		return
+	}
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&n).Select("nama_kepala_desa", "alasan").Updates(&n).Error; err != nil {
+			return err
+		}
+		return tx.Model(&n).Association("Mediasis").Replace(mediasiByID(terpilih))
+	}); err != nil {
+		c.HTML(http.StatusOK, "nominasi_pja_form.html", formNominasi(n, periode, terpilih, "❌ Gagal menyimpan usulan, silakan coba lagi"))
+		return
+	}
+	c.Redirect(http.StatusFound, fmt.Sprintf("/admin/penghargaan-pja/usulan/dosier/%d", n.ID))
+}
+
+// NominasiDelete menarik usulan selama periode masih dibuka
+func NominasiDelete(c *gin.Context) {
+	var n models.NominasiPJA
+	if err := config.DB.Preload("Periode").First(&n, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Usulan tidak ditemukan")
+		return
+	}
+	kembali := fmt.Sprintf("/admin/penghargaan-pja/periode/%d", n.PeriodeID)
+	if n.Periode.Status != models.PeriodePJADibuka {
+		kembaliDenganError(c, kembali, "error", "Usulan periode ini sudah ditutup")
+		return
+	}
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&n).Association("Mediasis").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Where("nominasi_id = ?", n.ID).Delete(&models.NilaiPJA{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&n).Error
+	}); err != nil {
+		kembaliDenganError(c, kembali, "error", "Gagal menghapus usulan")
+		return
+	}
+	c.Redirect(http.StatusFound, kembali)
+}
+
+// RincianNilaiKriteria adalah skor semua juri untuk satu kriteria di dosier
+type RincianNilaiKriteria struct {
+	models.KriteriaPJA
+	Skor    []int // urut sesuai daftar juri periode, -1 = belum dinilai
+	Rata    float64
+	Catatan []string
+}
+
+// rincianNilai menyusun tabel kriteria x juri untuk satu nominasi
+func rincianNilai(periode models.PeriodePJA, nominasiID uint) []RincianNilaiKriteria {
+	var nilais []models.NilaiPJA
+	config.DB.Where("nominasi_id = ?", nominasiID).Find(&nilais)
+	skor := map[[2]uint]models.NilaiPJA{}
+	for _, n := range nilais {
+		skor[[2]uint{n.KriteriaID, n.UserID}] = n
+	}
+	hasil := make([]RincianNilaiKriteria, len(periode.Kriterias))
+	for i, k := range periode.Kriterias {
+		r := RincianNilaiKriteria{KriteriaPJA: k}
+		jumlah, juri := 0, 0
+		for _, j := range periode.Juris {
+			n, ok := skor[[2]uint{k.ID, j.UserID}]
+			if !ok {
+				r.Skor = append(r.Skor, -1)
+				continue
+			}
+			r.Skor = append(r.Skor, n.Skor)
+			jumlah += n.Skor
+			juri++
+			if n.Catatan != "" {
+				r.Catatan = append(r.Catatan, j.User.Username+": "+n.Catatan)
+			}
+		}
+		if juri > 0 {
+			r.Rata = float64(jumlah) / float64(juri)
+		}
+		hasil[i] = r
+	}
+	return hasil
+}
+
+// NominasiDosier menampilkan dosier kandidat: profil usulan, kasus mediasi, data PJA dan (untuk admin) rincian nilai juri
+func NominasiDosier(c *gin.Context) {
+	var n models.NominasiPJA
+	if err := config.DB.Preload("Kelurahan.Kecamatan.Kabupaten").
+		Preload("Mediasis", func(db *gorm.DB) *gorm.DB { return db.Order("tanggal_mulai, id") }).
+		First(&n, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Usulan tidak ditemukan")
+		return
+	}
+	periode, _ := muatPeriodePJA(n.PeriodeID)
+
+	jenis := map[string]int{}
+	damai := 0
+	for _, m := range n.Mediasis {
+		jenis[m.JenisSengketa]++
+		if m.Hasil == models.MediasiDamai {
+			damai++
+		}
+	}
+	var pja models.Pja
+	adaPJA := config.DB.Where("kelurahan_id = ?", n.KelurahanID).First(&pja).Error == nil
+
+	_, role := penggunaLogin(c)
+	data := gin.H{
+		"Title":    "Dosier " + n.Kelurahan.Name,
+		"Nominasi": n,
+		"Periode":  periode,
+		"Jenis":    jenis,
+		"Damai":    damai,
+		"AdaPJA":   adaPJA,
+		"PJA":      pja,
+		"Dibuka":   periode.Status == models.PeriodePJADibuka,
+	}
+	if role == "admin" {
+		data["Rincian"] = rincianNilai(periode, n.ID)
+		data["Nilai"] = hitungNilaiPJA(periode, []uint{n.ID})[n.ID]
+	}
+	c.HTML(http.StatusOK, "nominasi_pja_dosier.html", data)
+}
+
+// ================== PENILAIAN JURI ==================
+
+// BarisPenilaian adalah satu nominasi di daftar tugas juri
+type BarisPenilaian struct {
+	models.NominasiPJA
+	Terisi int // kriteria yang sudah dinilai juri ini
+}
+
+// PenilaianIndex menampilkan usulan yang harus dinilai juri yang login (periode tahap penilaian)
+func PenilaianIndex(c *gin.Context) {
+	user, err := userLogin(c)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+	var periodes []models.PeriodePJA
+	config.DB.Preload("Kriterias").
+		Joins("JOIN juri_pjas ON juri_pjas.periode_id = periode_pjas.id").
+		Where("juri_pjas.user_id = ? AND periode_pjas.status = ?", user.ID, models.PeriodePJAPenilaian).
+		Order("periode_pjas.tahun DESC").Find(&periodes)
+
+	type tugasPeriode struct {
+		Periode   models.PeriodePJA
+		Nominasis []BarisPenilaian
+	}
+	var tugas []tugasPeriode
+	for _, p := range periodes {
+		var nominasis []models.NominasiPJA
+		config.DB.Preload("Kelurahan.Kecamatan.Kabupaten").Where("periode_id = ?", p.ID).Order("id").Find(&nominasis)
+		t := tugasPeriode{Periode: p}
+		for _, n := range nominasis {
+			var terisi int64
+			config.DB.Model(&models.NilaiPJA{}).
+				Where("nominasi_id = ? AND user_id = ? AND kriteria_id IN (?)", n.ID, user.ID,
+					config.DB.Model(&models.KriteriaPJA{}).Select("id").Where("periode_id = ?", p.ID)).
+				Count(&terisi)
+			t.Nominasis = append(t.Nominasis, BarisPenilaian{NominasiPJA: n, Terisi: int(terisi)})
+		}
+		tugas = append(tugas, t)
+	}
+
+	c.HTML(http.StatusOK, "penilaian_pja.html", gin.H{
+		"Title": "Penilaian PJA",
+		"Tugas": tugas,
+		"user":  sessions.Default(c).Get("user"),
+	})
+}
+
+// juriNominasi memastikan user login adalah juri periode nominasi yang sedang tahap penilaian
+func juriNominasi(c *gin.Context) (models.User, models.NominasiPJA, models.PeriodePJA, bool) {
+	var n models.NominasiPJA
+	var periode models.PeriodePJA
+	user, err := userLogin(c)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/login")
+		return user, n, periode, false
+	}
+	if err := config.DB.Preload("Kelurahan.Kecamatan.Kabupaten").
+		Preload("Mediasis", func(db *gorm.DB) *gorm.DB { return db.Order("tanggal_mulai, id") }).
+		First(&n, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Usulan tidak ditemukan")
+		return user, n, periode, false
+	}
+	periode, _ = muatPeriodePJA(n.PeriodeID)
+	juri := slices.ContainsFunc(periode.Juris, func(j models.JuriPJA) bool { return j.UserID == user.ID })
+	if !juri || periode.Status != models.PeriodePJAPenilaian {
+		c.String(http.StatusForbidden, "🚫 Anda bukan juri periode ini atau penilaian sudah ditutup.")
+		return user, n, periode, false
+	}
+	return user, n, periode, true
+}
+
+// PenilaianForm menampilkan dosier ringkas dan isian skor juri per kriteria
+func PenilaianForm(c *gin.Context) {
+	user, n, periode, ok := juriNominasi(c)
+	if !ok {
+		return
+	}
+	var nilais []models.NilaiPJA
+	config.DB.Where("nominasi_id = ? AND user_id = ?", n.ID, user.ID).Find(&nilais)
+	milikSaya := map[uint]*models.NilaiPJA{}
+	for i := range nilais {
+		milikSaya[nilais[i].KriteriaID] = &nilais[i]
+	}
+	c.HTML(http.StatusOK, "penilaian_pja_form.html", gin.H{
+		"Title":    "Penilaian " + n.Kelurahan.Name,
+		"Nominasi": n,
+		"Periode":  periode,
+		"Nilai":    milikSaya,
+		"Error":    c.Query("error"),
+		"Sukses":   c.Query("sukses"),
+		"user":     sessions.Default(c).Get("user"),
+	})
+}
+
+// PenilaianSimpan menyimpan skor juri (0-100) untuk semua kriteria sekaligus
+func PenilaianSimpan(c *gin.Context) {
+	user, n, periode, ok := juriNominasi(c)
+	if !ok {
+		return
+	}
+	kembali := fmt.Sprintf("/admin/penilaian-pja/%d", n.ID)
+	var nilais []models.NilaiPJA
+	for _, k := range periode.Kriterias {
+		skor, err := strconv.Atoi(c.PostForm(fmt.Sprintf("skor_%d", k.ID)))
+		if err != nil || skor < 0 || skor > 100 {
+			kembaliDenganError(c, kembali, "error", fmt.Sprintf("Skor \"%s\" harus 0-100", k.Nama))
+			return
+		}
+		nilais = append(nilais, models.NilaiPJA{
+			NominasiID: n.ID,
+			KriteriaID: k.ID,
+			UserID:     user.ID,
+			Skor:       skor,
+			Catatan:    strings.TrimSpace(utils.SanitizeInput(c.PostForm(fmt.Sprintf("catatan_%d", k.ID)))),
+		})
+	}
+	if len(nilais) > 0 {
+		if err := config.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "nominasi_id"}, {Name: "kriteria_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"skor", "catatan", "updated_at"}),
+		}).Create(&nilais).Error; err != nil {
+			kembaliDenganError(c, kembali, "error", "Gagal menyimpan nilai")
+			return
+		}
+	}
+	kembaliDenganError(c, kembali, "sukses", "Nilai tersimpan")
+}
+
+// jumlahPenilaianTertunda menghitung usulan yang belum selesai dinilai juri (untuk pengingat di halaman verifikasi)
+func jumlahPenilaianTertunda(c *gin.Context) int {
+	user, err := userLogin(c)
+	if err != nil {
+		return 0
+	}
+	var periodes []models.PeriodePJA
+	config.DB.Preload("Kriterias").
+		Joins("JOIN juri_pjas ON juri_pjas.periode_id = periode_pjas.id").
+		Where("juri_pjas.user_id = ? AND periode_pjas.status = ?", user.ID, models.PeriodePJAPenilaian).Find(&periodes)
+	tertunda := 0
+	for _, p := range periodes {
+		var selesai []uint
+		config.DB.Model(&models.NilaiPJA{}).Select("nominasi_id").
+			Where("user_id = ? AND kriteria_id IN (?)", user.ID,
+				config.DB.Model(&models.KriteriaPJA{}).Select("id").Where("periode_id = ?", p.ID)).
+			Group("nominasi_id").Having("COUNT(*) = ?", len(p.Kriterias)).Pluck("nominasi_id", &selesai)
+		var total int64
+		config.DB.Model(&models.NominasiPJA{}).Where("periode_id = ?", p.ID).Count(&total)
+		tertunda += int(total) - len(selesai)
+	}
+	return tertunda
+}
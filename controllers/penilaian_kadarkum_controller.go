@@ -0,0 +1,745 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Penilaian Keluarga Sadar Hukum (Kadarkum). Alurnya:
+//  1. admin menyusun kuesioner berisi indikator berbobot (total 100) dan ambang lulus, lalu mengaktifkannya;
+//  2. tim penilai mengisi skor 0-100 per indikator (persentase keluarga yang disurvei yang memenuhi)
+//     untuk satu desa/kelurahan per tahun; operator hanya untuk desa/kelurahan di wilayahnya;
+//  3. nilai = jumlah (skor x bobot) / total bobot, lulus kalau nilai >= ambang lulus kuesioner;
+//  4. rekap menampilkan sebaran nilai per kabupaten/kota dan capaian per indikator.
+
+// indikatorBawaan dipakai untuk kuesioner pertama (kuesioner berikutnya bisa menyalin kuesioner lain)
+var indikatorBawaan = []models.IndikatorKadarkum{
+	{Aspek: "Administrasi Kependudukan", Pertanyaan: "Keluarga memiliki KTP, Kartu Keluarga dan akta kelahiran seluruh anggota keluarga", Bobot: 20},
+	{Aspek: "Administrasi Kependudukan", Pertanyaan: "Perkawinan tercatat (buku nikah/akta perkawinan)", Bobot: 15},
+	{Aspek: "Kepatuhan Hukum", Pertanyaan: "Keluarga taat membayar Pajak Bumi dan Bangunan", Bobot: 15},
+	{Aspek: "Kepatuhan Hukum", Pertanyaan: "Tidak ada anggota keluarga yang terlibat tindak pidana atau narkotika dalam setahun terakhir", Bobot: 20},
+	{Aspek: "Kepatuhan Hukum", Pertanyaan: "Tidak terjadi kekerasan dalam rumah tangga", Bobot: 15},
+	{Aspek: "Pengetahuan Hukum", Pertanyaan: "Keluarga mengetahui layanan bantuan hukum (Posbankum/paralegal) di desa/kelurahan", Bobot: 15},
+}
+
+// ================== PERHITUNGAN NILAI ==================
+
+// hitungNilaiKadarkum menghitung nilai berbobot dari skor per indikator
+func hitungNilaiKadarkum(k models.KuesionerKadarkum, skor map[uint]int) float64 {
+	total, jumlahBobot := 0.0, 0
+	for _, i := range k.Indikators {
+		total += float64(skor[i.ID] * i.Bobot)
+		jumlahBobot += i.Bobot
+	}
+	if jumlahBobot == 0 {
+		return 0
+	}
+	return math.Round(total/float64(jumlahBobot)*100) / 100
+}
+
+// muatKuesioner mengambil kuesioner beserta indikatornya (urut)
+func muatKuesioner(id uint) (models.KuesionerKadarkum, error) {
+	var k models.KuesionerKadarkum
+	err := config.DB.
+		Preload("Indikators", func(db *gorm.DB) *gorm.DB { return db.Order("urutan, id") }).
+		First(&k, "id = ?", id).Error
+	return k, err
+}
+
+// kuesionerTerpakai: kuesioner yang sudah dipakai menilai tidak boleh diubah indikator/ambangnya
+func kuesionerTerpakai(id uint) bool {
+	var n int64
+	config.DB.Model(&models.PenilaianKadarkum{}).Where("kuesioner_id = ?", id).Count(&n)
+	return n > 0
+}
+
+// totalBobotIndikator harus 100 sebelum kuesioner diaktifkan
+func totalBobotIndikator(k models.KuesionerKadarkum) int {
+	total := 0
+	for _, i := range k.Indikators {
+		total += i.Bobot
+	}
+	return total
+}
+
+// ================== KUESIONER (ADMIN) ==================
+
+// RingkasanKuesioner adalah satu baris daftar kuesioner
+type RingkasanKuesioner struct {
+	models.KuesionerKadarkum
+	Indikator int64
+	Penilaian int64
+}
+
+// KuesionerIndex menampilkan daftar kuesioner penilaian Kadarkum
+func KuesionerIndex(c *gin.Context) {
+	var kuesioners []models.KuesionerKadarkum
+	config.DB.Order("aktif DESC, id DESC").Find(&kuesioners)
+	baris := make([]RingkasanKuesioner, len(kuesioners))
+	for i, k := range kuesioners {
+		baris[i].KuesionerKadarkum = k
+		config.DB.Model(&models.IndikatorKadarkum{}).Where("kuesioner_id = ?", k.ID).Count(&baris[i].Indikator)
+		config.DB.Model(&models.PenilaianKadarkum{}).Where("kuesioner_id = ?", k.ID).Count(&baris[i].Penilaian)
+	}
+	c.HTML(http.StatusOK, "kuesioner_kadarkum_index.html", gin.H{
+		"Title":      "Kuesioner Penilaian Kadarkum",
+		"Kuesioners": baris,
+		"Error":      c.Query("error"),
+	})
+}
+
+// KuesionerStore membuat kuesioner baru; indikator disalin dari kuesioner lain atau indikator bawaan
+func KuesionerStore(c *gin.Context) {
+	nama := strings.TrimSpace(utils.SanitizeInput(c.PostForm("nama")))
+	if nama == "" || len([]rune(nama)) > 191 {
+		kembaliDenganError(c, "/admin/kuesioner-kadarkum", "error", "Nama kuesioner wajib diisi (maksimal 191 karakter)")
+		return
+	}
+	k := models.KuesionerKadarkum{Nama: nama, AmbangLulus: 70}
+	indikator := indikatorBawaan
+	if id := idParam(c.PostForm("salin_dari")); id > 0 {
+		sumber, err := muatKuesioner(id)
+		if err != nil {
+			kembaliDenganError(c, "/admin/kuesioner-kadarkum", "error", "Kuesioner sumber tidak ditemukan")
+			return
+		}
+		k.Keterangan = sumber.Keterangan
+		k.AmbangLulus = sumber.AmbangLulus
+		indikator = sumber.Indikators
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&k).Error; err != nil {
+			return err
+		}
+		for i, ind := range indikator {
+			baru := models.IndikatorKadarkum{KuesionerID: k.ID, Aspek: ind.Aspek, Pertanyaan: ind.Pertanyaan, Bobot: ind.Bobot, Urutan: i + 1}
+			if err := tx.Create(&baru).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		kembaliDenganError(c, "/admin/kuesioner-kadarkum", "error", "Gagal membuat kuesioner")
+		return
+	}
+	c.Redirect(http.StatusFound, fmt.Sprintf("/admin/kuesioner-kadarkum/%d", k.ID))
+}
+
+// KuesionerDetail menampilkan isian kuesioner dan daftar indikatornya
+func KuesionerDetail(c *gin.Context) {
+	k, err := muatKuesioner(idParam(c.Param("id")))
+	if err != nil {
+		c.String(http.StatusNotFound, "Kuesioner tidak ditemukan")
+		return
+	}
+	c.HTML(http.StatusOK, "kuesioner_kadarkum_form.html", gin.H{
+		"Title":      "Kuesioner " + k.Nama,
+		"Kuesioner":  k,
+		"TotalBobot": totalBobotIndikator(k),
+		"Terkunci":   kuesionerTerpakai(k.ID),
+		"Error":      c.Query("error"),
+	})
+}
+
+// KuesionerUpdate mengubah nama, keterangan dan ambang lulus (ambang terkunci setelah kuesioner dipakai)
+func KuesionerUpdate(c *gin.Context) {
+	k, err := muatKuesioner(idParam(c.Param("id")))
+	if err != nil {
+		c.String(http.StatusNotFound, "Kuesioner tidak ditemukan")
+		return
+	}
+	kembali := fmt.Sprintf("/admin/kuesioner-kadarkum/%d", k.ID)
+	k.Nama = strings.TrimSpace(utils.SanitizeInput(c.PostForm("nama")))
+	k.Keterangan = strings.TrimSpace(utils.SanitizeInput(c.PostForm("keterangan")))
+	if k.Nama == "" || len([]rune(k.Nama)) > 191 {
+		kembaliDenganError(c, kembali, "error", "Nama kuesioner wajib diisi (maksimal 191 karakter)")
+		return
+	}
+	if !kuesionerTerpakai(k.ID) {
+		ambang, err := strconv.ParseFloat(c.PostForm("ambang_lulus"), 64)
+		if err != nil || ambang < 0 || ambang > 100 {
+			kembaliDenganError(c, kembali, "error", "Ambang lulus harus 0-100")
+			return
+		}
+		k.AmbangLulus = ambang
+	}
+	if err := config.DB.Omit("Indikators").Save(&k).Error; err != nil {
+		kembaliDenganError(c, kembali, "error", "Gagal menyimpan kuesioner")
+		return
+	}
+	c.Redirect(http.StatusFound, kembali)
+}
+
+// KuesionerAktifkan menjadikan kuesioner ini yang dipakai untuk penilaian baru
+func KuesionerAktifkan(c *gin.Context) {
+	k, err := muatKuesioner(idParam(c.Param("id")))
+	if err != nil {
+		c.String(http.StatusNotFound, "Kuesioner tidak ditemukan")
+		return
+	}
+	kembali := fmt.Sprintf("/admin/kuesioner-kadarkum/%d", k.ID)
+	if totalBobotIndikator(k) != 100 {
+		kembaliDenganError(c, kembali, "error", "Total bobot indikator harus 100% sebelum kuesioner diaktifkan")
+		return
+	}
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.KuesionerKadarkum{}).Where("aktif = ?", true).Update("aktif", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&k).Update("aktif", true).Error
+	}); err != nil {
+		kembaliDenganError(c, kembali, "error", "Gagal mengaktifkan kuesioner")
+		return
+	}
+	c.Redirect(http.StatusFound, "/admin/kuesioner-kadarkum")
+}
+
+// KuesionerDelete menghapus kuesioner yang belum pernah dipakai
+func KuesionerDelete(c *gin.Context) {
+	var k models.KuesionerKadarkum
+	if err := config.DB.First(&k, "id = ?", idParam(c.Param("id"))).Error; err != nil {
+		c.String(http.StatusNotFound, "Kuesioner tidak ditemukan")
+		return
+	}
+	if kuesionerTerpakai(k.ID) {
+		kembaliDenganError(c, "/admin/kuesioner-kadarkum", "error", "Kuesioner sudah dipakai menilai, tidak bisa dihapus")
+		return
+	}
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kuesioner_id = ?", k.ID).Delete(&models.IndikatorKadarkum{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&k).Error
+	}); err != nil {
+		kembaliDenganError(c, "/admin/kuesioner-kadarkum", "error", "Gagal menghapus kuesioner")
+		return
+	}
+	c.Redirect(http.StatusFound, "/admin/kuesioner-kadarkum")
+}
+
+// IndikatorStore menambah atau mengubah indikator (hanya selama kuesioner belum dipakai)
+func IndikatorStore(c *gin.Context) {
+	k, err := muatKuesioner(idParam(c.Param("kuesioner")))
+	if err != nil {
+		c.String(http.StatusNotFound, "Kuesioner tidak ditemukan")
+		return
+	}
+	kembali := fmt.Sprintf("/admin/kuesioner-kadarkum/%d", k.ID)
+	if kuesionerTerpakai(k.ID) {
+		kembaliDenganError(c, kembali, "error", "Kuesioner sudah dipakai menilai; buat salinan untuk mengubah indikator")
+		return
+	}
+
+	var ind models.IndikatorKadarkum
+	if id := idParam(c.PostForm("indikator_id")); id > 0 {
+		if config.DB.Where("kuesioner_id = ?", k.ID).First(&ind, id).Error != nil {
+			kembaliDenganError(c, kembali, "error", "Indikator tidak ditemukan")
+			return
+		}
+	} else {
+		ind = models.IndikatorKadarkum{KuesionerID: k.ID, Urutan: len(k.Indikators) + 1}
+	}
+	ind.Aspek = strings.TrimSpace(utils.SanitizeInput(c.PostForm("aspek")))
+	ind.Pertanyaan = strings.TrimSpace(utils.SanitizeInput(c.PostForm("pertanyaan")))
+	bobot, err := strconv.Atoi(c.PostForm("bobot"))
+	switch {
+	case ind.Pertanyaan == "":
+		kembaliDenganError(c, kembali, "error", "Pertanyaan indikator wajib diisi")
+		return
+	case len([]rune(ind.Aspek)) > 100:
+		kembaliDenganError(c, kembali, "error", "Aspek maksimal 100 karakter")
+		return
+	case err != nil || bobot < 1 || bobot > 100:
+		kembaliDenganError(c, kembali, "error", "Bobot indikator harus 1-100")
+		return
+	}
+	ind.Bobot = bobot
+	if err := config.DB.Save(&ind).Error; err != nil {
+		kembaliDenganError(c, kembali, "error", "Gagal menyimpan indikator")
+		return
+	}
+	c.Redirect(http.StatusFound, kembali+"#indikator")
+}
+
+// IndikatorDelete menghapus indikator (hanya selama kuesioner belum dipakai)
+func IndikatorDelete(c *gin.Context) {
+	var ind models.IndikatorKadarkum
+	if err := config.DB.First(&ind, "id = ?", idParam(c.Param("id"))).Error; err != nil {
+		c.String(http.StatusNotFound, "Indikator tidak ditemukan")
+		return
+	}
+	kembali := fmt.Sprintf("/admin/kuesioner-kadarkum/%d", ind.KuesionerID)
+	if kuesionerTerpakai(ind.KuesionerID) {
+		kembaliDenganError(c, kembali, "error", "Kuesioner sudah dipakai menilai; buat salinan untuk mengubah indikator")
+		return
+	}
+	if err := config.DB.Delete(&ind).Error; err != nil {
+		kembaliDenganError(c, kembali, "error", "Gagal menghapus indikator")
+		return
+	}
+	c.Redirect(http.StatusFound, kembali+"#indikator")
+}
+
+// ================== PENILAIAN ==================
+
+// PenilaianKadarkumIndex menampilkan hasil penilaian, bisa difilter tahun, kelurahan, kelulusan dan kata kunci
+func PenilaianKadarkumIndex(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	tahun, _ := strconv.Atoi(c.Query("tahun"))
+	kelurahanID, _ := strconv.Atoi(c.Query("kelurahan_id"))
+	lulus := c.Query("lulus")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit := 50
+
+	db := config.DB.Model(&models.PenilaianKadarkum{}).
+		Joins("JOIN kelurahans ON kelurahans.id = penilaian_kadarkums.kelurahan_id").
+		Scopes(scopeWilayahOperator(c, "penilaian_kadarkum"))
+	if q != "" {
+		db = db.Where("kelurahans.name LIKE ? OR penilaian_kadarkums.penilai LIKE ?", "%"+q+"%", "%"+q+"%")
+	}
+	if tahun > 0 {
+		db = db.Where("penilaian_kadarkums.tahun = ?", tahun)
+	}
+	if kelurahanID > 0 {
+		db = db.Where("penilaian_kadarkums.kelurahan_id = ?", kelurahanID)
+	}
+	switch lulus {
+	case "1":
+		db = db.Where("penilaian_kadarkums.lulus = ?", true)
+	case "0":
+		db = db.Where("penilaian_kadarkums.lulus = ?", false)
+	}
+
+	var total int64
+	db.Count(&total)
+	var penilaians []models.PenilaianKadarkum
+	db.Preload("Kelurahan.Kecamatan.Kabupaten").Preload("Kuesioner").
+		Order("penilaian_kadarkums.tahun DESC, penilaian_kadarkums.nilai DESC").
+		Offset((page - 1) * limit).Limit(limit).Find(&penilaians)
+
+	_, role := penggunaLogin(c)
+	c.HTML(http.StatusOK, "penilaian_kadarkum_index.html", gin.H{
+		"Title":       "Penilaian Kadarkum",
+		"Penilaians":  penilaians,
+		"Total":       total,
+		"Search":      q,
+		"Tahun":       tahun,
+		"KelurahanID": kelurahanID,
+		"Lulus":       lulus,
+		"Page":        page,
+		"TotalPages":  int(math.Ceil(float64(total) / float64(limit))),
+		"BolehKelola": role == "admin",
+		"Error":       c.Query("error"),
+	})
+}
+
+// formPenilaianKadarkum menyiapkan data halaman form (create/edit)
+func formPenilaianKadarkum(p models.PenilaianKadarkum, k models.KuesionerKadarkum, skor map[uint]int, pesan string) gin.H {
+	judul := "Catat Penilaian Kadarkum"
+	if p.ID != 0 {
+		judul = "Edit Penilaian Kadarkum"
+	}
+	var kelurahan models.Kelurahan
+	labelKelurahan := ""
+	if p.KelurahanID != 0 && config.DB.Preload("Kecamatan.Kabupaten").First(&kelurahan, p.KelurahanID).Error == nil {
+		labelKelurahan = kelurahan.Name + " (" + kelurahan.Kecamatan.Name + " - " + kelurahan.Kecamatan.Kabupaten.Name + ")"
+	}
+	return gin.H{
+		"Title":          judul,
+		"Penilaian":      p,
+		"Kuesioner":      k,
+		"Skor":           skor,
+		"LabelKelurahan": labelKelurahan,
+		"Error":          pesan,
+	}
+}
+
+// penilaianKadarkumDariForm membaca isian penilaian dan skor per indikator, lalu menghitung nilainya
+func penilaianKadarkumDariForm(c *gin.Context, p *models.PenilaianKadarkum, k models.KuesionerKadarkum) (map[uint]int, string) {
+	kelurahanID, _ := strconv.Atoi(c.PostForm("kelurahan_id"))
+	p.KelurahanID = uint(kelurahanID)
+	p.Tahun, _ = strconv.Atoi(c.PostForm("tahun"))
+	p.Tanggal = tanggalForm(c, "tanggal")
+	p.Penilai = strings.TrimSpace(utils.SanitizeInput(c.PostForm("penilai")))
+	p.JumlahKeluarga, _ = strconv.Atoi(c.PostForm("jumlah_keluarga"))
+	p.Catatan = strings.TrimSpace(utils.SanitizeInput(c.PostForm("catatan")))
+
+	skor := map[uint]int{}
+	pesanSkor := ""
+	for _, i := range k.Indikators {
+		s, err := strconv.Atoi(c.PostForm(fmt.Sprintf("skor_%d", i.ID)))
+		if err != nil || s < 0 || s > 100 {
+			if pesanSkor == "" {
+				pesanSkor = fmt.Sprintf("Skor indikator %d harus 0-100", i.Urutan)
+			}
+			continue
+		}
+		skor[i.ID] = s
+	}
+
+	var n, ganda int64
+	config.DB.Model(&models.Kelurahan{}).Where("id = ?", p.KelurahanID).Count(&n)
+	config.DB.Model(&models.PenilaianKadarkum{}).
+		Where("kelurahan_id = ? AND tahun = ? AND id <> ?", p.KelurahanID, p.Tahun, p.ID).Count(&ganda)
+	switch {
+	case n == 0:
+		return skor, "Pilih kelurahan/desa dari daftar"
+	case p.Tahun < 2000 || p.Tahun > time.Now().Year():
+		return skor, "Tahun penilaian tidak valid"
+	case ganda > 0:
+		return skor, fmt.Sprintf("Desa/kelurahan ini sudah dinilai untuk tahun %d, edit penilaian yang ada", p.Tahun)
+	case p.Penilai == "" || len([]rune(p.Penilai)) > 191:
+		return skor, "Nama penilai wajib diisi (maksimal 191 karakter)"
+	case p.JumlahKeluarga < 1:
+		return skor, "Jumlah keluarga yang disurvei minimal 1"
+	case p.Tanggal != nil && p.Tanggal.After(time.Now()):
+		return skor, "Tanggal penilaian tidak boleh di masa depan"
+	case pesanSkor != "":
+		return skor, pesanSkor
+	}
+	p.Nilai = hitungNilaiKadarkum(k, skor)
+	p.Lulus = p.Nilai >= k.AmbangLulus
+	return skor, ""
+}
+
+// simpanJawaban mengganti skor per indikator milik satu penilaian
+func simpanJawaban(tx *gorm.DB, penilaianID uint, skor map[uint]int) error {
+	if err := tx.Where("penilaian_id = ?", penilaianID).Delete(&models.JawabanKadarkum{}).Error; err != nil {
+		return err
+	}
+	jawabans := make([]models.JawabanKadarkum, 0, len(skor))
+	for indikatorID, s := range skor {
+		jawabans = append(jawabans, models.JawabanKadarkum{PenilaianID: penilaianID, IndikatorID: indikatorID, Skor: s})
+	}
+	if len(jawabans) == 0 {
+		return nil
+	}
+	return tx.Create(&jawabans).Error
+}
+
+// kuesionerAktif mengambil kuesioner yang dipakai untuk penilaian baru
+func kuesionerAktif() (models.KuesionerKadarkum, error) {
+	var k models.KuesionerKadarkum
+	if err := config.DB.Where("aktif = ?", true).First(&k).Error; err != nil {
+		return k, err
+	}
+	return muatKuesioner(k.ID)
+}
+
+func PenilaianKadarkumCreate(c *gin.Context) {
+	k, err := kuesionerAktif()
+	if err != nil {
+		kembaliDenganError(c, "/admin/penilaian-kadarkum", "error", "Belum ada kuesioner aktif, hubungi admin")
+		return
+	}
+	p := models.PenilaianKadarkum{KuesionerID: k.ID, Tahun: time.Now().Year()}
+	// dari halaman Kadarkum: ?kelurahan_id= langsung terisi kalau masih di wilayah operator
+	if id, err := strconv.Atoi(c.Query("kelurahan_id")); err == nil {
+		if kabupatenID, dibatasi := kabupatenOperator(c); !dibatasi || kabupatenKelurahan(uint(id)) == kabupatenID {
+			p.KelurahanID = uint(id)
+		}
+	}
+	c.HTML(http.StatusOK, "penilaian_kadarkum_form.html", formPenilaianKadarkum(p, k, nil, ""))
+}
+
+func PenilaianKadarkumStore(c *gin.Context) {
+	k, err := kuesionerAktif()
+	if err != nil {
+		kembaliDenganError(c, "/admin/penilaian-kadarkum", "error", "Belum ada kuesioner aktif, hubungi admin")
+		return
+	}
+	p := models.PenilaianKadarkum{KuesionerID: k.ID}
+	skor, msg := penilaianKadarkumDariForm(c, &p, k)
+	if msg == "" && totalBobotIndikator(k) != 100 {
+		msg = "Total bobot kuesioner aktif belum 100%, hubungi admin"
+	}
+	if msg != "" {
+		c.HTML(http.StatusOK, "penilaian_kadarkum_form.html", formPenilaianKadarkum(p, k, skor, msg))
+		return
+	}
+	p.Dicatat, _ = penggunaLogin(c)
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Kuesioner", "Kelurahan", "Jawabans").Create(&p).Error; err != nil {
+			return err
+		}
+		return simpanJawaban(tx, p.ID, skor)
+	}); err != nil {
+		log.Printf("Gagal menyimpan penilaian kadarkum: %v", err)
+		p.ID = 0
+		c.HTML(http.StatusOK, "penilaian_kadarkum_form.html", formPenilaianKadarkum(p, k, skor, "❌ Gagal menyimpan penilaian, silakan coba lagi"))
+		return
+	}
+	c.Redirect(http.StatusFound, fmt.Sprintf("/admin/penilaian-kadarkum/edit/%d", p.ID))
+}
+
+func PenilaianKadarkumEdit(c *gin.Context) {
+	var p models.PenilaianKadarkum
+	if err := config.DB.Preload("Jawabans").First(&p, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Penilaian tidak ditemukan")
+		return
+	}
+	k, _ := muatKuesioner(p.KuesionerID)
+	skor := map[uint]int{}
+	for _, j := range p.Jawabans {
+		skor[j.IndikatorID] = j.Skor
+	}
+	c.HTML(http.StatusOK, "penilaian_kadarkum_form.html", formPenilaianKadarkum(p, k, skor, c.Query("error")))
+}
+
+// PenilaianKadarkumUpdate menyimpan ulang penilaian; nilai dihitung ulang dengan kuesioner yang dipakai saat itu
+func PenilaianKadarkumUpdate(c *gin.Context) {
+	var p models.PenilaianKadarkum
+	if err := config.DB.First(&p, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Penilaian tidak ditemukan")
+		return
+	}
+	k, _ := muatKuesioner(p.KuesionerID)
+	skor, msg := penilaianKadarkumDariForm(c, &p, k)
+	if msg != "" {
+		c.HTML(http.StatusOK, "penilaian_kadarkum_form.html", formPenilaianKadarkum(p, k, skor, msg))
+		return
+	}
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Kuesioner", "Kelurahan", "Jawabans").Save(&p).Error; err != nil {
+			return err
+		}
+		return simpanJawaban(tx, p.ID, skor)
+	}); err != nil {
+		log.Printf("Gagal menyimpan penilaian kadarkum %d: %v", p.ID, err)
+		c.HTML(http.StatusOK, "penilaian_kadarkum_form.html", formPenilaianKadarkum(p, k, skor, "❌ Gagal menyimpan penilaian, silakan coba lagi"))
+		return
+	}
+	c.Redirect(http.StatusFound, fmt.Sprintf("/admin/penilaian-kadarkum/edit/%d", p.ID))
+}
+
+func PenilaianKadarkumDelete(c *gin.Context) {
+	var p models.PenilaianKadarkum
+	if err := config.DB.First(&p, c.Param("id")).Error; err != nil {
+		c.String(http.StatusNotFound, "Penilaian tidak ditemukan")
+		return
+	}
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("penilaian_id = ?", p.ID).Delete(&models.JawabanKadarkum{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&p).Error
+	}); err != nil {
+		kembaliDenganError(c, "/admin/penilaian-kadarkum", "error", "Gagal menghapus penilaian")
+		return
+	}
+	c.Redirect(http.StatusFound, "/admin/penilaian-kadarkum")
+}
+
+// penilaianKadarkumTerakhir mengambil penilaian terbaru tiap kelurahan (untuk badge di data Kadarkum)
+func penilaianKadarkumTerakhir(kelurahanIDs []uint) map[uint]models.PenilaianKadarkum {
+	hasil := map[uint]models.PenilaianKadarkum{}
+	if len(kelurahanIDs) == 0 {
+		return hasil
+	}
+	var penilaians []models.PenilaianKadarkum
+	config.DB.Where("kelurahan_id IN ?", kelurahanIDs).Order("tahun").Find(&penilaians)
+	for _, p := range penilaians {
+		hasil[p.KelurahanID] = p // urut tahun naik, yang terakhir menimpa
+	}
+	return hasil
+}
+
+// riwayatPenilaianKadarkum mengambil semua penilaian satu kelurahan, terbaru dulu
+func riwayatPenilaianKadarkum(kelurahanID uint) []models.PenilaianKadarkum {
+	var penilaians []models.PenilaianKadarkum
+	config.DB.Preload("Kuesioner").Where("kelurahan_id = ?", kelurahanID).Order("tahun DESC").Find(&penilaians)
+	return penilaians
+}
+
+// ================== REKAP ==================
+
+// RekapPenilaianKadarkum adalah ringkasan nilai Kadarkum satu kabupaten/kota pada satu tahun
+type RekapPenilaianKadarkum struct {
+	Nama      string
+	Terdaftar int // desa/kelurahan yang punya data Kadarkum (dokumen SK)
+	Dinilai   int
+	Lulus     int
+	Rata      float64
+	Sebaran   []int // jumlah penilaian per models.RentangNilaiKadarkum
+}
+
+// Rentang -> label models.RentangNilaiKadarkum untuk keterangan batang sebaran di template
+func (r RekapPenilaianKadarkum) Rentang() []string {
+	return models.RentangNilaiKadarkum
+}
+
+// CapaianIndikator adalah rata-rata skor satu indikator dari semua penilaian pada satu tahun
+type CapaianIndikator struct {
+	Aspek      string
+	Pertanyaan string
+	Bobot      int
+	Rata       float64
+	Jumlah     int
+}
+
+// rekapPenilaianKadarkum menghitung sebaran nilai per kabupaten/kota pada satu tahun; scopes membatasi
+// data Kadarkum yang dihitung terdaftar (mis. hanya yang terverifikasi untuk dashboard publik)
+func rekapPenilaianKadarkum(kabupatens []models.Kabupaten, tahun int, scopes ...func(*gorm.DB) *gorm.DB) []RekapPenilaianKadarkum {
+	indeksKab := make(map[uint]int, len(kabupatens))
+	hasil := make([]RekapPenilaianKadarkum, len(kabupatens))
+	kabupatenIDs := make([]uint, len(kabupatens))
+	for i, kab := range kabupatens {
+		indeksKab[kab.ID] = i
+		kabupatenIDs[i] = kab.ID
+		hasil[i].Nama = kab.Name
+		hasil[i].Sebaran = make([]int, len(models.RentangNilaiKadarkum))
+	}
+	if len(kabupatens) == 0 {
+		return hasil
+	}
+
+	var penilaians []struct {
+		KabupatenID uint
+		Nilai       float64
+		Lulus       bool
+	}
+	config.DB.Model(&models.PenilaianKadarkum{}).
+		Select("kecamatans.kabupaten_id, penilaian_kadarkums.nilai, penilaian_kadarkums.lulus").
+		Joins("JOIN kelurahans ON kelurahans.id = penilaian_kadarkums.kelurahan_id").
+		Joins("JOIN kecamatans ON kecamatans.id = kelurahans.kecamatan_id").
+		Where("penilaian_kadarkums.tahun = ? AND kecamatans.kabupaten_id IN ?", tahun, kabupatenIDs).
+		Scan(&penilaians)
+	jumlahNilai := make([]float64, len(kabupatens))
+	for _, p := range penilaians {
+		i, ok := indeksKab[p.KabupatenID]
+		if !ok {
+			continue
+		}
+		hasil[i].Dinilai++
+		if p.Lulus {
+			hasil[i].Lulus++
+		}
+		hasil[i].Sebaran[models.PenilaianKadarkum{Nilai: p.Nilai}.RentangNilai()]++
+		jumlahNilai[i] += p.Nilai
+	}
+
+	var terdaftar []struct {
+		KabupatenID uint
+		Jumlah      int
+	}
+	config.DB.Model(&models.Kadarkum{}).
+		Select("kecamatans.kabupaten_id, COUNT(DISTINCT kadarkums.kelurahan_id) AS jumlah").
+		Joins("JOIN kelurahans ON kelurahans.id = kadarkums.kelurahan_id").
+		Joins("JOIN kecamatans ON kecamatans.id = kelurahans.kecamatan_id").
+		Where("kecamatans.kabupaten_id IN ?", kabupatenIDs).
+		Scopes(scopes...).
+		Group("kecamatans.kabupaten_id").Scan(&terdaftar)
+	for _, t := range terdaftar {
+		if i, ok := indeksKab[t.KabupatenID]; ok {
+			hasil[i].Terdaftar = t.Jumlah
+		}
+	}
+
+	for i := range hasil {
+		if hasil[i].Dinilai > 0 {
+			hasil[i].Rata = math.Round(jumlahNilai[i]/float64(hasil[i].Dinilai)*100) / 100
+		}
+	}
+	return hasil
+}
+
+// totalRekapKadarkum menjumlah rekap semua kabupaten/kota (rata-rata ditimbang jumlah penilaian)
+func totalRekapKadarkum(rekap []RekapPenilaianKadarkum) RekapPenilaianKadarkum {
+	total := RekapPenilaianKadarkum{Nama: "Total", Sebaran: make([]int, len(models.RentangNilaiKadarkum))}
+	jumlahNilai := 0.0
+	for _, r := range rekap {
+		total.Terdaftar += r.Terdaftar
+		total.Dinilai += r.Dinilai
+		total.Lulus += r.Lulus
+		jumlahNilai += r.Rata * float64(r.Dinilai)
+		for i, n := range r.Sebaran {
+			total.Sebaran[i] += n
+		}
+	}
+	if total.Dinilai > 0 {
+		total.Rata = math.Round(jumlahNilai/float64(total.Dinilai)*100) / 100
+	}
+	return total
+}
+
+// capaianIndikatorKadarkum menghitung rata-rata skor per indikator, indikator terlemah di atas
+func capaianIndikatorKadarkum(tahun int, scopes ...func(*gorm.DB) *gorm.DB) []CapaianIndikator {
+	var capaian []CapaianIndikator
+	config.DB.Model(&models.JawabanKadarkum{}).
+		Select("indikator_kadarkums.aspek, indikator_kadarkums.pertanyaan, indikator_kadarkums.bobot, "+
+			"AVG(jawaban_kadarkums.skor) AS rata, COUNT(*) AS jumlah").
+		Joins("JOIN indikator_kadarkums ON indikator_kadarkums.id = jawaban_kadarkums.indikator_id").
+		Joins("JOIN penilaian_kadarkums ON penilaian_kadarkums.id = jawaban_kadarkums.penilaian_id").
+		Where("penilaian_kadarkums.tahun = ?", tahun).
+		Scopes(scopes...).
+		Group("indikator_kadarkums.id, indikator_kadarkums.aspek, indikator_kadarkums.pertanyaan, indikator_kadarkums.bobot").
+		Scan(&capaian)
+	sort.SliceStable(capaian, func(i, j int) bool { return capaian[i].Rata < capaian[j].Rata })
+	return capaian
+}
+
+// tahunPenilaianKadarkumTerakhir adalah tahun penilaian terbaru, false kalau belum ada penilaian sama sekali
+func tahunPenilaianKadarkumTerakhir() (int, bool) {
+	var tahun int
+	config.DB.Model(&models.PenilaianKadarkum{}).Select("COALESCE(MAX(tahun), 0)").Scan(&tahun)
+	return tahun, tahun > 0
+}
+
+// ringkasanPenilaianKadarkum untuk banner dashboard admin: total penilaian seprovinsi pada tahun penilaian terakhir
+func ringkasanPenilaianKadarkum() (RekapPenilaianKadarkum, int) {
+	tahun, ada := tahunPenilaianKadarkumTerakhir()
+	if !ada {
+		return RekapPenilaianKadarkum{}, 0
+	}
+	var kabupatens []models.Kabupaten
+	config.DB.Find(&kabupatens)
+	return totalRekapKadarkum(rekapPenilaianKadarkum(kabupatens, tahun)), tahun
+}
+
+// PenilaianKadarkumRekap menampilkan sebaran nilai Kadarkum per kabupaten/kota dan capaian per indikator
+func PenilaianKadarkumRekap(c *gin.Context) {
+	tahun := tahunRekap(c)
+	if c.Query("tahun") == "" {
+		if terakhir, ada := tahunPenilaianKadarkumTerakhir(); ada {
+			tahun = terakhir
+		}
+	}
+	var kabupatens []models.Kabupaten
+	db := config.DB.Order("name")
+	if kabupatenID, dibatasi := kabupatenOperator(c); dibatasi {
+		db = db.Where("id = ?", kabupatenID)
+	}
+	db.Find(&kabupatens)
+
+	rekap := rekapPenilaianKadarkum(kabupatens, tahun)
+	kuesioner := ""
+	if k, err := kuesionerAktif(); err == nil {
+		kuesioner = fmt.Sprintf("%s (ambang lulus %.0f)", k.Nama, k.AmbangLulus)
+	}
+	c.HTML(http.StatusOK, "penilaian_kadarkum_rekap.html", gin.H{
+		"Title":     "Rekap Penilaian Kadarkum",
+		"Tahun":     tahun,
+		"Rekap":     rekap,
+		"Total":     totalRekapKadarkum(rekap),
+		"Rentang":   models.RentangNilaiKadarkum,
+		"Capaian":   capaianIndikatorKadarkum(tahun, scopeWilayahOperator(c, "penilaian_kadarkum")),
+		"Kuesioner": kuesioner,
+	})
+}
@@ -1,9 +1,8 @@
 package controllers
 
 import (
+	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -12,7 +11,6 @@ import (
 	"go-admin/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -29,6 +27,7 @@ func PJAIndex(c *gin.Context) {
 
 	var pjas []models.Pja
 	db := config.DB.Model(&models.Pja{}).
+		Scopes(scopeWilayahOperator(c, "pja")).
 		Preload("Kelurahan").
 		Preload("Kelurahan.Kecamatan").
 		Preload("Kelurahan.Kecamatan.Kabupaten")
@@ -51,6 +50,12 @@ func PJAIndex(c *gin.Context) {
 
 	totalPages := int((total + int64(limit) - 1) / int64(limit))
 
+	// status tanda tangan elektronik untuk badge di tabel
+	ids := make([]uint, len(pjas))
+	for i, d := range pjas {
+		ids[i] = d.ID
+	}
+
 	c.HTML(http.StatusOK, "pja_index.html", gin.H{
 		"Title":      "Data PJA",
 		"Pjas":       pjas,
@@ -58,6 +63,8 @@ func PJAIndex(c *gin.Context) {
 		"Page":       page,
 		"Offset":     offset,
 		"TotalPages": totalPages,
+		"TTD":        statusTTDMap("pja", ids),
+		"FormZIP":    formZIP(c, "/admin/unduh-zip", "pja"),
 	})
 }
 
@@ -79,55 +86,57 @@ func PJAStore(c *gin.Context) {
 			"Title":          "Tambah PJA",
 			"ErrorKelurahan": "❌ PJA untuk kelurahan ini sudah ada",
 			"Catatan":        catatan,
+			"SK":             dataSKDariForm(c),
 		})
 		return
 	}
 
-	// Validasi file upload
-	file, err := c.FormFile("dokumen")
-	if err != nil {
-		c.HTML(http.StatusOK, "pja_create.html", gin.H{
-			"Title":     "Tambah PJA",
-			"ErrorFile": "❌ Dokumen wajib diupload",
-			"Catatan":   catatan,
-		})
-		return
+	// dokumen dari upload biasa atau upload resumable yang sudah selesai
+	st, ada, msg := siapkanDokumenForm(c, "pja", 0)
+	if !ada {
+		msg = "❌ Dokumen wajib diupload"
 	}
-	if !utils.ValidatePDFUpload(c, file) {
+	if msg != "" {
 		c.HTML(http.StatusOK, "pja_create.html", gin.H{
 			"Title":     "Tambah PJA",
-			"ErrorFile": "❌ File tidak valid. Pastikan file adalah PDF dan ukurannya di bawah 10MB.",
+			"ErrorFile": msg,
 			"Catatan":   catatan,
+			"SK":        dataSKDariForm(c),
 		})
 		return
 	}
 
-	uploadPath := "uploads/pja"
-	os.MkdirAll(uploadPath, os.ModePerm)
-
-	// Generate nama file unik menggunakan uuid
-	ext := filepath.Ext(file.Filename)
-	newName := uuid.New().String() + ext
-	fullPath := filepath.Join(uploadPath, newName)
+	// Buat record baru
+	pja := models.Pja{
+		KelurahanID:   uint(kelurahanID),
+		Catatan:       catatan,
+		DokumenPublik: c.PostForm("dokumen_publik") == "1",
+		DataSK:        dataSKDariForm(c),
+		Verifikasi:    models.Verifikasi{StatusVerifikasi: statusSetelahSimpan(c, "")},
+	}
+	// desa yang sudah pernah dinilai langsung membawa hasil penghargaan terakhirnya
+	hasilPenghargaanTerakhir(&pja)
 
-	if err := c.SaveUploadedFile(file, fullPath); err != nil {
+	if err := simpanDenganDokumen(st, func(tx *gorm.DB, path string) error {
+		pja.Dokumen = path
+		if err := tx.Create(&pja).Error; err != nil {
+			return err
+		}
+		return catatRiwayat(tx, c, "pja", pja.ID, "", pja.StatusVerifikasi, "")
+	}); err != nil {
+		log.Printf("Gagal menyimpan pja: %v", err)
 		c.HTML(http.StatusOK, "pja_create.html", gin.H{
 			"Title":     "Tambah PJA",
-			"ErrorFile": "❌ Gagal upload file",
+			"ErrorFile": "❌ Gagal menyimpan data, dokumen belum tersimpan. Silakan coba lagi.",
 			"Catatan":   catatan,
+			"SK":        dataSKDariForm(c),
 		})
 		return
 	}
 
-	// Buat record baru
-	publicPath := strings.ReplaceAll(fullPath, "\\", "/")
-	pja := models.Pja{
-		KelurahanID: uint(kelurahanID),
-		Dokumen:     publicPath,
-		Catatan:     catatan,
-	}
-
-	config.DB.Create(&pja)
+	// ekstrak teks PDF untuk pencarian isi dokumen dan verifikasi tanda tangan elektroniknya
+	go indeksDokumen("pja", pja.ID, pja.Dokumen)
+	go periksaTTD("pja", pja.ID, pja.Dokumen)
 	c.Redirect(http.StatusFound, "/admin/pja")
 }
 
@@ -142,9 +151,10 @@ func PJAView(c *gin.Context) {
 		c.String(http.StatusNotFound, err.Error())
 		return
 	}
-	c.Header("Content-Disposition", "inline; filename="+filepath.Base(pja.Dokumen))
-	c.Header("Content-Type", "application/pdf")
-	c.File(pja.Dokumen)
+	if tampilkanDenganTTD(c, "pja", pja.ID, pja.Dokumen) {
+		return
+	}
+	kirimDokumen(c, "pja", pja.ID, pja.Dokumen)
 }
 
 // ================== EDIT FORM ==================
@@ -165,8 +175,22 @@ func PJAEdit(c *gin.Context) {
 	}
 
 	c.HTML(http.StatusOK, "pja_edit.html", gin.H{
-		"Title": "Edit PJA",
-		"PJA":   pja,
+		"Title":             "Edit PJA",
+		"PJA":               pja,
+		"EntitasTipe":       "pja",
+		"EntitasID":         pja.ID,
+		"Lampirans":         daftarLampiran("pja", pja.ID),
+		"KategoriLampirans": semuaKategoriLampiran(),
+		"ErrorLampiran":     c.Query("error_lampiran"),
+		"OCR":               statusOCR("pja", pja.ID),
+		"OCRLampiran":       statusOCRLampiran("pja", pja.ID),
+		"TTD":               statusTTD("pja", pja.ID),
+		"Verifikasi":        infoVerifikasi("pja", pja.ID, pja.Verifikasi),
+		"Komentars":         daftarKomentar("pja", pja.ID),
+		"ErrorKomentar":     c.Query("error_komentar"),
+		"SaranSK":           saranSK("pja", pja.ID),
+		"HariPeringatanSK":  hariPeringatanSK(),
+		"RiwayatNominasi":   riwayatNominasiPJA(pja.KelurahanID),
 	})
 }
 
@@ -197,45 +221,48 @@ func PJAUpdate(c *gin.Context) {
 	// Update field
 	pja.KelurahanID = uint(kelurahanID)
 	pja.Catatan = utils.SanitizeInput(c.PostForm("catatan"))
+	pja.DokumenPublik = c.PostForm("dokumen_publik") == "1"
+	pja.DataSK = dataSKDariForm(c)
 
-	file, err := c.FormFile("dokumen")
-	// Jika ada file baru yang diupload
-	if err == nil {
-		if !utils.ValidatePDFUpload(c, file) {
-			c.HTML(http.StatusOK, "pja_edit.html", gin.H{
-				"Title":     "Edit PJA",
-				"PJA":       pja,
-				"ErrorFile": "❌ File tidak valid. Pastikan file adalah PDF dan ukurannya di bawah 10MB.",
-			})
-			return
-		}
-
-		uploadPath := "uploads/pja"
-		os.MkdirAll(uploadPath, os.ModePerm)
-
-		// Generate nama file unik menggunakan uuid
-		ext := filepath.Ext(file.Filename)
-		newName := uuid.New().String() + ext
-		newPath := filepath.Join(uploadPath, newName)
-
-		if err := c.SaveUploadedFile(file, newPath); err != nil {
-			c.HTML(http.StatusOK, "pja_edit.html", gin.H{
-				"Title":     "Edit PJA",
-				"PJA":       pja,
-				"ErrorFile": "❌ Gagal upload file",
-			})
-			return
+	// dokumen baru dari upload biasa atau upload resumable yang sudah selesai
+	st, adaFile, msg := siapkanDokumenForm(c, "pja", pja.ID)
+	if msg != "" {
+		c.HTML(http.StatusOK, "pja_edit.html", gin.H{
+			"Title":     "Edit PJA",
+			"PJA":       pja,
+			"ErrorFile": msg,
+		})
+		return
+	}
+	dokumenLama := pja.Dokumen
+	statusLama := pja.StatusVerifikasi
+	pja.StatusVerifikasi = statusSetelahSimpan(c, statusLama)
+	if err := simpanDenganDokumen(st, func(tx *gorm.DB, path string) error {
+		if adaFile {
+			pja.Dokumen = path
 		}
-
-		// Hapus file lama jika ada
-		if pja.Dokumen != "" {
-			_ = os.Remove(pja.Dokumen)
+		if err := tx.Save(&pja).Error; err != nil {
+			return err
 		}
-
-		pja.Dokumen = strings.ReplaceAll(newPath, "\\", "/")
+		return catatRiwayat(tx, c, "pja", pja.ID, statusLama, pja.StatusVerifikasi, "")
+	}); err != nil {
+		log.Printf("Gagal menyimpan pja %d: %v", pja.ID, err)
+		pja.Dokumen = dokumenLama
+		pja.StatusVerifikasi = statusLama
+		c.HTML(http.StatusOK, "pja_edit.html", gin.H{
+			"Title":     "Edit PJA",
+			"PJA":       pja,
+			"ErrorFile": "❌ Gagal menyimpan data, dokumen belum tersimpan. Silakan coba lagi.",
+		})
+		return
 	}
 
-	config.DB.Save(&pja)
+	// dokumen diganti: file lama dilepas (dihapus kalau tidak dipakai record lain), indeks ulang teksnya
+	if adaFile {
+		lepasFile(dokumenLama)
+		go indeksDokumen("pja", pja.ID, pja.Dokumen)
+		go periksaTTD("pja", pja.ID, pja.Dokumen)
+	}
 	c.Redirect(http.StatusFound, "/admin/pja")
 }
 
@@ -249,13 +276,35 @@ func PJADelete(c *gin.Context) {
 		return
 	}
 
-	// hapus file PDF kalau ada
-	if pja.Dokumen != "" {
-		_ = os.Remove(pja.Dokumen)
+	// semua penghapusan di DB satu transaksi, file baru dilepas setelah commit
+	var fileLampiran []string
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		paths, err := hapusLampiranEntitas(tx, "pja", pja.ID)
+		if err != nil {
+			return err
+		}
+		fileLampiran = paths
+		if err := hapusIndeksDokumen(tx, "pja", pja.ID); err != nil {
+			return err
+		}
+		// diskusi, riwayat verifikasi dan notifikasi record ini
+		paths, err = hapusJejakEntitas(tx, "pja", pja.ID)
+		if err != nil {
+			return err
+		}
+		fileLampiran = append(fileLampiran, paths...)
+		return tx.Delete(&pja).Error
+	}); err != nil {
+		log.Printf("Gagal menghapus pja %d: %v", pja.ID, err)
+		c.String(http.StatusInternalServerError, "❌ Gagal menghapus data, tidak ada yang berubah. Silakan coba lagi.")
+		return
 	}
 
-	// hapus record
-	config.DB.Delete(&pja)
+	// file hanya dihapus kalau tidak dipakai record lain
+	for _, p := range fileLampiran {
+		lepasFile(p)
+	}
+	lepasFile(pja.Dokumen)
 
 	c.Redirect(http.StatusFound, "/admin/pja")
 }
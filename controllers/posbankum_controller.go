@@ -1,18 +1,17 @@
 package controllers
 
 import (
+	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"go-admin/config"
 	"go-admin/models"
 	"go-admin/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -31,6 +30,7 @@ func PosbankumIndex(c *gin.Context) {
 
 	var posbankums []models.Posbankum
 	db := config.DB.Model(&models.Posbankum{}).
+		Scopes(scopeWilayahOperator(c, "posbankum")).
 		Preload("Kelurahan").
 		Preload("Kelurahan.Kecamatan").
 		Preload("Kelurahan.Kecamatan.Kabupaten")
@@ -57,6 +57,12 @@ func PosbankumIndex(c *gin.Context) {
 	// hitung total halaman
 	totalPages := int((total + int64(limit) - 1) / int64(limit))
 
+	// status tanda tangan elektronik untuk badge di tabel
+	ids := make([]uint, len(posbankums))
+	for i, d := range posbankums {
+		ids[i] = d.ID
+	}
+
 	c.HTML(http.StatusOK, "posbankum_index.html", gin.H{
 		"Title":      "Data Posbankum",
 		"Posbankums": posbankums,
@@ -64,21 +70,110 @@ func PosbankumIndex(c *gin.Context) {
 		"Page":       page,
 		"Offset":     offset,
 		"TotalPages": totalPages,
+		"TTD":        statusTTDMap("posbankum", ids),
+		"FormZIP":    formZIP(c, "/admin/unduh-zip", "posbankum"),
 	})
 }
 
 // ================== CREATE FORM ==================
 func PosbankumCreate(c *gin.Context) {
 	c.HTML(http.StatusOK, "posbankum_create.html", gin.H{
-		"Title": "Tambah Posbankum",
+		"Title":  "Tambah Posbankum",
+		"Profil": models.ProfilPosbankum{},
 	})
 }
 
+// ================== PROFIL ==================
+
+// profilPosbankumDariForm membaca dan memvalidasi profil dari form create/edit.
+// Foto tidak diisi di sini (lihat siapkanFotoForm). Pesan error dikembalikan per isian yang salah.
+func profilPosbankumDariForm(c *gin.Context) (models.ProfilPosbankum, []string) {
+	var errs []string
+	p := models.ProfilPosbankum{
+		Alamat:          strings.TrimSpace(utils.SanitizeInput(c.PostForm("alamat"))),
+		PenanggungJawab: strings.TrimSpace(utils.SanitizeInput(c.PostForm("penanggung_jawab"))),
+		JamBuka:         strings.TrimSpace(c.PostForm("jam_buka")),
+		JamTutup:        strings.TrimSpace(c.PostForm("jam_tutup")),
+		TanggalBerdiri:  tanggalForm(c, "tanggal_berdiri"),
+	}
+	if len([]rune(p.Alamat)) > 500 {
+		errs = append(errs, "Alamat maksimal 500 karakter")
+	}
+	if len([]rune(p.PenanggungJawab)) > 191 {
+		errs = append(errs, "Nama penanggung jawab terlalu panjang")
+	}
+
+	var ok bool
+	if p.Telepon, ok = utils.NormalisasiTelepon(c.PostForm("telepon")); !ok {
+		errs = append(errs, "Nomor telepon tidak valid (contoh: 0741-123456 atau 081234567890)")
+	}
+	if p.WhatsApp, ok = utils.NormalisasiTelepon(c.PostForm("whatsapp")); !ok || (p.WhatsApp != "" && !strings.HasPrefix(p.WhatsApp, "08")) {
+		errs = append(errs, "Nomor WhatsApp harus nomor HP (contoh: 081234567890)")
+	}
+
+	var hari []string
+	for _, h := range models.HariLayanan {
+		for _, dipilih := range c.PostFormArray("hari_layanan") {
+			if dipilih == h {
+				hari = append(hari, h)
+				break
+			}
+		}
+	}
+	p.HariLayanan = strings.Join(hari, ",")
+
+	if p.JamBuka != "" || p.JamTutup != "" {
+		buka, errBuka := time.Parse("15:04", p.JamBuka)
+		tutup, errTutup := time.Parse("15:04", p.JamTutup)
+		switch {
+		case errBuka != nil || errTutup != nil:
+			errs = append(errs, "Jam buka dan jam tutup wajib diisi lengkap (format JJ:MM)")
+		case !tutup.After(buka):
+			errs = append(errs, "Jam tutup harus setelah jam buka")
+		}
+	}
+
+	lat, lon := strings.TrimSpace(c.PostForm("latitude")), strings.TrimSpace(c.PostForm("longitude"))
+	if lat != "" || lon != "" {
+		la, errLat := strconv.ParseFloat(strings.ReplaceAll(lat, ",", "."), 64)
+		lo, errLon := strconv.ParseFloat(strings.ReplaceAll(lon, ",", "."), 64)
+		switch {
+		case errLat != nil || errLon != nil:
+			errs = append(errs, "Latitude dan longitude wajib diisi lengkap dalam angka desimal")
+		case la < -11 || la > 6 || lo < 95 || lo > 141:
+			errs = append(errs, "Koordinat berada di luar wilayah Indonesia, periksa urutan latitude/longitude")
+		default:
+			p.Latitude, p.Longitude = &la, &lo
+		}
+	}
+
+	if p.TanggalBerdiri != nil && p.TanggalBerdiri.After(time.Now()) {
+		errs = append(errs, "Tanggal berdiri tidak boleh di masa depan")
+	}
+	return p, errs
+}
+
+// PosbankumFoto menampilkan foto Posbankum; tanpa login hanya untuk data yang sudah terverifikasi
+func PosbankumFoto(c *gin.Context) {
+	var posbankum models.Posbankum
+	if err := config.DB.First(&posbankum, c.Param("id")).Error; err != nil || posbankum.Foto == "" {
+		c.String(http.StatusNotFound, "Foto tidak ditemukan")
+		return
+	}
+	if username, _ := penggunaLogin(c); username == "" && posbankum.StatusVerifikasi != models.StatusTerverifikasi {
+		c.String(http.StatusNotFound, "Foto tidak ditemukan")
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.File(posbankum.Foto)
+}
+
 // ================== STORE ==================
 func PosbankumStore(c *gin.Context) {
 	kelurahanID, _ := strconv.Atoi(c.PostForm("kelurahan_id"))
 
 	catatan := utils.SanitizeInput(c.PostForm("catatan"))
+	profil, errProfil := profilPosbankumDariForm(c)
 	// cek duplikasi
 	var existing models.Posbankum
 	if err := config.DB.Where("kelurahan_id = ?", kelurahanID).First(&existing).Error; err == nil {
@@ -86,56 +181,81 @@ func PosbankumStore(c *gin.Context) {
 			"Title":          "Tambah Posbankum",
 			"ErrorKelurahan": "❌ Posbankum untuk kelurahan ini sudah ada",
 			"Catatan":        catatan,
+			"SK":             dataSKDariForm(c),
+			"Profil":         profil,
+		})
+		return
+	}
+	if len(errProfil) > 0 {
+		c.HTML(http.StatusOK, "posbankum_create.html", gin.H{
+			"Title":       "Tambah Posbankum",
+			"ErrorProfil": errProfil,
+			"Catatan":     catatan,
+			"SK":          dataSKDariForm(c),
+			"Profil":      profil,
 		})
 		return
 	}
 
-	file, err := c.FormFile("dokumen")
-	if err != nil {
+	// dokumen dari upload biasa atau upload resumable yang sudah selesai
+	st, ada, msg := siapkanDokumenForm(c, "posbankum", 0)
+	if !ada {
+		msg = "❌ Dokumen wajib diupload"
+	}
+	if msg != "" {
 		c.HTML(http.StatusOK, "posbankum_create.html", gin.H{
 			"Title":     "Tambah Posbankum",
-			"ErrorFile": "❌ Dokumen wajib diupload",
+			"ErrorFile": msg,
 			"Catatan":   catatan,
+			"SK":        dataSKDariForm(c),
+			"Profil":    profil,
 		})
 		return
 	}
-
-	// validasi file
-	if !utils.ValidatePDFUpload(c, file) {
+	stFoto, msg := siapkanFotoForm(c, "posbankum", 0)
+	if msg != "" {
+		st.buang()
 		c.HTML(http.StatusOK, "posbankum_create.html", gin.H{
 			"Title":     "Tambah Posbankum",
-			"ErrorFile": "❌ File tidak valid. Pastikan file adalah PDF dan ukurannya di bawah 10MB.",
+			"ErrorFoto": msg,
 			"Catatan":   catatan,
+			"SK":        dataSKDariForm(c),
+			"Profil":    profil,
 		})
 		return
 	}
 
-	uploadPath := "uploads/posbankum"
-	os.MkdirAll(uploadPath, os.ModePerm)
-
-	// generate nama file unik
-	ext := filepath.Ext(file.Filename)
-	newName := uuid.New().String() + ext
-	fullPath := filepath.Join(uploadPath, newName)
+	posbankum := models.Posbankum{
+		KelurahanID:     uint(kelurahanID),
+		Catatan:         catatan,
+		DokumenPublik:   c.PostForm("dokumen_publik") == "1",
+		DataSK:          dataSKDariForm(c),
+		Verifikasi:      models.Verifikasi{StatusVerifikasi: statusSetelahSimpan(c, "")},
+		ProfilPosbankum: profil,
+	}
 
-	if err := c.SaveUploadedFile(file, fullPath); err != nil {
+	if err := simpanDenganBerkas([]*berkasStaging{st, stFoto}, func(tx *gorm.DB, paths []string) error {
+		posbankum.Dokumen = paths[0]
+		posbankum.Foto = paths[1]
+		if err := tx.Create(&posbankum).Error; err != nil {
+			return err
+		}
+		return catatRiwayat(tx, c, "posbankum", posbankum.ID, "", posbankum.StatusVerifikasi, "")
+	}); err != nil {
+		log.Printf("Gagal menyimpan posbankum: %v", err)
 		c.HTML(http.StatusOK, "posbankum_create.html", gin.H{
 			"Title":     "Tambah Posbankum",
-			"ErrorFile": "❌ Gagal upload file",
+			"ErrorFile": "❌ Gagal menyimpan data, dokumen belum tersimpan. Silakan coba lagi.",
 			"Catatan":   catatan,
+			"SK":        dataSKDariForm(c),
+			"Profil":    profil,
 		})
 		return
 	}
 
-	publicPath := strings.ReplaceAll(fullPath, "\\", "/")
-
-	posbankum := models.Posbankum{
-		KelurahanID: uint(kelurahanID),
-		Dokumen:     publicPath,
-		Catatan:     catatan,
-	}
-
-	config.DB.Create(&posbankum)
+	// ekstrak teks PDF untuk pencarian isi dokumen dan verifikasi tanda tangan elektroniknya
+	go indeksDokumen("posbankum", posbankum.ID, posbankum.Dokumen)
+	go periksaTTD("posbankum", posbankum.ID, posbankum.Dokumen)
 	c.Redirect(http.StatusFound, "/admin/posbankum") // Redirect tidak perlu diubah
 }
 
@@ -148,10 +268,10 @@ func PosbankumView(c *gin.Context) {
 		return
 	}
 
-	// Set header agar browser tahu ini adalah file untuk ditampilkan/diunduh
-	c.Header("Content-Disposition", "inline; filename="+filepath.Base(posbankum.Dokumen))
-	c.Header("Content-Type", "application/pdf")
-	c.File(posbankum.Dokumen)
+	if tampilkanDenganTTD(c, "posbankum", posbankum.ID, posbankum.Dokumen) {
+		return
+	}
+	kirimDokumen(c, "posbankum", posbankum.ID, posbankum.Dokumen)
 }
 
 // ================== EDIT FORM ==================
@@ -172,8 +292,22 @@ func PosbankumEdit(c *gin.Context) { // Ubah nama fungsi dari PosbankumEdit menj
 	}
 
 	c.HTML(http.StatusOK, "posbankum_edit.html", gin.H{
-		"Title":     "Edit Posbankum",
-		"Posbankum": posbankum,
+		"Title":             "Edit Posbankum",
+		"Posbankum":         posbankum,
+		"Profil":            posbankum.ProfilPosbankum,
+		"EntitasTipe":       "posbankum",
+		"EntitasID":         posbankum.ID,
+		"Lampirans":         daftarLampiran("posbankum", posbankum.ID),
+		"KategoriLampirans": semuaKategoriLampiran(),
+		"ErrorLampiran":     c.Query("error_lampiran"),
+		"OCR":               statusOCR("posbankum", posbankum.ID),
+		"OCRLampiran":       statusOCRLampiran("posbankum", posbankum.ID),
+		"TTD":               statusTTD("posbankum", posbankum.ID),
+		"Verifikasi":        infoVerifikasi("posbankum", posbankum.ID, posbankum.Verifikasi),
+		"Komentars":         daftarKomentar("posbankum", posbankum.ID),
+		"ErrorKomentar":     c.Query("error_komentar"),
+		"SaranSK":           saranSK("posbankum", posbankum.ID),
+		"HariPeringatanSK":  hariPeringatanSK(),
 	})
 }
 
@@ -197,6 +331,7 @@ func PosbankumUpdate(c *gin.Context) {
 		c.HTML(http.StatusOK, "posbankum_edit.html", gin.H{
 			"Title":          "Edit Posbankum",
 			"Posbankum":      posbankum,
+			"Profil":         posbankum.ProfilPosbankum,
 			"ErrorKelurahan": "❌ Posbankum untuk kelurahan ini sudah ada",
 		})
 		return
@@ -205,45 +340,87 @@ func PosbankumUpdate(c *gin.Context) {
 	posbankum.KelurahanID = uint(kelurahanID)
 
 	posbankum.Catatan = utils.SanitizeInput(c.PostForm("catatan"))
+	posbankum.DokumenPublik = c.PostForm("dokumen_publik") == "1"
+	posbankum.DataSK = dataSKDariForm(c)
+
+	// profil dari form; foto lama tetap dipakai kecuali diganti atau dihapus
+	fotoLama := posbankum.Foto
+	profil, errProfil := profilPosbankumDariForm(c)
+	profil.Foto = fotoLama
+	if c.PostForm("hapus_foto") == "1" {
+		profil.Foto = ""
+	}
+	posbankum.ProfilPosbankum = profil
+	if len(errProfil) > 0 {
+		c.HTML(http.StatusOK, "posbankum_edit.html", gin.H{
+			"Title":       "Edit Posbankum",
+			"Posbankum":   posbankum,
+			"Profil":      profil,
+			"ErrorProfil": errProfil,
+		})
+		return
+	}
 
 	// cek file baru
-	file, err := c.FormFile("dokumen")
-	if err == nil {
-		if !utils.ValidatePDFUpload(c, file) {
-			c.HTML(http.StatusOK, "posbankum_edit.html", gin.H{
-				"Title":     "Edit Posbankum",
-				"Posbankum": posbankum,
-				"ErrorFile": "❌ File tidak valid. Pastikan file adalah PDF dan ukurannya di bawah 10MB.",
-			})
-			return
+	// dokumen baru dari upload biasa atau upload resumable yang sudah selesai
+	st, adaFile, msg := siapkanDokumenForm(c, "posbankum", posbankum.ID)
+	if msg != "" {
+		c.HTML(http.StatusOK, "posbankum_edit.html", gin.H{
+			"Title":     "Edit Posbankum",
+			"Posbankum": posbankum,
+			"Profil":    profil,
+			"ErrorFile": msg,
+		})
+		return
+	}
+	stFoto, msg := siapkanFotoForm(c, "posbankum", posbankum.ID)
+	if msg != "" {
+		st.buang()
+		c.HTML(http.StatusOK, "posbankum_edit.html", gin.H{
+			"Title":     "Edit Posbankum",
+			"Posbankum": posbankum,
+			"Profil":    profil,
+			"ErrorFoto": msg,
+		})
+		return
+	}
+	dokumenLama := posbankum.Dokumen
+	statusLama := posbankum.StatusVerifikasi
+	posbankum.StatusVerifikasi = statusSetelahSimpan(c, statusLama)
+	if err := simpanDenganBerkas([]*berkasStaging{st, stFoto}, func(tx *gorm.DB, paths []string) error {
+		if adaFile {
+			posbankum.Dokumen = paths[0]
 		}
-
-		uploadPath := "uploads/posbankum"
-		os.MkdirAll(uploadPath, os.ModePerm)
-
-		// generate nama file unik
-		ext := filepath.Ext(file.Filename)
-		newName := uuid.New().String() + ext
-		newPath := filepath.Join(uploadPath, newName)
-
-		if err := c.SaveUploadedFile(file, newPath); err != nil {
-			c.HTML(http.StatusOK, "posbankum_edit.html", gin.H{
-				"Title":     "Edit Posbankum",
-				"Posbankum": posbankum,
-				"ErrorFile": "❌ Gagal upload file",
-			})
-			return
+		if stFoto != nil {
+			posbankum.Foto = paths[1]
 		}
-
-		// hapus file lama kalau ada
-		if posbankum.Dokumen != "" {
-			_ = os.Remove(posbankum.Dokumen)
+		if err := tx.Save(&posbankum).Error; err != nil {
+			return err
 		}
-
-		posbankum.Dokumen = strings.ReplaceAll(newPath, "\\", "/")
+		return catatRiwayat(tx, c, "posbankum", posbankum.ID, statusLama, posbankum.StatusVerifikasi, "")
+	}); err != nil {
+		log.Printf("Gagal menyimpan posbankum %d: %v", posbankum.ID, err)
+		posbankum.Dokumen = dokumenLama
+		posbankum.Foto = fotoLama
+		posbankum.StatusVerifikasi = statusLama
+		c.HTML(http.StatusOK, "posbankum_edit.html", gin.H{
+			"Title":     "Edit Posbankum",
+			"Posbankum": posbankum,
+			"Profil":    posbankum.ProfilPosbankum,
+			"ErrorFile": "❌ Gagal menyimpan data, dokumen belum tersimpan. Silakan coba lagi.",
+		})
+		return
 	}
 
-	config.DB.Save(&posbankum)
+	if posbankum.Foto != fotoLama {
+		lepasFile(fotoLama)
+	}
+	// dokumen diganti: file lama dilepas (dihapus kalau tidak dipakai record lain), indeks ulang teksnya
+	if adaFile {
+		lepasFile(dokumenLama)
+		go indeksDokumen("posbankum", posbankum.ID, posbankum.Dokumen)
+		go periksaTTD("posbankum", posbankum.ID, posbankum.Dokumen)
+	}
 	c.Redirect(http.StatusFound, "/admin/posbankum") // Redirect tidak perlu diubah
 }
 
@@ -257,13 +434,47 @@ func PosbankumDelete(c *gin.Context) {
 		return
 	}
 
-	// hapus file dari storage kalau ada
-	if posbankum.Dokumen != "" {
-		_ = os.Remove(posbankum.Dokumen)
+	// semua penghapusan di DB satu transaksi, file baru dilepas setelah commit
+	var fileLampiran []string
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// lampiran beserta indeksnya
+		paths, err := hapusLampiranEntitas(tx, "posbankum", posbankum.ID)
+		if err != nil {
+			return err
+		}
+		fileLampiran = paths
+		if err := hapusIndeksDokumen(tx, "posbankum", posbankum.ID); err != nil {
+			return err
+		}
+		// diskusi, riwayat verifikasi dan notifikasi record ini
+		paths, err = hapusJejakEntitas(tx, "posbankum", posbankum.ID)
+		if err != nil {
+			return err
+		}
+		fileLampiran = append(fileLampiran, paths...)
+
+		// register konsultasi ikut terhapus
+		if err := tx.Where("konsultasi_id IN (?)", tx.Model(&models.Konsultasi{}).Select("id").
+			Where("posbankum_id = ?", posbankum.ID)).Delete(&models.TindakLanjutKonsultasi{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("posbankum_id = ?", posbankum.ID).Delete(&models.Konsultasi{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&posbankum).Error
+	}); err != nil {
+		log.Printf("Gagal menghapus posbankum %d: %v", posbankum.ID, err)
+		c.String(http.StatusInternalServerError, "❌ Gagal menghapus data, tidak ada yang berubah. Silakan coba lagi.")
+		return
 	}
 
-	// hapus record dari DB
-	config.DB.Delete(&posbankum)
+	// file hanya dihapus kalau tidak dipakai record lain
+	for _, p := range fileLampiran {
+		lepasFile(p)
+	}
+	lepasFile(posbankum.Dokumen)
+	lepasFile(posbankum.Foto)
 
 	c.Redirect(http.StatusFound, "/admin/posbankum") // Redirect tidak perlu diubah
 }
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
 
 	"go-admin/config"
@@ -42,9 +43,10 @@ type TimelineEvent struct {
 
 // Struktur untuk menampung nama dan dokumen Paralegal
 type PublicParalegalData struct {
-	ID      uint // ID diperlukan untuk link dokumen di template
-	Nama    string
-	Dokumen string
+	ID            uint // ID diperlukan untuk link dokumen di template
+	Nama          string
+	Dokumen       string
+	DokumenPublik bool
 }
 
 // Struktur untuk data Paralegal per kelurahan
@@ -113,6 +115,7 @@ type PublicDashboardData struct {
 	TotalKadarkumProvinsi   int
 	TotalPjaProvinsi        int
 	TotalParalegalProvinsi  int
+	DemografiParalegal      DemografiParalegal // sebaran jenis kelamin & umur untuk tab Paralegal
 	TotalKelurahanProvinsi  int
 	PersenPosbankumProvinsi float64
 	PersenKadarkumProvinsi  float64
@@ -123,12 +126,16 @@ type PublicDashboardData struct {
 // ==================== CONTROLLER ====================
 
 func LandingPage(c *gin.Context) {
+	skBerlaku := filterSKBerlaku()         // SK kedaluwarsa bisa dikecualikan dari hitungan (lihat Pengaturan)
+	terverifikasi := filterTerverifikasi() // hanya data yang disetujui verifikator Kanwil (lihat Pengaturan)
 	var totalPosbankum, totalKadarkum, totalPja, totalParalegal int64
 
-	config.DB.Model(&models.Posbankum{}).Count(&totalPosbankum)
-	config.DB.Model(&models.Kadarkum{}).Count(&totalKadarkum)
-	config.DB.Model(&models.Pja{}).Count(&totalPja)
-	config.DB.Model(&models.Paralegal{}).Count(&totalParalegal)
+	config.DB.Model(&models.Posbankum{}).Scopes(skBerlaku("posbankums"), terverifikasi("posbankums")).Count(&totalPosbankum)
+	config.DB.Model(&models.Kadarkum{}).Scopes(skBerlaku("kadarkums"), terverifikasi("kadarkums")).Count(&totalKadarkum)
+	config.DB.Model(&models.Pja{}).Scopes(skBerlaku("pjas"), terverifikasi("pjas")).Count(&totalPja)
+	// paralegal baru dihitung kalau Posbankum-nya juga sudah terverifikasi
+	config.DB.Model(&models.Paralegal{}).Joins("JOIN posbankums ON posbankums.id = paralegals.posbankum_id").
+		Scopes(terverifikasi("paralegals"), terverifikasi("posbankums")).Count(&totalParalegal)
 
 	// Data dummy untuk testimonials
 	testimonials := []Testimonial{
@@ -171,6 +178,8 @@ func LandingPage(c *gin.Context) {
 }
 
 func PublicDashboard(c *gin.Context) {
+	skBerlaku := filterSKBerlaku()         // SK kedaluwarsa bisa dikecualikan dari hitungan (lihat Pengaturan)
+	terverifikasi := filterTerverifikasi() // hanya data yang disetujui verifikator Kanwil (lihat Pengaturan)
 	var provinsi models.Provinsi
 
 	if err := config.DB.Preload("Kabupatens.Kecamatans.Kelurahans").First(&provinsi).Error; err != nil {
@@ -204,14 +213,14 @@ func PublicDashboard(c *gin.Context) {
 			// ================== POSBANKUM ==================
 			var totalPos, tercapaiPos int64
 			config.DB.Model(&models.Kelurahan{}).Where("kecamatan_id = ?", kec.ID).Count(&totalPos)
-			config.DB.Model(&models.Posbankum{}).
+			config.DB.Model(&models.Posbankum{}).Scopes(skBerlaku("posbankums"), terverifikasi("posbankums")).
 				Joins("JOIN kelurahans ON kelurahans.id = posbankums.kelurahan_id").
 				Where("kelurahans.kecamatan_id = ?", kec.ID).Count(&tercapaiPos)
 
 			var kelurahanDocsPos []PublicKelurahanDokumen
 			for _, kel := range kec.Kelurahans {
 				var posbankums []models.Posbankum
-				config.DB.Where("kelurahan_id = ?", kel.ID).Find(&posbankums)
+				config.DB.Scopes(skBerlaku("posbankums"), terverifikasi("posbankums")).Where("kelurahan_id = ?", kel.ID).Find(&posbankums)
 
 				tercapai := 0
 				if len(posbankums) > 0 {
@@ -240,14 +249,14 @@ func PublicDashboard(c *gin.Context) {
 			// ================== KADARKUM ==================
 			var totalK, tercapaiK int64
 			config.DB.Model(&models.Kelurahan{}).Where("kecamatan_id = ?", kec.ID).Count(&totalK)
-			config.DB.Model(&models.Kadarkum{}).
+			config.DB.Model(&models.Kadarkum{}).Scopes(skBerlaku("kadarkums"), terverifikasi("kadarkums")).
 				Joins("JOIN kelurahans ON kelurahans.id = kadarkums.kelurahan_id").
 				Where("kelurahans.kecamatan_id = ?", kec.ID).Count(&tercapaiK)
 
 			var kelurahanDocsKadarkum []PublicKelurahanDokumen
 			for _, kel := range kec.Kelurahans {
 				var kadarkums []models.Kadarkum
-				config.DB.Where("kelurahan_id = ?", kel.ID).Find(&kadarkums)
+				config.DB.Scopes(skBerlaku("kadarkums"), terverifikasi("kadarkums")).Where("kelurahan_id = ?", kel.ID).Find(&kadarkums)
 				tercapai := 0
 				if len(kadarkums) > 0 {
 					tercapai = 1
@@ -274,14 +283,14 @@ func PublicDashboard(c *gin.Context) {
 			// ================== PJA ==================
 			var totalP, tercapaiP int64
 			config.DB.Model(&models.Kelurahan{}).Where("kecamatan_id = ?", kec.ID).Count(&totalP)
-			config.DB.Model(&models.Pja{}).
+			config.DB.Model(&models.Pja{}).Scopes(skBerlaku("pjas"), terverifikasi("pjas")).
 				Joins("JOIN kelurahans ON kelurahans.id = pjas.kelurahan_id").
 				Where("kelurahans.kecamatan_id = ?", kec.ID).Count(&tercapaiP)
 
 			var kelurahanDocsPja []PublicKelurahanDokumen
 			for _, kel := range kec.Kelurahans {
 				var pjas []models.Pja
-				config.DB.Where("kelurahan_id = ?", kel.ID).Find(&pjas)
+				config.DB.Scopes(skBerlaku("pjas"), terverifikasi("pjas")).Where("kelurahan_id = ?", kel.ID).Find(&pjas)
 				tercapai := 0
 				if len(pjas) > 0 {
 					tercapai = 1
@@ -314,14 +323,16 @@ func PublicDashboard(c *gin.Context) {
 				config.DB.Model(&models.Paralegal{}).
 					Joins("JOIN posbankums ON posbankums.id = paralegals.posbankum_id").
 					Where("posbankums.kelurahan_id = ?", kel.ID).
+					Scopes(terverifikasi("paralegals"), terverifikasi("posbankums")).
 					Find(&paralegalsFromDB)
 
 				var paralegalDataForKelurahan []PublicParalegalData
 				for _, p := range paralegalsFromDB {
 					paralegalDataForKelurahan = append(paralegalDataForKelurahan, PublicParalegalData{
-						ID:      p.ID, // Kirim ID ke template
-						Nama:    p.Nama,
-						Dokumen: p.Dokumen,
+						ID:            p.ID, // Kirim ID ke template
+						Nama:          p.Nama,
+						Dokumen:       p.Dokumen,
+						DokumenPublik: p.DokumenPublik,
 					})
 				}
 
@@ -394,6 +405,7 @@ func PublicDashboard(c *gin.Context) {
 		TotalKadarkumProvinsi:   tercapaiKadProv,
 		TotalPjaProvinsi:        tercapaiPJAProv,
 		TotalParalegalProvinsi:  totalParalegalProv,
+		DemografiParalegal:      demografiParalegal(terverifikasi("paralegals")),
 		TotalKelurahanProvinsi:  totalKelurahanProv,
 		PersenPosbankumProvinsi: hitungPersen(tercapaiPosProv, totalKelurahanProv),
 		PersenKadarkumProvinsi:  hitungPersen(tercapaiKadProv, totalKelurahanProv),
@@ -423,6 +435,8 @@ type MapDetailData struct {
 
 // MapDataAPI menyediakan data untuk peta interaktif
 func MapDataAPI(c *gin.Context) {
+	skBerlaku := filterSKBerlaku()         // SK kedaluwarsa bisa dikecualikan dari hitungan (lihat Pengaturan)
+	terverifikasi := filterTerverifikasi() // hanya data yang disetujui verifikator Kanwil (lihat Pengaturan)
 	var kabupatens []models.Kabupaten
 	if err := config.DB.Preload("Kecamatans.Kelurahans").Find(&kabupatens).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kabupaten"})
@@ -455,7 +469,7 @@ func MapDataAPI(c *gin.Context) {
 			var tercapaiKec int64
 			totalKelurahanKec := len(kec.Kelurahans)
 
-			config.DB.Model(&models.Posbankum{}).
+			config.DB.Model(&models.Posbankum{}).Scopes(skBerlaku("posbankums"), terverifikasi("posbankums")).
 				Joins("JOIN kelurahans ON kelurahans.id = posbankums.kelurahan_id").
 				Where("kelurahans.kecamatan_id = ?", kec.ID).Count(&tercapaiKec)
 
@@ -480,3 +494,53 @@ func MapDataAPI(c *gin.Context) {
 
 	c.JSON(http.StatusOK, mapData)
 }
+
+// LokasiPosbankum adalah satu titik Posbankum di peta publik
+type LokasiPosbankum struct {
+	ID              uint    `json:"id"`
+	Nama            string  `json:"nama"`
+	Wilayah         string  `json:"wilayah"`
+	Alamat          string  `json:"alamat"`
+	Telepon         string  `json:"telepon"`
+	WhatsApp        string  `json:"whatsapp"`
+	LinkWhatsApp    string  `json:"link_whatsapp"`
+	PenanggungJawab string  `json:"penanggung_jawab"`
+	Jadwal          string  `json:"jadwal"`
+	Foto            string  `json:"foto"`
+	Lat             float64 `json:"lat"`
+	Lon             float64 `json:"lon"`
+}
+
+// MapPosbankumAPI menyediakan lokasi Posbankum yang sudah punya koordinat untuk peta interaktif
+func MapPosbankumAPI(c *gin.Context) {
+	skBerlaku := filterSKBerlaku()
+	terverifikasi := filterTerverifikasi()
+	var posbankums []models.Posbankum
+	config.DB.Scopes(skBerlaku("posbankums"), terverifikasi("posbankums")).
+		Preload("Kelurahan.Kecamatan.Kabupaten").
+		Where("latitude IS NOT NULL AND longitude IS NOT NULL").
+		Find(&posbankums)
+
+	lokasi := make([]LokasiPosbankum, 0, len(posbankums))
+	for _, p := range posbankums {
+		l := LokasiPosbankum{
+			ID:              p.ID,
+			Nama:            "Posbankum " + p.Kelurahan.Name,
+			Wilayah:         namaWilayah(p.Kelurahan),
+			Alamat:          p.Alamat,
+			Telepon:         p.Telepon,
+			WhatsApp:        p.WhatsApp,
+			LinkWhatsApp:    p.LinkWhatsApp(),
+			PenanggungJawab: p.PenanggungJawab,
+			Jadwal:          p.JadwalLayanan(),
+			Lat:             *p.Latitude,
+			Lon:             *p.Longitude,
+		}
+		// foto hanya bisa dibuka publik kalau datanya terverifikasi (lihat PosbankumFoto)
+		if p.Foto != "" && p.StatusVerifikasi == models.StatusTerverifikasi {
+			l.Foto = fmt.Sprintf("/foto/posbankum/%d", p.ID)
+		}
+		lokasi = append(lokasi, l)
+	}
+	c.JSON(http.StatusOK, lokasi)
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ================== HELPER ==================
+
+// hariPeringatanSK adalah berapa hari sebelum habis SK dianggap "segera berakhir"
+func hariPeringatanSK() int {
+	hari, err := strconv.Atoi(nilaiPengaturan(PengaturanHariPeringatanSK, "60"))
+	if err != nil || hari < 0 {
+		return 60
+	}
+	return hari
+}
+
+// filterSKBerlaku membaca pengaturan sekali lalu mengembalikan pembuat scope per tabel.
+// Kalau admin memilih mengecualikan SK kedaluwarsa, record dengan berlaku_sampai lewat tidak dihitung tercapai.
+//
+//	skBerlaku := filterSKBerlaku()
+//	config.DB.Model(&models.Posbankum{}).Scopes(skBerlaku("posbankums")).Count(&n)
+func filterSKBerlaku() func(tabel string) func(*gorm.DB) *gorm.DB {
+	kecualikan := pengaturanAktif(PengaturanKecualikanSK, false)
+	return func(tabel string) func(*gorm.DB) *gorm.DB {
+		return func(db *gorm.DB) *gorm.DB {
+			if !kecualikan {
+				return db
+			}
+			return db.Where("(" + tabel + ".berlaku_sampai IS NULL OR " + tabel + ".berlaku_sampai >= CURDATE())")
+		}
+	}
+}
+
+// tanggalForm membaca input type=date, nil kalau kosong/tidak valid
+func tanggalForm(c *gin.Context, nama string) *time.Time {
+	t, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(c.PostForm(nama)), time.Local)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// dataSKDariForm membaca field SK dari form create/edit
+func dataSKDariForm(c *gin.Context) models.DataSK {
+	return models.DataSK{
+		NomorSK:       strings.TrimSpace(utils.SanitizeInput(c.PostForm("nomor_sk"))),
+		TanggalSK:     tanggalForm(c, "tanggal_sk"),
+		PejabatSK:     strings.TrimSpace(utils.SanitizeInput(c.PostForm("pejabat_sk"))),
+		BerlakuSampai: tanggalForm(c, "berlaku_sampai"),
+	}
+}
+
+// saranSK menebak metadata SK dari teks dokumen yang sudah terindeks (PDF atau OCR)
+func saranSK(tipe string, id uint) utils.SaranSK {
+	var halaman []string
+	config.DB.Model(&models.DokumenTeks{}).
+		Where("tipe = ? AND entitas_id = ?", tipe, id).
+		Order("halaman").
+		Pluck("isi", &halaman)
+	if len(halaman) == 0 {
+		return utils.SaranSK{}
+	}
+	return utils.TebakSK(halaman)
+}
+
+// ringkasanSK menghitung jumlah SK kedaluwarsa dan segera berakhir di ketiga tabel
+func ringkasanSK() (kedaluwarsa, segera int64) {
+	batas := time.Now().AddDate(0, 0, hariPeringatanSK())
+	for _, m := range []any{&models.Posbankum{}, &models.Kadarkum{}, &models.Pja{}} {
+		var k, s int64
+		config.DB.Model(m).Where("berlaku_sampai < CURDATE()").Count(&k)
+		config.DB.Model(m).Where("berlaku_sampai >= CURDATE() AND berlaku_sampai <= ?", batas.Format("2006-01-02")).Count(&s)
+		kedaluwarsa += k
+		segera += s
+	}
+	return kedaluwarsa, segera
+}
+
+// ================== HALAMAN MASA BERLAKU SK ==================
+
+// BarisSK adalah satu record dengan masa berlaku SK untuk halaman pemantauan
+type BarisSK struct {
+	Tipe    string
+	ID      uint
+	Wilayah string
+	models.DataSK
+	SisaHari int
+	Status   string
+}
+
+func SKIndex(c *gin.Context) {
+	status := c.DefaultQuery("status", "perhatian")
+	hari := hariPeringatanSK()
+	batas := time.Now().AddDate(0, 0, hari).Format("2006-01-02")
+
+	filter := func(db *gorm.DB) *gorm.DB {
+		switch status {
+		case models.SKKedaluwarsa:
+			return db.Where("berlaku_sampai < CURDATE()")
+		case models.SKSegeraHabis:
+			return db.Where("berlaku_sampai >= CURDATE() AND berlaku_sampai <= ?", batas)
+		case "tanpa-sk":
+			return db.Where("nomor_sk IS NULL OR nomor_sk = ''")
+		default: // perhatian = kedaluwarsa + segera berakhir
+			return db.Where("berlaku_sampai <= ?", batas)
+		}
+	}
+
+	var baris []BarisSK
+	tambah := func(tipe string, id uint, kel models.Kelurahan, sk models.DataSK) {
+		b := BarisSK{
+			Tipe:    tipe,
+			ID:      id,
+			Wilayah: kel.Name + ", " + kel.Kecamatan.Name + ", " + kel.Kecamatan.Kabupaten.Name,
+			DataSK:  sk,
+			Status:  sk.StatusSK(hari),
+		}
+		if sk.BerlakuSampai != nil {
+			b.SisaHari = int(time.Until(*sk.BerlakuSampai).Hours() / 24)
+		}
+		baris = append(baris, b)
+	}
+
+	var posbankums []models.Posbankum
+	config.DB.Preload("Kelurahan.Kecamatan.Kabupaten").Scopes(filter).Order("berlaku_sampai").Find(&posbankums)
+	for _, d := range posbankums {
+		tambah("posbankum", d.ID, d.Kelurahan, d.DataSK)
+	}
+
+	var kadarkums []models.Kadarkum
+	config.DB.Preload("Kelurahan.Kecamatan.Kabupaten").Scopes(filter).Order("berlaku_sampai").Find(&kadarkums)
+	for _, d := range kadarkums {
+		tambah("kadarkum", d.ID, d.Kelurahan, d.DataSK)
+	}
+
+	var pjas []models.Pja
+	config.DB.Preload("Kelurahan.Kecamatan.Kabupaten").Scopes(filter).Order("berlaku_sampai").Find(&pjas)
+	for _, d := range pjas {
+		tambah("pja", d.ID, d.Kelurahan, d.DataSK)
+	}
+
+	kedaluwarsa, segera := ringkasanSK()
+
+	c.HTML(http.StatusOK, "sk.html", gin.H{
+		"Title":          "Masa Berlaku SK",
+		"Baris":          baris,
+		"Status":         status,
+		"HariPeringatan": hari,
+		"Kedaluwarsa":    kedaluwarsa,
+		"Segera":         segera,
+		"Dikecualikan":   pengaturanAktif(PengaturanKecualikanSK, false),
+		"user":           sessions.Default(c).Get("user"),
+	})
+}
@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errTanpaAkses dipakai kalau grup direktori user tidak dipetakan ke role apa pun
+var errTanpaAkses = errors.New("akun direktori Anda tidak punya akses ke aplikasi ini")
+
+// ================== HELPER ==================
+
+func tokenAcak() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// userDariDirektori membuat (just-in-time) atau memperbarui user hasil login SSO.
+// Role selalu diambil ulang dari grup direktori supaya perubahan grup langsung berlaku.
+func userDariDirektori(identitas *utils.IdentitasDirektori, sumber string) (models.User, error) {
+	var user models.User
+
+	role := utils.RoleDariGrup(identitas.Grup,
+		nilaiPengaturan(PengaturanGrupAdmin, ""),
+		nilaiPengaturan(PengaturanGrupVerifikator, ""),
+		nilaiPengaturan(PengaturanGrupOperator, ""),
+		nilaiPengaturan(PengaturanGrupUser, ""))
+	if role == "" {
+		return user, errTanpaAkses
+	}
+
+	err := config.DB.Where("sumber = ? AND external_id = ?", sumber, identitas.ExternalID).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// username sudah dipakai akun lain (misal akun lokal) -> jangan diambil alih
+		var count int64
+		config.DB.Model(&models.User{}).Where("username = ?", identitas.Username).Count(&count)
+		if count > 0 {
+			return user, errors.New("username " + identitas.Username + " sudah dipakai akun lain")
+		}
+
+		user = models.User{
+			Username:   identitas.Username,
+			Role:       role,
+			Sumber:     sumber,
+			ExternalID: identitas.ExternalID,
+			Email:      identitas.Email,
+		}
+		if err := config.DB.Create(&user).Error; err != nil {
+			return user, err
+		}
+		log.Printf("User %s dibuat otomatis dari %s", user.Username, sumber)
+		antreEmailAkunBaru(user)
+		return user, nil
+	}
+	if err != nil {
+		return user, err
+	}
+
+	user.Role = role
+	user.Email = identitas.Email
+	if err := config.DB.Save(&user).Error; err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+// ================== OIDC ==================
+
+// OIDCLogin mengarahkan user ke halaman login provider OIDC
+func OIDCLogin(c *gin.Context) {
+	if !utils.OIDCAktif() {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	cfg, _, err := utils.OIDCConfig(c.Request.Context())
+	if err != nil {
+		log.Printf("Gagal discovery OIDC: %v", err)
+		loginGagal(c, "❌ Provider SSO tidak bisa dihubungi")
+		return
+	}
+
+	// nonce diikat ke ID token supaya token curian/putar ulang tidak bisa dipakai di sesi lain
+	state, nonce := tokenAcak(), tokenAcak()
+	session := sessions.Default(c)
+	session.Set("oidc_state", state)
+	session.Set("oidc_nonce", nonce)
+	session.Save()
+
+	c.Redirect(http.StatusFound, cfg.AuthCodeURL(state, oidc.Nonce(nonce)))
+}
+
+// OIDCCallback menerima kode otorisasi dari provider, memverifikasi ID token, lalu login
+func OIDCCallback(c *gin.Context) {
+	session := sessions.Default(c)
+	state, _ := session.Get("oidc_state").(string)
+	nonce, _ := session.Get("oidc_nonce").(string)
+	session.Delete("oidc_state")
+	session.Delete("oidc_nonce")
+	session.Save()
+
+	if state == "" || nonce == "" || c.Query("state") != state {
+		loginGagal(c, "❌ Sesi login SSO tidak valid, silakan coba lagi")
+		return
+	}
+	if e := c.Query("error"); e != "" {
+		loginGagal(c, "❌ Login SSO dibatalkan: "+utils.SanitizeInput(e))
+		return
+	}
+
+	ctx := c.Request.Context()
+	cfg, verifier, err := utils.OIDCConfig(ctx)
+	if err != nil {
+		log.Printf("Gagal discovery OIDC: %v", err)
+		loginGagal(c, "❌ Provider SSO tidak bisa dihubungi")
+		return
+	}
+
+	token, err := cfg.Exchange(ctx, c.Query("code"))
+	if err != nil {
+		log.Printf("Gagal tukar kode OIDC: %v", err)
+		loginGagal(c, "❌ Login SSO gagal")
+		return
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		loginGagal(c, "❌ Provider SSO tidak mengirim ID token")
+		return
+	}
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		log.Printf("ID token OIDC tidak valid: %v", err)
+		loginGagal(c, "❌ Login SSO gagal")
+		return
+	}
+	if idToken.Nonce != nonce {
+		log.Printf("Nonce ID token OIDC tidak cocok untuk subject %s", idToken.Subject)
+		loginGagal(c, "❌ Login SSO gagal")
+		return
+	}
+
+	identitas, err := utils.IdentitasDariIDToken(idToken)
+	if err != nil {
+		log.Printf("Klaim OIDC tidak lengkap: %v", err)
+		loginGagal(c, "❌ Data akun SSO tidak lengkap")
+		return
+	}
+
+	user, err := userDariDirektori(identitas, "oidc")
+	if err != nil {
+		loginGagal(c, "❌ "+err.Error())
+		return
+	}
+
+	loginBerhasil(c, user)
+}
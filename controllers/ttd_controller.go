@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================== VERIFIKASI ==================
+
+// periksaTTD memverifikasi tanda tangan elektronik PDF lalu menyimpan hasilnya.
+// Hasil lama untuk record yang sama selalu diganti. Dipanggil lewat goroutine setelah upload.
+func periksaTTD(tipe string, id uint, path string) {
+	if path == "" || strings.ToLower(filepath.Ext(path)) != ".pdf" || !utils.FileAda(path) {
+		config.DB.Where("tipe = ? AND entitas_id = ?", tipe, id).Delete(&models.TandaTanganDokumen{})
+		return
+	}
+
+	hasil, err := utils.PeriksaTTDPDF(path)
+	if err != nil {
+		log.Printf("Gagal memeriksa tanda tangan %s/%d: %v", tipe, id, err)
+		return
+	}
+
+	var ttd models.TandaTanganDokumen
+	config.DB.Where("tipe = ? AND entitas_id = ?", tipe, id).First(&ttd)
+
+	ttd.Tipe = tipe
+	ttd.EntitasID = id
+	ttd.Path = path
+	ttd.Status = hasil.Status
+	ttd.Penandatangan = hasil.Penandatangan
+	ttd.Penerbit = hasil.Penerbit
+	ttd.WaktuTTD = hasil.WaktuTTD
+	ttd.JumlahTTD = hasil.JumlahTTD
+	ttd.Pesan = hasil.Pesan
+
+	if err := config.DB.Save(&ttd).Error; err != nil {
+		log.Printf("Gagal simpan hasil verifikasi tanda tangan %s/%d: %v", tipe, id, err)
+	}
+}
+
+// statusTTD mengambil hasil verifikasi satu dokumen, nil kalau belum diperiksa
+func statusTTD(tipe string, id uint) *models.TandaTanganDokumen {
+	var ttd models.TandaTanganDokumen
+	if err := config.DB.Where("tipe = ? AND entitas_id = ?", tipe, id).First(&ttd).Error; err != nil {
+		return nil
+	}
+	return &ttd
+}
+
+// statusTTDMap mengambil hasil verifikasi untuk satu halaman index, key = ID record
+func statusTTDMap(tipe string, ids []uint) map[uint]*models.TandaTanganDokumen {
+	hasil := make(map[uint]*models.TandaTanganDokumen, len(ids))
+	if len(ids) == 0 {
+		return hasil
+	}
+
+	var rows []models.TandaTanganDokumen
+	config.DB.Where("tipe = ? AND entitas_id IN ?", tipe, ids).Find(&rows)
+	for i := range rows {
+		hasil[rows[i].EntitasID] = &rows[i]
+	}
+	return hasil
+}
+
+// VerifikasiUlangSemuaTTD memeriksa ulang tanda tangan semua PDF, mis. setelah trust store diperbarui.
+// Dipakai oleh perintah `go run . verifikasi-ttd`.
+func VerifikasiUlangSemuaTTD() {
+	_, jumlahCA, err := utils.MuatTrustStore()
+	if err != nil {
+		log.Printf("Trust store tidak bisa dibaca: %v", err)
+	}
+	log.Printf("Verifikasi ulang tanda tangan dengan %d sertifikat terpercaya", jumlahCA)
+
+	total := 0
+	periksa := func(tipe string, id uint, path string) {
+		periksaTTD(tipe, id, path)
+		total++
+	}
+
+	var posbankums []models.Posbankum
+	config.DB.Where("dokumen <> ''").Find(&posbankums)
+	for _, d := range posbankums {
+		periksa("posbankum", d.ID, d.Dokumen)
+	}
+
+	var paralegals []models.Paralegal
+	config.DB.Where("dokumen <> ''").Find(&paralegals)
+	for _, d := range paralegals {
+		periksa("paralegal", d.ID, d.Dokumen)
+	}
+
+	var pjas []models.Pja
+	config.DB.Where("dokumen <> ''").Find(&pjas)
+	for _, d := range pjas {
+		periksa("pja", d.ID, d.Dokumen)
+	}
+
+	var kadarkums []models.Kadarkum
+	config.DB.Where("dokumen <> ''").Find(&kadarkums)
+	for _, d := range kadarkums {
+		periksa("kadarkum", d.ID, d.Dokumen)
+	}
+
+	var lampirans []models.Lampiran
+	config.DB.Where("content_type = ?", "application/pdf").Find(&lampirans)
+	for _, l := range lampirans {
+		periksa("lampiran", l.ID, l.Path)
+	}
+
+	var ringkasan []struct {
+		Status string
+		Total  int64
+	}
+	config.DB.Model(&models.TandaTanganDokumen{}).Select("status, COUNT(*) AS total").Group("status").Scan(&ringkasan)
+	log.Printf("Verifikasi ulang selesai: %d dokumen diperiksa, %v", total, ringkasan)
+}
+
+// ================== TAMPILAN DOKUMEN ==================
+
+// tampilkanDenganTTD menampilkan halaman pembungkus berisi badge status tanda tangan,
+// PDF aslinya dimuat di iframe lewat URL yang sama dengan ?raw=1.
+// false kalau dokumen belum diperiksa sehingga langsung dikirim apa adanya.
+func tampilkanDenganTTD(c *gin.Context, tipe string, id uint, filePath string) bool {
+	if c.Query("raw") == "1" {
+		return false
+	}
+	ttd := statusTTD(tipe, id)
+	if ttd == nil || ttd.Path != filePath {
+		return false
+	}
+
+	// parameter link bertanda tangan (exp, sig) ikut dibawa ke iframe
+	q := c.Request.URL.Query()
+	q.Set("raw", "1")
+
+	c.HTML(http.StatusOK, "dokumen_view.html", gin.H{
+		"Title":   "Dokumen " + tipe + " - " + filepath.Base(filePath),
+		"TTD":     ttd,
+		"SrcFile": "?" + q.Encode(),
+	})
+	return true
+}
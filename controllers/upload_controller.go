@@ -0,0 +1,521 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Upload resumable mengikuti protokol tus 1.0 (core + creation, termination, expiration).
+// Browser mengirim file per potongan lewat PATCH; kalau koneksi putus, upload dilanjutkan dari
+// offset terakhir. Setelah selesai, ID upload dikirim lewat field <nama>_upload di form biasa.
+const tusVersi = "1.0.0"
+
+// ================== HELPER ==================
+
+// folderUploadSementara ada di luar uploads/ supaya file setengah jadi tidak pernah terlayani
+func folderUploadSementara() string {
+	if dir := os.Getenv("UPLOAD_SEMENTARA_DIR"); dir != "" {
+		return dir
+	}
+	return "uploads_sementara"
+}
+
+// umurUploadResumable adalah batas upload tanpa aktivitas sebelum dibersihkan (env UPLOAD_KEDALUWARSA_JAM)
+func umurUploadResumable() time.Duration {
+	if jam, err := strconv.Atoi(os.Getenv("UPLOAD_KEDALUWARSA_JAM")); err == nil && jam > 0 {
+		return time.Duration(jam) * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+func tipeDokumenValid(tipe string) bool {
+	switch tipe {
+	case "posbankum", "paralegal", "pja", "kadarkum":
+		return true
+	}
+	return false
+}
+
+// maksUploadDokumen adalah batas ukuran dokumen utama per tipe, diatur di Pengaturan (MB)
+func maksUploadDokumen(tipe string) int64 {
+	mb, err := strconv.Atoi(nilaiPengaturan(PengaturanMaksUploadPrefix+tipe, ""))
+	if err != nil || mb <= 0 {
+		return utils.MaxUploadSize
+	}
+	return int64(mb) * 1024 * 1024
+}
+
+// MaksUploadMB dipakai template untuk teks bantuan di form upload
+func MaksUploadMB(tipe string) int64 {
+	return maksUploadDokumen(tipe) / (1024 * 1024)
+}
+
+// bacaMetadataTus membaca header Upload-Metadata: "kunci base64,kunci base64"
+func bacaMetadataTus(header string) map[string]string {
+	meta := map[string]string{}
+	for _, pasangan := range strings.Split(header, ",") {
+		kunci, nilai, _ := strings.Cut(strings.TrimSpace(pasangan), " ")
+		if kunci == "" {
+			continue
+		}
+		isi, err := base64.StdEncoding.DecodeString(nilai)
+		if err != nil {
+			continue
+		}
+		meta[kunci] = string(isi)
+	}
+	return meta
+}
+
+// uploadSibuk mencegah dua PATCH menulis ke file yang sama bersamaan
+var uploadSibuk = struct {
+	sync.Mutex
+	id map[string]bool
+}{id: map[string]bool{}}
+
+func kunciUpload(id string) bool {
+	uploadSibuk.Lock()
+	defer uploadSibuk.Unlock()
+	if uploadSibuk.id[id] {
+		return false
+	}
+	uploadSibuk.id[id] = true
+	return true
+}
+
+func lepasUpload(id string) {
+	uploadSibuk.Lock()
+	delete(uploadSibuk.id, id)
+	uploadSibuk.Unlock()
+}
+
+// uploadMilikUser mengambil upload milik user yang sedang login
+func uploadMilikUser(c *gin.Context) (models.UploadResumable, bool) {
+	var up models.UploadResumable
+	username, _ := sessions.Default(c).Get("user").(string)
+	if err := config.DB.Where("id = ? AND username = ?", c.Param("id"), username).First(&up).Error; err != nil {
+		return up, false
+	}
+	return up, true
+}
+
+func headerTus(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersi)
+	c.Header("Cache-Control", "no-store")
+}
+
+// cekVersiTus menolak klien yang tidak memakai tus 1.0.0
+func cekVersiTus(c *gin.Context) bool {
+	headerTus(c)
+	if c.GetHeader("Tus-Resumable") != tusVersi {
+		c.Header("Tus-Version", tusVersi)
+		c.String(http.StatusPreconditionFailed, "Versi protokol upload tidak didukung")
+		return false
+	}
+	return true
+}
+
+// ================== ENDPOINT TUS ==================
+
+// UploadOpsi menjawab OPTIONS: kemampuan server upload
+func UploadOpsi(c *gin.Context) {
+	headerTus(c)
+	var maks int64
+	for _, tipe := range []string{"posbankum", "paralegal", "pja", "kadarkum"} {
+		if m := maksUploadDokumen(tipe); m > maks {
+			maks = m
+		}
+	}
+	c.Header("Tus-Version", tusVersi)
+	c.Header("Tus-Extension", "creation,termination,expiration")
+	c.Header("Tus-Max-Size", strconv.FormatInt(maks, 10))
+	c.Status(http.StatusNoContent)
+}
+
+// UploadBuat membuat upload baru (POST) dan mengembalikan URL-nya di header Location
+func UploadBuat(c *gin.Context) {
+	if !cekVersiTus(c) {
+		return
+	}
+
+	ukuran, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || ukuran <= 0 {
+		c.String(http.StatusBadRequest, "Upload-Length tidak valid")
+		return
+	}
+	meta := bacaMetadataTus(c.GetHeader("Upload-Metadata"))
+	tipe := meta["tipe"]
+	if !tipeDokumenValid(tipe) {
+		c.String(http.StatusBadRequest, "Tipe dokumen tidak valid")
+		return
+	}
+	if maks := maksUploadDokumen(tipe); ukuran > maks {
+		c.String(http.StatusRequestEntityTooLarge, "Ukuran file melebihi %d MB", maks/(1024*1024))
+		return
+	}
+
+	if err := os.MkdirAll(folderUploadSementara(), 0o700); err != nil {
+		c.String(http.StatusInternalServerError, "Folder upload tidak bisa dibuat")
+		return
+	}
+	id := uuid.New().String()
+	path := filepath.Join(folderUploadSementara(), id+".part")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "File upload tidak bisa dibuat")
+		return
+	}
+	f.Close()
+
+	username, _ := sessions.Default(c).Get("user").(string)
+	up := models.UploadResumable{
+		ID:       id,
+		Tipe:     tipe,
+		NamaAsli: utils.SanitizeInput(filepath.Base(meta["filename"])),
+		Ukuran:   ukuran,
+		Path:     path,
+		Username: username,
+	}
+	if err := config.DB.Create(&up).Error; err != nil {
+		_ = os.Remove(path)
+		c.String(http.StatusInternalServerError, "Gagal mencatat upload")
+		return
+	}
+
+	c.Header("Location", "/admin/upload/"+id)
+	c.Header("Upload-Expires", time.Now().Add(umurUploadResumable()).UTC().Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+// UploadStatus (HEAD) memberi tahu offset terakhir supaya browser bisa melanjutkan
+func UploadStatus(c *gin.Context) {
+	if !cekVersiTus(c) {
+		return
+	}
+	up, ok := uploadMilikUser(c)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(up.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(up.Ukuran, 10))
+	c.Status(http.StatusOK)
+}
+
+// UploadLanjut (PATCH) menambahkan satu potongan file mulai dari Upload-Offset.
+// Potongan terakhir memicu validasi isi file; file yang tidak valid langsung dibuang.
+func UploadLanjut(c *gin.Context) {
+	if !cekVersiTus(c) {
+		return
+	}
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.String(http.StatusUnsupportedMediaType, "Content-Type harus application/offset+octet-stream")
+		return
+	}
+	// kunci dulu baru baca record, supaya offset yang dibaca pasti yang terbaru
+	if !kunciUpload(c.Param("id")) {
+		c.String(http.StatusLocked, "Upload ini sedang dikirim dari tab lain")
+		return
+	}
+	defer lepasUpload(c.Param("id"))
+	up, ok := uploadMilikUser(c)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset != up.Offset {
+		c.Header("Upload-Offset", strconv.FormatInt(up.Offset, 10))
+		c.String(http.StatusConflict, "Upload-Offset tidak sesuai")
+		return
+	}
+	if up.Selesai {
+		c.Header("Upload-Offset", strconv.FormatInt(up.Offset, 10))
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	f, err := os.OpenFile(up.Path, os.O_WRONLY, 0o600)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "File upload tidak ditemukan")
+		return
+	}
+	// potong sisa tulisan yang tidak sempat tercatat (mis. koneksi putus di tengah PATCH sebelumnya)
+	f.Truncate(up.Offset)
+	f.Seek(up.Offset, io.SeekStart)
+
+	// yang tersimpan tetap dicatat walaupun koneksi putus di tengah jalan
+	n, errSalin := io.Copy(f, io.LimitReader(c.Request.Body, up.Ukuran-up.Offset))
+	errTutup := f.Close()
+	if errTutup == nil {
+		up.Offset += n
+	}
+	if up.Offset == up.Ukuran {
+		up.Selesai = true
+	}
+	config.DB.Model(&up).Updates(map[string]any{"offset": up.Offset, "selesai": up.Selesai})
+
+	if errSalin != nil || errTutup != nil {
+		log.Printf("Upload %s terputus di offset %d: %v %v", up.ID, up.Offset, errSalin, errTutup)
+		c.String(http.StatusInternalServerError, "Potongan file tidak tersimpan utuh, lanjutkan upload")
+		return
+	}
+
+	if up.Selesai {
+		if msg := validasiUploadSelesai(up); msg != "" {
+			hapusUploadResumable(up)
+			c.String(http.StatusUnprocessableEntity, msg)
+			return
+		}
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(up.Offset, 10))
+	c.Header("Upload-Expires", time.Now().Add(umurUploadResumable()).UTC().Format(http.TimeFormat))
+	c.Status(http.StatusNoContent)
+}
+
+// UploadHapus (DELETE) membatalkan upload
+func UploadHapus(c *gin.Context) {
+	if !cekVersiTus(c) {
+		return
+	}
+	if !kunciUpload(c.Param("id")) {
+		c.String(http.StatusLocked, "Upload ini sedang dikirim dari tab lain")
+		return
+	}
+	defer lepasUpload(c.Param("id"))
+	up, ok := uploadMilikUser(c)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	hapusUploadResumable(up)
+	c.Status(http.StatusNoContent)
+}
+
+// validasiUploadSelesai memeriksa file yang sudah lengkap: ukuran dan isi harus benar-benar PDF
+func validasiUploadSelesai(up models.UploadResumable) string {
+	info, err := os.Stat(up.Path)
+	if err != nil || info.Size() != up.Ukuran {
+		return "File upload tidak lengkap, silakan upload ulang"
+	}
+
+	if utils.ContentTypeFile(up.Path) != "application/pdf" {
+		return "Tipe file tidak diizinkan, harus PDF"
+	}
+	return ""
+}
+
+func hapusUploadResumable(up models.UploadResumable) {
+	if err := os.Remove(up.Path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Gagal menghapus upload sementara %s: %v", up.Path, err)
+	}
+	config.DB.Delete(&up)
+}
+
+// ================== DIPAKAI FORM ==================
+
+// siapkanDokumenForm menaruh dokumen utama dari form di folder staging, baik dari upload biasa
+// (field "dokumen") maupun upload resumable yang sudah selesai (field "dokumen_upload").
+// Dokumen baru masuk ke penyimpanan blob lewat simpanDenganDokumen saat record-nya disimpan.
+// ada=false kalau form tidak membawa dokumen; pesan tidak kosong berarti gagal dan siap ditampilkan di form.
+func siapkanDokumenForm(c *gin.Context, tipe string, entitasID uint) (st *berkasStaging, ada bool, pesan string) {
+	if id := c.PostForm("dokumen_upload"); id != "" {
+		st, pesan = pakaiUploadResumable(c, id, tipe, entitasID)
+		return st, true, pesan
+	}
+
+	file, err := c.FormFile("dokumen")
+	if err != nil {
+		return nil, false, ""
+	}
+
+	maks := maksUploadDokumen(tipe)
+	if _, err := utils.ValidateUpload(file, []string{"pdf"}, maks); err != nil {
+		return nil, true, fmt.Sprintf("❌ File tidak valid. Pastikan file adalah PDF dan ukurannya di bawah %dMB.", maks/(1024*1024))
+	}
+
+	// pindai antivirus sebelum file masuk ke uploads/
+	if msg := periksaMalware(c, file, tipe, entitasID); msg != "" {
+		return nil, true, msg
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, true, "❌ Gagal upload file"
+	}
+	defer src.Close()
+	st, err = stagingDariReader(src, "application/pdf")
+	if err != nil {
+		log.Printf("Gagal menyimpan dokumen %s: %v", tipe, err)
+		return nil, true, "❌ Gagal upload file"
+	}
+	return st, true, ""
+}
+
+// maksUkuranFoto adalah batas ukuran foto (Posbankum, Paralegal)
+const maksUkuranFoto = 5 * 1024 * 1024
+
+// siapkanFotoForm memvalidasi foto opsional dari form (field "foto", JPG/PNG) lalu menaruhnya di staging.
+// st nil tanpa pesan berarti form tidak membawa foto.
+func siapkanFotoForm(c *gin.Context, tipe string, entitasID uint) (*berkasStaging, string) {
+	file, err := c.FormFile("foto")
+	if err != nil {
+		return nil, ""
+	}
+	contentType, err := utils.ValidateUpload(file, []string{"jpg", "png"}, maksUkuranFoto)
+	if err != nil {
+		return nil, "❌ Foto: " + err.Error()
+	}
+	if msg := periksaMalware(c, file, tipe, entitasID); msg != "" {
+		return nil, msg
+	}
+	src, err := file.Open()
+	if err != nil {
+		return nil, "❌ Gagal upload foto"
+	}
+	defer src.Close()
+	st, err := stagingDariReader(src, contentType)
+	if err != nil {
+		log.Printf("Gagal menyimpan foto %s: %v", tipe, err)
+		return nil, "❌ Gagal upload foto"
+	}
+	return st, ""
+}
+
+// pakaiUploadResumable memindahkan upload resumable yang sudah selesai ke folder staging
+func pakaiUploadResumable(c *gin.Context, id, tipe string, entitasID uint) (*berkasStaging, string) {
+	var up models.UploadResumable
+	username, _ := sessions.Default(c).Get("user").(string)
+	if err := config.DB.Where("id = ? AND username = ? AND tipe = ?", id, username, tipe).First(&up).Error; err != nil {
+		return nil, "❌ Upload dokumen tidak ditemukan atau sudah kedaluwarsa, silakan pilih file lagi"
+	}
+	if !up.Selesai {
+		return nil, "❌ Upload dokumen belum selesai"
+	}
+
+	// pindai antivirus sebelum file masuk ke uploads/
+	hasil, err := utils.ScanFile(up.Path)
+	if err != nil {
+		log.Printf("Pemindaian antivirus gagal untuk %s/%d: %v", tipe, entitasID, err)
+		if utils.ScanGagalTertutup() {
+			return nil, "❌ Pemindai antivirus sedang tidak tersedia, coba upload lagi nanti."
+		}
+	} else if !hasil.Bersih {
+		tujuan := pathKarantinaBaru()
+		if err := pindahkanFile(up.Path, tujuan); err != nil {
+			log.Printf("Gagal menyalin file ke karantina: %v", err)
+			tujuan = ""
+		}
+		config.DB.Delete(&up)
+		catatKarantina(models.Karantina{
+			Tipe:      tipe,
+			EntitasID: entitasID,
+			NamaAsli:  up.NamaAsli,
+			Path:      tujuan,
+			Virus:     hasil.Virus,
+			Sumber:    "upload",
+			Username:  username,
+			IP:        c.ClientIP(),
+		})
+		return nil, fmt.Sprintf("❌ File terdeteksi malware (%s) dan sudah dikarantina. Hubungi admin.", hasil.Virus)
+	}
+
+	st, err := stagingDariFile(up.Path, "application/pdf")
+	if err != nil {
+		log.Printf("Gagal memindahkan upload %s: %v", up.ID, err)
+		return nil, "❌ Gagal upload file"
+	}
+	config.DB.Delete(&up)
+	return st, ""
+}
+
+// pindahkanFile memakai rename, atau salin+hapus kalau folder sementara ada di disk lain
+func pindahkanFile(asal, tujuan string) error {
+	if err := os.Rename(asal, tujuan); err == nil {
+		return nil
+	}
+	src, err := os.Open(asal)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(tujuan, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(tujuan)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(asal)
+}
+
+// ================== PEMBERSIHAN ==================
+
+// BersihkanUploadTerbengkalai menghapus upload yang tidak ada aktivitas melewati batas umur,
+// file .part di folder sementara yang tidak punya record lagi, dan sisa file staging.
+func BersihkanUploadTerbengkalai() {
+	batas := time.Now().Add(-umurUploadResumable())
+
+	var lama []models.UploadResumable
+	config.DB.Where("updated_at < ?", batas).Find(&lama)
+	for _, up := range lama {
+		hapusUploadResumable(up)
+	}
+
+	file, _ := filepath.Glob(filepath.Join(folderUploadSementara(), "*.part"))
+	yatim := 0
+	for _, path := range file {
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().After(batas) {
+			continue
+		}
+		var total int64
+		config.DB.Model(&models.UploadResumable{}).Where("id = ?", strings.TrimSuffix(filepath.Base(path), ".part")).Count(&total)
+		if total == 0 {
+			os.Remove(path)
+			yatim++
+		}
+	}
+
+	if len(lama) > 0 || yatim > 0 {
+		log.Printf("Pembersihan upload: %d upload terbengkalai dan %d file sementara dihapus", len(lama), yatim)
+	}
+
+	BersihkanStaging()
+}
+
+// MulaiPembersihUpload menjalankan BersihkanUploadTerbengkalai tiap jam di background
+func MulaiPembersihUpload() {
+	go func() {
+		for {
+			BersihkanUploadTerbengkalai()
+			time.Sleep(time.Hour)
+		}
+	}()
+}
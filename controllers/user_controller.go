@@ -14,6 +14,7 @@ import (
 
 	"go-admin/config"
 	"go-admin/models"
+	"go-admin/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/microcosm-cc/bluemonday"
@@ -26,6 +27,40 @@ func hashPassword(password string) (string, error) {
 	return string(bytes), err
 }
 
+// roleValid -> role yang bisa dipilih di form user
+func roleValid(role string) bool {
+	switch role {
+	case "admin", "verifikator", "operator", "user":
+		return true
+	}
+	return false
+}
+
+// kabupatenDariForm -> wilayah kerja, hanya untuk role operator
+func kabupatenDariForm(c *gin.Context, role string) *uint {
+	id, err := strconv.Atoi(c.PostForm("kabupaten_id"))
+	if role != "operator" || err != nil || id <= 0 {
+		return nil
+	}
+	kabupatenID := uint(id)
+	return &kabupatenID
+}
+
+// idKabupaten -> id wilayah kerja untuk menandai pilihan di form, 0 kalau kosong
+func idKabupaten(kabupatenID *uint) uint {
+	if kabupatenID == nil {
+		return 0
+	}
+	return *kabupatenID
+}
+
+// semuaKabupaten -> pilihan wilayah kerja operator
+func semuaKabupaten() []models.Kabupaten {
+	var kabupatens []models.Kabupaten
+	config.DB.Order("name").Find(&kabupatens)
+	return kabupatens
+}
+
 // ================= CRUD =================
 
 // Index -> list semua user dengan pagination + search
@@ -41,7 +76,7 @@ func UserIndex(c *gin.Context) {
 	search := c.Query("q")
 
 	var users []models.User
-	db := config.DB.Model(&models.User{})
+	db := config.DB.Model(&models.User{}).Preload("Kabupaten")
 
 	if search != "" {
 		like := "%" + search + "%"
@@ -69,7 +104,9 @@ func UserIndex(c *gin.Context) {
 // Show form tambah user
 func UserCreateForm(c *gin.Context) {
 	c.HTML(http.StatusOK, "user_create.html", gin.H{
-		"Title": "Tambah User",
+		"Title":            "Tambah User",
+		"Kabupatens":       semuaKabupaten(),
+		"KabupatenDipilih": uint(0),
 	})
 }
 
@@ -77,6 +114,8 @@ func UserCreateForm(c *gin.Context) {
 func UserCreate(c *gin.Context) {
 	password := c.PostForm("password")
 	role := c.PostForm("role")
+	kabupatenID := kabupatenDariForm(c, role)
+	email := strings.TrimSpace(c.PostForm("email"))
 
 	// Sanitasi input username untuk mencegah XSS dan membersihkan spasi
 	p := bluemonday.StrictPolicy() // Gunakan StrictPolicy untuk menghapus semua HTML
@@ -89,8 +128,38 @@ func UserCreate(c *gin.Context) {
 		log.Printf("Validasi password gagal: %v", err)
 		c.HTML(http.StatusBadRequest, "user_create.html", gin.H{
 
-			"Title":         "Tambah User",
-			"ErrorPassword": err.Error(),
+			"Title":            "Tambah User",
+			"ErrorPassword":    err.Error(),
+			"Kabupatens":       semuaKabupaten(),
+			"KabupatenDipilih": idKabupaten(kabupatenID),
+		})
+		return
+	}
+
+	// Validasi role, operator wajib punya wilayah kerja
+	if msg := validasiRole(role, kabupatenID); msg != "" {
+		c.HTML(http.StatusBadRequest, "user_create.html", gin.H{
+			"Title":            "Tambah User",
+			"ErrorRole":        msg,
+			"Username":         username,
+			"Email":            email,
+			"Role":             role,
+			"Kabupatens":       semuaKabupaten(),
+			"KabupatenDipilih": idKabupaten(kabupatenID),
+		})
+		return
+	}
+
+	// Email opsional, dipakai untuk notifikasi
+	if email != "" && !utils.EmailValid(email) {
+		c.HTML(http.StatusBadRequest, "user_create.html", gin.H{
+			"Title":            "Tambah User",
+			"ErrorEmail":       "Alamat email tidak valid",
+			"Username":         username,
+			"Email":            email,
+			"Role":             role,
+			"Kabupatens":       semuaKabupaten(),
+			"KabupatenDipilih": idKabupaten(kabupatenID),
 		})
 		return
 	}
@@ -105,9 +174,11 @@ func UserCreate(c *gin.Context) {
 
 	// Membuat objek user baru
 	user := models.User{
-		Username: username,
-		Password: hashed,
-		Role:     role,
+		Username:    username,
+		Password:    hashed,
+		Role:        role,
+		Email:       email,
+		KabupatenID: kabupatenID,
 	}
 
 	// Cek apakah username sudah ada
@@ -118,7 +189,9 @@ func UserCreate(c *gin.Context) {
 			"Title":         "Tambah User",
 			"ErrorUsername": "Username sudah ada",
 
-			"Username": username,
+			"Username":         username,
+			"Kabupatens":       semuaKabupaten(),
+			"KabupatenDipilih": idKabupaten(kabupatenID),
 		})
 		return
 	}
@@ -129,9 +202,21 @@ func UserCreate(c *gin.Context) {
 		c.String(http.StatusInternalServerError, "Gagal simpan user")
 		return
 	}
+	antreEmailAkunBaru(user)
 	c.Redirect(http.StatusFound, "/admin/users")
 }
 
+// validasiRole -> pesan error kalau role tidak dikenal atau operator tanpa wilayah kerja
+func validasiRole(role string, kabupatenID *uint) string {
+	if !roleValid(role) {
+		return "Role tidak dikenal"
+	}
+	if role == "operator" && kabupatenID == nil {
+		return "Operator wajib punya wilayah kerja (kabupaten/kota)"
+	}
+	return ""
+}
+
 // validatePassword -> validasi password
 func validatePassword(password string) error {
 	if len(password) < 8 {
@@ -202,8 +287,10 @@ func UserEditForm(c *gin.Context) {
 
 	// kirim data user dengan ID ke template
 	c.HTML(http.StatusOK, "user_edit.html", gin.H{
-		"Title": "Edit User",
-		"User":  user,
+		"Title":            "Edit User",
+		"User":             user,
+		"Kabupatens":       semuaKabupaten(),
+		"KabupatenDipilih": idKabupaten(user.KabupatenID),
 	})
 }
 
@@ -219,8 +306,36 @@ func UserUpdate(c *gin.Context) {
 	// Username tidak bisa diubah → abaikan input username
 	password := c.PostForm("password")
 	role := c.PostForm("role")
+	kabupatenID := kabupatenDariForm(c, role)
+	email := strings.TrimSpace(c.PostForm("email"))
+	if msg := validasiRole(role, kabupatenID); msg != "" {
+		c.HTML(http.StatusBadRequest, "user_edit.html", gin.H{
+			"Title":            "Edit User",
+			"User":             user,
+			"ErrorRole":        msg,
+			"Kabupatens":       semuaKabupaten(),
+			"KabupatenDipilih": idKabupaten(kabupatenID),
+		})
+		return
+	}
+
+	if email != "" && !utils.EmailValid(email) {
+		c.HTML(http.StatusBadRequest, "user_edit.html", gin.H{
+			"Title":            "Edit User",
+			"User":             user,
+			"ErrorEmail":       "Alamat email tidak valid",
+			"Kabupatens":       semuaKabupaten(),
+			"KabupatenDipilih": idKabupaten(kabupatenID),
+		})
+		return
+	}
 
 	user.Role = role
+	user.KabupatenID = kabupatenID
+	// email akun SSO selalu diperbarui dari direktori saat login
+	if user.Sumber == "" || user.Sumber == "lokal" {
+		user.Email = email
+	}
 
 	// update password kalau diisi
 	if password != "" {
@@ -228,9 +343,11 @@ func UserUpdate(c *gin.Context) {
 		if err := validatePassword(password); err != nil {
 			log.Printf("Validasi password gagal saat update: %v", err)
 			c.HTML(http.StatusBadRequest, "user_edit.html", gin.H{
-				"Title":         "Edit User",
-				"User":          user,
-				"ErrorPassword": err.Error(),
+				"Title":            "Edit User",
+				"User":             user,
+				"ErrorPassword":    err.Error(),
+				"Kabupatens":       semuaKabupaten(),
+				"KabupatenDipilih": idKabupaten(kabupatenID),
 			})
 			return
 		}
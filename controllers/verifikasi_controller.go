@@ -0,0 +1,437 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Alur data program: operator kabupaten/kota mengisi data (draf) lalu mengajukannya,
+// verifikator Kanwil menyetujui (terverifikasi) atau menolak dengan catatan (ditolak).
+// Dashboard, peta dan laporan hanya menghitung data terverifikasi kecuali diatur lain di Pengaturan.
+
+// ================== ROLE & WILAYAH ==================
+
+// penggunaLogin mengembalikan username dan role dari session
+func penggunaLogin(c *gin.Context) (string, string) {
+	session := sessions.Default(c)
+	username, _ := session.Get("user").(string)
+	role, _ := session.Get("role").(string)
+	return username, role
+}
+
+// kabupatenOperator mengembalikan wilayah kerja operator yang sedang login.
+// dibatasi=false untuk role lain; operator tanpa wilayah mendapat id 0 (tidak boleh mengelola apa pun).
+func kabupatenOperator(c *gin.Context) (id uint, dibatasi bool) {
+	username, role := penggunaLogin(c)
+	if role != "operator" {
+		return 0, false
+	}
+	var user models.User
+	if err := config.DB.Where("username = ?", username).First(&user).Error; err != nil || user.KabupatenID == nil {
+		return 0, true
+	}
+	return *user.KabupatenID, true
+}
+
+// kelurahanDiKabupaten adalah subquery id kelurahan dalam satu kabupaten/kota
+func kelurahanDiKabupaten(kabupatenID uint) *gorm.DB {
+	return config.DB.Table("kelurahans").Select("kelurahans.id").
+		Joins("JOIN kecamatans ON kecamatans.id = kelurahans.kecamatan_id").
+		Where("kecamatans.kabupaten_id = ?", kabupatenID)
+}
+
+// scopeWilayahOperator membatasi daftar record ke kabupaten/kota operator yang login
+func scopeWilayahOperator(c *gin.Context, tipe string) func(*gorm.DB) *gorm.DB {
+	kabupatenID, dibatasi := kabupatenOperator(c)
+	return func(db *gorm.DB) *gorm.DB {
+		if !dibatasi {
+			return db
+		}
+		kelurahan := kelurahanDiKabupaten(kabupatenID)
+		if tipe == "paralegal" || tipe == "konsultasi" {
+			return db.Where(tipe+"s.posbankum_id IN (?)",
+				config.DB.Model(&models.Posbankum{}).Select("id").Where("kelurahan_id IN (?)", kelurahan))
+		}
+		return db.Where(tipe+"s.kelurahan_id IN (?)", kelurahan)
+	}
+}
+
+// kabupatenKelurahan mencari kabupaten/kota tempat kelurahan berada
+func kabupatenKelurahan(kelurahanID uint) uint {
+	var kabupatenID uint
+	config.DB.Table("kelurahans").Select("kecamatans.kabupaten_id").
+		Joins("JOIN kecamatans ON kecamatans.id = kelurahans.kecamatan_id").
+		Where("kelurahans.id = ?", kelurahanID).Scan(&kabupatenID)
+	return kabupatenID
+}
+
+// kelurahanRecord mencari kelurahan sebuah record; paralegal dan konsultasi lewat Posbankum-nya, lampiran lewat
+// record induknya, peserta pelatihan dan sertifikat lewat paralegalnya
+func kelurahanRecord(tipe string, id uint) uint {
+	var kelurahanID uint
+	switch tipe {
+	case "posbankum":
+		config.DB.Model(&models.Posbankum{}).Where("id = ?", id).Pluck("kelurahan_id", &kelurahanID)
+	case "kadarkum":
+		config.DB.Model(&models.Kadarkum{}).Where("id = ?", id).Pluck("kelurahan_id", &kelurahanID)
+	case "pja":
+		config.DB.Model(&models.Pja{}).Where("id = ?", id).Pluck("kelurahan_id", &kelurahanID)
+	case "mediasi":
+		config.DB.Model(&models.Mediasi{}).Where("id = ?", id).Pluck("kelurahan_id", &kelurahanID)
+	case "nominasi_pja":
+		config.DB.Model(&models.NominasiPJA{}).Where("id = ?", id).Pluck("kelurahan_id", &kelurahanID)
+	case "penilaian_kadarkum":
+		config.DB.Model(&models.PenilaianKadarkum{}).Where("id = ?", id).Pluck("kelurahan_id", &kelurahanID)
+	case "paralegal":
+		var posbankumID uint
+		config.DB.Model(&models.Paralegal{}).Where("id = ?", id).Pluck("posbankum_id", &posbankumID)
+		return kelurahanRecord("posbankum", posbankumID)
+	case "konsultasi":
+		var posbankumID uint
+		config.DB.Model(&models.Konsultasi{}).Where("id = ?", id).Pluck("posbankum_id", &posbankumID)
+		return kelurahanRecord("posbankum", posbankumID)
+	case "lampiran":
+		var l models.Lampiran
+		if err := config.DB.First(&l, id).Error; err == nil {
+			return kelurahanRecord(l.EntitasType, l.EntitasID)
+		}
+	case "peserta":
+		var paralegalID uint
+		config.DB.Model(&models.PesertaPelatihan{}).Where("id = ?", id).Pluck("paralegal_id", &paralegalID)
+		return kelurahanRecord("paralegal", paralegalID)
+	case "sertifikat":
+		var paralegalID uint
+		config.DB.Model(&models.SertifikatParalegal{}).Where("id = ?", id).Pluck("paralegal_id", &paralegalID)
+		return kelurahanRecord("paralegal", paralegalID)
+	}
+	return kelurahanID
+}
+
+// WilayahOperator menolak operator yang membuka atau mengubah data di luar kabupaten/kotanya.
+// Yang dicek: record di :id pada URL, serta tujuan baru dari form (kelurahan_id, posbankum_id,
+// paralegal_id, atau record induk lampiran). Tipe kosong berarti tipe record diambil dari :tipe pada URL.
+// Role lain diteruskan tanpa pemeriksaan.
+func WilayahOperator(tipe string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		kabupatenID, dibatasi := kabupatenOperator(c)
+		if !dibatasi {
+			c.Next()
+			return
+		}
+
+		tipeRecord := tipe
+		if tipeRecord == "" {
+			tipeRecord = c.Param("tipe")
+		}
+		var kelurahan []uint
+		if id, err := strconv.Atoi(c.Param("id")); err == nil {
+			kelurahan = append(kelurahan, kelurahanRecord(tipeRecord, uint(id)))
+		}
+		if c.Request.Method != http.MethodGet {
+			if id, err := strconv.Atoi(c.PostForm("kelurahan_id")); err == nil {
+				kelurahan = append(kelurahan, uint(id))
+			}
+			if id, err := strconv.Atoi(c.PostForm("posbankum_id")); err == nil {
+				kelurahan = append(kelurahan, kelurahanRecord("posbankum", uint(id)))
+			}
+			if id, err := strconv.Atoi(c.PostForm("paralegal_id")); err == nil {
+				kelurahan = append(kelurahan, kelurahanRecord("paralegal", uint(id)))
+			}
+			if id, err := strconv.Atoi(c.PostForm("entitas_id")); err == nil && tipe == "lampiran" {
+				kelurahan = append(kelurahan, kelurahanRecord(c.PostForm("entitas_type"), uint(id)))
+			}
+		}
+
+		if kabupatenID == 0 {
+			c.String(http.StatusForbidden, "🚫 Akun operator Anda belum punya wilayah kerja, hubungi admin.")
+			c.Abort()
+			return
+		}
+		boleh := true
+		for _, id := range kelurahan {
+			if kabupatenKelurahan(id) != kabupatenID {
+				boleh = false
+			}
+		}
+		if !boleh {
+			c.String(http.StatusForbidden, "🚫 Data ini di luar wilayah kerja Anda.")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// ================== STATUS ==================
+
+// filterTerverifikasi membaca pengaturan sekali lalu mengembalikan pembuat scope per tabel,
+// dipakai berdampingan dengan filterSKBerlaku di dashboard, peta dan laporan.
+//
+//	terverifikasi := filterTerverifikasi()
+//	config.DB.Model(&models.Posbankum{}).Scopes(terverifikasi("posbankums")).Count(&n)
+func filterTerverifikasi() func(tabel string) func(*gorm.DB) *gorm.DB {
+	semua := pengaturanAktif(PengaturanHitungBelumVerifikasi, false)
+	return func(tabel string) func(*gorm.DB) *gorm.DB {
+		return func(db *gorm.DB) *gorm.DB {
+			if semua {
+				return db
+			}
+			return db.Where(tabel+".status_verifikasi = ?", models.StatusTerverifikasi)
+		}
+	}
+}
+
+// statusSetelahSimpan menentukan status record yang disimpan dari form create/edit (statusLama kosong = record baru).
+// Tombol "Ajukan" langsung mengirim ke antrean verifikasi. Perubahan oleh selain admin mengembalikan
+// record ke draf supaya diperiksa ulang; admin Kanwil boleh mengoreksi tanpa mengubah status.
+func statusSetelahSimpan(c *gin.Context, statusLama string) string {
+	if c.PostForm("ajukan") == "1" {
+		return models.StatusDiajukan
+	}
+	if _, role := penggunaLogin(c); role == "admin" && statusLama != "" {
+		return statusLama
+	}
+	return models.StatusDraf
+}
+
+// catatRiwayat menulis perpindahan status (dan notifikasinya) di transaksi yang sama dengan perubahan record-nya
+func catatRiwayat(tx *gorm.DB, c *gin.Context, tipe string, id uint, dari, ke, komentar string) error {
+	if dari == ke {
+		return nil
+	}
+	username, _ := penggunaLogin(c)
+	if err := tx.Create(&models.RiwayatVerifikasi{
+		Tipe:      tipe,
+		EntitasID: id,
+		Dari:      dari,
+		Ke:        ke,
+		Komentar:  komentar,
+		Username:  username,
+	}).Error; err != nil {
+		return err
+	}
+	return beritahuVerifikasi(tx, tipe, id, ke, komentar, username)
+}
+
+// InfoVerifikasi ditampilkan di halaman edit record
+type InfoVerifikasi struct {
+	models.Verifikasi
+	Riwayat []models.RiwayatVerifikasi
+	Jejak   []JejakRecord // riwayat status digabung komentar diskusi
+}
+
+// infoVerifikasi mengambil status dan riwayat verifikasi satu record, terbaru di atas
+func infoVerifikasi(tipe string, id uint, v models.Verifikasi) InfoVerifikasi {
+	info := InfoVerifikasi{Verifikasi: v}
+	config.DB.Where("tipe = ? AND entitas_id = ?", tipe, id).Order("id DESC").Find(&info.Riwayat)
+	info.Jejak = jejakRecord(tipe, id, info.Riwayat)
+	return info
+}
+
+// jumlahMenungguVerifikasi untuk penanda di dashboard admin
+func jumlahMenungguVerifikasi() int64 {
+	var total int64
+	for _, m := range []any{&models.Posbankum{}, &models.Paralegal{}, &models.Pja{}, &models.Kadarkum{}} {
+		var n int64
+		config.DB.Model(m).Where("status_verifikasi = ?", models.StatusDiajukan).Count(&n)
+		total += n
+	}
+	return total
+}
+
+// ================== ANTREAN VERIFIKASI ==================
+
+// BarisVerifikasi adalah satu record di antrean verifikasi
+type BarisVerifikasi struct {
+	Tipe     string
+	ID       uint
+	Label    string
+	Wilayah  string
+	Dokumen  string
+	NomorSK  string
+	Status   string
+	Catatan  string
+	Terakhir models.RiwayatVerifikasi // perpindahan status terakhir (siapa mengajukan/memutuskan, kapan)
+	Komentar int64                    // jumlah komentar di diskusi record
+}
+
+// maksBarisVerifikasi membatasi jumlah record per tipe yang ditampilkan sekaligus
+const maksBarisVerifikasi = 200
+
+// modelVerifikasi memetakan tipe ke model untuk query generik
+func modelVerifikasi(tipe string) any {
+	switch tipe {
+	case "posbankum":
+		return &models.Posbankum{}
+	case "paralegal":
+		return &models.Paralegal{}
+	case "pja":
+		return &models.Pja{}
+	case "kadarkum":
+		return &models.Kadarkum{}
+	}
+	return nil
+}
+
+func namaWilayah(kel models.Kelurahan) string {
+	return fmt.Sprintf("%s, %s, %s", kel.Name, kel.Kecamatan.Name, kel.Kecamatan.Kabupaten.Name)
+}
+
+// barisVerifikasi mengambil record satu tipe dengan status tertentu
+func barisVerifikasi(tipe, status string) []BarisVerifikasi {
+	var hasil []BarisVerifikasi
+	tambah := func(id uint, label, wilayah, dokumen, nomorSK string, v models.Verifikasi) {
+		hasil = append(hasil, BarisVerifikasi{
+			Tipe: tipe, ID: id, Label: label, Wilayah: wilayah, Dokumen: dokumen, NomorSK: nomorSK,
+			Status: v.StatusVerifikasi, Catatan: v.CatatanVerifikasi,
+		})
+	}
+
+	db := config.DB.Where("status_verifikasi = ?", status).Order("updated_at DESC").Limit(maksBarisVerifikasi)
+	switch tipe {
+	case "posbankum":
+		var rows []models.Posbankum
+		db.Preload("Kelurahan.Kecamatan.Kabupaten").Find(&rows)
+		for _, d := range rows {
+			tambah(d.ID, d.Kelurahan.Name, namaWilayah(d.Kelurahan), d.Dokumen, d.NomorSK, d.Verifikasi)
+		}
+	case "kadarkum":
+		var rows []models.Kadarkum
+		db.Preload("Kelurahan.Kecamatan.Kabupaten").Find(&rows)
+		for _, d := range rows {
+			tambah(d.ID, d.Kelurahan.Name, namaWilayah(d.Kelurahan), d.Dokumen, d.NomorSK, d.Verifikasi)
+		}
+	case "pja":
+		var rows []models.Pja
+		db.Preload("Kelurahan.Kecamatan.Kabupaten").Find(&rows)
+		for _, d := range rows {
+			tambah(d.ID, d.Kelurahan.Name, namaWilayah(d.Kelurahan), d.Dokumen, d.NomorSK, d.Verifikasi)
+		}
+	case "paralegal":
+		var rows []models.Paralegal
+		db.Preload("Posbankum.Kelurahan.Kecamatan.Kabupaten").Find(&rows)
+		for _, d := range rows {
+			tambah(d.ID, d.Nama, namaWilayah(d.Posbankum.Kelurahan), d.Dokumen, "", d.Verifikasi)
+		}
+	}
+
+	for i := range hasil {
+		config.DB.Where("tipe = ? AND entitas_id = ?", tipe, hasil[i].ID).Order("id DESC").
+			Limit(1).Find(&hasil[i].Terakhir)
+		hasil[i].Komentar = jumlahKomentar(tipe, hasil[i].ID)
+	}
+	return hasil
+}
+
+// VerifikasiIndex menampilkan antrean verifikasi (bawaan: yang sudah diajukan)
+func VerifikasiIndex(c *gin.Context) {
+	status := c.DefaultQuery("status", models.StatusDiajukan)
+	switch status {
+	case models.StatusDraf, models.StatusDiajukan, models.StatusTerverifikasi, models.StatusDitolak:
+	default:
+		status = models.StatusDiajukan
+	}
+	tipe := c.Query("tipe")
+
+	var baris []BarisVerifikasi
+	for _, t := range []string{"posbankum", "paralegal", "kadarkum", "pja"} {
+		if tipe == "" || tipe == t {
+			baris = append(baris, barisVerifikasi(t, status)...)
+		}
+	}
+
+	c.HTML(http.StatusOK, "verifikasi.html", gin.H{
+		"Title":        "Verifikasi Data",
+		"Baris":        baris,
+		"Status":       status,
+		"Tipe":         tipe,
+		"Menunggu":     jumlahMenungguVerifikasi(),
+		"Maks":         maksBarisVerifikasi,
+		"Error":        c.Query("error"),
+		"Sukses":       c.Query("sukses"),
+		"BolehAksi":    status == models.StatusDiajukan,
+		"PenilaianPJA": jumlahPenilaianTertunda(c),
+		"user":         sessions.Default(c).Get("user"),
+	})
+}
+
+var errSudahDiputuskan = errors.New("data sudah diputuskan atau tidak sedang diajukan")
+
+// VerifikasiPutuskan menyetujui atau menolak satu record yang sedang diajukan
+func VerifikasiPutuskan(c *gin.Context) {
+	tipe := c.Param("tipe")
+	id, _ := strconv.Atoi(c.Param("id"))
+	model := modelVerifikasi(tipe)
+	if model == nil || id <= 0 {
+		c.String(http.StatusBadRequest, "Data tidak valid")
+		return
+	}
+	// kembali ke antrean dengan filter tipe yang sama
+	q := url.Values{}
+	if t := c.PostForm("filter_tipe"); t != "" {
+		q.Set("tipe", t)
+	}
+	kembali := func(key, pesan string) {
+		q.Set(key, pesan)
+		c.Redirect(http.StatusFound, "/admin/verifikasi?"+q.Encode())
+	}
+
+	komentar := strings.TrimSpace(utils.SanitizeInput(c.PostForm("komentar")))
+	var ke string
+	switch c.PostForm("aksi") {
+	case "setujui":
+		ke = models.StatusTerverifikasi
+	case "tolak":
+		ke = models.StatusDitolak
+		if komentar == "" {
+			kembali("error", "Alasan penolakan wajib diisi")
+			return
+		}
+	default:
+		c.String(http.StatusBadRequest, "Aksi tidak valid")
+		return
+	}
+
+	username, _ := penggunaLogin(c)
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// hanya record yang masih diajukan, supaya dua verifikator tidak memutuskan bersamaan
+		res := tx.Model(model).Where("id = ? AND status_verifikasi = ?", id, models.StatusDiajukan).
+			UpdateColumns(map[string]any{
+				"status_verifikasi":  ke,
+				"catatan_verifikasi": komentar,
+				"diverifikasi_oleh":  username,
+				"diverifikasi_at":    time.Now(),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errSudahDiputuskan
+		}
+		return catatRiwayat(tx, c, tipe, uint(id), models.StatusDiajukan, ke, komentar)
+	})
+	if errors.Is(err, errSudahDiputuskan) {
+		kembali("error", "Data sudah diputuskan verifikator lain atau tidak sedang diajukan")
+		return
+	}
+	if err != nil {
+		log.Printf("Gagal memutuskan verifikasi %s/%d: %v", tipe, id, err)
+		kembali("error", "Gagal menyimpan keputusan, coba lagi")
+		return
+	}
+	kembali("sukses", fmt.Sprintf("%s #%d %s", strings.ToUpper(tipe[:1])+tipe[1:], id, ke))
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ================== PENGATURAN ==================
+
+// watermarkTipeDariForm membaca checkbox tipe dokumen yang diberi watermark
+func watermarkTipeDariForm(c *gin.Context) string {
+	var tipe []string
+	for _, t := range c.PostFormArray(PengaturanWatermarkTipe) {
+		switch t {
+		case "posbankum", "paralegal", "pja", "kadarkum":
+			tipe = append(tipe, t)
+		}
+	}
+	return strings.Join(tipe, ",")
+}
+
+// dokumenPublik true kalau record ditandai boleh dibuka dari halaman publik
+func dokumenPublik(tipe string, id uint) bool {
+	var publik bool
+	switch tipe {
+	case "posbankum":
+		config.DB.Model(&models.Posbankum{}).Where("id = ?", id).Pluck("dokumen_publik", &publik)
+	case "paralegal":
+		config.DB.Model(&models.Paralegal{}).Where("id = ?", id).Pluck("dokumen_publik", &publik)
+	case "pja":
+		config.DB.Model(&models.Pja{}).Where("id = ?", id).Pluck("dokumen_publik", &publik)
+	case "kadarkum":
+		config.DB.Model(&models.Kadarkum{}).Where("id = ?", id).Pluck("dokumen_publik", &publik)
+	}
+	return publik
+}
+
+// perluWatermark menentukan apakah PDF ini diberi watermark saat dibuka user login.
+// Dokumen utama mengikuti Pengaturan, lampiran mengikuti kategorinya.
+func perluWatermark(tipe string, id uint) bool {
+	if tipe == "lampiran" {
+		var l models.Lampiran
+		if err := config.DB.Preload("Kategori").First(&l, id).Error; err != nil {
+			return false
+		}
+		return l.Kategori.Watermark
+	}
+
+	aktif := false
+	for _, t := range strings.Split(nilaiPengaturan(PengaturanWatermarkTipe, ""), ",") {
+		if t == tipe {
+			aktif = true
+		}
+	}
+	if !aktif {
+		return false
+	}
+	// dokumen publik bisa diunduh siapa saja, watermark-nya opsional
+	return pengaturanAktif(PengaturanWatermarkPublik, false) || !dokumenPublik(tipe, id)
+}
+
+// ================== KIRIM ==================
+
+// salinanWatermark membuat salinan PDF berstempel username, waktu dan ID watermark akses ini.
+// File sementara hasilnya wajib dihapus pemanggil.
+func salinanWatermark(filePath string, akses models.AksesDokumen) (string, error) {
+	teks := fmt.Sprintf("Dibuka oleh %s | %s | ID %s | Dilarang menyebarluaskan",
+		akses.Username, time.Now().Format("02-01-2006 15:04:05"), akses.IDWatermark)
+	return utils.WatermarkPDF(filePath, teks)
+}
+
+// kirimDenganWatermark mengirim salinan PDF yang sudah distempel, file asli tetap utuh.
+// Kalau stempel gagal dibuat, dokumen tidak dikirim supaya tidak ada salinan tanpa jejak.
+func kirimDenganWatermark(c *gin.Context, filePath string, akses models.AksesDokumen) {
+	salinan, err := salinanWatermark(filePath, akses)
+	if err != nil {
+		log.Printf("Gagal watermark %s/%d: %v", akses.Tipe, akses.EntitasID, err)
+		c.String(http.StatusInternalServerError, "Dokumen tidak bisa disiapkan saat ini, coba lagi nanti.")
+		return
+	}
+	defer os.Remove(salinan)
+
+	c.Header("Content-Disposition", "inline; filename="+filepath.Base(filePath))
+	c.Header("Content-Type", "application/pdf")
+	c.Header("Cache-Control", "private, no-store")
+	c.File(salinan)
+}
@@ -0,0 +1,330 @@
+package controllers
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-admin/config"
+	"go-admin/models"
+	"go-admin/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ================== FORM ==================
+
+// FormZIP adalah data untuk partial "zip_form" (pilih program + wilayah lalu unduh ZIP)
+type FormZIP struct {
+	Action     string
+	Program    string             // kosong = user memilih program sendiri
+	Kabupatens []models.Kabupaten // beserta Kecamatans
+}
+
+// formZIP menyiapkan pilihan wilayah untuk tombol unduh ZIP di halaman index admin.
+// Operator hanya mendapat pilihan kabupaten/kota wilayah kerjanya.
+func formZIP(c *gin.Context, action, program string) FormZIP {
+	var kabupatens []models.Kabupaten
+	db := config.DB.Preload("Kecamatans", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name")
+	}).Order("name")
+	if kabupatenID, dibatasi := kabupatenOperator(c); dibatasi {
+		db = db.Where("id = ?", kabupatenID)
+	}
+	db.Find(&kabupatens)
+	return FormZIP{Action: action, Program: program, Kabupatens: kabupatens}
+}
+
+// ================== ISI ARSIP ==================
+
+// berkasZIP adalah satu file yang akan dimasukkan ke arsip
+type berkasZIP struct {
+	Tipe      string // tipe untuk log akses: posbankum, paralegal, pja, kadarkum, lampiran
+	ID        uint
+	Program   string
+	RecordID  uint
+	Kabupaten string
+	Kecamatan string
+	Kelurahan string
+	Nama      string // nama paralegal
+	Jenis     string // dokumen atau lampiran
+	Kategori  string
+	Path      string
+	NamaAsli  string
+}
+
+// namaAman membuang karakter yang tidak boleh ada di nama folder/file ZIP
+func namaAman(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(s))
+	if s == "" || s == "." || s == ".." {
+		return "_"
+	}
+	return s
+}
+
+// folder mengikuti struktur Kabupaten/Kecamatan/Kelurahan
+func (b berkasZIP) folder() string {
+	return namaAman(b.Kabupaten) + "/" + namaAman(b.Kecamatan) + "/" + namaAman(b.Kelurahan)
+}
+
+func (b berkasZIP) jalur() string {
+	ext := filepath.Ext(b.Path)
+	if b.Jenis == "lampiran" {
+		nama := strings.TrimSuffix(b.NamaAsli, filepath.Ext(b.NamaAsli))
+		return fmt.Sprintf("%s/%s-%d/lampiran/%s-%d-%s%s", b.folder(), b.Program, b.RecordID,
+			namaAman(b.Kategori), b.ID, namaAman(nama), ext)
+	}
+	if b.Nama != "" {
+		return fmt.Sprintf("%s/%s-%d-%s%s", b.folder(), b.Program, b.RecordID, namaAman(b.Nama), ext)
+	}
+	return fmt.Sprintf("%s/%s-%d%s", b.folder(), b.Program, b.RecordID, ext)
+}
+
+// scopeWilayahZIP memfilter record lewat kolom kelurahan_id ke kabupaten atau kecamatan terpilih
+func scopeWilayahZIP(kolomKelurahan string, kabupatenID, kecamatanID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Joins("JOIN kelurahans ON kelurahans.id = " + kolomKelurahan).
+			Joins("JOIN kecamatans ON kecamatans.id = kelurahans.kecamatan_id")
+		if kecamatanID > 0 {
+			return db.Where("kecamatans.id = ?", kecamatanID)
+		}
+		return db.Where("kecamatans.kabupaten_id = ?", kabupatenID)
+	}
+}
+
+// tambahLampiranZIP memasukkan lampiran satu record ke daftar berkas
+func tambahLampiranZIP(daftar []berkasZIP, induk berkasZIP, lampirans []models.Lampiran) []berkasZIP {
+	for _, l := range lampirans {
+		b := induk
+		b.Tipe = "lampiran"
+		b.ID = l.ID
+		b.Jenis = "lampiran"
+		b.Kategori = l.Kategori.Nama
+		b.Path = l.Path
+		b.NamaAsli = l.NamaAsli
+		daftar = append(daftar, b)
+	}
+	return daftar
+}
+
+// kumpulkanBerkasZIP mengambil dokumen utama dan lampiran satu program di wilayah terpilih.
+// Sama seperti rekap dan ekspor, hanya record terverifikasi yang ikut (lihat Pengaturan).
+func kumpulkanBerkasZIP(program string, kabupatenID, kecamatanID uint) []berkasZIP {
+	var daftar []berkasZIP
+	terverifikasi := filterTerverifikasi()
+	tambah := func(id uint, kel models.Kelurahan, nama, dokumen string, lampirans []models.Lampiran) {
+		induk := berkasZIP{
+			Tipe:      program,
+			ID:        id,
+			Program:   program,
+			RecordID:  id,
+			Kabupaten: kel.Kecamatan.Kabupaten.Name,
+			Kecamatan: kel.Kecamatan.Name,
+			Kelurahan: kel.Name,
+			Nama:      nama,
+			Jenis:     "dokumen",
+			Path:      dokumen,
+			NamaAsli:  filepath.Base(dokumen),
+		}
+		if dokumen != "" {
+			daftar = append(daftar, induk)
+		}
+		daftar = tambahLampiranZIP(daftar, induk, lampirans)
+	}
+
+	urut := "kabupatens.name, kecamatans.name, kelurahans.name"
+	switch program {
+	case "posbankum":
+		var rows []models.Posbankum
+		config.DB.Preload("Kelurahan.Kecamatan.Kabupaten").Preload("Lampirans", urutLampiran).Preload("Lampirans.Kategori").
+			Scopes(scopeWilayahZIP("posbankums.kelurahan_id", kabupatenID, kecamatanID), terverifikasi("posbankums")).
+			Joins("JOIN kabupatens ON kabupatens.id = kecamatans.kabupaten_id").Order(urut).Find(&rows)
+		for _, r := range rows {
+			tambah(r.ID, r.Kelurahan, "", r.Dokumen, r.Lampirans)
+		}
+	case "pja":
+		var rows []models.Pja
+		config.DB.Preload("Kelurahan.Kecamatan.Kabupaten").Preload("Lampirans", urutLampiran).Preload("Lampirans.Kategori").
+			Scopes(scopeWilayahZIP("pjas.kelurahan_id", kabupatenID, kecamatanID), terverifikasi("pjas")).
+			Joins("JOIN kabupatens ON kabupatens.id = kecamatans.kabupaten_id").Order(urut).Find(&rows)
+		for _, r := range rows {
+			tambah(r.ID, r.Kelurahan, "", r.Dokumen, r.Lampirans)
+		}
+	case "kadarkum":
+		var rows []models.Kadarkum
+		config.DB.Preload("Kelurahan.Kecamatan.Kabupaten").Preload("Lampirans", urutLampiran).Preload("Lampirans.Kategori").
+			Scopes(scopeWilayahZIP("kadarkums.kelurahan_id", kabupatenID, kecamatanID), terverifikasi("kadarkums")).
+			Joins("JOIN kabupatens ON kabupatens.id = kecamatans.kabupaten_id").Order(urut).Find(&rows)
+		for _, r := range rows {
+			tambah(r.ID, r.Kelurahan, "", r.Dokumen, r.Lampirans)
+		}
+	case "paralegal":
+		// paralegal tidak punya kelurahan sendiri, wilayahnya ikut Posbankum
+		var rows []models.Paralegal
+		config.DB.Preload("Posbankum.Kelurahan.Kecamatan.Kabupaten").Preload("Lampirans", urutLampiran).Preload("Lampirans.Kategori").
+			Joins("JOIN posbankums ON posbankums.id = paralegals.posbankum_id").
+			Scopes(scopeWilayahZIP("posbankums.kelurahan_id", kabupatenID, kecamatanID),
+				terverifikasi("paralegals"), terverifikasi("posbankums")).
+			Joins("JOIN kabupatens ON kabupatens.id = kecamatans.kabupaten_id").Order(urut + ", paralegals.nama").Find(&rows)
+		for _, r := range rows {
+			tambah(r.ID, r.Posbankum.Kelurahan, r.Nama, r.Dokumen, r.Lampirans)
+		}
+	}
+	return daftar
+}
+
+// ================== HANDLER ==================
+
+// UnduhZIP mengalirkan (streaming) arsip ZIP berisi semua dokumen satu program di satu kabupaten/kecamatan.
+// File ditulis satu per satu langsung ke response, jadi arsip besar tidak ditampung di memori.
+// Setiap file tetap tercatat di log akses, mengikuti batas unduhan dan watermark.
+// Kalau klien memutus unduhan, penulisan berhenti supaya sisa file tidak ikut tercatat di log akses.
+func UnduhZIP(c *gin.Context) {
+	program := c.Query("program")
+	switch program {
+	case "posbankum", "paralegal", "pja", "kadarkum":
+	default:
+		c.String(http.StatusBadRequest, "Program tidak valid")
+		return
+	}
+
+	// wilayah: kab-<id> atau kec-<id>
+	jenis, idStr, _ := strings.Cut(c.Query("wilayah"), "-")
+	idWilayah, _ := strconv.Atoi(idStr)
+	var kabupatenID, kecamatanID, kabupatenWilayah uint
+	var namaWilayah string
+	switch {
+	case jenis == "kab" && idWilayah > 0:
+		var kab models.Kabupaten
+		if err := config.DB.First(&kab, idWilayah).Error; err != nil {
+			c.String(http.StatusNotFound, "Kabupaten tidak ditemukan")
+			return
+		}
+		kabupatenID, namaWilayah = kab.ID, kab.Name
+		kabupatenWilayah = kab.ID
+	case jenis == "kec" && idWilayah > 0:
+		var kec models.Kecamatan
+		if err := config.DB.Preload("Kabupaten").First(&kec, idWilayah).Error; err != nil {
+			c.String(http.StatusNotFound, "Kecamatan tidak ditemukan")
+			return
+		}
+		kecamatanID, namaWilayah = kec.ID, kec.Kabupaten.Name+"-"+kec.Name
+		kabupatenWilayah = kec.KabupatenID
+	default:
+		c.String(http.StatusBadRequest, "Pilih kabupaten atau kecamatan")
+		return
+	}
+
+	// operator hanya boleh mengunduh kabupaten/kota wilayah kerjanya
+	if operatorKab, dibatasi := kabupatenOperator(c); dibatasi && (operatorKab == 0 || operatorKab != kabupatenWilayah) {
+		c.String(http.StatusForbidden, "🚫 Anda hanya boleh mengunduh dokumen di wilayah kerja Anda.")
+		return
+	}
+
+	daftar := kumpulkanBerkasZIP(program, kabupatenID, kecamatanID)
+	if len(daftar) == 0 {
+		c.String(http.StatusNotFound, "Tidak ada dokumen %s di %s", program, namaWilayah)
+		return
+	}
+
+	namaZIP := fmt.Sprintf("dokumen-%s-%s-%s.zip", program,
+		strings.ReplaceAll(namaAman(namaWilayah), " ", "_"), time.Now().Format("20060102"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="`+namaZIP+`"`)
+	c.Header("Cache-Control", "private, no-store")
+	c.Status(http.StatusOK)
+
+	zw := zip.NewWriter(c.Writer)
+	manifest := [][]string{{"no", "program", "id", "kabupaten", "kecamatan", "kelurahan", "nama", "jenis",
+		"kategori", "file_di_zip", "nama_file_asli", "ukuran_byte", "sha256", "status", "id_watermark"}}
+
+	for i, b := range daftar {
+		if err := c.Request.Context().Err(); err != nil {
+			log.Printf("Unduhan ZIP %s dibatalkan klien setelah %d dari %d file: %v", namaZIP, i, len(daftar), err)
+			return
+		}
+		baris := []string{strconv.Itoa(i + 1), b.Program, strconv.Itoa(int(b.RecordID)), b.Kabupaten, b.Kecamatan,
+			b.Kelurahan, b.Nama, b.Jenis, b.Kategori, "", b.NamaAsli, "", "", "", ""}
+
+		jalur, ukuran, sha, idWatermark, status := tulisBerkasZIP(c, zw, b)
+		baris[9], baris[11], baris[12], baris[13], baris[14] = jalur, ukuran, sha, status, idWatermark
+		manifest = append(manifest, baris)
+		if status == "terputus" {
+			return
+		}
+
+		c.Writer.Flush()
+	}
+
+	if w, err := zw.Create("manifest.csv"); err == nil {
+		w.Write([]byte("\xEF\xBB\xBF")) // BOM supaya Excel membaca UTF-8 dengan benar
+		cw := csv.NewWriter(w)
+		cw.WriteAll(manifest)
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("Gagal menutup ZIP %s: %v", namaZIP, err)
+	}
+}
+
+// tulisBerkasZIP menyalin satu file ke arsip dan mengembalikan data untuk manifest
+func tulisBerkasZIP(c *gin.Context, zw *zip.Writer, b berkasZIP) (jalur, ukuran, sha, idWatermark, status string) {
+	if !utils.FileAda(b.Path) {
+		return "", "", "", "", "file tidak ditemukan"
+	}
+
+	akses, diizinkan, err := catatAkses(c, b.Tipe, b.ID, b.Path)
+	if err != nil {
+		return "", "", "", "", "dilewati: log akses gagal dicatat (gangguan sistem)"
+	}
+	if !diizinkan {
+		return "", "", "", "", "dilewati: batas unduhan harian tercapai"
+	}
+
+	sumber := b.Path
+	if akses.IDWatermark != "" {
+		salinan, err := salinanWatermark(b.Path, akses)
+		if err != nil {
+			log.Printf("Gagal watermark %s/%d untuk ZIP: %v", b.Tipe, b.ID, err)
+			return "", "", "", "", "dilewati: watermark gagal dibuat"
+		}
+		defer os.Remove(salinan)
+		sumber = salinan
+	}
+
+	f, err := os.Open(sumber)
+	if err != nil {
+		return "", "", "", "", "gagal dibuka"
+	}
+	defer f.Close()
+
+	// PDF/JPG/PNG sudah terkompresi, disimpan apa adanya supaya cepat
+	jalur = b.jalur()
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: jalur, Method: zip.Store, Modified: time.Now()})
+	if err != nil {
+		return "", "", "", "", "gagal ditulis"
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, h), f)
+	if err != nil {
+		log.Printf("ZIP terputus di %s: %v", jalur, err)
+		return jalur, "", "", akses.IDWatermark, "terputus"
+	}
+	return jalur, strconv.FormatInt(n, 10), hex.EncodeToString(h.Sum(nil)), akses.IDWatermark, "ok"
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"go-admin/config"
 	"go-admin/controllers"
+	"go-admin/models"
 	"go-admin/routes"
+	"go-admin/utils"
 	"html/template"
 	"log"
 	"os"
@@ -88,19 +90,25 @@ func main() {
 
 	// load HTML templates + register functions
 	funcMap := template.FuncMap{
-		"add":              add,
-		"sub":              sub,
-		"iter":             iter,
-		"now":              now,
-		"calcPersen":       calcPersen,
-		"calcTotal":        calcTotal,
-		"totalTercapai":    totalTercapai,
-		"totalKeseluruhan": totalKeseluruhan,
-		"isSlice":          isSlice,
-		"hasPrefix":        strings.HasPrefix,
-		"hasSuffix":        strings.HasSuffix,
-		"toJSON":           toJSON,
-		"mod":              mod,
+		"add":                  add,
+		"sub":                  sub,
+		"iter":                 iter,
+		"now":                  now,
+		"calcPersen":           calcPersen,
+		"calcTotal":            calcTotal,
+		"totalTercapai":        totalTercapai,
+		"totalKeseluruhan":     totalKeseluruhan,
+		"isSlice":              isSlice,
+		"hasPrefix":            strings.HasPrefix,
+		"hasSuffix":            strings.HasSuffix,
+		"contains":             strings.Contains,
+		"toJSON":               toJSON,
+		"mod":                  mod,
+		"linkDokumen":          utils.LinkDokumen,
+		"maksUploadMB":         controllers.MaksUploadMB,
+		"nikSamaran":           controllers.NIKSamaran,
+		"labelHasilKonsultasi": models.LabelHasilKonsultasi,
+		"labelHasilMediasi":    models.LabelHasilMediasi,
 	}
 	r.SetFuncMap(funcMap)
 	r.LoadHTMLGlob("templates/*")
@@ -120,15 +128,53 @@ func main() {
 	// ============ CONNECT DATABASE ============
 	config.ConnectDB()
 
+	// ============ PERINTAH CLI ============
+	// go run . reindex-dokumen  -> ekstrak ulang teks semua PDF yang sudah ada
+	// go run . rescan-dokumen   -> pindai ulang seluruh arsip dengan antivirus
+	// go run . verifikasi-ttd   -> periksa ulang tanda tangan elektronik semua PDF (mis. setelah trust store diubah)
+	// go run . bersihkan-upload -> hapus upload resumable yang terbengkalai
+	// go run . migrasi-blob     -> pindahkan file lama uploads/<tipe>/<uuid> ke penyimpanan berbasis hash
+	// go run . periksa-blob     -> hitung ulang hash semua dokumen, tandai yang rusak/hilang
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "reindex-dokumen":
+			controllers.IndeksUlangSemuaDokumen()
+		case "rescan-dokumen":
+			controllers.PindaiUlangSemuaDokumen()
+		case "verifikasi-ttd":
+			controllers.VerifikasiUlangSemuaTTD()
+		case "bersihkan-upload":
+			controllers.BersihkanUploadTerbengkalai()
+		case "migrasi-blob":
+			controllers.MigrasiKeBlob()
+		case "periksa-blob":
+			controllers.PeriksaIntegritasBlob("")
+		default:
+			log.Fatalf("Perintah tidak dikenal: %s", os.Args[1])
+		}
+		return
+	}
+
+	// worker OCR untuk PDF hasil scan (jalan di background, upload tidak menunggu)
+	controllers.MulaiWorkerOCR()
+
+	// upload resumable yang ditinggal (koneksi putus, form tidak jadi dikirim) dibersihkan tiap jam
+	controllers.MulaiPembersihUpload()
+
+	// hash semua dokumen diperiksa ulang berkala untuk mendeteksi file rusak/hilang
+	controllers.MulaiPemeriksaIntegritas()
+
+	// email notifikasi dikirim dari outbox (SMTP_HOST di .env), pengingat SK diperiksa tiap hari
+	controllers.MulaiPengirimEmail()
+
 	// ============ SETUP ROUTES ============
 	// jadi := r.Group("/")
 	{
 		// route app
 		routes.SetupRoutes(r)
 
-		// serve static files & uploads
+		// serve static files (uploads TIDAK diserve langsung, lewat /view-document)
 		r.Static("/static", "./static")
-		r.Static("/uploads", "./uploads")
 	}
 	if err := r.Run("127.0.0.1:8182"); err != nil {
 		log.Fatal("Gagal menjalankan server:", err)
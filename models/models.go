@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -60,62 +62,896 @@ type Kelurahan struct {
 
 // ================= Entity Utama =================
 
+// DataSK adalah metadata Surat Keputusan yang menjadi dasar Posbankum, Kadarkum dan PJA.
+// Di-embed sehingga kolomnya (nomor_sk, tanggal_sk, ...) ada langsung di tabel masing-masing.
+type DataSK struct {
+	NomorSK       string     `gorm:"column:nomor_sk;type:varchar(150)"`
+	TanggalSK     *time.Time `gorm:"column:tanggal_sk;type:date"`
+	PejabatSK     string     `gorm:"column:pejabat_sk;type:varchar(191)"`
+	BerlakuSampai *time.Time `gorm:"type:date;index"` // kosong = berlaku tanpa batas
+}
+
+// Status masa berlaku SK
+const (
+	SKTanpaBatas  = ""
+	SKBerlaku     = "berlaku"
+	SKSegeraHabis = "segera"
+	SKKedaluwarsa = "kedaluwarsa"
+)
+
+// StatusSK menghitung status masa berlaku terhadap hari ini dengan ambang peringatan sekian hari
+func (d DataSK) StatusSK(hariPeringatan int) string {
+	if d.BerlakuSampai == nil {
+		return SKTanpaBatas
+	}
+	now := time.Now()
+	hariIni := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	sampai := time.Date(d.BerlakuSampai.Year(), d.BerlakuSampai.Month(), d.BerlakuSampai.Day(), 0, 0, 0, 0, time.Local)
+	switch {
+	case sampai.Before(hariIni):
+		return SKKedaluwarsa
+	case !sampai.After(hariIni.AddDate(0, 0, hariPeringatan)):
+		return SKSegeraHabis
+	default:
+		return SKBerlaku
+	}
+}
+
+// Status alur verifikasi data program
+const (
+	StatusDraf          = "draf"
+	StatusDiajukan      = "diajukan"
+	StatusTerverifikasi = "terverifikasi"
+	StatusDitolak       = "ditolak"
+)
+
+// Verifikasi adalah status alur verifikasi: operator kabupaten/kota mengisi lalu mengajukan,
+// verifikator Kanwil menyetujui atau menolak. Di-embed di Posbankum, Kadarkum, PJA dan Paralegal.
+// Bawaan kolom "terverifikasi" supaya data yang sudah ada sebelum alur ini tetap terhitung.
+type Verifikasi struct {
+	StatusVerifikasi  string `gorm:"type:varchar(20);not null;default:'terverifikasi';index"`
+	CatatanVerifikasi string `gorm:"type:text"` // komentar verifikator terakhir
+	DiverifikasiOleh  string `gorm:"type:varchar(191)"`
+	DiverifikasiAt    *time.Time
+}
+
+// HariLayanan adalah kode hari yang bisa dipilih untuk jadwal layanan Posbankum, urut Senin-Minggu
+var HariLayanan = []string{"senin", "selasa", "rabu", "kamis", "jumat", "sabtu", "minggu"}
+
+// ProfilPosbankum adalah lokasi, kontak dan jadwal layanan Posbankum untuk warga.
+// Di-embed di Posbankum; semua kolom opsional supaya data lama tetap valid.
+type ProfilPosbankum struct {
+	Alamat          string     `gorm:"type:text"`
+	Telepon         string     `gorm:"type:varchar(20)"` // dinormalisasi ke 08xxx, lihat utils.NormalisasiTelepon
+	WhatsApp        string     `gorm:"column:whatsapp;type:varchar(20)"`
+	PenanggungJawab string     `gorm:"type:varchar(191)"`
+	HariLayanan     string     `gorm:"type:varchar(100)"` // kode hari dipisah koma, mis. "senin,selasa"
+	JamBuka         string     `gorm:"type:varchar(5)"`   // HH:MM
+	JamTutup        string     `gorm:"type:varchar(5)"`
+	Latitude        *float64   `gorm:"type:decimal(10,7)"`
+	Longitude       *float64   `gorm:"type:decimal(10,7)"`
+	Foto            string     `gorm:"type:text"` // path foto di penyimpanan blob
+	TanggalBerdiri  *time.Time `gorm:"type:date"`
+}
+
+// PilihanHari untuk checkbox hari layanan di form
+type PilihanHari struct {
+	Kode    string
+	Label   string
+	Dipilih bool
+}
+
+// DaftarHari mengembalikan semua hari beserta tanda dipilih atau tidak
+func (p ProfilPosbankum) DaftarHari() []PilihanHari {
+	dipilih := map[string]bool{}
+	for _, h := range strings.Split(p.HariLayanan, ",") {
+		dipilih[h] = true
+	}
+	hasil := make([]PilihanHari, len(HariLayanan))
+	for i, h := range HariLayanan {
+		hasil[i] = PilihanHari{Kode: h, Label: strings.ToUpper(h[:1]) + h[1:], Dipilih: dipilih[h]}
+	}
+	return hasil
+}
+
+// JadwalLayanan meringkas hari dan jam layanan, mis. "Senin, Selasa, Rabu 08:00-15:00"
+func (p ProfilPosbankum) JadwalLayanan() string {
+	var hari []string
+	for _, h := range p.DaftarHari() {
+		if h.Dipilih {
+			hari = append(hari, h.Label)
+		}
+	}
+	jadwal := strings.Join(hari, ", ")
+	if p.JamBuka != "" && p.JamTutup != "" {
+		jadwal = strings.TrimSpace(jadwal + " " + p.JamBuka + "-" + p.JamTutup)
+	}
+	return jadwal
+}
+
+// AdaLokasi -> true kalau koordinat GPS sudah diisi
+func (p ProfilPosbankum) AdaLokasi() bool {
+	return p.Latitude != nil && p.Longitude != nil
+}
+
+// LinkWhatsApp adalah tautan wa.me untuk nomor WhatsApp (kosong kalau tidak ada)
+func (p ProfilPosbankum) LinkWhatsApp() string {
+	if p.WhatsApp == "" {
+		return ""
+	}
+	return "https://wa.me/62" + strings.TrimPrefix(p.WhatsApp, "0")
+}
+
 // Posbankum
 type Posbankum struct {
-	ID          uint   `gorm:"primaryKey"`
-	KelurahanID uint   `gorm:"not null"`
-	Dokumen     string `gorm:"type:text;not null"`
-	Catatan     string `gorm:"type:text"`
-	CreatedAt   *time.Time
-	UpdatedAt   *time.Time
+	ID              uint   `gorm:"primaryKey"`
+	KelurahanID     uint   `gorm:"not null"`
+	Dokumen         string `gorm:"type:text;not null"`
+	DokumenPublik   bool   `gorm:"not null;default:false"` // boleh dibuka dari halaman publik
+	Catatan         string `gorm:"type:text"`
+	DataSK          `gorm:"embedded"`
+	Verifikasi      `gorm:"embedded"`
+	ProfilPosbankum `gorm:"embedded"`
+	CreatedAt       *time.Time
+	UpdatedAt       *time.Time
 
 	Kelurahan  Kelurahan
 	Paralegals []Paralegal `gorm:"foreignKey:PosbankumID"`
+	Lampirans  []Lampiran  `gorm:"polymorphic:Entitas;polymorphicValue:posbankum"`
+}
+
+// JenjangPendidikan untuk pilihan pendidikan terakhir paralegal
+var JenjangPendidikan = []string{"SD", "SMP", "SMA/SMK", "D3", "S1/D4", "S2", "S3"}
+
+// ProfilParalegal adalah identitas dan kontak paralegal. Di-embed di Paralegal;
+// kolom opsional di database supaya data lama tetap valid, kewajiban NIK dicek di form.
+type ProfilParalegal struct {
+	NIK            string     `gorm:"-"`                                         // NIK terbuka, hanya dipakai saat mengisi form
+	NIKTerenkripsi string     `gorm:"column:nik_terenkripsi;type:text"`          // AES-GCM, lihat utils.EnkripsiNIK
+	NIKHash        *string    `gorm:"column:nik_hash;type:char(64);uniqueIndex"` // indeks buta untuk cek duplikasi, NULL untuk data lama
+	JenisKelamin   string     `gorm:"type:varchar(1)"`                           // "L" atau "P"
+	TanggalLahir   *time.Time `gorm:"type:date"`
+	Pendidikan     string     `gorm:"type:varchar(20)"`
+	Telepon        string     `gorm:"type:varchar(20)"` // dinormalisasi ke 08xxx, lihat utils.NormalisasiTelepon
+	Alamat         string     `gorm:"type:text"`
+	Foto           string     `gorm:"type:text"` // path foto di penyimpanan blob
+}
+
+// LabelJenisKelamin -> "Laki-laki"/"Perempuan" (kosong kalau belum diisi)
+func (p ProfilParalegal) LabelJenisKelamin() string {
+	switch p.JenisKelamin {
+	case "L":
+		return "Laki-laki"
+	case "P":
+		return "Perempuan"
+	}
+	return ""
+}
+
+// PilihanPendidikan untuk dropdown pendidikan terakhir di form
+func (p ProfilParalegal) PilihanPendidikan() []string {
+	return JenjangPendidikan
+}
+
+// Umur dalam tahun pada tanggal acuan; -1 kalau tanggal lahir belum diisi
+func (p ProfilParalegal) Umur(pada time.Time) int {
+	if p.TanggalLahir == nil {
+		return -1
+	}
+	lahir := *p.TanggalLahir
+	umur := pada.Year() - lahir.Year()
+	if pada.Month() < lahir.Month() || (pada.Month() == lahir.Month() && pada.Day() < lahir.Day()) {
+		umur--
+	}
+	return umur
 }
 
 // Paralegal
 type Paralegal struct {
-	ID          uint   `gorm:"primaryKey"`
-	PosbankumID uint   `gorm:"not null"`
-	Nama        string `gorm:"not null"`
-	Dokumen     string `gorm:"type:text"`
-	CreatedAt   *time.Time
-	UpdatedAt   *time.Time
+	ID              uint   `gorm:"primaryKey"`
+	PosbankumID     uint   `gorm:"not null"`
+	Nama            string `gorm:"not null"`
+	Dokumen         string `gorm:"type:text"`
+	DokumenPublik   bool   `gorm:"not null;default:false"` // boleh dibuka dari halaman publik
+	Verifikasi      `gorm:"embedded"`
+	ProfilParalegal `gorm:"embedded"`
+	CreatedAt       *time.Time
+	UpdatedAt       *time.Time
 
 	Posbankum Posbankum
+	Lampirans []Lampiran `gorm:"polymorphic:Entitas;polymorphicValue:paralegal"`
 }
 
 // PJA
 type Pja struct {
-	ID          uint   `gorm:"primaryKey"`
-	KelurahanID uint   `gorm:"not null"`
-	Dokumen     string `gorm:"type:text;not null"`
-	Catatan     string `gorm:"type:text"`
-	CreatedAt   *time.Time
-	UpdatedAt   *time.Time
+	ID            uint   `gorm:"primaryKey"`
+	KelurahanID   uint   `gorm:"not null"`
+	Dokumen       string `gorm:"type:text;not null"`
+	DokumenPublik bool   `gorm:"not null;default:false"` // boleh dibuka dari halaman publik
+	Catatan       string `gorm:"type:text"`
+	DataSK        `gorm:"embedded"`
+	Verifikasi    `gorm:"embedded"`
+	// hasil penghargaan PJA terakhir, diisi saat periode penilaian ditetapkan
+	HasilPenghargaan string  `gorm:"type:varchar(20)"`
+	TahunPenghargaan int     `gorm:"not null;default:0"`
+	NilaiPenghargaan float64 `gorm:"not null;default:0"`
+	CreatedAt        *time.Time
+	UpdatedAt        *time.Time
 
 	Kelurahan Kelurahan
+	Lampirans []Lampiran `gorm:"polymorphic:Entitas;polymorphicValue:pja"`
 }
 
 // Kadarkum
 type Kadarkum struct {
-	ID          uint   `gorm:"primaryKey"`
-	KelurahanID uint   `gorm:"not null"`
-	Dokumen     string `gorm:"type:text;not null"`
-	Catatan     string `gorm:"type:text"`
-	CreatedAt   *time.Time
-	UpdatedAt   *time.Time
+	ID            uint   `gorm:"primaryKey"`
+	KelurahanID   uint   `gorm:"not null"`
+	Dokumen       string `gorm:"type:text;not null"`
+	DokumenPublik bool   `gorm:"not null;default:false"` // boleh dibuka dari halaman publik
+	Catatan       string `gorm:"type:text"`
+	DataSK        `gorm:"embedded"`
+	Verifikasi    `gorm:"embedded"`
+	CreatedAt     *time.Time
+	UpdatedAt     *time.Time
 
 	Kelurahan Kelurahan
+	Lampirans []Lampiran `gorm:"polymorphic:Entitas;polymorphicValue:kadarkum"`
 }
 
 // ================= Auth =================
 
 // User
 type User struct {
+	ID          uint   `gorm:"primaryKey"`
+	Username    string `gorm:"unique;not null"`
+	Password    string `gorm:"not null"`
+	Role        string `gorm:"type:enum('admin','verifikator','operator','user');default:'user'"`
+	Sumber      string `gorm:"type:varchar(20);not null;default:'lokal'"` // lokal, oidc, ldap
+	ExternalID  string `gorm:"type:varchar(191);index"`                   // subject OIDC / DN LDAP
+	Email       string `gorm:"type:varchar(191)"`
+	KabupatenID *uint  // wilayah kerja role operator
+	CreatedAt   *time.Time
+
+	Kabupaten *Kabupaten
+}
+
+// ================= Lampiran =================
+
+// KategoriLampiran adalah jenis lampiran yang diatur admin (SK, foto, laporan kegiatan, sertifikat, ...)
+type KategoriLampiran struct {
+	ID            uint   `gorm:"primaryKey"`
+	Nama          string `gorm:"type:varchar(100);unique;not null"`
+	TipeDiizinkan string `gorm:"type:varchar(100);not null;default:'pdf'"` // dipisah koma: pdf,jpg,png
+	MaksUkuranMB  int    `gorm:"not null;default:10"`
+	Urutan        int    `gorm:"not null;default:0"`
+	Watermark     bool   `gorm:"not null;default:false"` // PDF kategori ini diberi watermark nama pembuka
+	CreatedAt     *time.Time
+	UpdatedAt     *time.Time
+}
+
+// Lampiran adalah file tambahan milik Posbankum, Kadarkum, PJA, atau Paralegal
+type Lampiran struct {
+	ID          uint   `gorm:"primaryKey"`
+	EntitasType string `gorm:"type:varchar(30);not null;index:idx_lampiran_entitas"` // posbankum, kadarkum, pja, paralegal
+	EntitasID   uint   `gorm:"not null;index:idx_lampiran_entitas"`
+	KategoriID  uint   `gorm:"not null"`
+	Path        string `gorm:"type:text;not null"`
+	NamaAsli    string `gorm:"type:varchar(255)"`
+	ContentType string `gorm:"type:varchar(100)"`
+	Ukuran      int64
+	Urutan      int    `gorm:"not null;default:0"`
+	Keterangan  string `gorm:"type:text"`
+	CreatedAt   *time.Time
+	UpdatedAt   *time.Time
+
+	Kategori KategoriLampiran `gorm:"foreignKey:KategoriID"`
+}
+
+// ================= Log Akses Dokumen =================
+
+// AksesDokumen mencatat setiap kali dokumen dibuka/diunduh
+type AksesDokumen struct {
+	ID          uint       `gorm:"primaryKey"`
+	Tipe        string     `gorm:"type:varchar(30);not null;index:idx_akses_entitas"` // posbankum, kadarkum, pja, paralegal
+	EntitasID   uint       `gorm:"not null;index:idx_akses_entitas"`
+	Versi       string     `gorm:"type:varchar(255)"`       // nama file yang dibuka saat itu
+	Username    string     `gorm:"type:varchar(191);index"` // kosong = pengunjung anonim
+	IP          string     `gorm:"type:varchar(64)"`
+	UserAgent   string     `gorm:"type:text"`
+	Diizinkan   bool       `gorm:"not null;default:true"`  // false kalau ditolak karena batas unduhan
+	IDWatermark string     `gorm:"type:varchar(36);index"` // ID yang dicetak di watermark PDF, kosong kalau tanpa watermark
+	CreatedAt   *time.Time `gorm:"index"`
+}
+
+// BatasAksesDokumen membatasi jumlah unduhan per user (atau per IP untuk anonim) per hari
+type BatasAksesDokumen struct {
+	ID          uint   `gorm:"primaryKey"`
+	Tipe        string `gorm:"type:varchar(30);not null;uniqueIndex:idx_batas_entitas"`
+	EntitasID   uint   `gorm:"not null;uniqueIndex:idx_batas_entitas"`
+	MaksPerHari int    `gorm:"not null"`
+	Keterangan  string `gorm:"type:text"`
+	CreatedAt   *time.Time
+	UpdatedAt   *time.Time
+}
+
+// ================= Indeks Teks Dokumen =================
+
+// DokumenTeks menyimpan teks hasil ekstraksi PDF per halaman untuk pencarian full-text
+type DokumenTeks struct {
+	ID        uint    `gorm:"primaryKey"`
+	Tipe      string  `gorm:"type:varchar(30);not null;index:idx_teks_entitas"` // posbankum, kadarkum, pja, paralegal, lampiran
+	EntitasID uint    `gorm:"not null;index:idx_teks_entitas"`
+	Halaman   int     `gorm:"not null"`
+	Isi       string  `gorm:"type:longtext;index:idx_teks_isi,class:FULLTEXT"`
+	Sumber    string  `gorm:"type:varchar(10);not null;default:'pdf'"` // pdf (text layer) atau ocr
+	Keyakinan float64 // rata-rata confidence OCR 0-100, 0 untuk teks asli PDF
+	CreatedAt *time.Time
+}
+
+// Status job OCR
+const (
+	OCRMenunggu = "menunggu"
+	OCRProses   = "proses"
+	OCRSelesai  = "selesai"
+	OCRGagal    = "gagal"
+)
+
+// OCRJob adalah antrean OCR untuk PDF hasil scan yang tidak punya text layer
+type OCRJob struct {
+	ID            uint    `gorm:"primaryKey"`
+	Tipe          string  `gorm:"type:varchar(30);not null;uniqueIndex:idx_ocr_entitas"`
+	EntitasID     uint    `gorm:"not null;uniqueIndex:idx_ocr_entitas"`
+	Path          string  `gorm:"type:text;not null"`
+	Status        string  `gorm:"type:varchar(20);not null;default:'menunggu';index"`
+	Percobaan     int     `gorm:"not null;default:0"`
+	Pesan         string  `gorm:"type:text"`
+	JumlahHalaman int     `gorm:"not null;default:0"`
+	RataKeyakinan float64 `gorm:"not null;default:0"`
+	SelesaiAt     *time.Time
+	CreatedAt     *time.Time
+	UpdatedAt     *time.Time
+}
+
+// ================= Karantina =================
+
+// Karantina mencatat file yang terdeteksi malware, filenya dipindah ke folder karantina
+type Karantina struct {
+	ID        uint   `gorm:"primaryKey"`
+	Tipe      string `gorm:"type:varchar(30);not null"` // posbankum, kadarkum, pja, paralegal, lampiran
+	EntitasID uint   // 0 kalau terdeteksi saat tambah data baru
+	NamaAsli  string `gorm:"type:varchar(255)"`
+	Path      string `gorm:"type:text;not null"` // lokasi file di folder karantina
+	Virus     string `gorm:"type:varchar(255)"`
+	Sumber    string `gorm:"type:varchar(20);not null"` // upload atau pindai-ulang
+	Username  string `gorm:"type:varchar(191)"`
+	IP        string `gorm:"type:varchar(64)"`
+	Ditinjau  bool   `gorm:"not null;default:false;index"`
+	CreatedAt *time.Time
+}
+
+// ================= Tanda Tangan Elektronik =================
+
+// TandaTanganDokumen menyimpan hasil verifikasi tanda tangan elektronik (mis. BSrE) satu dokumen PDF
+type TandaTanganDokumen struct {
+	ID            uint   `gorm:"primaryKey"`
+	Tipe          string `gorm:"type:varchar(30);not null;uniqueIndex:idx_ttd_entitas"` // posbankum, kadarkum, pja, paralegal, lampiran
+	EntitasID     uint   `gorm:"not null;uniqueIndex:idx_ttd_entitas"`
+	Path          string `gorm:"type:text;not null"`              // file yang diperiksa
+	Status        string `gorm:"type:varchar(20);not null;index"` // valid, belum-cek-cabut, kedaluwarsa, tidak-valid, tanpa-ttd, diubah
+	Penandatangan string `gorm:"type:varchar(255)"`
+	Penerbit      string `gorm:"type:varchar(255)"`
+	WaktuTTD      *time.Time
+	JumlahTTD     int    `gorm:"not null;default:0"`
+	Pesan         string `gorm:"type:text"`
+	CreatedAt     *time.Time
+	UpdatedAt     *time.Time
+}
+
+// ================= Penyimpanan Dokumen (blob) =================
+
+// Status integritas blob
+const (
+	BlobOK     = "ok"
+	BlobRusak  = "rusak"  // isi file tidak cocok lagi dengan hash-nya
+	BlobHilang = "hilang" // file tidak ada di disk
+)
+
+// BlobDokumen adalah satu file unik di uploads/blob yang dinamai sesuai SHA-256 isinya.
+// File yang sama (mis. satu SK untuk banyak kelurahan) hanya disimpan sekali dan dipakai banyak record.
+type BlobDokumen struct {
+	SHA256      string `gorm:"type:char(64);primaryKey"`
+	Path        string `gorm:"type:varchar(255);uniqueIndex;not null"`
+	Ukuran      int64  `gorm:"not null"`
+	ContentType string `gorm:"type:varchar(100)"`
+	JumlahRef   int    `gorm:"not null;default:0"` // jumlah record yang memakai file ini, dihitung ulang tiap ada perubahan
+	Status      string `gorm:"type:varchar(20);not null;default:'ok';index"`
+	Pesan       string `gorm:"type:text"`
+	DiperiksaAt *time.Time
+	CreatedAt   *time.Time
+	UpdatedAt   *time.Time
+}
+
+// ================= Upload Resumable =================
+
+// UploadResumable adalah upload dokumen yang dikirim bertahap (protokol tus), filenya di folder sementara
+// sampai dipakai oleh form. Upload yang tidak selesai/tidak dipakai dibersihkan otomatis.
+type UploadResumable struct {
+	ID        string `gorm:"type:varchar(36);primaryKey"` // uuid, juga dipakai di URL upload
+	Tipe      string `gorm:"type:varchar(30);not null"`   // posbankum, kadarkum, pja, paralegal
+	NamaAsli  string `gorm:"type:varchar(255)"`
+	Ukuran    int64  `gorm:"not null"`           // total ukuran file (Upload-Length)
+	Offset    int64  `gorm:"not null;default:0"` // byte yang sudah diterima
+	Path      string `gorm:"type:text;not null"`
+	Username  string `gorm:"type:varchar(191);not null"`
+	Selesai   bool   `gorm:"not null;default:false"`
+	CreatedAt *time.Time
+	UpdatedAt *time.Time `gorm:"index"`
+}
+
+// ================= Riwayat Verifikasi =================
+
+// RiwayatVerifikasi mencatat setiap perpindahan status verifikasi sebuah record
+type RiwayatVerifikasi struct {
+	ID        uint       `gorm:"primaryKey"`
+	Tipe      string     `gorm:"type:varchar(30);not null;index:idx_riwayat_entitas"` // posbankum, kadarkum, pja, paralegal
+	EntitasID uint       `gorm:"not null;index:idx_riwayat_entitas"`
+	Dari      string     `gorm:"type:varchar(20)"` // kosong = record baru dibuat
+	Ke        string     `gorm:"type:varchar(20);not null"`
+	Komentar  string     `gorm:"type:text"`
+	Username  string     `gorm:"type:varchar(191)"`
+	CreatedAt *time.Time `gorm:"index"`
+}
+
+// ================= Email Notifikasi =================
+
+// Jenis email notifikasi
+const (
+	EmailDiajukan      = "data_diajukan"      // ke verifikator: ada data baru diajukan
+	EmailDitolak       = "data_ditolak"       // ke pengaju: data ditolak beserta catatan
+	EmailTerverifikasi = "data_terverifikasi" // ke pengaju: data disetujui
+	EmailSKBerakhir    = "sk_berakhir"        // ke operator wilayah & admin: SK segera/sudah berakhir
+	EmailAkunBaru      = "akun_baru"          // ke pemilik akun: akun baru dibuat
+	EmailPekerjaan     = "pekerjaan"          // ke admin: pekerjaan background selesai/gagal (integritas, OCR)
+	EmailKomentar      = "komentar"           // ke user yang disebut (@username) atau dibalas di diskusi record
+	EmailMalware       = "malware"            // ke admin: file terdeteksi malware dan dikarantina
+)
+
+// Status pengiriman email di outbox
+const (
+	EmailAntre    = "antre"
+	EmailTerkirim = "terkirim"
+	EmailGagal    = "gagal" // percobaan habis, bisa dikirim ulang manual dari halaman admin
+)
+
+// EmailOutbox adalah email yang menunggu/selesai dikirim. Ditulis di transaksi yang sama dengan
+// perubahan datanya, lalu dikirim worker di background dengan percobaan ulang.
+type EmailOutbox struct {
+	ID         uint      `gorm:"primaryKey"`
+	UserID     *uint     `gorm:"index"`
+	Ke         string    `gorm:"type:varchar(191);not null"`
+	Jenis      string    `gorm:"type:varchar(30);not null;index"`
+	Kunci      string    `gorm:"type:varchar(191);index"` // pencegah email ganda untuk event yang sama (mis. pengingat SK)
+	Subjek     string    `gorm:"type:varchar(255);not null"`
+	Isi        string    `gorm:"type:text;not null"`
+	Status     string    `gorm:"type:varchar(20);not null;default:'antre';index:idx_outbox_antrean"`
+	Percobaan  int       `gorm:"not null;default:0"`
+	PesanError string    `gorm:"type:text"`
+	KirimAt    time.Time `gorm:"not null;index:idx_outbox_antrean"` // jadwal percobaan berikutnya
+	TerkirimAt *time.Time
+	CreatedAt  *time.Time `gorm:"index"`
+	UpdatedAt  *time.Time
+}
+
+// PreferensiEmail menyimpan pilihan user per jenis email; tidak ada baris = aktif
+type PreferensiEmail struct {
+	ID     uint   `gorm:"primaryKey"`
+	UserID uint   `gorm:"not null;uniqueIndex:idx_preferensi_email"`
+	Jenis  string `gorm:"type:varchar(30);not null;uniqueIndex:idx_preferensi_email"`
+	Aktif  bool   `gorm:"not null;default:true"`
+}
+
+// ================= Notifikasi =================
+
+// Notifikasi tampil di lonceng & halaman Notifikasi user, jenisnya sama dengan jenis email
+type Notifikasi struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"not null;index:idx_notifikasi_user"`
+	Jenis     string     `gorm:"type:varchar(30);not null"`
+	Kunci     string     `gorm:"type:varchar(191);index"` // pencegah notifikasi ganda, sama seperti EmailOutbox
+	Judul     string     `gorm:"type:varchar(255);not null"`
+	Pesan     string     `gorm:"type:text"`
+	Tautan    string     `gorm:"type:varchar(255)"` // path di aplikasi, mis. /admin/verifikasi
+	DibacaAt  *time.Time `gorm:"index:idx_notifikasi_user"`
+	CreatedAt *time.Time
+}
+
+// ================= Diskusi =================
+
+// Komentar adalah satu pesan di diskusi sebuah record program. Komentar tidak bisa diubah atau dihapus
+// supaya tetap menjadi jejak audit; balasan menunjuk komentar induknya (satu tingkat).
+type Komentar struct {
+	ID                  uint   `gorm:"primaryKey"`
+	Tipe                string `gorm:"type:varchar(30);not null;index:idx_komentar_entitas"` // posbankum, kadarkum, pja, paralegal
+	EntitasID           uint   `gorm:"not null;index:idx_komentar_entitas"`
+	IndukID             *uint  `gorm:"index"`
+	Username            string `gorm:"type:varchar(191);not null"`
+	Isi                 string `gorm:"type:text;not null"`
+	Disebut             string `gorm:"type:text"` // username yang disebut, dipisah koma
+	LampiranPath        string `gorm:"type:text"`
+	LampiranNama        string `gorm:"type:varchar(255)"`
+	LampiranContentType string `gorm:"type:varchar(100)"`
+	LampiranUkuran      int64
+	CreatedAt           *time.Time `gorm:"index"`
+}
+
+// ================= Pelatihan & Sertifikasi Paralegal =================
+
+// Jenjang kurikulum pelatihan paralegal (juga jenjang sertifikatnya)
+const (
+	JenjangDasar    = "dasar"
+	JenjangLanjutan = "lanjutan"
+	JenjangTematik  = "tematik"
+)
+
+// JenjangPelatihan untuk pilihan di form dan filter
+var JenjangPelatihan = []string{JenjangDasar, JenjangLanjutan, JenjangTematik}
+
+// Status peserta pelatihan
+const (
+	PesertaTerdaftar  = "terdaftar"
+	PesertaLulus      = "lulus"
+	PesertaTidakLulus = "tidak_lulus"
+)
+
+// Pelatihan adalah satu kegiatan Pelatihan Paralegal terakreditasi
+type Pelatihan struct {
+	ID             uint       `gorm:"primaryKey"`
+	Judul          string     `gorm:"type:varchar(191);not null"`
+	Penyelenggara  string     `gorm:"type:varchar(191);not null"`
+	Jenjang        string     `gorm:"type:varchar(20);not null;index"`
+	TanggalMulai   *time.Time `gorm:"type:date;not null;index"`
+	TanggalSelesai *time.Time `gorm:"type:date"`
+	Lokasi         string     `gorm:"type:varchar(255)"`
+	Keterangan     string     `gorm:"type:text"`
+	CreatedAt      *time.Time
+	UpdatedAt      *time.Time
+
+	Pesertas []PesertaPelatihan
+}
+
+// PesertaPelatihan adalah paralegal yang didaftarkan ke sebuah pelatihan
+type PesertaPelatihan struct {
+	ID          uint   `gorm:"primaryKey"`
+	PelatihanID uint   `gorm:"not null;uniqueIndex:idx_peserta_pelatihan"`
+	ParalegalID uint   `gorm:"not null;uniqueIndex:idx_peserta_pelatihan;index"`
+	Status      string `gorm:"type:varchar(20);not null;default:terdaftar"`
+	Didaftarkan string `gorm:"type:varchar(191)"` // username yang mendaftarkan
+	CreatedAt   *time.Time
+	UpdatedAt   *time.Time
+
+	Pelatihan Pelatihan
+	Paralegal Paralegal
+}
+
+// LabelStatus untuk ditampilkan di tabel peserta
+func (p PesertaPelatihan) LabelStatus() string {
+	switch p.Status {
+	case PesertaLulus:
+		return "Lulus"
+	case PesertaTidakLulus:
+		return "Tidak lulus"
+	default:
+		return "Terdaftar"
+	}
+}
+
+// SertifikatParalegal adalah sertifikat pelatihan yang dimiliki paralegal; PelatihanID kosong
+// untuk sertifikat dari pelatihan yang tidak tercatat di aplikasi.
+type SertifikatParalegal struct {
+	ID            uint       `gorm:"primaryKey"`
+	ParalegalID   uint       `gorm:"not null;index"`
+	PelatihanID   *uint      `gorm:"index"`
+	Jenjang       string     `gorm:"type:varchar(20);not null;index"`
+	Nomor         string     `gorm:"type:varchar(100);not null;uniqueIndex"`
+	Penerbit      string     `gorm:"type:varchar(191)"`
+	TanggalTerbit *time.Time `gorm:"type:date;not null"`
+	BerlakuSampai *time.Time `gorm:"type:date;index"` // kosong = berlaku tanpa batas
+	CreatedAt     *time.Time
+	UpdatedAt     *time.Time
+
+	Paralegal Paralegal
+	Pelatihan *Pelatihan
+}
+
+// Status masa berlaku sertifikat, memakai aturan yang sama dengan SK
+func (s SertifikatParalegal) Status(hariPeringatan int) string {
+	return DataSK{BerlakuSampai: s.BerlakuSampai}.StatusSK(hariPeringatan)
+}
+
+// ================= Konsultasi Hukum =================
+
+// KategoriKonsultasi untuk pilihan kategori perkara di register konsultasi
+var KategoriKonsultasi = []string{"Keluarga", "Waris", "Pertanahan", "Pidana", "Perdata", "KDRT",
+	"Ketenagakerjaan", "Administrasi Kependudukan", "Lainnya"}
+
+// Hasil layanan konsultasi
+const (
+	HasilSelesai = "selesai" // nasihat hukum diberikan dan masalah tuntas
+	HasilMediasi = "mediasi" // diselesaikan lewat mediasi/perdamaian
+	HasilDirujuk = "dirujuk" // dirujuk ke Organisasi Bantuan Hukum (OBH) atau instansi lain
+	HasilProses  = "proses"  // masih ditangani, perlu tindak lanjut
+)
+
+// HasilKonsultasi untuk pilihan hasil di form dan filter
+var HasilKonsultasi = []string{HasilProses, HasilSelesai, HasilMediasi, HasilDirujuk}
+
+// LabelHasilKonsultasi untuk ditampilkan di tabel dan laporan
+func LabelHasilKonsultasi(hasil string) string {
+	switch hasil {
+	case HasilSelesai:
+		return "Selesai"
+	case HasilMediasi:
+		return "Mediasi"
+	case HasilDirujuk:
+		return "Dirujuk"
+	default:
+		return "Dalam proses"
+	}
+}
+
+// Konsultasi adalah satu layanan konsultasi hukum di Posbankum. Identitas klien hanya disimpan
+// kalau klien setuju; tanpa persetujuan klien dicatat anonim (jenis kelamin saja).
+type Konsultasi struct {
+	ID                uint       `gorm:"primaryKey"`
+	PosbankumID       uint       `gorm:"not null;index:idx_konsultasi_posbankum"`
+	ParalegalID       *uint      `gorm:"index"` // paralegal yang menangani, boleh kosong
+	Tanggal           *time.Time `gorm:"type:date;not null;index:idx_konsultasi_posbankum"`
+	PersetujuanKlien  bool       `gorm:"not null;default:false"`
+	NamaKlien         string     `gorm:"type:varchar(191)"`
+	KontakKlien       string     `gorm:"type:varchar(20)"`
+	JenisKelaminKlien string     `gorm:"type:varchar(1)"` // "L" atau "P"
+	Kategori          string     `gorm:"type:varchar(50);not null;index"`
+	Uraian            string     `gorm:"type:text"`
+	Hasil             string     `gorm:"type:varchar(20);not null;default:proses;index"`
+	Rujukan           string     `gorm:"type:varchar(191)"` // OBH/instansi tujuan rujukan
+	Dicatat           string     `gorm:"type:varchar(191)"` // username yang mencatat
+	CreatedAt         *time.Time
+	UpdatedAt         *time.Time
+
+	Posbankum     Posbankum
+	Paralegal     *Paralegal
+	TindakLanjuts []TindakLanjutKonsultasi
+}
+
+// KodeKlien adalah nomor register yang dipakai sebagai pengganti nama klien anonim, mis. K-000123
+func (k Konsultasi) KodeKlien() string {
+	return fmt.Sprintf("K-%06d", k.ID)
+}
+
+// LabelHasil -> lihat LabelHasilKonsultasi
+func (k Konsultasi) LabelHasil() string {
+	return LabelHasilKonsultasi(k.Hasil)
+}
+
+// TindakLanjutKonsultasi adalah catatan perkembangan sebuah konsultasi (mis. hasil rujukan)
+type TindakLanjutKonsultasi struct {
+	ID           uint       `gorm:"primaryKey"`
+	KonsultasiID uint       `gorm:"not null;index"`
+	Tanggal      *time.Time `gorm:"type:date;not null"`
+	Catatan      string     `gorm:"type:text;not null"`
+	Username     string     `gorm:"type:varchar(191)"`
+	CreatedAt    *time.Time
+}
+
+// ================= Mediasi Desa & Penghargaan PJA =================
+
+// JenisSengketa untuk pilihan jenis perkara di log mediasi kepala desa/lurah
+var JenisSengketa = []string{"Tanah/Batas Lahan", "Waris", "Keluarga/Perkawinan", "Utang Piutang",
+	"Penganiayaan Ringan", "Ketertiban Lingkungan", "Lainnya"}
+
+// Hasil mediasi perkara di desa/kelurahan
+const (
+	MediasiProses = "proses" // mediasi masih berjalan
+	MediasiDamai  = "damai"  // para pihak sepakat berdamai
+	MediasiGagal  = "gagal"  // tidak tercapai kesepakatan
+)
+
+// HasilPerdamaian untuk pilihan hasil di form dan filter
+var HasilPerdamaian = []string{MediasiProses, MediasiDamai, MediasiGagal}
+
+// LabelHasilMediasi untuk ditampilkan di tabel dan dosier
+func LabelHasilMediasi(hasil string) string {
+	switch hasil {
+	case MediasiDamai:
+		return "Damai"
+	case MediasiGagal:
+		return "Tidak sepakat"
+	default:
+		return "Dalam proses"
+	}
+}
+
+// Mediasi adalah satu perkara yang diselesaikan kepala desa/lurah di luar pengadilan (non-litigasi).
+// Kasus-kasus ini menjadi bahan dosier pengusulan Peacemaker Justice Award (PJA).
+type Mediasi struct {
+	ID                 uint       `gorm:"primaryKey"`
+	KelurahanID        uint       `gorm:"not null;index:idx_mediasi_kelurahan"`
+	Mediator           string     `gorm:"type:varchar(191);not null"` // kepala desa/lurah yang memediasi
+	PihakPertama       string     `gorm:"type:varchar(191);not null"`
+	PihakKedua         string     `gorm:"type:varchar(191);not null"`
+	JenisSengketa      string     `gorm:"type:varchar(50);not null;index"`
+	Uraian             string     `gorm:"type:text"`
+	TanggalMulai       *time.Time `gorm:"type:date;not null;index:idx_mediasi_kelurahan"`
+	TanggalSelesai     *time.Time `gorm:"type:date"`
+	Hasil              string     `gorm:"type:varchar(20);not null;default:proses;index"`
+	DokumenKesepakatan string     `gorm:"type:varchar(255)"` // PDF akta/berita acara perdamaian
+	Dicatat            string     `gorm:"type:varchar(191)"` // username yang mencatat
+	CreatedAt          *time.Time
+	UpdatedAt          *time.Time
+
+	Kelurahan Kelurahan
+}
+
+// LabelHasil -> lihat LabelHasilMediasi
+func (m Mediasi) LabelHasil() string {
+	return LabelHasilMediasi(m.Hasil)
+}
+
+// Tahap putaran penghargaan PJA
+const (
+	PeriodePJADibuka    = "dibuka"    // kelurahan/desa bisa diusulkan
+	PeriodePJAPenilaian = "penilaian" // juri memberi nilai, usulan ditutup
+	PeriodePJASelesai   = "selesai"   // hasil sudah ditetapkan dan dicatat ke data PJA
+)
+
+// TahapPeriodePJA berurutan sesuai alur putaran
+var TahapPeriodePJA = []string{PeriodePJADibuka, PeriodePJAPenilaian, PeriodePJASelesai}
+
+// Hasil penghargaan untuk desa/kelurahan yang diusulkan
+const (
+	PenghargaanPemenang = "pemenang"
+	PenghargaanNominasi = "nominasi" // masuk nominasi tapi tidak menang
+)
+
+// PeriodePJA adalah satu putaran tahunan pengusulan dan penilaian PJA
+type PeriodePJA struct {
 	ID        uint   `gorm:"primaryKey"`
-	Username  string `gorm:"unique;not null"`
-	Password  string `gorm:"not null"`
-	Role      string `gorm:"type:enum('admin','user');default:'user'"`
+	Tahun     int    `gorm:"not null;uniqueIndex"`
+	Status    string `gorm:"type:varchar(20);not null;default:dibuka"`
+	Catatan   string `gorm:"type:text"`
 	CreatedAt *time.Time
+	UpdatedAt *time.Time
+
+	Kriterias []KriteriaPJA `gorm:"foreignKey:PeriodeID"`
+	Juris     []JuriPJA     `gorm:"foreignKey:PeriodeID"`
+}
+
+// KriteriaPJA adalah satu butir rubrik penilaian; bobot dalam persen, total satu periode 100
+type KriteriaPJA struct {
+	ID         uint   `gorm:"primaryKey"`
+	PeriodeID  uint   `gorm:"not null;index"`
+	Nama       string `gorm:"type:varchar(191);not null"`
+	Keterangan string `gorm:"type:text"`
+	Bobot      int    `gorm:"not null"`
+	Urutan     int    `gorm:"not null;default:0"`
+}
+
+// JuriPJA adalah user (admin/verifikator) yang ditunjuk menilai pada satu periode
+type JuriPJA struct {
+	ID        uint `gorm:"primaryKey"`
+	PeriodeID uint `gorm:"not null;uniqueIndex:idx_juri_pja"`
+	UserID    uint `gorm:"not null;uniqueIndex:idx_juri_pja"`
+
+	User User
+}
+
+// NominasiPJA adalah desa/kelurahan yang diusulkan pada satu periode beserta dosier kasus mediasinya
+type NominasiPJA struct {
+	ID             uint    `gorm:"primaryKey"`
+	PeriodeID      uint    `gorm:"not null;uniqueIndex:idx_nominasi_pja"`
+	KelurahanID    uint    `gorm:"not null;uniqueIndex:idx_nominasi_pja;index"`
+	NamaKepalaDesa string  `gorm:"type:varchar(191);not null"`
+	Alasan         string  `gorm:"type:text"`
+	Diusulkan      string  `gorm:"type:varchar(191)"` // username pengusul
+	NilaiAkhir     float64 `gorm:"not null;default:0"`
+	Peringkat      int     `gorm:"not null;default:0"`
+	Hasil          string  `gorm:"type:varchar(20)"` // kosong sampai periode selesai
+	CreatedAt      *time.Time
+	UpdatedAt      *time.Time
+
+	Periode   PeriodePJA
+	Kelurahan Kelurahan
+	Mediasis  []Mediasi `gorm:"many2many:nominasi_pja_mediasis"`
+}
+
+// NilaiPJA adalah skor (0-100) satu juri untuk satu kriteria pada satu nominasi
+type NilaiPJA struct {
+	ID         uint   `gorm:"primaryKey"`
+	NominasiID uint   `gorm:"not null;uniqueIndex:idx_nilai_pja"`
+	KriteriaID uint   `gorm:"not null;uniqueIndex:idx_nilai_pja"`
+	UserID     uint   `gorm:"not null;uniqueIndex:idx_nilai_pja"`
+	Skor       int    `gorm:"not null"`
+	Catatan    string `gorm:"type:text"`
+	UpdatedAt  *time.Time
+}
+
+// ================= Penilaian Kadarkum =================
+
+// Rentang nilai untuk sebaran hasil penilaian Kadarkum di dashboard
+var RentangNilaiKadarkum = []string{"0-19", "20-39", "40-59", "60-79", "80-100"}
+
+// KuesionerKadarkum adalah instrumen penilaian kesadaran hukum keluarga di desa/kelurahan.
+// Hanya satu kuesioner yang aktif dipakai untuk penilaian baru; kuesioner yang sudah dipakai dikunci
+// (indikator dan ambang tidak bisa diubah) agar nilai tahun-tahun sebelumnya tetap sebanding.
+type KuesionerKadarkum struct {
+	ID          uint    `gorm:"primaryKey"`
+	Nama        string  `gorm:"type:varchar(191);not null"`
+	Keterangan  string  `gorm:"type:text"`
+	AmbangLulus float64 `gorm:"not null;default:70"` // nilai minimal agar desa/kelurahan lulus penilaian
+	Aktif       bool    `gorm:"not null;default:false"`
+	CreatedAt   *time.Time
+	UpdatedAt   *time.Time
+
+	Indikators []IndikatorKadarkum `gorm:"foreignKey:KuesionerID"`
+}
+
+// IndikatorKadarkum adalah satu butir pertanyaan kuesioner; bobot dalam persen, total satu kuesioner 100
+type IndikatorKadarkum struct {
+	ID          uint   `gorm:"primaryKey"`
+	KuesionerID uint   `gorm:"not null;index"`
+	Aspek       string `gorm:"type:varchar(100)"` // pengelompokan, mis. "Pengetahuan Hukum"
+	Pertanyaan  string `gorm:"type:text;not null"`
+	Bobot       int    `gorm:"not null"`
+	Urutan      int    `gorm:"not null;default:0"`
+}
+
+// PenilaianKadarkum adalah hasil penilaian satu desa/kelurahan pada satu tahun oleh tim penilai
+type PenilaianKadarkum struct {
+	ID             uint       `gorm:"primaryKey"`
+	KuesionerID    uint       `gorm:"not null;index"`
+	KelurahanID    uint       `gorm:"not null;uniqueIndex:idx_penilaian_kadarkum"`
+	Tahun          int        `gorm:"not null;uniqueIndex:idx_penilaian_kadarkum;index"`
+	Tanggal        *time.Time `gorm:"type:date"`
+	Penilai        string     `gorm:"type:varchar(191);not null"` // nama anggota tim penilai
+	JumlahKeluarga int        `gorm:"not null;default:0"`         // keluarga yang disurvei
+	Catatan        string     `gorm:"type:text"`
+	Nilai          float64    `gorm:"not null;default:0"` // dihitung otomatis dari skor x bobot indikator
+	Lulus          bool       `gorm:"not null;default:false"`
+	Dicatat        string     `gorm:"type:varchar(191)"` // username yang mencatat
+	CreatedAt      *time.Time
+	UpdatedAt      *time.Time
+
+	Kuesioner KuesionerKadarkum
+	Kelurahan Kelurahan
+	Jawabans  []JawabanKadarkum `gorm:"foreignKey:PenilaianID"`
+}
+
+// RentangNilai -> indeks RentangNilaiKadarkum tempat nilai penilaian ini jatuh
+func (p PenilaianKadarkum) RentangNilai() int {
+	i := int(p.Nilai) / 20
+	if i > len(RentangNilaiKadarkum)-1 {
+		i = len(RentangNilaiKadarkum) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return i
+}
+
+// JawabanKadarkum adalah skor (0-100, persentase keluarga yang memenuhi) satu indikator pada satu penilaian
+type JawabanKadarkum struct {
+	ID          uint `gorm:"primaryKey"`
+	PenilaianID uint `gorm:"not null;uniqueIndex:idx_jawaban_kadarkum"`
+	IndikatorID uint `gorm:"not null;uniqueIndex:idx_jawaban_kadarkum"`
+	Skor        int  `gorm:"not null"`
+}
+
+// ================= Pengaturan =================
+
+// Pengaturan menyimpan konfigurasi aplikasi yang bisa diubah admin (key-value)
+type Pengaturan struct {
+	ID        uint   `gorm:"primaryKey"`
+	Kunci     string `gorm:"type:varchar(100);unique;not null"`
+	Nilai     string `gorm:"type:text"`
+	UpdatedAt *time.Time
 }
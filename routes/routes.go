@@ -2,6 +2,7 @@ package routes
 
 import (
 	"go-admin/controllers"
+	"go-admin/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,26 +25,31 @@ func SetupRoutes(r *gin.Engine) {
 	// Tambahkan middleware MethodOverride
 	r.Use(MethodOverride())
 
+	// Rate limit publik per IP klien, auth & API per user login / API token tervalidasi.
+	// Budget bisa diubah lewat .env (RATE_LIMIT_<NAMA>_PER_MENIT / _BURST)
+	limitPublik := utils.RateLimit(utils.NewRateLimiter("publik", 60, 20))
+	limitAuth := utils.RateLimitIdentitas(utils.NewRateLimiter("auth", 300, 60))
+	limitAPI := utils.RateLimitIdentitas(utils.NewRateLimiter("api", 120, 30))
+
 	// ================= LANDING PAGE & STATISTIK =================
-	r.GET("/", controllers.LandingPage)
-	r.GET("/detail", controllers.PublicDashboard) // <-- RUTE BARU DITAMBAHKAN DI SINI
+	r.GET("/", limitPublik, controllers.LandingPage)
+	r.GET("/detail", limitPublik, controllers.PublicDashboard) // <-- RUTE BARU DITAMBAHKAN DI SINI
+
+	// dokumen: boleh tanpa login kalau link bertanda tangan / dokumen publik (dicek di handler)
+	r.GET("/view-document/:type/:id", limitPublik, controllers.ViewDocument)
+	r.GET("/foto/posbankum/:id", limitPublik, controllers.PosbankumFoto)
 
 	// ================= AUTH =================
 	r.GET("/login", controllers.ShowLogin)
-	r.POST("/login", controllers.DoLogin)
+	r.POST("/login", limitPublik, controllers.DoLogin)
 	r.GET("/logout", controllers.Logout)
-
-	// ================= ROUTES UMUM (BUTUH LOGIN) =================
-	auth := r.Group("/")
-	auth.Use(controllers.AuthRequired())
-	{
-		auth.GET("/view-document/:type/:id", controllers.ViewDocument)
-	}
+	r.GET("/login/oidc", controllers.OIDCLogin)
+	r.GET("/login/oidc/callback", controllers.OIDCCallback)
 
 	// ================= ROUTES ADMIN (UNTUK HALAMAN WEB) =================
 	// Grup ini khusus untuk halaman-halaman yang merender HTML dan butuh role "admin".
 	admin := r.Group("/admin")
-	admin.Use(controllers.AuthRequired(), controllers.RoleRequired("admin"))
+	admin.Use(limitAuth, controllers.AuthRequired(), controllers.RoleRequired("admin"))
 	{
 		// ================= DASHBOARD =================
 		admin.GET("/", controllers.AdminPanel)
@@ -56,54 +62,245 @@ func SetupRoutes(r *gin.Engine) {
 		admin.POST("/users/update/:id", controllers.UserUpdate)
 		admin.POST("/users/delete/:id", controllers.UserDelete)
 
+		// ================= MASTER WILAYAH =================
+		admin.GET("/provinsi", controllers.ProvinsiIndex)
+		admin.GET("/kabupaten", controllers.KabupatenIndex)
+		admin.GET("/kecamatan", controllers.KecamatanIndex)
+		admin.GET("/kelurahan", controllers.KelurahanIndex)
+
+		// ================= PENGATURAN =================
+		admin.GET("/pengaturan", controllers.PengaturanForm)
+		admin.POST("/pengaturan", controllers.PengaturanUpdate)
+		admin.GET("/rate-limit", controllers.RateLimitStats)
+
+		// ================= LOG AKSES DOKUMEN =================
+		admin.GET("/akses-dokumen", controllers.AksesDokumenIndex)
+		admin.POST("/akses-dokumen/batas", controllers.BatasAksesStore)
+		admin.POST("/akses-dokumen/batas/delete/:id", controllers.BatasAksesDelete)
+
+		// ================= LAMPIRAN =================
+		admin.GET("/kategori-lampiran", controllers.KategoriLampiranIndex)
+		admin.POST("/kategori-lampiran/store", controllers.KategoriLampiranStore)
+		admin.POST("/kategori-lampiran/update/:id", controllers.KategoriLampiranUpdate)
+		admin.POST("/kategori-lampiran/delete/:id", controllers.KategoriLampiranDelete)
+
+		// ================= PENCARIAN ISI DOKUMEN =================
+		admin.GET("/pencarian-dokumen", controllers.PencarianDokumen)
+		admin.POST("/ocr/ulang/:id", controllers.OCRUlang)
+
+		// ================= KARANTINA MALWARE =================
+		admin.GET("/karantina", controllers.KarantinaIndex)
+		admin.POST("/karantina/tinjau/:id", controllers.KarantinaTinjau)
+		admin.POST("/karantina/delete/:id", controllers.KarantinaDelete)
+
+		// ================= MASA BERLAKU SK =================
+		admin.GET("/sk", controllers.SKIndex)
+
+		// ================= INTEGRITAS DOKUMEN =================
+		admin.GET("/integritas", controllers.IntegritasIndex)
+		admin.POST("/integritas/periksa", controllers.IntegritasPeriksa)
+
+		// ================= EMAIL NOTIFIKASI =================
+		admin.GET("/email", controllers.EmailIndex)
+		admin.POST("/email/kirim-ulang/:id", controllers.EmailKirimUlang)
+		admin.POST("/email/tes", controllers.EmailTes)
+
+		// ================= PELATIHAN PARALEGAL (KELOLA KEGIATAN) =================
+		admin.GET("/pelatihan/create", controllers.PelatihanCreate)
+		admin.POST("/pelatihan/store", controllers.PelatihanStore)
+		admin.GET("/pelatihan/edit/:id", controllers.PelatihanEdit)
+		admin.POST("/pelatihan/update/:id", controllers.PelatihanUpdate)
+		admin.POST("/pelatihan/delete/:id", controllers.PelatihanDelete)
+
+		// ================= PENGHARGAAN PJA (PERIODE, RUBRIK, JURI, PENETAPAN) =================
+		admin.POST("/penghargaan-pja/periode/store", controllers.PeriodeStore)
+		admin.POST("/penghargaan-pja/periode/tahap/:periode", controllers.PeriodeTahap)
+		admin.POST("/penghargaan-pja/periode/tetapkan/:periode", controllers.PeriodeTetapkan)
+		admin.POST("/penghargaan-pja/periode/kriteria/:periode", controllers.KriteriaStore)
+		admin.POST("/penghargaan-pja/kriteria/delete/:id", controllers.KriteriaDelete)
+		admin.POST("/penghargaan-pja/periode/juri/:periode", controllers.JuriStore)
+		admin.POST("/penghargaan-pja/juri/delete/:id", controllers.JuriDelete)
+
+		// ================= KUESIONER PENILAIAN KADARKUM =================
+		admin.GET("/kuesioner-kadarkum", controllers.KuesionerIndex)
+		admin.POST("/kuesioner-kadarkum/store", controllers.KuesionerStore)
+		admin.GET("/kuesioner-kadarkum/:id", controllers.KuesionerDetail)
+		admin.POST("/kuesioner-kadarkum/update/:id", controllers.KuesionerUpdate)
+		admin.POST("/kuesioner-kadarkum/aktifkan/:id", controllers.KuesionerAktifkan)
+		admin.POST("/kuesioner-kadarkum/delete/:id", controllers.KuesionerDelete)
+		admin.POST("/kuesioner-kadarkum/indikator/store/:kuesioner", controllers.IndikatorStore)
+		admin.POST("/kuesioner-kadarkum/indikator/delete/:id", controllers.IndikatorDelete)
+	}
+
+	// ================= ROUTES DATA PROGRAM (ADMIN & OPERATOR) =================
+	// Operator kabupaten/kota hanya boleh mengelola data di wilayahnya (dicek WilayahOperator).
+	kelola := r.Group("/admin")
+	kelola.Use(limitAuth, controllers.AuthRequired(), controllers.RoleRequired("admin", "operator"))
+	{
+		posbankum := kelola.Group("/posbankum", controllers.WilayahOperator("posbankum"))
+		paralegal := kelola.Group("/paralegal", controllers.WilayahOperator("paralegal"))
+		kadarkum := kelola.Group("/kadarkum", controllers.WilayahOperator("kadarkum"))
+		pja := kelola.Group("/pja", controllers.WilayahOperator("pja"))
+
 		// ================= POSBANKUM CRUD =================
-		admin.GET("/posbankum", controllers.PosbankumIndex)
-		admin.GET("/posbankum/create", controllers.PosbankumCreate)
-		admin.POST("/posbankum/store", controllers.PosbankumStore)
-		admin.GET("/posbankum/view/:id", controllers.PosbankumView)
-		admin.GET("/posbankum/edit/:id", controllers.PosbankumEdit)
-		admin.POST("/posbankum/update/:id", controllers.PosbankumUpdate)
-		admin.POST("/posbankum/delete/:id", controllers.PosbankumDelete)
+		posbankum.GET("", controllers.PosbankumIndex)
+		posbankum.GET("/create", controllers.PosbankumCreate)
+		posbankum.POST("/store", controllers.PosbankumStore)
+		posbankum.GET("/view/:id", controllers.PosbankumView)
+		posbankum.GET("/edit/:id", controllers.PosbankumEdit)
+		posbankum.POST("/update/:id", controllers.PosbankumUpdate)
+		posbankum.POST("/delete/:id", controllers.PosbankumDelete)
 
 		// ================= PARALEGAL CRUD =================
-		admin.GET("/paralegal", controllers.ParalegalIndex)
-		admin.GET("/paralegal/create", controllers.ParalegalCreate)
-		admin.POST("/paralegal/store", controllers.ParalegalStore)
-		admin.GET("/paralegal/view/:id", controllers.ParalegalView)
-		admin.GET("/paralegal/edit/:id", controllers.ParalegalEdit)
-		admin.POST("/paralegal/update/:id", controllers.ParalegalUpdate)
-		admin.POST("/paralegal/delete/:id", controllers.ParalegalDelete)
+		paralegal.GET("", controllers.ParalegalIndex)
+		paralegal.GET("/create", controllers.ParalegalCreate)
+		paralegal.POST("/store", controllers.ParalegalStore)
+		paralegal.GET("/view/:id", controllers.ParalegalView)
+		paralegal.GET("/foto/:id", controllers.ParalegalFoto)
+		paralegal.GET("/edit/:id", controllers.ParalegalEdit)
+		paralegal.POST("/update/:id", controllers.ParalegalUpdate)
+		paralegal.POST("/delete/:id", controllers.ParalegalDelete)
 
 		// ================= KADARKUM CRUD =================
-		admin.GET("/kadarkum", controllers.KadarkumIndex)
-		admin.GET("/kadarkum/create", controllers.KadarkumCreate)
-		admin.POST("/kadarkum/store", controllers.KadarkumStore)
-		admin.GET("/kadarkum/view/:id", controllers.KadarkumView)
-		admin.GET("/kadarkum/edit/:id", controllers.KadarkumEdit)
-		admin.POST("/kadarkum/update/:id", controllers.KadarkumUpdate)
-		admin.POST("/kadarkum/delete/:id", controllers.KadarkumDelete)
+		kadarkum.GET("", controllers.KadarkumIndex)
+		kadarkum.GET("/create", controllers.KadarkumCreate)
+		kadarkum.POST("/store", controllers.KadarkumStore)
+		kadarkum.GET("/view/:id", controllers.KadarkumView)
+		kadarkum.GET("/edit/:id", controllers.KadarkumEdit)
+		kadarkum.POST("/update/:id", controllers.KadarkumUpdate)
+		kadarkum.POST("/delete/:id", controllers.KadarkumDelete)
 
 		// ================= PJA CRUD =================
-		admin.GET("/pja", controllers.PJAIndex)
-		admin.GET("/pja/create", controllers.PJACreate)
-		admin.POST("/pja/store", controllers.PJAStore)
-		admin.GET("/pja/view/:id", controllers.PJAView)
-		admin.GET("/pja/edit/:id", controllers.PJAEdit)
-		admin.POST("/pja/update/:id", controllers.PJAUpdate)
-		admin.POST("/pja/delete/:id", controllers.PJADelete)
+		pja.GET("", controllers.PJAIndex)
+		pja.GET("/create", controllers.PJACreate)
+		pja.POST("/store", controllers.PJAStore)
+		pja.GET("/view/:id", controllers.PJAView)
+		pja.GET("/edit/:id", controllers.PJAEdit)
+		pja.POST("/update/:id", controllers.PJAUpdate)
+		pja.POST("/delete/:id", controllers.PJADelete)
 
-		// ================= MASTER WILAYAH =================
-		admin.GET("/provinsi", controllers.ProvinsiIndex)
-		admin.GET("/kabupaten", controllers.KabupatenIndex)
-		admin.GET("/kecamatan", controllers.KecamatanIndex)
-		admin.GET("/kelurahan", controllers.KelurahanIndex)
+		// ================= UNDUH ZIP DOKUMEN =================
+		// operator dibatasi ke kabupaten/kota wilayah kerjanya di dalam handler
+		kelola.GET("/unduh-zip", controllers.UnduhZIP)
+
+		// ================= LAMPIRAN =================
+		lampiran := kelola.Group("/lampiran", controllers.WilayahOperator("lampiran"))
+		lampiran.POST("/store", controllers.LampiranStore)
+		lampiran.POST("/pindah/:id", controllers.LampiranPindah)
+		lampiran.POST("/delete/:id", controllers.LampiranDelete)
+
+		// ================= PELATIHAN & SERTIFIKASI PARALEGAL =================
+		kelola.GET("/pelatihan", controllers.PelatihanIndex)
+		kelola.GET("/pelatihan/detail/:id", controllers.PelatihanDetail)
+		kelola.GET("/pelatihan/cari-paralegal", controllers.PelatihanCariParalegal)
+		peserta := kelola.Group("/pelatihan/peserta", controllers.WilayahOperator("peserta"))
+		peserta.POST("/store", controllers.PesertaStore)
+		peserta.POST("/update/:id", controllers.PesertaUpdate)
+		peserta.POST("/delete/:id", controllers.PesertaDelete)
+		sertifikat := kelola.Group("/sertifikat", controllers.WilayahOperator("sertifikat"))
+		sertifikat.POST("/store", controllers.SertifikatStore)
+		sertifikat.POST("/delete/:id", controllers.SertifikatDelete)
+
+		// ================= REGISTER KONSULTASI HUKUM =================
+		konsultasi := kelola.Group("/konsultasi", controllers.WilayahOperator("konsultasi"))
+		konsultasi.GET("", controllers.KonsultasiIndex)
+		konsultasi.GET("/rekap", controllers.KonsultasiRekap)
+		konsultasi.GET("/paralegal", controllers.KonsultasiParalegal)
+		konsultasi.GET("/create", controllers.KonsultasiCreate)
+		konsultasi.POST("/store", controllers.KonsultasiStore)
+		konsultasi.GET("/edit/:id", controllers.KonsultasiEdit)
+		konsultasi.POST("/update/:id", controllers.KonsultasiUpdate)
+		konsultasi.POST("/delete/:id", controllers.KonsultasiDelete)
+		konsultasi.POST("/tindak-lanjut/:id", controllers.TindakLanjutStore)
+
+		// ================= LOG MEDIASI KEPALA DESA =================
+		mediasi := kelola.Group("/mediasi", controllers.WilayahOperator("mediasi"))
+		mediasi.GET("", controllers.MediasiIndex)
+		mediasi.GET("/create", controllers.MediasiCreate)
+		mediasi.POST("/store", controllers.MediasiStore)
+		mediasi.GET("/view/:id", controllers.MediasiView)
+		mediasi.GET("/edit/:id", controllers.MediasiEdit)
+		mediasi.POST("/update/:id", controllers.MediasiUpdate)
+		mediasi.POST("/delete/:id", controllers.MediasiDelete)
+
+		// ================= USULAN PENGHARGAAN PJA =================
+		kelola.GET("/penghargaan-pja", controllers.PenghargaanIndex)
+		kelola.GET("/penghargaan-pja/periode/:periode", controllers.PeriodeDetail)
+		usulan := kelola.Group("/penghargaan-pja/usulan", controllers.WilayahOperator("nominasi_pja"))
+		usulan.GET("/create", controllers.NominasiCreate)
+		usulan.POST("/store", controllers.NominasiStore)
+		usulan.GET("/dosier/:id", controllers.NominasiDosier)
+		usulan.GET("/edit/:id", controllers.NominasiEdit)
+		usulan.POST("/update/:id", controllers.NominasiUpdate)
+		usulan.POST("/delete/:id", controllers.NominasiDelete)
+
+		// ================= PENILAIAN KADARKUM =================
+		penilaianKadarkum := kelola.Group("/penilaian-kadarkum", controllers.WilayahOperator("penilaian_kadarkum"))
+		penilaianKadarkum.GET("", controllers.PenilaianKadarkumIndex)
+		penilaianKadarkum.GET("/rekap", controllers.PenilaianKadarkumRekap)
+		penilaianKadarkum.GET("/create", controllers.PenilaianKadarkumCreate)
+		penilaianKadarkum.POST("/store", controllers.PenilaianKadarkumStore)
+		penilaianKadarkum.GET("/edit/:id", controllers.PenilaianKadarkumEdit)
+		penilaianKadarkum.POST("/update/:id", controllers.PenilaianKadarkumUpdate)
+		penilaianKadarkum.POST("/delete/:id", controllers.PenilaianKadarkumDelete)
+
+		// ================= UPLOAD RESUMABLE (tus 1.0) =================
+		kelola.OPTIONS("/upload", controllers.UploadOpsi)
+		kelola.POST("/upload", controllers.UploadBuat)
+		kelola.HEAD("/upload/:id", controllers.UploadStatus)
+		kelola.PATCH("/upload/:id", controllers.UploadLanjut)
+		kelola.DELETE("/upload/:id", controllers.UploadHapus)
+	}
+
+	// ================= ROUTES VERIFIKASI (ADMIN & VERIFIKATOR KANWIL) =================
+	verifikasi := r.Group("/admin/verifikasi")
+	verifikasi.Use(limitAuth, controllers.AuthRequired(), controllers.RoleRequired("admin", "verifikator"))
+	{
+		verifikasi.GET("", controllers.VerifikasiIndex)
+		verifikasi.POST("/:tipe/:id", controllers.VerifikasiPutuskan)
+	}
+
+	// ================= PENILAIAN JURI PJA (ADMIN & VERIFIKATOR YANG DITUNJUK) =================
+	penilaian := r.Group("/admin/penilaian-pja")
+	penilaian.Use(limitAuth, controllers.AuthRequired(), controllers.RoleRequired("admin", "verifikator"))
+	{
+		penilaian.GET("", controllers.PenilaianIndex)
+		penilaian.GET("/:id", controllers.PenilaianForm)
+		penilaian.POST("/:id", controllers.PenilaianSimpan)
+	}
+
+	// ================= DISKUSI RECORD (ADMIN, VERIFIKATOR & OPERATOR) =================
+	diskusi := r.Group("/admin/diskusi")
+	diskusi.Use(limitAuth, controllers.AuthRequired(), controllers.RoleRequired("admin", "verifikator", "operator"),
+		controllers.WilayahOperator(""))
+	{
+		diskusi.GET("/:tipe/:id", controllers.DiskusiIndex)
+		diskusi.POST("/:tipe/:id", controllers.DiskusiStore)
+	}
+
+	// ================= NOTIFIKASI (SEMUA ROLE) =================
+	notifikasi := r.Group("/notifikasi")
+	notifikasi.Use(limitAuth, controllers.AuthRequired())
+	{
+		notifikasi.GET("", controllers.NotifikasiIndex)
+		notifikasi.GET("/jumlah", controllers.NotifikasiJumlah)
+		notifikasi.POST("/baca/:id", controllers.NotifikasiBaca)
+		notifikasi.POST("/baca-semua", controllers.NotifikasiBacaSemua)
+	}
+
+	// ================= ROUTES AKUN (SEMUA ROLE) =================
+	akun := r.Group("/akun")
+	akun.Use(limitAuth, controllers.AuthRequired())
+	{
+		akun.GET("/notifikasi", controllers.NotifikasiAkun)
+		akun.POST("/notifikasi", controllers.NotifikasiAkunSimpan)
 	}
 
 	// ================= ROUTES API (UNTUK DATA JSON) =================
 	// Grup ini khusus untuk endpoint API yang mengembalikan data JSON.
 	// Cukup pakai AuthRequired() saja, karena tidak merender halaman admin.
 	api := r.Group("/api")
-	api.Use(controllers.AuthRequired())
+	api.Use(limitAPI, controllers.AuthRequired())
 	{
 		api.GET("/kelurahan/search", controllers.KelurahanSearch)
 		api.GET("/posbankum/search", controllers.PosbankumSearch)
@@ -113,14 +310,16 @@ func SetupRoutes(r *gin.Engine) {
 	}
 
 	// Endpoint API publik (tanpa auth)
-	r.GET("/api/map-data", controllers.MapDataAPI)
+	r.GET("/api/map-data", limitPublik, controllers.MapDataAPI)
+	r.GET("/api/map-posbankum", limitPublik, controllers.MapPosbankumAPI)
 
 	// ================= ROUTES USER =================
 	user := r.Group("/user")
-	user.Use(controllers.AuthRequired(), controllers.RoleRequired("user"))
+	user.Use(limitAuth, controllers.AuthRequired(), controllers.RoleRequired("user"))
 	{
 		user.GET("/", controllers.UserDashboard)
 		user.POST("/cetak-pdf", controllers.CetakPDF)
+		user.GET("/unduh-zip", controllers.UnduhZIP)
 	}
 
 }
@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FolderBlob adalah tempat dokumen disimpan berdasarkan hash isinya: uploads/blob/ab/abcd...pdf
+const FolderBlob = "uploads/blob"
+
+// EkstensiBlob ditentukan dari content type hasil sniffing, bukan nama file asli,
+// supaya isi yang sama selalu mendapat path yang sama
+func EkstensiBlob(contentType string) string {
+	switch contentType {
+	case "application/pdf":
+		return ".pdf"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	}
+	return ".bin"
+}
+
+// PathBlob mengembalikan lokasi file untuk hash dan ekstensi tertentu
+func PathBlob(sha, ext string) string {
+	return FolderBlob + "/" + sha[:2] + "/" + sha + ext
+}
+
+// TulisSementara menyalin isi r ke file baru di dir sambil menghitung SHA-256-nya
+func TulisSementara(r io.Reader, dir string) (path, sha string, ukuran int64, err error) {
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(dir, ".masuk-*")
+	if err != nil {
+		return
+	}
+
+	h := sha256.New()
+	ukuran, err = io.Copy(io.MultiWriter(tmp, h), r)
+	if errTutup := tmp.Close(); err == nil {
+		err = errTutup
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	return tmp.Name(), hex.EncodeToString(h.Sum(nil)), ukuran, nil
+}
+
+// PasangBlob memindahkan file sementara ke lokasi blob sesuai hash-nya.
+// Kalau isi yang sama sudah tersimpan, file sementara dibuang dan path yang lama dipakai.
+func PasangBlob(tmp, sha, ext string) (string, error) {
+	path := PathBlob(sha, ext)
+	if FileAda(path) {
+		os.Remove(tmp)
+		return path, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", err
+	}
+	os.Chmod(tmp, 0o644)
+	if err := os.Rename(tmp, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// SimpanBlob menyalin isi r langsung ke penyimpanan blob, mengembalikan hash, path dan ukurannya
+func SimpanBlob(r io.Reader, contentType string) (sha, path string, ukuran int64, err error) {
+	tmp, sha, ukuran, err := TulisSementara(r, FolderBlob)
+	if err != nil {
+		return
+	}
+	path, err = PasangBlob(tmp, sha, EkstensiBlob(contentType))
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return
+}
+
+// HashFile menghitung ulang SHA-256 file di disk
+func HashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", n, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// PathDiBlob true kalau path ada di penyimpanan blob (bukan file lama uploads/<tipe>/<uuid>)
+func PathDiBlob(path string) bool {
+	return strings.HasPrefix(filepath.ToSlash(path), FolderBlob+"/")
+}
+
+// ContentTypeFile menebak content type dari 512 byte pertama file
+func ContentTypeFile(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	buffer := make([]byte, 512)
+	n, _ := io.ReadFull(f, buffer)
+	return http.DetectContentType(buffer[:n])
+}
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Konfigurasi SMTP dari .env:
+//
+//	SMTP_HOST      host server SMTP, kosong = email nonaktif (pesan tetap diantrekan)
+//	SMTP_PORT      bawaan 1025 (port SMTP MailHog untuk uji lokal)
+//	SMTP_USERNAME  / SMTP_PASSWORD, kosong = tanpa AUTH
+//	SMTP_FROM      alamat pengirim, bawaan no-reply@localhost
+//	SMTP_TLS       "true" untuk TLS langsung (port 465); selain itu STARTTLS dipakai kalau server menawarkannya
+//	SMTP_TIMEOUT   batas waktu satu pengiriman (format time.Duration), bawaan 30s
+
+// EmailAktif -> true kalau SMTP sudah dikonfigurasi
+func EmailAktif() bool {
+	return os.Getenv("SMTP_HOST") != ""
+}
+
+// PengirimEmail adalah alamat From untuk semua email aplikasi
+func PengirimEmail() string {
+	return envOr("SMTP_FROM", "no-reply@localhost")
+}
+
+// EmailValid -> true kalau s satu alamat email tanpa nama/komentar
+func EmailValid(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+func timeoutSMTP() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("SMTP_TIMEOUT")); err == nil && d > 0 {
+		return d
+	}
+	return 30 * time.Second
+}
+
+// SusunEmail membentuk pesan MIME text/plain UTF-8 (quoted-printable, aman untuk teks berbahasa Indonesia)
+func SusunEmail(dari, ke, subjek, isi string) []byte {
+	domain := "localhost"
+	if i := strings.LastIndex(dari, "@"); i >= 0 {
+		domain = dari[i+1:]
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", dari)
+	fmt.Fprintf(&buf, "To: %s\r\n", ke)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subjek))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", uuid.New().String(), domain)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	qp.Write([]byte(strings.ReplaceAll(strings.ReplaceAll(isi, "\r\n", "\n"), "\n", "\r\n")))
+	qp.Close()
+	return buf.Bytes()
+}
+
+// KirimEmail mengirim satu email ke satu penerima lewat SMTP
+func KirimEmail(ke, subjek, isi string) error {
+	if !EmailAktif() {
+		return errors.New("SMTP_HOST belum diatur")
+	}
+	if !EmailValid(ke) {
+		return fmt.Errorf("alamat email tidak valid: %q", ke)
+	}
+	dari := PengirimEmail()
+	host := os.Getenv("SMTP_HOST")
+	alamat := net.JoinHostPort(host, envOr("SMTP_PORT", "1025"))
+	timeout := timeoutSMTP()
+
+	var conn net.Conn
+	var err error
+	if os.Getenv("SMTP_TLS") == "true" {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", alamat, &tls.Config{ServerName: host})
+	} else {
+		conn, err = net.DialTimeout("tcp", alamat, timeout)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && os.Getenv("SMTP_TLS") != "true" {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if user := os.Getenv("SMTP_USERNAME"); user != "" {
+		if err := client.Auth(smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(dari); err != nil {
+		return err
+	}
+	if err := client.Rcpt(ke); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(SusunEmail(dari, ke, subjek, isi)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
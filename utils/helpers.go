@@ -1,10 +1,13 @@
 package utils
 
 import (
+	"fmt"
 	"html"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/microcosm-cc/bluemonday"
@@ -24,22 +27,44 @@ func SanitizeInput(input string) string {
 
 // ValidatePDFUpload checks if the uploaded file is a valid PDF and within the size limit.
 func ValidatePDFUpload(c *gin.Context, file *multipart.FileHeader) bool {
+	_, err := ValidateUpload(file, []string{"pdf"}, MaxUploadSize)
+	return err == nil
+}
+
+// uploadContentTypes maps allowed extensions to the content type sniffed from the file header.
+var uploadContentTypes = map[string]string{
+	"pdf":  "application/pdf",
+	"jpg":  "image/jpeg",
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+}
+
+// ValidateUpload checks the file size and sniffs the real content type against the allowed
+// extensions (pdf, jpg, png). It returns the detected content type.
+func ValidateUpload(file *multipart.FileHeader, allowed []string, maxSize int64) (string, error) {
 	// Check size
-	if file.Size > MaxUploadSize {
-		return false
+	if file.Size > maxSize {
+		return "", fmt.Errorf("ukuran file melebihi %d MB", maxSize/(1024*1024))
 	}
 
 	// Check content type
 	src, err := file.Open()
 	if err != nil {
-		return false
+		return "", fmt.Errorf("file tidak bisa dibaca")
 	}
 	defer src.Close()
 
 	buffer := make([]byte, 512)
-	if _, err := src.Read(buffer); err != nil {
-		return false
+	n, err := src.Read(buffer)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("file tidak bisa dibaca")
 	}
 
-	return http.DetectContentType(buffer) == "application/pdf"
+	detected := http.DetectContentType(buffer[:n])
+	for _, ext := range allowed {
+		if uploadContentTypes[strings.ToLower(strings.TrimSpace(ext))] == detected {
+			return detected, nil
+		}
+	}
+	return "", fmt.Errorf("tipe file tidak diizinkan, harus %s", strings.ToUpper(strings.Join(allowed, "/")))
 }
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// polaTelepon: nomor Indonesia setelah dinormalisasi, 0 diikuti 8-13 digit
+var polaTelepon = regexp.MustCompile(`^0[0-9]{8,13}$`)
+
+// NormalisasiTelepon membuang spasi/tanda baca dan mengubah awalan +62/62 menjadi 0.
+// Mengembalikan false kalau hasilnya bukan nomor telepon Indonesia yang valid; string kosong dianggap valid.
+func NormalisasiTelepon(s string) (string, bool) {
+	s = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "").Replace(strings.TrimSpace(s))
+	if s == "" {
+		return "", true
+	}
+	switch {
+	case strings.HasPrefix(s, "+62"):
+		s = "0" + s[3:]
+	case strings.HasPrefix(s, "62"):
+		s = "0" + s[2:]
+	}
+	return s, polaTelepon.MatchString(s)
+}
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NIK (Nomor Induk Kependudukan) 16 digit: 6 digit kode wilayah, 6 digit tanggal lahir DDMMYY
+// (tanggal ditambah 40 untuk perempuan), lalu 4 digit nomor urut.
+var polaNIK = regexp.MustCompile(`^[0-9]{16}$`)
+
+// NormalisasiNIK membuang spasi/titik/strip yang sering ikut tersalin dari KTP
+func NormalisasiNIK(s string) string {
+	return strings.NewReplacer(" ", "", ".", "", "-", "").Replace(strings.TrimSpace(s))
+}
+
+// InfoNIK memvalidasi format NIK lalu mengambil jenis kelamin ("L"/"P") dan tanggal lahir dari dalamnya.
+// ok=false kalau bukan 16 digit, kode provinsi/tanggal tidak masuk akal, atau nomor urut 0000.
+func InfoNIK(nik string) (jenisKelamin string, tanggalLahir time.Time, ok bool) {
+	if !polaNIK.MatchString(nik) {
+		return "", time.Time{}, false
+	}
+	provinsi, _ := strconv.Atoi(nik[0:2])
+	hari, _ := strconv.Atoi(nik[6:8])
+	bulan, _ := strconv.Atoi(nik[8:10])
+	tahun, _ := strconv.Atoi(nik[10:12])
+	if provinsi < 11 || nik[12:] == "0000" {
+		return "", time.Time{}, false
+	}
+
+	jenisKelamin = "L"
+	if hari > 40 {
+		jenisKelamin = "P"
+		hari -= 40
+	}
+	if bulan < 1 || bulan > 12 || hari < 1 {
+		return "", time.Time{}, false
+	}
+	// tahun dua digit: pakai abad 20xx kecuali hasilnya di masa depan
+	tanggalLahir = time.Date(2000+tahun, time.Month(bulan), hari, 0, 0, 0, 0, time.Local)
+	if tanggalLahir.After(time.Now()) {
+		tanggalLahir = time.Date(1900+tahun, time.Month(bulan), hari, 0, 0, 0, 0, time.Local)
+	}
+	// tanggal yang "meluber" (mis. 31 Februari) berarti NIK salah
+	if tanggalLahir.Day() != hari {
+		return "", time.Time{}, false
+	}
+	return jenisKelamin, tanggalLahir, true
+}
+
+// NIKValid mengecek format NIK (lihat InfoNIK)
+func NIKValid(nik string) bool {
+	_, _, ok := InfoNIK(nik)
+	return ok
+}
+
+// SamarkanNIK menampilkan NIK tanpa membuka seluruh digitnya, mis. 1571••••••••0001
+func SamarkanNIK(nik string) string {
+	if len(nik) != 16 {
+		return ""
+	}
+	return nik[:4] + strings.Repeat("•", 8) + nik[12:]
+}
+
+// kunciNIK memakai NIK_ENCRYPTION_KEY, kalau kosong pakai SESSION_SECRET.
+// Mengganti kunci membuat NIK yang sudah tersimpan tidak bisa dibaca lagi.
+func kunciNIK(tujuan string) []byte {
+	k := os.Getenv("NIK_ENCRYPTION_KEY")
+	if k == "" {
+		k = os.Getenv("SESSION_SECRET")
+	}
+	// kunci enkripsi dan kunci indeks dibedakan supaya hash NIK tidak membocorkan kunci AES
+	h := sha256.Sum256([]byte(tujuan + ":" + k))
+	return h[:]
+}
+
+// EnkripsiNIK mengenkripsi NIK dengan AES-256-GCM (nonce acak di depan, hasil base64)
+func EnkripsiNIK(nik string) (string, error) {
+	block, err := aes.NewCipher(kunciNIK("nik-enkripsi"))
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(nik), nil)), nil
+}
+
+// DekripsiNIK membuka NIK hasil EnkripsiNIK
+func DekripsiNIK(s string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(kunciNIK("nik-enkripsi"))
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("data NIK terenkripsi tidak valid")
+	}
+	nik, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(nik), nil
+}
+
+// HashNIK adalah indeks buta (HMAC-SHA256) untuk unique constraint dan cek duplikasi
+// tanpa menyimpan NIK dalam bentuk terbuka
+func HashNIK(nik string) string {
+	mac := hmac.New(sha256.New, kunciNIK("nik-indeks"))
+	mac.Write([]byte(nik))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HasilOCR adalah teks satu halaman beserta rata-rata keyakinan Tesseract (0-100)
+type HasilOCR struct {
+	Teks      string
+	Keyakinan float64
+}
+
+// OCRPDF merender tiap halaman PDF jadi gambar (pdftoppm) lalu membacanya dengan Tesseract.
+// Binary dan bahasa bisa diatur lewat env PDFTOPPM_BIN, TESSERACT_BIN, OCR_BAHASA dan OCR_DPI.
+func OCRPDF(path string) ([]HasilOCR, error) {
+	tmp, err := os.MkdirTemp("", "ocr-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	dpi := envOr("OCR_DPI", "300")
+	if _, err := jalankan(ctx, envOr("PDFTOPPM_BIN", "pdftoppm"), "-r", dpi, "-png", path, filepath.Join(tmp, "hal")); err != nil {
+		return nil, fmt.Errorf("pdftoppm: %v", err)
+	}
+
+	// pdftoppm memberi nama hal-1.png, hal-01.png, dst. tergantung jumlah halaman
+	gambar, _ := filepath.Glob(filepath.Join(tmp, "hal-*.png"))
+	sort.Slice(gambar, func(i, j int) bool {
+		return nomorHalaman(gambar[i]) < nomorHalaman(gambar[j])
+	})
+	if len(gambar) == 0 {
+		return nil, fmt.Errorf("PDF tidak punya halaman yang bisa dirender")
+	}
+
+	bahasa := envOr("OCR_BAHASA", "ind+eng")
+	hasil := make([]HasilOCR, 0, len(gambar))
+	for _, g := range gambar {
+		tsv, err := jalankan(ctx, envOr("TESSERACT_BIN", "tesseract"), g, "stdout", "-l", bahasa, "tsv")
+		if err != nil {
+			return nil, fmt.Errorf("tesseract %s: %v", filepath.Base(g), err)
+		}
+		hasil = append(hasil, bacaTSV(tsv))
+	}
+	return hasil, nil
+}
+
+func jalankan(ctx context.Context, bin string, args ...string) ([]byte, error) {
+	var out, errOut bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &errOut
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%v %s", err, strings.TrimSpace(errOut.String()))
+	}
+	return out.Bytes(), nil
+}
+
+func nomorHalaman(path string) int {
+	nama := strings.TrimSuffix(filepath.Base(path), ".png")
+	n, _ := strconv.Atoi(nama[strings.LastIndex(nama, "-")+1:])
+	return n
+}
+
+// bacaTSV menyusun ulang teks per baris dari output TSV Tesseract dan menghitung rata-rata confidence kata.
+// Kolom TSV: level page_num block_num par_num line_num word_num left top width height conf text
+func bacaTSV(tsv []byte) HasilOCR {
+	var (
+		teks       strings.Builder
+		barisLalu  string
+		totalConf  float64
+		jumlahKata int
+	)
+
+	sc := bufio.NewScanner(bytes.NewReader(tsv))
+	sc.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for sc.Scan() {
+		kolom := strings.Split(sc.Text(), "\t")
+		if len(kolom) < 12 || kolom[0] != "5" { // level 5 = kata
+			continue
+		}
+		kata := strings.TrimSpace(kolom[11])
+		conf, err := strconv.ParseFloat(kolom[10], 64)
+		if kata == "" || err != nil || conf < 0 {
+			continue
+		}
+
+		baris := kolom[2] + "." + kolom[3] + "." + kolom[4]
+		switch {
+		case barisLalu == "":
+		case baris != barisLalu:
+			teks.WriteString("\n")
+		default:
+			teks.WriteString(" ")
+		}
+		barisLalu = baris
+
+		teks.WriteString(kata)
+		totalConf += conf
+		jumlahKata++
+	}
+
+	h := HasilOCR{Teks: teks.String()}
+	if jumlahKata > 0 {
+		h.Keyakinan = totalConf / float64(jumlahKata)
+	}
+	return h
+}
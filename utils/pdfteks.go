@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"html/template"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// binPdftotext memakai env PDFTOTEXT_BIN, default "pdftotext" dari poppler-utils
+func binPdftotext() string {
+	return envOr("PDFTOTEXT_BIN", "pdftotext")
+}
+
+// EkstrakTeksPDF mengambil teks PDF per halaman (index 0 = halaman 1) memakai pdftotext.
+// pdftotext memisahkan halaman dengan karakter form feed.
+func EkstrakTeksPDF(path string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	var out, errOut bytes.Buffer
+	cmd := exec.CommandContext(ctx, binPdftotext(), "-layout", "-enc", "UTF-8", path, "-")
+	cmd.Stdout = &out
+	cmd.Stderr = &errOut
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("pdftotext %s: %v %s", path, err, strings.TrimSpace(errOut.String()))
+	}
+
+	halaman := strings.Split(out.String(), "\f")
+	// form feed terakhir menghasilkan elemen kosong
+	if n := len(halaman); n > 0 && strings.TrimSpace(halaman[n-1]) == "" {
+		halaman = halaman[:n-1]
+	}
+	for i := range halaman {
+		halaman[i] = strings.ToValidUTF8(halaman[i], "")
+	}
+	return halaman, nil
+}
+
+// FileAda true kalau path menunjuk ke file biasa yang ada di disk
+func FileAda(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// SnippetSorot memotong teks di sekitar kemunculan pertama kata kunci dan menandainya dengan <mark>.
+// Teks di-escape dulu sehingga aman ditampilkan sebagai HTML.
+func SnippetSorot(teks, kataKunci string, lebar int) template.HTML {
+	teks = strings.Join(strings.Fields(teks), " ")
+	kataKunci = strings.TrimSpace(kataKunci)
+	if teks == "" {
+		return ""
+	}
+
+	lower := strings.ToLower(teks)
+	pos := -1
+	panjang := 0
+	// cari frasa utuh dulu, kalau tidak ketemu cari per kata
+	for _, k := range append([]string{kataKunci}, strings.Fields(kataKunci)...) {
+		if k == "" {
+			continue
+		}
+		if i := strings.Index(lower, strings.ToLower(k)); i >= 0 {
+			pos, panjang = i, len(k)
+			break
+		}
+	}
+
+	// ToLower bisa mengubah panjang byte untuk huruf tertentu, jadi cek lagi batasnya
+	if pos < 0 || pos+panjang > len(teks) {
+		return template.HTML(html.EscapeString(potongRune(teks, 2*lebar)) + "…")
+	}
+
+	awal := pos - lebar
+	if awal < 0 {
+		awal = 0
+	}
+	akhir := pos + panjang + lebar
+	if akhir > len(teks) {
+		akhir = len(teks)
+	}
+	// jangan memotong di tengah karakter multi-byte
+	for awal > 0 && !utf8.RuneStart(teks[awal]) {
+		awal--
+	}
+	for akhir < len(teks) && !utf8.RuneStart(teks[akhir]) {
+		akhir++
+	}
+
+	var b strings.Builder
+	if awal > 0 {
+		b.WriteString("…")
+	}
+	b.WriteString(html.EscapeString(teks[awal:pos]))
+	b.WriteString("<mark>")
+	b.WriteString(html.EscapeString(teks[pos : pos+panjang]))
+	b.WriteString("</mark>")
+	b.WriteString(html.EscapeString(teks[pos+panjang : akhir]))
+	if akhir < len(teks) {
+		b.WriteString("…")
+	}
+	return template.HTML(b.String())
+}
+
+// potongRune mengambil n karakter pertama tanpa merusak karakter multi-byte
+func potongRune(s string, n int) string {
+	r := []rune(s)
+	if n > len(r) {
+		n = len(r)
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// bucket menyimpan sisa token untuk satu klien (IP, user login atau API token)
+type bucket struct {
+	token    float64
+	terakhir time.Time
+}
+
+// RateLimiter adalah token bucket per klien dengan budget per menit dan burst
+type RateLimiter struct {
+	Nama      string
+	PerMenit  int
+	Burst     int
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	bersihkan time.Time
+
+	diizinkan atomic.Int64
+	ditolak   atomic.Int64
+}
+
+// StatRateLimit adalah ringkasan metrik satu limiter
+type StatRateLimit struct {
+	Nama      string `json:"nama"`
+	PerMenit  int    `json:"per_menit"`
+	Burst     int    `json:"burst"`
+	Klien     int    `json:"klien_aktif"`
+	Diizinkan int64  `json:"diizinkan"`
+	Ditolak   int64  `json:"ditolak"`
+}
+
+var (
+	daftarLimiterMu sync.Mutex
+	daftarLimiter   []*RateLimiter
+)
+
+// NewRateLimiter membuat limiter dari env RATE_LIMIT_<NAMA>_PER_MENIT dan RATE_LIMIT_<NAMA>_BURST.
+// Budget per menit 0 berarti tanpa batas.
+func NewRateLimiter(nama string, perMenit, burst int) *RateLimiter {
+	prefix := "RATE_LIMIT_" + strings.ToUpper(nama)
+	if v, err := strconv.Atoi(os.Getenv(prefix + "_PER_MENIT")); err == nil {
+		perMenit = v
+	}
+	if v, err := strconv.Atoi(os.Getenv(prefix + "_BURST")); err == nil {
+		burst = v
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	rl := &RateLimiter{
+		Nama:      nama,
+		PerMenit:  perMenit,
+		Burst:     burst,
+		buckets:   map[string]*bucket{},
+		bersihkan: time.Now(),
+	}
+
+	daftarLimiterMu.Lock()
+	daftarLimiter = append(daftarLimiter, rl)
+	daftarLimiterMu.Unlock()
+	return rl
+}
+
+// Allow mengambil satu token untuk key. Kalau habis, kembalikan waktu tunggu sampai token berikutnya.
+func (rl *RateLimiter) Allow(key string) (bool, int, time.Duration) {
+	if rl.PerMenit <= 0 {
+		return true, rl.Burst, 0
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	perDetik := float64(rl.PerMenit) / 60
+
+	// buang bucket klien yang sudah lama tidak aktif (sudah penuh lagi)
+	if now.Sub(rl.bersihkan) > time.Minute {
+		penuh := time.Duration(float64(rl.Burst)/perDetik*float64(time.Second)) + time.Minute
+		for k, b := range rl.buckets {
+			if now.Sub(b.terakhir) > penuh {
+				delete(rl.buckets, k)
+			}
+		}
+		rl.bersihkan = now
+	}
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{token: float64(rl.Burst), terakhir: now}
+		rl.buckets[key] = b
+	}
+
+	b.token = math.Min(float64(rl.Burst), b.token+now.Sub(b.terakhir).Seconds()*perDetik)
+	b.terakhir = now
+
+	if b.token < 1 {
+		rl.ditolak.Add(1)
+		tunggu := time.Duration((1 - b.token) / perDetik * float64(time.Second))
+		return false, 0, tunggu
+	}
+
+	b.token--
+	rl.diizinkan.Add(1)
+	return true, int(b.token), 0
+}
+
+// Stat mengembalikan metrik limiter saat ini
+func (rl *RateLimiter) Stat() StatRateLimit {
+	rl.mu.Lock()
+	klien := len(rl.buckets)
+	rl.mu.Unlock()
+
+	return StatRateLimit{
+		Nama:      rl.Nama,
+		PerMenit:  rl.PerMenit,
+		Burst:     rl.Burst,
+		Klien:     klien,
+		Diizinkan: rl.diizinkan.Load(),
+		Ditolak:   rl.ditolak.Load(),
+	}
+}
+
+// SemuaStatRateLimit mengembalikan metrik semua limiter yang terdaftar
+func SemuaStatRateLimit() []StatRateLimit {
+	daftarLimiterMu.Lock()
+	defer daftarLimiterMu.Unlock()
+
+	hasil := make([]StatRateLimit, 0, len(daftarLimiter))
+	for _, rl := range daftarLimiter {
+		hasil = append(hasil, rl.Stat())
+	}
+	return hasil
+}
+
+// ValidasiTokenAPI memeriksa API token ke penyimpanan token dan mengembalikan pemiliknya.
+// Selama aplikasi belum punya API token nilainya nil, jadi header token selalu diabaikan.
+var ValidasiTokenAPI func(token string) (pemilik string, ok bool)
+
+// tokenDariHeader membaca X-API-Token atau Authorization: Bearer
+func tokenDariHeader(c *gin.Context) string {
+	if token := c.GetHeader("X-API-Token"); token != "" {
+		return token
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
+
+// kunciKlien memakai IP klien. c.ClientIP() sudah menghormati daftar trusted proxy dari main.go.
+func kunciKlien(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// kunciIdentitas memakai API token yang sudah divalidasi, lalu user sesi login, baru IP klien.
+// Token yang tidak lolos validasi tidak dipakai sebagai kunci supaya klien tidak bisa mengarang
+// bucket baru; sesi cookie sudah ditandatangani server sehingga username tidak bisa dipalsukan.
+func kunciIdentitas(c *gin.Context) string {
+	if token := tokenDariHeader(c); token != "" && ValidasiTokenAPI != nil {
+		if _, ok := ValidasiTokenAPI(token); ok {
+			// token tidak disimpan mentah di memori
+			sum := sha256.Sum256([]byte(token))
+			return "token:" + hex.EncodeToString(sum[:8])
+		}
+	}
+	if _, ada := c.Get(sessions.DefaultKey); ada {
+		if user, _ := sessions.Default(c).Get("user").(string); user != "" {
+			return "user:" + user
+		}
+	}
+	return kunciKlien(c)
+}
+
+// RateLimit membatasi per IP klien, dipakai untuk route publik
+func RateLimit(rl *RateLimiter) gin.HandlerFunc {
+	return rateLimit(rl, kunciKlien)
+}
+
+// RateLimitIdentitas membatasi per API token/user login (lihat kunciIdentitas), dipakai untuk route
+// auth dan API supaya user di belakang satu NAT kantor tidak berbagi budget
+func RateLimitIdentitas(rl *RateLimiter) gin.HandlerFunc {
+	return rateLimit(rl, kunciIdentitas)
+}
+
+// rateLimit adalah middleware yang menolak request dengan 429 + Retry-After kalau budget habis
+func rateLimit(rl *RateLimiter, kunci func(*gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, sisa, tunggu := rl.Allow(kunci(c))
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(rl.PerMenit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(sisa))
+
+		if !ok {
+			detik := int(math.Ceil(tunggu.Seconds()))
+			if detik < 1 {
+				detik = 1
+			}
+			c.Header("Retry-After", strconv.Itoa(detik))
+
+			pesan := "Terlalu banyak permintaan, coba lagi dalam " + strconv.Itoa(detik) + " detik"
+			if strings.Contains(c.GetHeader("Accept"), "application/json") || strings.HasPrefix(c.Request.URL.Path, "/api/") {
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": pesan})
+				return
+			}
+			c.String(http.StatusTooManyRequests, "🚫 "+pesan)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitIdentitasBucketPerToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	lama := ValidasiTokenAPI
+	ValidasiTokenAPI = func(token string) (string, bool) {
+		return token, token == "token-a" || token == "token-b"
+	}
+	defer func() { ValidasiTokenAPI = lama }()
+
+	r := gin.New()
+	r.GET("/api", RateLimitIdentitas(&RateLimiter{Nama: "tes", PerMenit: 1, Burst: 1, buckets: map[string]*bucket{}}),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	minta := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		if token != "" {
+			req.Header.Set("X-API-Token", token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	cek := func(token string, mau int) {
+		t.Helper()
+		if got := minta(token); got != mau {
+			t.Fatalf("token %q: status %d, mau %d", token, got, mau)
+		}
+	}
+
+	// dua token dari IP yang sama punya bucket sendiri-sendiri
+	cek("token-a", http.StatusOK)
+	cek("token-a", http.StatusTooManyRequests)
+	cek("token-b", http.StatusOK)
+
+	// token yang tidak valid tidak membuat bucket baru, jatuh ke bucket IP
+	cek("palsu-1", http.StatusOK)
+	cek("palsu-2", http.StatusTooManyRequests)
+}
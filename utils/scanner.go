@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HasilScan adalah hasil pemindaian satu file
+type HasilScan struct {
+	Bersih bool
+	Virus  string // nama signature kalau terinfeksi
+}
+
+// Scanner adalah antivirus yang dipanggil sebelum file upload disimpan.
+// Implementasi lain (mis. layanan cloud) cukup memenuhi interface ini lalu dipasang dengan SetScanner.
+type Scanner interface {
+	Nama() string
+	Scan(r io.Reader) (HasilScan, error)
+}
+
+// ================= NOOP =================
+
+// NoopScanner dipakai kalau tidak ada antivirus yang dikonfigurasi, semua file dianggap bersih
+type NoopScanner struct{}
+
+func (NoopScanner) Nama() string { return "tidak ada" }
+
+func (NoopScanner) Scan(r io.Reader) (HasilScan, error) {
+	return HasilScan{Bersih: true}, nil
+}
+
+// ================= CLAMAV =================
+
+// ClamdScanner mengirim file ke daemon clamd dengan perintah INSTREAM
+type ClamdScanner struct {
+	Network string // "unix" atau "tcp"
+	Address string // /var/run/clamav/clamd.ctl atau 127.0.0.1:3310
+	Timeout time.Duration
+}
+
+func (s ClamdScanner) Nama() string { return "ClamAV (" + s.Network + ":" + s.Address + ")" }
+
+// Scan mengalirkan isi file ke clamd dalam potongan (chunk) dengan prefix panjang 4 byte big-endian,
+// diakhiri chunk kosong, lalu membaca balasan "stream: OK" atau "stream: <virus> FOUND".
+func (s ClamdScanner) Scan(r io.Reader) (HasilScan, error) {
+	conn, err := net.DialTimeout(s.Network, s.Address, s.Timeout)
+	if err != nil {
+		return HasilScan{}, fmt.Errorf("clamd tidak bisa dihubungi: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(s.Timeout))
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return HasilScan{}, err
+	}
+
+	buf := make([]byte, 32*1024)
+	panjang := make([]byte, 4)
+	for {
+		n, errBaca := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(panjang, uint32(n))
+			if _, err := conn.Write(panjang); err != nil {
+				return HasilScan{}, err
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return HasilScan{}, err
+			}
+		}
+		if errBaca == io.EOF {
+			break
+		}
+		if errBaca != nil {
+			return HasilScan{}, errBaca
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return HasilScan{}, err
+	}
+
+	balasan, err := io.ReadAll(conn)
+	if err != nil {
+		return HasilScan{}, err
+	}
+	return bacaBalasanClamd(string(bytes.TrimRight(balasan, "\x00\n")))
+}
+
+func bacaBalasanClamd(balasan string) (HasilScan, error) {
+	balasan = strings.TrimPrefix(balasan, "stream: ")
+	switch {
+	case balasan == "OK":
+		return HasilScan{Bersih: true}, nil
+	case strings.HasSuffix(balasan, " FOUND"):
+		return HasilScan{Virus: strings.TrimSuffix(balasan, " FOUND")}, nil
+	default:
+		// mis. "INSTREAM size limit exceeded. ERROR"
+		return HasilScan{}, fmt.Errorf("clamd: %s", balasan)
+	}
+}
+
+// ================= KONFIGURASI =================
+
+var (
+	scannerMu    sync.RWMutex
+	scannerAktif Scanner
+)
+
+// scannerDariEnv membaca CLAMD_ADDRESS, formatnya "unix:/path/clamd.ctl" atau "tcp:host:port"
+func scannerDariEnv() Scanner {
+	alamat := os.Getenv("CLAMD_ADDRESS")
+	if alamat == "" {
+		return NoopScanner{}
+	}
+
+	network, address, ok := strings.Cut(alamat, ":")
+	if !ok || (network != "unix" && network != "tcp") {
+		log.Printf("CLAMD_ADDRESS tidak valid (%s), pakai format unix:/path atau tcp:host:port", alamat)
+		return NoopScanner{}
+	}
+
+	timeout := 60 * time.Second
+	if d, err := time.ParseDuration(os.Getenv("CLAMD_TIMEOUT")); err == nil && d > 0 {
+		timeout = d
+	}
+	return ClamdScanner{Network: network, Address: address, Timeout: timeout}
+}
+
+// ScannerDokumen mengembalikan scanner yang aktif (dibaca dari env saat pertama dipakai)
+func ScannerDokumen() Scanner {
+	scannerMu.RLock()
+	s := scannerAktif
+	scannerMu.RUnlock()
+	if s != nil {
+		return s
+	}
+
+	scannerMu.Lock()
+	defer scannerMu.Unlock()
+	if scannerAktif == nil {
+		scannerAktif = scannerDariEnv()
+	}
+	return scannerAktif
+}
+
+// SetScanner mengganti scanner yang dipakai aplikasi
+func SetScanner(s Scanner) {
+	scannerMu.Lock()
+	scannerAktif = s
+	scannerMu.Unlock()
+}
+
+// ScanGagalTertutup true kalau upload harus ditolak saat antivirus error/tidak bisa dihubungi.
+// Default true, set CLAMD_FAIL_OPEN=true untuk tetap menerima file.
+func ScanGagalTertutup() bool {
+	return os.Getenv("CLAMD_FAIL_OPEN") != "true"
+}
+
+// ScanUpload memindai file upload sebelum disimpan ke disk
+func ScanUpload(file *multipart.FileHeader) (HasilScan, error) {
+	src, err := file.Open()
+	if err != nil {
+		return HasilScan{}, err
+	}
+	defer src.Close()
+	return ScannerDokumen().Scan(src)
+}
+
+// ScanFile memindai file yang sudah ada di disk
+func ScanFile(path string) (HasilScan, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return HasilScan{}, err
+	}
+	defer f.Close()
+	return ScannerDokumen().Scan(f)
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// kunciLinkDokumen memakai DOCUMENT_SIGNING_KEY, kalau kosong pakai SESSION_SECRET
+func kunciLinkDokumen() []byte {
+	if k := os.Getenv("DOCUMENT_SIGNING_KEY"); k != "" {
+		return []byte(k)
+	}
+	return []byte(os.Getenv("SESSION_SECRET"))
+}
+
+// MasaBerlakuLink adalah umur link dokumen bertanda tangan (env DOCUMENT_LINK_TTL, dalam menit)
+func MasaBerlakuLink() time.Duration {
+	if menit, err := strconv.Atoi(os.Getenv("DOCUMENT_LINK_TTL")); err == nil && menit > 0 {
+		return time.Duration(menit) * time.Minute
+	}
+	return 30 * time.Minute
+}
+
+func tandaTanganDokumen(tipe, id string, exp int64) string {
+	mac := hmac.New(sha256.New, kunciLinkDokumen())
+	fmt.Fprintf(mac, "%s/%s/%d", tipe, id, exp)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// LinkDokumen membuat URL /view-document yang ditandatangani HMAC dan kedaluwarsa setelah MasaBerlakuLink
+func LinkDokumen(tipe string, id uint) string {
+	idStr := strconv.FormatUint(uint64(id), 10)
+	exp := time.Now().Add(MasaBerlakuLink()).Unix()
+	return fmt.Sprintf("/view-document/%s/%s?exp=%d&sig=%s", tipe, idStr, exp, tandaTanganDokumen(tipe, idStr, exp))
+}
+
+// VerifikasiLinkDokumen mengecek signature dan masa berlaku link dokumen
+func VerifikasiLinkDokumen(tipe, id, expStr, sig string) bool {
+	if sig == "" {
+		return false
+	}
+	exp, err := strconv.ParseInt(expStr, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(tandaTanganDokumen(tipe, id, exp)))
+}
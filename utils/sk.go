@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SaranSK adalah tebakan metadata SK dari teks dokumen, hanya saran untuk diperiksa admin
+type SaranSK struct {
+	NomorSK       string
+	TanggalSK     string // format 2006-01-02 supaya langsung cocok dengan input type=date
+	PejabatSK     string
+	BerlakuSampai string
+}
+
+// Kosong true kalau tidak ada satu pun field yang berhasil ditebak
+func (s SaranSK) Kosong() bool {
+	return s.NomorSK == "" && s.TanggalSK == "" && s.PejabatSK == "" && s.BerlakuSampai == ""
+}
+
+var bulanIndonesia = map[string]time.Month{
+	"januari": time.January, "februari": time.February, "maret": time.March,
+	"april": time.April, "mei": time.May, "juni": time.June,
+	"juli": time.July, "agustus": time.August, "september": time.September,
+	"oktober": time.October, "november": time.November, "nopember": time.November,
+	"desember": time.December,
+}
+
+var (
+	reNomorSK = regexp.MustCompile(`(?i)\bnomor\s*:?\s*([0-9A-Za-z][0-9A-Za-z./\-]*[0-9A-Za-z])`)
+	// "pada tanggal 12 Januari 2024" / "tanggal : 12 Januari 2024"
+	reTanggalSK = regexp.MustCompile(`(?i)(?:ditetapkan[^\n]*?)?pada\s+tanggal\s*:?\s*(\d{1,2})\s+([a-z]+)\s+(\d{4})`)
+	reTanggal   = regexp.MustCompile(`(?i)(\d{1,2})\s+(januari|februari|maret|april|mei|juni|juli|agustus|september|oktober|nopember|november|desember)\s+(\d{4})`)
+	// "berlaku sampai dengan tanggal 31 Desember 2026" / "berlaku s.d. 31 Desember 2026"
+	reBerlakuSampai = regexp.MustCompile(`(?i)berlaku\s+(?:sampai\s+dengan|s\.?\s?d\.?|hingga)\s+(?:tanggal\s+)?(\d{1,2})\s+([a-z]+)\s+(\d{4})`)
+	// "berlaku selama 3 (tiga) tahun"
+	reBerlakuSelama = regexp.MustCompile(`(?i)berlaku\s+(?:untuk\s+)?(?:jangka\s+waktu\s+)?selama\s+(\d{1,2})\s*(?:\([a-z ]+\)\s*)?tahun`)
+	// jabatan penandatangan yang umum di SK desa/kabupaten
+	reJabatan = regexp.MustCompile(`(?i)^\s*(kepala\s+desa|lurah|camat|bupati|walikota|wali\s+kota|gubernur|kepala\s+kantor\s+wilayah)\b.*$`)
+)
+
+func tanggalIndonesia(hari, bulan, tahun string) (time.Time, bool) {
+	b, ok := bulanIndonesia[strings.ToLower(bulan)]
+	if !ok {
+		return time.Time{}, false
+	}
+	h, _ := strconv.Atoi(hari)
+	t, _ := strconv.Atoi(tahun)
+	if h < 1 || h > 31 || t < 1900 {
+		return time.Time{}, false
+	}
+	return time.Date(t, b, h, 0, 0, 0, 0, time.Local), true
+}
+
+// TebakSK mencari nomor, tanggal, pejabat penandatangan dan masa berlaku SK dari teks PDF/OCR.
+// halaman diurutkan dari halaman pertama, penandatangan biasanya di halaman terakhir.
+func TebakSK(halaman []string) SaranSK {
+	var saran SaranSK
+	semua := strings.Join(halaman, "\n")
+
+	if m := reNomorSK.FindStringSubmatch(semua); m != nil {
+		saran.NomorSK = m[1]
+	}
+
+	var tanggal time.Time
+	if m := reTanggalSK.FindStringSubmatch(semua); m != nil {
+		tanggal, _ = tanggalIndonesia(m[1], m[2], m[3])
+	} else if m := reTanggal.FindStringSubmatch(semua); m != nil {
+		tanggal, _ = tanggalIndonesia(m[1], m[2], m[3])
+	}
+	if !tanggal.IsZero() {
+		saran.TanggalSK = tanggal.Format("2006-01-02")
+	}
+
+	if m := reBerlakuSampai.FindStringSubmatch(semua); m != nil {
+		if t, ok := tanggalIndonesia(m[1], m[2], m[3]); ok {
+			saran.BerlakuSampai = t.Format("2006-01-02")
+		}
+	} else if m := reBerlakuSelama.FindStringSubmatch(semua); m != nil && !tanggal.IsZero() {
+		tahun, _ := strconv.Atoi(m[1])
+		saran.BerlakuSampai = tanggal.AddDate(tahun, 0, -1).Format("2006-01-02")
+	}
+
+	// penandatangan: baris jabatan terakhir, nama biasanya beberapa baris di bawahnya
+	if len(halaman) > 0 {
+		baris := strings.Split(halaman[len(halaman)-1], "\n")
+		for i := len(baris) - 1; i >= 0; i-- {
+			if !reJabatan.MatchString(baris[i]) {
+				continue
+			}
+			jabatan := strings.Join(strings.Fields(baris[i]), " ")
+			saran.PejabatSK = jabatan
+			for _, b := range baris[i+1:] {
+				nama := strings.Join(strings.Fields(b), " ")
+				if nama == "" || strings.EqualFold(nama, "ttd") || strings.HasPrefix(strings.ToLower(nama), "ttd") {
+					continue
+				}
+				saran.PejabatSK = nama + " (" + jabatan + ")"
+				break
+			}
+			break
+		}
+	}
+
+	return saran
+}
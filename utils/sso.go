@@ -0,0 +1,236 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-ldap/ldap/v3"
+	"golang.org/x/oauth2"
+)
+
+// ErrKredensialDirektori dipakai kalau bind LDAP gagal atau user tidak ditemukan di direktori
+var ErrKredensialDirektori = errors.New("username atau password direktori salah")
+
+// IdentitasDirektori adalah data user hasil login SSO (OIDC maupun LDAP)
+type IdentitasDirektori struct {
+	ExternalID string
+	Username   string
+	Email      string
+	Grup       []string
+}
+
+// envOr mengambil env, pakai nilai bawaan kalau kosong
+func envOr(key, bawaan string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return bawaan
+}
+
+// ================= OIDC =================
+
+var (
+	oidcMu       sync.Mutex
+	oidcProvider *oidc.Provider
+)
+
+// OIDCAktif true kalau provider OIDC sudah dikonfigurasi di .env
+func OIDCAktif() bool {
+	return os.Getenv("OIDC_ISSUER") != "" && os.Getenv("OIDC_CLIENT_ID") != ""
+}
+
+// OIDCConfig menyiapkan konfigurasi OAuth2 dan verifier ID token.
+// Discovery ke issuer baru dilakukan saat pertama dipakai, lalu di-cache.
+func OIDCConfig(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+
+	if oidcProvider == nil {
+		provider, err := oidc.NewProvider(ctx, os.Getenv("OIDC_ISSUER"))
+		if err != nil {
+			return nil, nil, err
+		}
+		oidcProvider = provider
+	}
+
+	clientID := os.Getenv("OIDC_CLIENT_ID")
+	cfg := &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		Endpoint:     oidcProvider.Endpoint(),
+		Scopes:       strings.Fields(envOr("OIDC_SCOPES", "openid profile email groups")),
+	}
+	return cfg, oidcProvider.Verifier(&oidc.Config{ClientID: clientID}), nil
+}
+
+// IdentitasDariIDToken membaca subject, username, email, dan grup dari klaim ID token
+func IdentitasDariIDToken(token *oidc.IDToken) (*IdentitasDirektori, error) {
+	var klaim map[string]any
+	if err := token.Claims(&klaim); err != nil {
+		return nil, err
+	}
+
+	identitas := &IdentitasDirektori{ExternalID: token.Subject}
+	if v, ok := klaim[envOr("OIDC_USERNAME_CLAIM", "preferred_username")].(string); ok {
+		identitas.Username = v
+	}
+	if v, ok := klaim["email"].(string); ok {
+		identitas.Email = v
+	}
+	if identitas.Username == "" {
+		identitas.Username = identitas.Email
+	}
+	if identitas.Username == "" {
+		return nil, errors.New("klaim username tidak ada di ID token")
+	}
+
+	if daftar, ok := klaim[envOr("OIDC_GROUPS_CLAIM", "groups")].([]any); ok {
+		for _, g := range daftar {
+			if s, ok := g.(string); ok {
+				identitas.Grup = append(identitas.Grup, s)
+			}
+		}
+	}
+	return identitas, nil
+}
+
+// ================= LDAP =================
+
+// LDAPAktif true kalau server LDAP sudah dikonfigurasi di .env
+func LDAPAktif() bool {
+	return os.Getenv("LDAP_URL") != "" && os.Getenv("LDAP_BASE_DN") != ""
+}
+
+// LDAPAuthenticate mencari DN user pakai akun layanan, lalu bind ulang pakai password user.
+func LDAPAuthenticate(username, password string) (*IdentitasDirektori, error) {
+	// password kosong = unauthenticated bind, yang di banyak server dianggap sukses
+	if username == "" || password == "" {
+		return nil, ErrKredensialDirektori
+	}
+
+	conn, err := ldap.DialURL(os.Getenv("LDAP_URL"))
+	if err != nil {
+		return nil, fmt.Errorf("gagal koneksi LDAP: %w", err)
+	}
+	defer conn.Close()
+
+	if os.Getenv("LDAP_STARTTLS") == "true" {
+		u, _ := url.Parse(os.Getenv("LDAP_URL"))
+		if err := conn.StartTLS(&tls.Config{ServerName: u.Hostname()}); err != nil {
+			return nil, fmt.Errorf("gagal StartTLS LDAP: %w", err)
+		}
+	}
+
+	if bindDN := os.Getenv("LDAP_BIND_DN"); bindDN != "" {
+		if err := conn.Bind(bindDN, os.Getenv("LDAP_BIND_PASSWORD")); err != nil {
+			return nil, fmt.Errorf("gagal bind akun layanan LDAP: %w", err)
+		}
+	}
+
+	attrUsername := envOr("LDAP_USERNAME_ATTR", "uid")
+	attrGrup := envOr("LDAP_GROUP_ATTR", "memberOf")
+	filter := fmt.Sprintf(envOr("LDAP_USER_FILTER", "(uid=%s)"), ldap.EscapeFilter(username))
+
+	hasil, err := conn.Search(ldap.NewSearchRequest(
+		os.Getenv("LDAP_BASE_DN"),
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		filter,
+		[]string{attrUsername, "mail", attrGrup},
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("gagal mencari user LDAP: %w", err)
+	}
+	if len(hasil.Entries) != 1 {
+		return nil, ErrKredensialDirektori
+	}
+	entry := hasil.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		return nil, ErrKredensialDirektori
+	}
+
+	identitas := &IdentitasDirektori{
+		ExternalID: entry.DN,
+		Username:   entry.GetAttributeValue(attrUsername),
+		Email:      entry.GetAttributeValue("mail"),
+		Grup:       entry.GetAttributeValues(attrGrup),
+	}
+	if identitas.Username == "" {
+		identitas.Username = username
+	}
+
+	// OpenLDAP tanpa overlay memberOf: cari grup yang memuat DN user
+	if filterGrup := os.Getenv("LDAP_GROUP_FILTER"); filterGrup != "" {
+		if bindDN := os.Getenv("LDAP_BIND_DN"); bindDN != "" {
+			_ = conn.Bind(bindDN, os.Getenv("LDAP_BIND_PASSWORD"))
+		}
+		grup, err := conn.Search(ldap.NewSearchRequest(
+			envOr("LDAP_GROUP_BASE_DN", os.Getenv("LDAP_BASE_DN")),
+			ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+			fmt.Sprintf(filterGrup, ldap.EscapeFilter(entry.DN)),
+			[]string{"dn"},
+			nil,
+		))
+		if err == nil {
+			for _, g := range grup.Entries {
+				identitas.Grup = append(identitas.Grup, g.DN)
+			}
+		}
+	}
+
+	return identitas, nil
+}
+
+// ================= Pemetaan Grup =================
+
+// RoleDariGrup memetakan grup direktori ke role aplikasi, role tertinggi yang menang.
+// Tiap daftar berisi nama grup dipisah koma; grup cocok kalau sama
+// dengan DN lengkap atau CN-nya. Hasil kosong berarti user tidak punya akses.
+func RoleDariGrup(grup []string, grupAdmin, grupVerifikator, grupOperator, grupUser string) string {
+	if grupCocok(grup, grupAdmin) {
+		return "admin"
+	}
+	if grupCocok(grup, grupVerifikator) {
+		return "verifikator"
+	}
+	if grupCocok(grup, grupOperator) {
+		return "operator"
+	}
+	if grupCocok(grup, grupUser) {
+		return "user"
+	}
+	return ""
+}
+
+func grupCocok(grup []string, daftar string) bool {
+	for _, target := range strings.Split(daftar, ",") {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		for _, g := range grup {
+			if strings.EqualFold(g, target) || strings.EqualFold(namaGrup(g), target) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// namaGrup mengambil nilai RDN pertama, misal "cn=jadi-admin,ou=groups,..." -> "jadi-admin"
+func namaGrup(g string) string {
+	dn, err := ldap.ParseDN(g)
+	if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) == 0 {
+		return g
+	}
+	return dn.RDNs[0].Attributes[0].Value
+}
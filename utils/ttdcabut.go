@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// ================= PENCABUTAN SERTIFIKAT (CRL/OCSP) =================
+
+// Hasil pemeriksaan pencabutan sertifikat penanda tangan
+const (
+	cabutTidakDiketahui = iota // tidak ada sumber CRL/OCSP yang bisa dipakai
+	cabutBaik                  // sertifikat tidak dicabut
+	cabutDicabut               // sertifikat sudah dicabut penerbitnya
+)
+
+// batas ukuran respons CRL/OCSP yang diunduh
+const maksUnduhCabut = 20 << 20
+
+var klienCabut = &http.Client{Timeout: 10 * time.Second}
+
+// cacheCRL menyimpan CRL yang sudah diunduh sampai NextUpdate supaya verifikasi ulang massal
+// tidak mengunduh CRL yang sama untuk setiap dokumen
+var (
+	cacheCRLMu sync.Mutex
+	cacheCRL   = map[string]*x509.RevocationList{}
+)
+
+// cekPencabutanAktif -> false kalau server tidak boleh/bisa mengakses CRL/OCSP penerbit (TTD_CEK_PENCABUTAN=0).
+// CRL lokal (*.crl di folder trust store) tetap dipakai.
+func cekPencabutanAktif() bool {
+	return envOr("TTD_CEK_PENCABUTAN", "1") != "0"
+}
+
+// periksaPencabutan memeriksa status sertifikat lewat CRL lokal, OCSP, lalu titik distribusi CRL.
+// Sumber yang tidak bisa dihubungi atau tidak sah dilewati; keterangan menjelaskan sumber yang dipakai.
+func periksaPencabutan(cert, issuer *x509.Certificate) (int, string) {
+	if hasil, ok := cekCRLLokal(cert, issuer); ok {
+		return hasil, "CRL lokal"
+	}
+	if !cekPencabutanAktif() {
+		return cabutTidakDiketahui, "pemeriksaan CRL/OCSP online dimatikan"
+	}
+
+	for _, url := range cert.OCSPServer {
+		if hasil, err := cekOCSP(url, cert, issuer); err == nil {
+			return hasil, "OCSP " + url
+		}
+	}
+	for _, url := range cert.CRLDistributionPoints {
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			continue
+		}
+		crl, err := unduhCRL(url, issuer)
+		if err != nil {
+			continue
+		}
+		return statusDariCRL(crl, cert), "CRL " + url
+	}
+	return cabutTidakDiketahui, "CRL/OCSP penerbit tidak bisa diperiksa"
+}
+
+// statusDariCRL mencari serial sertifikat di daftar pencabutan
+func statusDariCRL(crl *x509.RevocationList, cert *x509.Certificate) int {
+	for _, r := range crl.RevokedCertificateEntries {
+		if r.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return cabutDicabut
+		}
+	}
+	return cabutBaik
+}
+
+// crlMasihBerlaku -> CRL ditandatangani penerbit sertifikat dan belum lewat NextUpdate
+func crlMasihBerlaku(crl *x509.RevocationList, issuer *x509.Certificate) bool {
+	if crl.CheckSignatureFrom(issuer) != nil {
+		return false
+	}
+	return crl.NextUpdate.IsZero() || time.Now().Before(crl.NextUpdate)
+}
+
+// cekCRLLokal memakai file *.crl (PEM atau DER) di folder trust store, untuk server tanpa akses internet
+func cekCRLLokal(cert, issuer *x509.Certificate) (int, bool) {
+	files, err := os.ReadDir(folderTrustStore())
+	if err != nil {
+		return cabutTidakDiketahui, false
+	}
+	for _, f := range files {
+		if f.IsDir() || strings.ToLower(filepath.Ext(f.Name())) != ".crl" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(folderTrustStore(), f.Name()))
+		if err != nil {
+			continue
+		}
+		if blok, _ := pem.Decode(data); blok != nil {
+			data = blok.Bytes
+		}
+		crl, err := x509.ParseRevocationList(data)
+		if err != nil || !bytes.Equal(crl.RawIssuer, cert.RawIssuer) || !crlMasihBerlaku(crl, issuer) {
+			continue
+		}
+		return statusDariCRL(crl, cert), true
+	}
+	return cabutTidakDiketahui, false
+}
+
+// unduhCRL mengambil CRL dari cache atau dari URL titik distribusinya
+func unduhCRL(url string, issuer *x509.Certificate) (*x509.RevocationList, error) {
+	cacheCRLMu.Lock()
+	crl, ok := cacheCRL[url]
+	cacheCRLMu.Unlock()
+	if ok && crlMasihBerlaku(crl, issuer) {
+		return crl, nil
+	}
+
+	data, err := unduhCabut(klienCabut.Get(url))
+	if err != nil {
+		return nil, err
+	}
+	crl, err = x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, err
+	}
+	if !crlMasihBerlaku(crl, issuer) {
+		return nil, errors.New("CRL tidak ditandatangani penerbit atau sudah kedaluwarsa")
+	}
+
+	cacheCRLMu.Lock()
+	cacheCRL[url] = crl
+	cacheCRLMu.Unlock()
+	return crl, nil
+}
+
+// cekOCSP menanyakan status sertifikat ke responder OCSP penerbit.
+// Respons diverifikasi terhadap penerbit (atau responder yang didelegasikan penerbit).
+func cekOCSP(url string, cert, issuer *x509.Certificate) (int, error) {
+	req, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return cabutTidakDiketahui, err
+	}
+	data, err := unduhCabut(klienCabut.Post(url, "application/ocsp-request", bytes.NewReader(req)))
+	if err != nil {
+		return cabutTidakDiketahui, err
+	}
+	resp, err := ocsp.ParseResponseForCert(data, cert, issuer)
+	if err != nil {
+		return cabutTidakDiketahui, err
+	}
+	if !resp.NextUpdate.IsZero() && time.Now().After(resp.NextUpdate) {
+		return cabutTidakDiketahui, errors.New("respons OCSP sudah kedaluwarsa")
+	}
+	switch resp.Status {
+	case ocsp.Good:
+		return cabutBaik, nil
+	case ocsp.Revoked:
+		return cabutDicabut, nil
+	}
+	return cabutTidakDiketahui, errors.New("status OCSP tidak diketahui")
+}
+
+func unduhCabut(resp *http.Response, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maksUnduhCabut))
+}
@@ -0,0 +1,368 @@
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Status tanda tangan elektronik dokumen PDF
+const (
+	TTDValid         = "valid"           // tanda tangan cocok, sertifikat dipercaya dan tidak dicabut (CRL/OCSP)
+	TTDBelumCekCabut = "belum-cek-cabut" // tanda tangan cocok dan sertifikat dipercaya, status pencabutan tidak bisa diperiksa
+	TTDKedaluwarsa   = "kedaluwarsa"     // tanda tangan cocok, sertifikat sudah kedaluwarsa tapi berlaku pada waktu tanda tangan
+	TTDTidakValid    = "tidak-valid"     // tanda tangan rusak, sertifikat tidak dipercaya atau dicabut
+	TTDTanpa         = "tanpa-ttd"       // PDF tidak punya tanda tangan elektronik
+	TTDDiubah        = "diubah"          // tanda tangan valid tapi ada perubahan setelah ditandatangani
+)
+
+// HasilTTD adalah ringkasan verifikasi tanda tangan satu PDF
+type HasilTTD struct {
+	Status        string
+	Penandatangan string     // CN sertifikat penanda tangan
+	Penerbit      string     // CN penerbit sertifikat (mis. BSrE)
+	WaktuTTD      *time.Time // signingTime dari penanda tangan sendiri, hanya informasi
+	JumlahTTD     int
+	Pesan         string
+}
+
+// ================= STRUKTUR CMS (PKCS#7) =================
+
+var (
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidRSAPSS        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}
+)
+
+var hashDariOID = map[string]crypto.Hash{
+	"1.3.14.3.2.26":          crypto.SHA1,
+	"2.16.840.1.101.3.4.2.1": crypto.SHA256,
+	"2.16.840.1.101.3.4.2.2": crypto.SHA384,
+	"2.16.840.1.101.3.4.2.3": crypto.SHA512,
+}
+
+type cmsContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type cmsSignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContent     cmsContentInfo
+	Certificates     asn1.RawValue   `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue   `asn1:"optional,tag:1"`
+	SignerInfos      []cmsSignerInfo `asn1:"set"`
+}
+
+type cmsIssuerSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+type cmsSignerInfo struct {
+	Version            int
+	SID                cmsIssuerSerial
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type cmsAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+// ================= TRUST STORE =================
+
+// folderTrustStore berisi sertifikat root/CA yang dipercaya (PEM atau DER), mis. root BSrE
+func folderTrustStore() string {
+	return envOr("TTD_TRUST_DIR", "truststore")
+}
+
+// MuatTrustStore membaca semua sertifikat .pem/.crt/.cer di folder trust store
+func MuatTrustStore() (*x509.CertPool, int, error) {
+	pool := x509.NewCertPool()
+	jumlah := 0
+
+	files, err := os.ReadDir(folderTrustStore())
+	if err != nil {
+		return pool, 0, err
+	}
+	for _, f := range files {
+		ext := strings.ToLower(filepath.Ext(f.Name()))
+		if f.IsDir() || (ext != ".pem" && ext != ".crt" && ext != ".cer") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(folderTrustStore(), f.Name()))
+		if err != nil {
+			continue
+		}
+		for {
+			var blok *pem.Block
+			blok, data = pem.Decode(data)
+			if blok == nil {
+				break
+			}
+			if cert, err := x509.ParseCertificate(blok.Bytes); err == nil {
+				pool.AddCert(cert)
+				jumlah++
+			}
+		}
+		// file DER tanpa header PEM
+		if cert, err := x509.ParseCertificate(data); err == nil {
+			pool.AddCert(cert)
+			jumlah++
+		}
+	}
+	return pool, jumlah, nil
+}
+
+// ================= VERIFIKASI =================
+
+var reByteRange = regexp.MustCompile(`/ByteRange\s*\[\s*(\d+)\s+(\d+)\s+(\d+)\s+(\d+)\s*\]`)
+
+// urutanStatusTTD dipakai untuk mengambil status terlemah dari beberapa tanda tangan
+var urutanStatusTTD = map[string]int{TTDValid: 0, TTDBelumCekCabut: 1, TTDKedaluwarsa: 2}
+
+// PeriksaTTDPDF mencari tanda tangan elektronik di PDF lalu memverifikasi isi, rantai sertifikat dan
+// status pencabutannya. Status akhir adalah status terlemah dari semua tanda tangan; "diubah" kalau ada
+// revisi setelah tanda tangan terakhir.
+func PeriksaTTDPDF(path string) (HasilTTD, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return HasilTTD{}, err
+	}
+
+	ranges := reByteRange.FindAllSubmatch(data, -1)
+	if len(ranges) == 0 {
+		return HasilTTD{Status: TTDTanpa}, nil
+	}
+
+	roots, _, _ := MuatTrustStore()
+	hasil := HasilTTD{Status: TTDValid, JumlahTTD: len(ranges)}
+	akhirTerjauh := 0
+
+	for i, m := range ranges {
+		var br [4]int
+		for j := range br {
+			br[j], _ = strconv.Atoi(string(m[j+1]))
+		}
+
+		info, err := verifikasiSatuTTD(data, br, roots)
+		if info.penandatangan != "" {
+			hasil.Penandatangan = info.penandatangan
+			hasil.Penerbit = info.penerbit
+			hasil.WaktuTTD = info.waktu
+		}
+		if err != nil {
+			hasil.Status = TTDTidakValid
+			hasil.Pesan = fmt.Sprintf("Tanda tangan ke-%d: %v", i+1, err)
+			return hasil, nil
+		}
+		if urutanStatusTTD[info.status] > urutanStatusTTD[hasil.Status] {
+			hasil.Status = info.status
+			hasil.Pesan = fmt.Sprintf("Tanda tangan ke-%d: %s", i+1, info.catatan)
+		} else if hasil.Pesan == "" {
+			hasil.Pesan = info.catatan
+		}
+		if akhir := br[2] + br[3]; akhir > akhirTerjauh {
+			akhirTerjauh = akhir
+		}
+	}
+
+	// byte setelah revisi yang ditandatangani = ada incremental update sesudahnya
+	if sisa := bytes.TrimSpace(data[akhirTerjauh:]); len(sisa) > 0 {
+		hasil.Status = TTDDiubah
+		hasil.Pesan = fmt.Sprintf("Ada %d byte perubahan setelah tanda tangan terakhir", len(data)-akhirTerjauh)
+	}
+	return hasil, nil
+}
+
+type infoTTD struct {
+	penandatangan string
+	penerbit      string
+	waktu         *time.Time
+	status        string // valid, belum-cek-cabut atau kedaluwarsa kalau tanda tangan sah
+	catatan       string
+}
+
+func verifikasiSatuTTD(data []byte, br [4]int, roots *x509.CertPool) (infoTTD, error) {
+	var info infoTTD
+	if br[0] != 0 || br[1] >= br[2] || br[2]+br[3] > len(data) {
+		return info, errors.New("ByteRange tidak valid")
+	}
+
+	// /Contents <hex> ada tepat di celah ByteRange
+	celah := bytes.TrimSpace(data[br[1]:br[2]])
+	if len(celah) < 2 || celah[0] != '<' || celah[len(celah)-1] != '>' {
+		return info, errors.New("isi tanda tangan tidak ditemukan")
+	}
+	der, err := hex.DecodeString(string(bytes.Join(bytes.Fields(celah[1:len(celah)-1]), nil)))
+	if err != nil {
+		return info, errors.New("isi tanda tangan bukan hex")
+	}
+
+	var ci cmsContentInfo
+	if _, err := asn1.Unmarshal(der, &ci); err != nil || !ci.ContentType.Equal(oidSignedData) {
+		return info, errors.New("format tanda tangan bukan PKCS#7/CAdES")
+	}
+	var sd cmsSignedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return info, fmt.Errorf("struktur SignedData rusak: %v", err)
+	}
+	if len(sd.EncapContent.Content.Bytes) > 0 {
+		return info, errors.New("tanda tangan non-detached (adbe.pkcs7.sha1) belum didukung")
+	}
+	if len(sd.SignerInfos) == 0 {
+		return info, errors.New("tidak ada data penanda tangan")
+	}
+	si := sd.SignerInfos[0]
+
+	var certs []*x509.Certificate
+	if len(sd.Certificates.Bytes) > 0 {
+		certs, err = x509.ParseCertificates(sd.Certificates.Bytes)
+		if err != nil {
+			return info, fmt.Errorf("sertifikat rusak: %v", err)
+		}
+	}
+	var signer *x509.Certificate
+	for _, c := range certs {
+		if c.SerialNumber.Cmp(si.SID.Serial) == 0 && bytes.Equal(c.RawIssuer, si.SID.Issuer.FullBytes) {
+			signer = c
+			break
+		}
+	}
+	if signer == nil {
+		return info, errors.New("sertifikat penanda tangan tidak disertakan")
+	}
+	info.penandatangan = signer.Subject.CommonName
+	info.penerbit = signer.Issuer.CommonName
+
+	h, ok := hashDariOID[si.DigestAlgorithm.Algorithm.String()]
+	if !ok {
+		return info, fmt.Errorf("algoritma hash %s tidak didukung", si.DigestAlgorithm.Algorithm)
+	}
+	hasher := h.New()
+	hasher.Write(data[br[0] : br[0]+br[1]])
+	hasher.Write(data[br[2] : br[2]+br[3]])
+	digestIsi := hasher.Sum(nil)
+
+	// dengan signed attributes, yang ditandatangani adalah attribute-nya (tag diganti jadi SET)
+	ditandatangani := digestIsi
+	if len(si.SignedAttrs.FullBytes) > 0 {
+		var digestAttr []byte
+		sisa := si.SignedAttrs.Bytes
+		for len(sisa) > 0 {
+			var attr cmsAttribute
+			if sisa, err = asn1.Unmarshal(sisa, &attr); err != nil {
+				return info, errors.New("signed attributes rusak")
+			}
+			switch {
+			case attr.Type.Equal(oidMessageDigest):
+				asn1.Unmarshal(attr.Values.Bytes, &digestAttr)
+			case attr.Type.Equal(oidSigningTime):
+				var t time.Time
+				if _, err := asn1.Unmarshal(attr.Values.Bytes, &t); err == nil {
+					info.waktu = &t
+				}
+			}
+		}
+		if !bytes.Equal(digestAttr, digestIsi) {
+			return info, errors.New("isi dokumen tidak cocok dengan tanda tangan")
+		}
+
+		attrs := append([]byte{}, si.SignedAttrs.FullBytes...)
+		attrs[0] = 0x31
+		hasher = h.New()
+		hasher.Write(attrs)
+		ditandatangani = hasher.Sum(nil)
+	}
+
+	if si.SignatureAlgorithm.Algorithm.Equal(oidRSAPSS) {
+		return info, errors.New("tanda tangan RSA-PSS belum didukung")
+	}
+	switch pub := signer.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(pub, h, ditandatangani, si.Signature); err != nil {
+			return info, errors.New("tanda tangan tidak cocok dengan sertifikat")
+		}
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, ditandatangani, si.Signature) {
+			return info, errors.New("tanda tangan tidak cocok dengan sertifikat")
+		}
+	default:
+		return info, errors.New("jenis kunci sertifikat tidak didukung")
+	}
+
+	// rantai sertifikat dicek pada waktu sekarang. signingTime ditulis sendiri oleh penanda tangan,
+	// jadi hanya dipakai untuk membedakan sertifikat yang sudah kedaluwarsa dari yang tidak pernah sah
+	// (belum ada dukungan timestamp RFC 3161 dari TSA terpercaya).
+	intermediates := x509.NewCertPool()
+	for _, c := range certs {
+		if c != signer {
+			intermediates.AddCert(c)
+		}
+	}
+	opsi := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   time.Now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	chains, err := signer.Verify(opsi)
+	if err != nil {
+		var invalid x509.CertificateInvalidError
+		if !errors.As(err, &invalid) || invalid.Reason != x509.Expired || info.waktu == nil {
+			return info, fmt.Errorf("sertifikat tidak dipercaya oleh trust store: %v", err)
+		}
+		opsi.CurrentTime = *info.waktu
+		if _, err := signer.Verify(opsi); err != nil {
+			return info, fmt.Errorf("sertifikat sudah kedaluwarsa dan tidak berlaku pada waktu tanda tangan: %v", err)
+		}
+		info.status = TTDKedaluwarsa
+		info.catatan = fmt.Sprintf("Sertifikat berlaku sampai %s; waktu tanda tangan %s hanya menurut penanda tangan",
+			signer.NotAfter.Format("02-01-2006"), info.waktu.Format("02-01-2006 15:04"))
+		return info, nil
+	}
+
+	// penerbit langsung sertifikat penanda tangan ada di posisi kedua rantai
+	if len(chains) == 0 || len(chains[0]) < 2 {
+		info.status = TTDBelumCekCabut
+		info.catatan = "Sertifikat penanda tangan tidak punya penerbit untuk pemeriksaan pencabutan"
+		return info, nil
+	}
+	status, sumber := periksaPencabutan(signer, chains[0][1])
+	switch status {
+	case cabutDicabut:
+		return info, fmt.Errorf("sertifikat penanda tangan sudah dicabut (%s)", sumber)
+	case cabutBaik:
+		info.status = TTDValid
+		info.catatan = "Sertifikat tidak dicabut menurut " + sumber
+	default:
+		info.status = TTDBelumCekCabut
+		info.catatan = "Status pencabutan belum diperiksa: " + sumber
+	}
+	return info, nil
+}
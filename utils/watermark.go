@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// WatermarkPDF menempelkan teks semi-transparan di footer setiap halaman PDF.
+// Halaman stempel dibuat dengan gofpdf lalu ditumpuk ke tiap halaman dengan qpdf --overlay
+// (binary bisa diatur lewat env QPDF_BIN). File asli tidak diubah; hasilnya file sementara
+// yang wajib dihapus pemanggil.
+func WatermarkPDF(src, teks string) (string, error) {
+	tmp, err := os.MkdirTemp("", "watermark-*")
+	if err != nil {
+		return "", err
+	}
+
+	stempel := filepath.Join(tmp, "stempel.pdf")
+	if err := buatStempel(stempel, teks); err != nil {
+		os.RemoveAll(tmp)
+		return "", fmt.Errorf("gagal membuat stempel: %v", err)
+	}
+
+	hasil, err := os.CreateTemp("", "dokumen-*.pdf")
+	if err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+	hasil.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	var errOut strings.Builder
+	cmd := exec.CommandContext(ctx, envOr("QPDF_BIN", "qpdf"), "--overlay", stempel, "--repeat=1", "--", src, hasil.Name())
+	cmd.Stderr = &errOut
+	err = cmd.Run()
+	os.RemoveAll(tmp)
+
+	// exit code 3 = berhasil dengan peringatan (mis. xref PDF sedikit rusak)
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 3) {
+		os.Remove(hasil.Name())
+		return "", fmt.Errorf("qpdf: %v %s", err, strings.TrimSpace(errOut.String()))
+	}
+	return hasil.Name(), nil
+}
+
+// buatStempel membuat satu halaman A4 kosong berisi teks footer abu-abu transparan.
+// qpdf menyesuaikan ukuran stempel dengan ukuran tiap halaman dokumen.
+func buatStempel(path, teks string) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetAlpha(0.45, "Normal")
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.SetTextColor(90, 90, 90)
+	pdf.SetXY(10, 286)
+	pdf.CellFormat(190, 5, tr(teks), "", 0, "C", false, 0, "")
+
+	return pdf.OutputFileAndClose(path)
+}